@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// atomicSuffix names the sibling a final output is staged in until it is
+// complete.
+const atomicSuffix = ".tmp"
+
+// writeFileAtomic writes path through a "<path>.tmp" sibling that is renamed
+// over path only after write returned nil and the data was flushed, synced
+// and closed. On any error the sibling is removed and path is left as it
+// was, so a crash or failed write never leaves a truncated report that a
+// later run without -force would skip as done. The sibling is registered
+// with the temp-file registry, so fatalf and panics clean it up too.
+func writeFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	tmp := path + atomicSuffix
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	tempFiles.track(tmp)
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			removeTempFile(tmp)
+		}
+	}()
+
+	w := bufio.NewWriterSize(f, writerBufferSize)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename %s: %w", tmp, err)
+	}
+	tempFiles.untrack(tmp)
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicErrorLeavesNoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	injected := errors.New("disk full")
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "{\"partial\":"); err != nil {
+			return err
+		}
+		return injected
+	})
+	if !errors.Is(err, injected) {
+		t.Fatalf("err=%v want injected error", err)
+	}
+	if fileExists(path) {
+		t.Fatalf("%s should not exist after a failed write", path)
+	}
+	if fileExists(path + atomicSuffix) {
+		t.Fatalf("temp sibling left behind")
+	}
+}
+
+func TestWriteFileAtomicReplacesOnSuccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "SHA256SUMS.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("write old: %v", err)
+	}
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		return errors.New("boom")
+	}); err == nil {
+		t.Fatalf("expected error")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "old\n" {
+		t.Fatalf("failed write clobbered existing file: %q", string(data))
+	}
+
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new\n")
+		return err
+	}); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != "new\n" {
+		t.Fatalf("content=%q want new", string(data))
+	}
+}
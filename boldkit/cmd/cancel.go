@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitInterrupted is the exit status after SIGINT/SIGTERM (128 + SIGINT).
+const exitInterrupted = 130
+
+// runCtx is canceled by the first SIGINT or SIGTERM once Execute installs
+// the handler. Record loops poll it through interrupted, row readers through
+// Options.Context, and openInput streams, archive and checksum copies and
+// taxdump loads read through contextReader, so a Ctrl-C stops any stage and
+// unwinds through the deferred flushes and closes.
+var runCtx = context.Background()
+
+// partialOutputs holds output files that are still being written. fatalf
+// removes them when the run was interrupted; keepOutputs releases them once
+// a stage finishes.
+var partialOutputs = &tempRegistry{paths: make(map[string]struct{})}
+
+// installSignalCancel points runCtx at a context canceled on SIGINT/SIGTERM.
+// After the first signal the default handling is restored, so a second
+// Ctrl-C kills the process outright.
+func installSignalCancel() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	runCtx = ctx
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+}
+
+// interrupted returns a context.Canceled error once runCtx is done.
+func interrupted() error {
+	return canceled(runCtx)
+}
+
+// canceled is interrupted for an explicit context.
+func canceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return nil
+}
+
+// ctxReader fails every Read once ctx is done, so the io.Copy or scanner
+// draining it stops at its next read.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func contextReader(ctx context.Context, r io.Reader) io.Reader {
+	return ctxReader{ctx: ctx, r: r}
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := canceled(r.ctx); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// createOutput is os.Create for an output that should not survive an
+// interrupted run.
+func createOutput(path string) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	partialOutputs.track(path)
+	return f, nil
+}
+
+// keepOutputs marks outputs as complete so an interrupt in a later stage
+// leaves them in place.
+func keepOutputs(paths ...string) {
+	for _, path := range paths {
+		partialOutputs.untrack(path)
+	}
+}
+
+// removePartialOutputs deletes every output still being written.
+func removePartialOutputs() {
+	partialOutputs.cleanup()
+}
+
+// interruptedIn reports whether any error in args wraps a context
+// cancellation.
+func interruptedIn(args []any) bool {
+	for _, a := range args {
+		if err, ok := a.(error); ok && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countdownCtx reports cancellation after Err has been polled n times.
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestFormatInterruptedRemovesPartialOutputs(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n>P1\nGGCC\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	prev := runCtx
+	runCtx = &countdownCtx{Context: context.Background(), n: 1}
+	defer func() {
+		runCtx = prev
+	}()
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"blast", "sintax"},
+		RequireRanks: []string{"genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("formatFasta err=%v want context.Canceled", err)
+	}
+	if !interruptedIn([]any{err}) {
+		t.Fatalf("interruptedIn should recognise %v", err)
+	}
+	removePartialOutputs()
+
+	err = filepath.Walk(outDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".fasta") {
+			t.Errorf("partial output left behind: %s", path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("walk output: %v", err)
+	}
+}
+
+func TestKeepOutputsSurvivesInterrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "done.fasta")
+	f, err := createOutput(path)
+	if err != nil {
+		t.Fatalf("createOutput: %v", err)
+	}
+	_ = f.Close()
+	keepOutputs(path)
+	removePartialOutputs()
+	if !fileExists(path) {
+		t.Fatalf("kept output %s was removed", path)
+	}
+}
+
+func TestPackageInterruptedRemovesPartialArchive(t *testing.T) {
+	tmp := t.TempDir()
+	srcDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(srcDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeTestFasta(t, filepath.Join(srcDir, "COI-5P.fasta"), ">P1\nACGT\n")
+	dest := filepath.Join(tmp, "releases", "markers.tar.gz")
+
+	prev := runCtx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runCtx = ctx
+	defer func() {
+		runCtx = prev
+	}()
+
+	err := packageDirGzip(srcDir, dest, false, false, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("packageDirGzip err=%v want context.Canceled", err)
+	}
+	removePartialOutputs()
+	if fileExists(dest) {
+		t.Fatalf("partial archive left behind: %s", dest)
+	}
+}
+
+func TestParseCSVRowsHonorsOptionsContext(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(input, []byte("processid,species\nP1,Homo sapiens\nP2,Canis lupus\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.Context = &countdownCtx{Context: context.Background(), n: 2}
+	rows := 0
+	err := ParseRows(input, opts, func(Row) error {
+		rows++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseRows err=%v want context.Canceled", err)
+	}
+	// The header and P1 are delivered before the third poll cancels.
+	if rows != 2 {
+		t.Fatalf("rows=%d want 2 before the cancel", rows)
+	}
+}
@@ -22,9 +22,23 @@ func runClassify(args []string) {
 	qcMaxN := fs.Int("qc-max-n", 0, "QC maximum N count")
 	qcMaxAmbig := fs.Int("qc-max-ambig", 0, "QC maximum IUPAC ambiguous count")
 	qcMaxInvalid := fs.Int("qc-max-invalid", 0, "QC maximum invalid character count")
+	qcMinGC := fs.Float64("qc-min-gc", 0, "QC minimum GC percent (0 disables)")
+	qcMaxGC := fs.Float64("qc-max-gc", 100, "QC maximum GC percent (100 disables)")
+	qcNormalizeCase := fs.Bool("qc-normalize-case", false, "QC uppercase every base before checks")
+	qcStripGaps := fs.Bool("qc-strip-gaps", false, "QC remove '-' and '.' gap characters before checks")
+	qcTrimTerminalN := fs.Bool("qc-trim-terminal-n", false, "QC strip leading/trailing N and '-' before length and N checks")
+	qcForwardPrimer := fs.String("qc-forward-primer", "", "QC IUPAC forward primer to trim from the 5' end")
+	qcReversePrimer := fs.String("qc-reverse-primer", "", "QC IUPAC reverse primer (5'->3') to trim from the 3' end")
+	qcPrimerMismatches := fs.Int("qc-primer-mismatches", 2, "QC mismatches allowed when locating primers")
+	qcPrimerMaxOffset := fs.Int("qc-primer-max-offset", defaultPrimerMaxOffset, "QC bases a primer match may sit from its end of the read")
+	qcDropMissingPrimer := fs.Bool("qc-drop-missing-primer", false, "QC drop records where a configured primer is not found")
+	qcMaxHomopolymer := fs.Int("qc-max-homopolymer", 0, "QC maximum single-base run length (0 disables)")
 	qcDedupe := fs.Bool("qc-dedupe", true, "QC drop duplicate sequences")
+	qcDedupeRevComp := fs.Bool("qc-dedupe-revcomp", false, "QC also treat reverse complements as duplicates")
 	qcDedupeIDs := fs.Bool("qc-dedupe-ids", true, "QC drop duplicate IDs")
 	qcProgress := fs.Bool("qc-progress", true, "Show QC progress bar (approximate)")
+	qcWorkers := fs.Int("qc-workers", 1, "QC goroutines for per-record sequence checks (output order follows global -keep-order)")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
 	formatProgress := fs.Bool("format-progress", true, "Show format progress bar (approximate)")
 	qcOnly := fs.Bool("qc-only", false, "Run QC only (skip classifier formatting)")
 	compress := fs.Bool("compress", false, "Compress classifier output directories (.tar.gz)")
@@ -38,6 +52,46 @@ func runClassify(args []string) {
 	if len(classifierList) == 0 {
 		fatalf("classifier must not be empty")
 	}
+	if err := validateGCBounds(*qcMinGC, qcMaxGC); err != nil {
+		fatalf("%v", err)
+	}
+	emptyPolicy, err := normalizeEmptySeqPolicy(*onEmptySeq)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *qcMaxHomopolymer < 0 {
+		fatalf("qc-max-homopolymer must be >= 0")
+	}
+	if err := validatePrimerConfig("qc-", normalizePrimer(*qcForwardPrimer), normalizePrimer(*qcReversePrimer), *qcPrimerMismatches, *qcPrimerMaxOffset); err != nil {
+		fatalf("%v", err)
+	}
+	qcCfg := QCConfig{
+		MinLen:            *qcMin,
+		MaxLen:            *qcMax,
+		MaxN:              *qcMaxN,
+		MaxAmbig:          *qcMaxAmbig,
+		MaxInvalid:        *qcMaxInvalid,
+		MinGC:             *qcMinGC,
+		MaxGC:             qcMaxGC,
+		MaxHomopolymer:    *qcMaxHomopolymer,
+		NormalizeCase:     *qcNormalizeCase,
+		StripGaps:         *qcStripGaps,
+		TrimTerminalN:     *qcTrimTerminalN,
+		ForwardPrimer:     normalizePrimer(*qcForwardPrimer),
+		ReversePrimer:     normalizePrimer(*qcReversePrimer),
+		PrimerMismatches:  *qcPrimerMismatches,
+		PrimerMaxOffset:   *qcPrimerMaxOffset,
+		DropMissingPrimer: *qcDropMissingPrimer,
+		DedupeSeqs:        *qcDedupe,
+		DedupeRevComp:     *qcDedupeRevComp,
+		DedupeIDs:         *qcDedupeIDs,
+		RequireRanks:      ranks,
+		TaxdumpDir:        *taxdumpDir,
+		TaxidMapPath:      *taxidMap,
+		Progress:          *qcProgress,
+		OnEmptySeq:        emptyPolicy,
+		Workers:           *qcWorkers,
+	}
 
 	if *input == "" {
 		markerList := splitList(*markers)
@@ -50,35 +104,22 @@ func runClassify(args []string) {
 				fatalf("marker %s: %v", marker, err)
 			}
 			baseOut := filepath.Join(*outDir, safeTag(marker))
-			if err := classifyOne(markerInput, baseOut, classifierList, ranks, *taxdumpDir, *taxidMap, *qcMin, *qcMax, *qcMaxN, *qcMaxAmbig, *qcMaxInvalid, *qcDedupe, *qcDedupeIDs, *qcProgress, *formatProgress, *qcOnly, *compress, *force); err != nil {
+			if err := classifyOne(markerInput, baseOut, classifierList, ranks, *taxdumpDir, *taxidMap, qcCfg, *formatProgress, *qcOnly, *compress, *force); err != nil {
 				fatalf("classify %s failed: %v", marker, err)
 			}
 		}
 		return
 	}
 
-	if err := classifyOne(*input, *outDir, classifierList, ranks, *taxdumpDir, *taxidMap, *qcMin, *qcMax, *qcMaxN, *qcMaxAmbig, *qcMaxInvalid, *qcDedupe, *qcDedupeIDs, *qcProgress, *formatProgress, *qcOnly, *compress, *force); err != nil {
+	if err := classifyOne(*input, *outDir, classifierList, ranks, *taxdumpDir, *taxidMap, qcCfg, *formatProgress, *qcOnly, *compress, *force); err != nil {
 		fatalf("classify failed: %v", err)
 	}
 }
 
-func classifyOne(input, outDir string, classifierList, ranks []string, taxdumpDir, taxidMap string, qcMin, qcMax, qcMaxN, qcMaxAmbig, qcMaxInvalid int, qcDedupe, qcDedupeIDs, qcProgress, formatProgress, qcOnly, compress, force bool) error {
+func classifyOne(input, outDir string, classifierList, ranks []string, taxdumpDir, taxidMap string, qcCfg QCConfig, formatProgress, qcOnly, compress, force bool) error {
 	base := qcBaseName(input)
 	qcOut := filepath.Join(outDir, "qc", base+".fasta")
-	qcCfg := qcConfig{
-		MinLen:       qcMin,
-		MaxLen:       qcMax,
-		MaxN:         qcMaxN,
-		MaxAmbig:     qcMaxAmbig,
-		MaxInvalid:   qcMaxInvalid,
-		DedupeSeqs:   qcDedupe,
-		DedupeIDs:    qcDedupeIDs,
-		RequireRanks: ranks,
-		TaxdumpDir:   taxdumpDir,
-		TaxidMapPath: taxidMap,
-		OutputPath:   qcOut,
-		Progress:     qcProgress,
-	}
+	qcCfg.OutputPath = qcOut
 
 	logf("QC -> %s", qcOut)
 	if err := qcFasta(input, qcCfg); err != nil {
@@ -103,6 +144,7 @@ func classifyOne(input, outDir string, classifierList, ranks []string, taxdumpDi
 			TaxdumpDir:   taxdumpDir,
 			TaxidMapPath: taxidMap,
 			Progress:     formatProgress,
+			OnEmptySeq:   qcCfg.OnEmptySeq,
 		}
 		logf("Format %s -> %s", name, outPath)
 		if err := formatFasta(cfg); err != nil {
@@ -111,7 +153,7 @@ func classifyOne(input, outDir string, classifierList, ranks []string, taxdumpDi
 
 		if compress {
 			archive := filepath.Join(outDir, name+".tar.gz")
-			if err := packageDirGzip(outPath, archive, force); err != nil {
+			if err := packageDirGzip(outPath, archive, force, false, 0); err != nil {
 				return fmt.Errorf("compress %s failed: %w", name, err)
 			}
 		}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type convertConfig struct {
+	Input string
+	// From is the source format: sintax, rdp or idtaxa.
+	From string
+	// LineagePath is the idtaxa lineage TSV (defaults to idtaxa_lineage.tsv
+	// next to Input).
+	LineagePath string
+	Classifiers []string
+	// Ranks labels the lineage levels for RDP taxonomy output.
+	Ranks      []string
+	OutDir     string
+	Subdirs    bool
+	OnEmptySeq string
+}
+
+// convertTargets are the classifier outputs that need only names, not
+// taxids, and so can be rebuilt from another classifier's lineage.
+var convertTargets = map[string]struct{}{
+	"sintax": {},
+	"rdp":    {},
+	"idtaxa": {},
+	"protax": {},
+	"qiime2": {},
+	"dada2":  {},
+	"mothur": {},
+}
+
+// convertTargetNames returns the convertTargets in formatClassifiers order.
+func convertTargetNames() []string {
+	names := make([]string, 0, len(convertTargets))
+	for _, name := range formatClassifiers {
+		if _, ok := convertTargets[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	input := fs.String("input", "", "Formatted reference FASTA (sintax.fasta, rdp_train_seqs.fasta or idtaxa_seqs.fasta)")
+	from := fs.String("from", "", "Source format: sintax, rdp or idtaxa")
+	lineage := fs.String("lineage", "", "idtaxa lineage TSV (default: idtaxa_lineage.tsv next to -input)")
+	classifiers := fs.String("classifier", "rdp", "Comma-separated target classifiers ("+strings.Join(convertTargetNames(), ",")+")")
+	ranks := fs.String("ranks", "kingdom,phylum,class,order,family,genus,species", "Comma-separated rank names for the lineage levels (RDP taxonomy labels)")
+	outDir := fs.String("outdir", "converted", "Output directory")
+	subdirs := fs.Bool("subdirs", false, "Write each classifier's outputs to its own subdirectory of -outdir")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	if *input == "" {
+		fatalf("input is required")
+	}
+	policy, err := normalizeEmptySeqPolicy(*onEmptySeq)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	cfg := convertConfig{
+		Input:       *input,
+		From:        strings.ToLower(strings.TrimSpace(*from)),
+		LineagePath: *lineage,
+		Classifiers: splitList(*classifiers),
+		Ranks:       splitList(*ranks),
+		OutDir:      *outDir,
+		Subdirs:     *subdirs,
+		OnEmptySeq:  policy,
+	}
+	if err := convertFormat(cfg); err != nil {
+		fatalf("convert failed: %v", err)
+	}
+}
+
+func convertFormat(cfg convertConfig) error {
+	if len(cfg.Classifiers) == 0 {
+		return fmt.Errorf("classifier must not be empty")
+	}
+	wantRdp := false
+	for _, c := range cfg.Classifiers {
+		name := strings.ToLower(strings.TrimSpace(c))
+		if _, ok := convertTargets[name]; !ok {
+			return fmt.Errorf("cannot convert to %q: only %s can be built without taxids", c, strings.Join(convertTargetNames(), ", "))
+		}
+		if name == "rdp" {
+			wantRdp = true
+		}
+	}
+
+	namesFor, err := convertSource(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return fmt.Errorf("create outdir: %w", err)
+	}
+	writers, err := openFormatWriters(cfg.OutDir, cfg.Classifiers, cfg.Subdirs, 0)
+	if err != nil {
+		return err
+	}
+	defer closeFormatWriters(writers)
+	writers.sintaxPrefixes = convertSintaxPrefixes(cfg.Ranks)
+
+	in, err := openInput(cfg.Input)
+	if err != nil {
+		return fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	var written, emptySeq int
+	err = parseFasta(in, withEmptySeqPolicy(cfg.OnEmptySeq, &emptySeq, func(rec fastaRecord) error {
+		id, names, err := namesFor(rec)
+		if err != nil {
+			return err
+		}
+		if wantRdp && len(names) > len(cfg.Ranks) {
+			return fmt.Errorf("record %q: %d lineage levels but only %d -ranks for rdp", id, len(names), len(cfg.Ranks))
+		}
+		if err := writers.writeLineageOutputs(id, names, rec.seq); err != nil {
+			return err
+		}
+		written++
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	if wantRdp {
+		if err := writeRdpFromFasta(cfg.Input, cfg.OnEmptySeq, cfg.Ranks, nil, writers, namesFor); err != nil {
+			return fmt.Errorf("rdp format: %w", err)
+		}
+	}
+
+	logf("convert: %s -> %s records=%d", cfg.From, strings.Join(cfg.Classifiers, ","), written)
+	if emptySeq > 0 {
+		logf("convert: skipped %d empty-sequence records", emptySeq)
+	}
+	writers.keep()
+	return nil
+}
+
+// convertSource returns the per-record lineage parser for cfg.From. Every
+// parsed lineage must re-render to the exact input string.
+func convertSource(cfg convertConfig) (func(fastaRecord) (string, []string, error), error) {
+	switch cfg.From {
+	case "sintax":
+		prefixes := convertSintaxPrefixes(cfg.Ranks)
+		return func(rec fastaRecord) (string, []string, error) {
+			id, tax, ok := strings.Cut(rec.id, ";tax=")
+			if !ok {
+				return "", nil, fmt.Errorf("record %q: missing ;tax= lineage", rec.id)
+			}
+			names, err := parseSintaxLineage(strings.TrimSuffix(tax, ";"), prefixes)
+			if err != nil {
+				return "", nil, fmt.Errorf("record %q: %w", id, err)
+			}
+			return id, names, nil
+		}, nil
+	case "rdp":
+		return func(rec fastaRecord) (string, []string, error) {
+			fields := strings.Fields(rec.header)
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("record %q: missing RDP lineage", rec.id)
+			}
+			names, err := parseRootedLineage(fields[1])
+			if err != nil {
+				return "", nil, fmt.Errorf("record %q: %w", rec.id, err)
+			}
+			return rec.id, names, nil
+		}, nil
+	case "idtaxa":
+		path := cfg.LineagePath
+		if path == "" {
+			path = filepath.Join(filepath.Dir(cfg.Input), "idtaxa_lineage.tsv")
+		}
+		lineages, err := loadIDTaxaLineages(path)
+		if err != nil {
+			return nil, err
+		}
+		return func(rec fastaRecord) (string, []string, error) {
+			names, ok := lineages[rec.id]
+			if !ok {
+				return "", nil, fmt.Errorf("record %q: not in %s", rec.id, filepath.Base(path))
+			}
+			return rec.id, names, nil
+		}, nil
+	case "":
+		return nil, fmt.Errorf("from is required (sintax, rdp or idtaxa)")
+	}
+	return nil, fmt.Errorf("unsupported source format %q (want sintax, rdp or idtaxa)", cfg.From)
+}
+
+// convertSintaxPrefixes is the prefix set format writes for ranks (see
+// sintaxPrefixesFor), or nil for the positional sintaxPrefixes when ranks
+// are standard ones in standard order, which also admits a trailing t level.
+func convertSintaxPrefixes(ranks []string) []string {
+	prefixes := sintaxPrefixesFor(ranks)
+	if len(prefixes) > len(sintaxPrefixes) {
+		return prefixes
+	}
+	for i, p := range prefixes {
+		if p != sintaxPrefixes[i] {
+			return prefixes
+		}
+	}
+	return nil
+}
+
+// parseSintaxLineage reverses sintaxLineage: "d:A,p:B,..." with the given
+// per-level prefixes (nil means sintaxPrefixes) in order and no gaps.
+func parseSintaxLineage(tax string, prefixes []string) ([]string, error) {
+	if prefixes == nil {
+		prefixes = sintaxPrefixes
+	}
+	parts := strings.Split(tax, ",")
+	names := make([]string, 0, len(parts))
+	for i, part := range parts {
+		prefix, name, ok := strings.Cut(part, ":")
+		if i >= len(prefixes) || !ok || prefix != prefixes[i] || name == "" {
+			want := "no further ranks"
+			if i < len(prefixes) {
+				want = prefixes[i] + ":<name>"
+			}
+			return nil, fmt.Errorf("sintax lineage %q: level %d is %q, want %s", tax, i+1, part, want)
+		}
+		names = append(names, name)
+	}
+	if err := checkConvertNames(names); err != nil {
+		return nil, fmt.Errorf("sintax lineage %q: %w", tax, err)
+	}
+	if sintaxLineage(names, prefixes) != tax {
+		return nil, fmt.Errorf("sintax lineage %q does not round-trip", tax)
+	}
+	return names, nil
+}
+
+// parseRootedLineage reverses the "Root;A;B;..." lineage shared by the RDP
+// training FASTA and the idtaxa lineage TSV.
+func parseRootedLineage(lineage string) ([]string, error) {
+	parts := strings.Split(lineage, ";")
+	if len(parts) < 2 || parts[0] != "Root" {
+		return nil, fmt.Errorf("lineage %q: expected Root;<names>", lineage)
+	}
+	names := parts[1:]
+	if err := checkConvertNames(names); err != nil {
+		return nil, fmt.Errorf("lineage %q: %w", lineage, err)
+	}
+	if "Root;"+strings.Join(names, ";") != lineage {
+		return nil, fmt.Errorf("lineage %q does not round-trip", lineage)
+	}
+	return names, nil
+}
+
+// checkConvertNames rejects names the writers would alter: empty levels and
+// anything sanitizeTaxon would rewrite.
+func checkConvertNames(names []string) error {
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("level %d is empty", i+1)
+		}
+		if sanitizeTaxon(name) != name {
+			return fmt.Errorf("name %q does not round-trip (sanitized to %q)", name, sanitizeTaxon(name))
+		}
+	}
+	return nil
+}
+
+func loadIDTaxaLineages(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open idtaxa lineage: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	out := make(map[string][]string, 1<<16)
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		id, lineage, ok := strings.Cut(text, "\t")
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: expected id<TAB>lineage", filepath.Base(path), line)
+		}
+		names, err := parseRootedLineage(lineage)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filepath.Base(path), line, err)
+		}
+		out[id] = names
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan idtaxa lineage: %w", err)
+	}
+	return out, nil
+}
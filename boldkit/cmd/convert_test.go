@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertSintaxToRdpAndIdtaxa(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "sintax.fasta")
+	writeTestFasta(t, input, ">P1;tax=d:Animalia,p:Chordata,c:Mammalia,o:Primates,f:Hominidae,g:Homo,s:Homo_sapiens\nACGT\n"+
+		">P2;tax=d:Animalia,p:Chordata,c:Mammalia,o:Carnivora,f:Canidae,g:Canis,s:Canis_lupus\nGGCC\n")
+	outDir := filepath.Join(tmp, "out")
+
+	cfg := convertConfig{
+		Input:       input,
+		From:        "sintax",
+		Classifiers: []string{"rdp", "idtaxa"},
+		Ranks:       splitList("kingdom,phylum,class,order,family,genus,species"),
+		OutDir:      outDir,
+		OnEmptySeq:  emptySeqSkip,
+	}
+	if err := convertFormat(cfg); err != nil {
+		t.Fatalf("convertFormat failed: %v", err)
+	}
+	rdp, err := os.ReadFile(filepath.Join(outDir, "rdp_train_seqs.fasta"))
+	if err != nil {
+		t.Fatalf("read rdp: %v", err)
+	}
+	if !strings.Contains(string(rdp), ">P1\tRoot;Animalia;Chordata;Mammalia;Primates;Hominidae;Homo;Homo_sapiens\nACGT\n") {
+		t.Fatalf("unexpected rdp output:\n%s", string(rdp))
+	}
+	lineage, err := os.ReadFile(filepath.Join(outDir, "idtaxa_lineage.tsv"))
+	if err != nil {
+		t.Fatalf("read idtaxa lineage: %v", err)
+	}
+	if !strings.Contains(string(lineage), "P2\tRoot;Animalia;Chordata;Mammalia;Carnivora;Canidae;Canis;Canis_lupus\n") {
+		t.Fatalf("unexpected idtaxa lineage:\n%s", string(lineage))
+	}
+
+	// Round-trip the idtaxa output back to sintax.
+	back := filepath.Join(tmp, "back")
+	cfg = convertConfig{
+		Input:       filepath.Join(outDir, "idtaxa_seqs.fasta"),
+		From:        "idtaxa",
+		Classifiers: []string{"sintax"},
+		OutDir:      back,
+		OnEmptySeq:  emptySeqSkip,
+	}
+	if err := convertFormat(cfg); err != nil {
+		t.Fatalf("convertFormat idtaxa failed: %v", err)
+	}
+	sintax, err := os.ReadFile(filepath.Join(back, "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	orig, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("read input: %v", err)
+	}
+	if string(sintax) != string(orig) {
+		t.Fatalf("sintax round-trip mismatch:\n%s", string(sintax))
+	}
+}
+
+func TestConvertSintaxRankPrefixes(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "sintax.fasta")
+	want := ">P1;tax=d:Animalia,f:Nymphalidae,u:Satyrinae,r:Satyrini,g:Erebia,s:Erebia_aethiops\nACGT\n"
+	writeTestFasta(t, input, want)
+	outDir := filepath.Join(tmp, "out")
+
+	cfg := convertConfig{
+		Input:       input,
+		From:        "sintax",
+		Classifiers: []string{"sintax", "rdp"},
+		Ranks:       splitList("kingdom,family,subfamily,tribe,genus,species"),
+		OutDir:      outDir,
+		OnEmptySeq:  emptySeqSkip,
+	}
+	if err := convertFormat(cfg); err != nil {
+		t.Fatalf("convertFormat failed: %v", err)
+	}
+	rdp, err := os.ReadFile(filepath.Join(outDir, "rdp_train_seqs.fasta"))
+	if err != nil {
+		t.Fatalf("read rdp: %v", err)
+	}
+	if !strings.Contains(string(rdp), ">P1\tRoot;Animalia;Nymphalidae;Satyrinae;Satyrini;Erebia;Erebia_aethiops\n") {
+		t.Fatalf("unexpected rdp output:\n%s", string(rdp))
+	}
+	sintax, err := os.ReadFile(filepath.Join(outDir, "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	if string(sintax) != want {
+		t.Fatalf("sintax did not round-trip:\n%s", string(sintax))
+	}
+
+	// Positional prefixes do not name a tribe level.
+	cfg.Ranks = nil
+	cfg.Classifiers = []string{"sintax"}
+	cfg.OutDir = filepath.Join(tmp, "positional")
+	if err := convertFormat(cfg); err == nil {
+		t.Fatal("expected positional parse to reject u: and r: levels")
+	}
+}
+
+func TestConvertRejectsNonRoundTripLineage(t *testing.T) {
+	for _, tax := range []string{
+		"d:Animalia,c:Mammalia",
+		"d:Animalia,p:",
+		"d:Animalia,p:Chordata phylum",
+	} {
+		if _, err := parseSintaxLineage(tax, nil); err == nil {
+			t.Fatalf("expected error for %q", tax)
+		}
+	}
+	if _, err := parseRootedLineage("Animalia;Chordata"); err == nil {
+		t.Fatalf("expected error for lineage without Root")
+	}
+	if err := convertFormat(convertConfig{From: "sintax", Classifiers: []string{"blast"}}); err == nil {
+		t.Fatalf("expected error for taxid-based target")
+	}
+}
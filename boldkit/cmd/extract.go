@@ -2,48 +2,162 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 const writerBufferSize = 1 << 20
 
+// extractOptions holds buildTaxonkit output settings that are independent of
+// the curation protocol.
+type extractOptions struct {
+	// EmitMarker appends a marker_code column (from marker_code/markercode;
+	// "unknown" when empty). Off keeps the ten-column header contract.
+	EmitMarker bool
+	// FilterCountries and FilterInstitutions are allowlists matched
+	// case-insensitively against "country/ocean" and "inst"; rows outside a
+	// non-empty list are skipped.
+	FilterCountries    []string
+	FilterInstitutions []string
+	// EmitSubspecies appends a subspecies column (from the input's
+	// "subspecies" header) after processid; taxonkit builds it as the rank
+	// below species. Off keeps the ten-column header contract.
+	EmitSubspecies bool
+	// EmitSource appends a source_file column holding the basename of the
+	// input each row was read from, for tracing rows back to their shard.
+	EmitSource bool
+	// InputFormat forces the reader (tsv, csv, parquet, json); empty detects
+	// it from the input extension.
+	InputFormat string
+	// NullMarker, when set, is written in place of rank values that were
+	// present in the input but blanked as null or placeholder tokens, so
+	// consumers can tell "None in BOLD" from a missing column or empty cell.
+	// It must not be a valid taxon name: taxonkit would build it as one.
+	NullMarker string
+	// Append adds rows whose processid is not yet in an existing output
+	// instead of recreating it; the header must match what this run would
+	// write. Only the none protocol supports it: bioscan-5m priming resolves
+	// BINs from the full data set, which a delta alone does not carry.
+	Append bool
+	// Inputs, when it holds more than one path, is read in order as one
+	// logical input; every file must carry the first file's header. Curation
+	// protocols prime on a single input and do not support it.
+	Inputs []string
+}
+
+// foldedSet lowercases values into a lookup set; nil when values is empty.
+func foldedSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[strings.ToLower(strings.TrimSpace(v))] = struct{}{}
+	}
+	return set
+}
+
+func inFoldedSet(set map[string]struct{}, value []byte) bool {
+	_, ok := set[strings.ToLower(strings.TrimSpace(string(value)))]
+	return ok
+}
+
 func runExtract(args []string) {
 	fs := flag.NewFlagSet("extract", flag.ExitOnError)
-	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV or Parquet)")
+	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV, CSV, Parquet or JSON)")
+	inputFormat := fs.String("input-format", "auto", "Input format: auto (by extension), tsv, csv, parquet, or json (BOLD API array or NDJSON)")
 	output := fs.String("output", "taxonkit_input.tsv", "Output taxonkit input TSV")
-	curateProtocol := fs.String("curate-protocol", extractCurationProtocolNone, "Extraction curation profile (none,bioscan-5m)")
+	curateProtocol := fs.String("curate-protocol", extractCurationProtocolNone, "Extraction curation profile (none,bioscan-5m,gbif-backbone)")
 	curateReport := fs.String("curate-report", "", "Optional extraction curation JSON report path")
 	curateAudit := fs.String("curate-audit", "", "Optional extraction curation audit TSV path")
+	curateMaxBins := fs.Int("curate-max-bins", 0, "Cap bioscan-5m priming at this many BINs with resolved species; rare BINs are pruned mid-pass with -curate-min-bin-records, otherwise priming aborts (0 disables)")
+	curateMinBinRecords := fs.Int("curate-min-bin-records", 0, "Forget BINs with fewer resolved records than this, pruning during priming; they never adopt a BIN canonical species (<=1 keeps all)")
+	curateProvisionalMinSupport := fs.Int("curate-provisional-min-support", 0, "Only form \"Genus sp. BIN\" labels for BINs seen in at least this many rows (<=1 disables)")
+	binMajority := fs.Float64("bioscan-bin-majority", 0.5, "Share of a BIN's resolved records its top species must exceed to become the BIN canonical species (bioscan-5m; 0 accepts any unique top species)")
+	binMinCount := fs.Int("bioscan-bin-min-count", 0, "Observations of the top species required before a BIN adopts it (bioscan-5m; 0 or 1 disables)")
+	curateReportOnly := fs.Bool("curate-report-only", false, "Run bioscan-5m curation for its report and audit only; the TSV keeps the none-protocol labels")
+	curateSubfamilyTemplate := fs.String("curate-subfamily-template", defaultSubfamilyTemplate, "bioscan-5m label for a subfamily missing between family and tribe ({family} is replaced; empty disables the fill)")
+	curateProvisionalTemplate := fs.String("curate-provisional-template", defaultProvisionalTemplate, "bioscan-5m provisional species label; needs {genus} and {bin} or {processid}")
+	gbifBackbone := fs.String("gbif-backbone", "", "GBIF backbone Taxon.tsv for -curate-protocol gbif-backbone")
+	nullTokens := fs.String("null-tokens", defaultNullTokens, "Comma-separated labels treated as null, matched case-sensitively like taxonkit --null (same list pipeline passes to it)")
+	placeholderTokens := fs.String("placeholder-tokens", "", "Comma-separated extra labels treated as empty (case-insensitive, e.g. \"environmental sample,incertae sedis\")")
+	emitMarker := fs.Bool("emit-marker", false, "Append a marker_code column to the output TSV")
+	emitSubspecies := fs.Bool("emit-subspecies", false, "Append the input's subspecies column after processid (taxonkit builds it as the rank below species)")
+	emitSource := fs.Bool("emit-source", false, "Append a source_file column (input basename) to the output TSV")
+	filterCountry := fs.String("filter-country", "", "Comma-separated country/ocean allowlist (case-insensitive exact match)")
+	filterInst := fs.String("filter-institution", "", "Comma-separated institution (inst) allowlist (case-insensitive exact match)")
+	preserveNull := fs.String("preserve-null-marker", "", "Write this sentinel (e.g. __null__) for rank values blanked as null/placeholder tokens instead of empty; must not be a valid taxon name")
 	progressOn := fs.Bool("progress", true, "Show progress bar")
 	force := fs.Bool("force", false, "Overwrite existing outputs")
+	expectSHA256 := fs.String("expect-sha256", "", "Abort unless the input file's SHA-256 hex digest matches this value")
+	appendOut := fs.Bool("append", false, "Append rows with new processids to an existing -output instead of recreating it; a failed run truncates it back (-curate-protocol none only)")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
+	if strings.ContainsAny(*preserveNull, "\t\r\n") {
+		fatalf("-preserve-null-marker must not contain tabs or newlines")
+	}
+	format, err := normalizeInputFormat(*inputFormat)
+	if err != nil {
+		fatalf("%v", err)
+	}
 	curationCfg := extractCurationConfig{
-		Protocol:   *curateProtocol,
-		ReportPath: *curateReport,
-		AuditPath:  *curateAudit,
+		Protocol:              *curateProtocol,
+		ReportPath:            *curateReport,
+		AuditPath:             *curateAudit,
+		GBIFBackbonePath:      *gbifBackbone,
+		MaxBins:               *curateMaxBins,
+		MinBinRecords:         *curateMinBinRecords,
+		ProvisionalMinSupport: *curateProvisionalMinSupport,
+		BinMajority:           *binMajority,
+		BinMinCount:           *binMinCount,
+		ReportOnly:            *curateReportOnly,
+		SubfamilyTemplate:     *curateSubfamilyTemplate,
+		NoSubfamilyFill:       *curateSubfamilyTemplate == "",
+		ProvisionalTemplate:   *curateProvisionalTemplate,
+		Tokens:                newLabelTokens(*nullTokens, *placeholderTokens),
 	}.normalized()
 	if err := curationCfg.validate(); err != nil {
 		fatalf("invalid extraction curation config: %v", err)
 	}
 
-	if !*force && fileExists(*output) {
+	inputs, err := expandInputs(*input)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if len(inputs) > 1 {
+		if curationCfg.enabled() {
+			fatalf("-input %s matches %d files; -curate-protocol %s needs a single input", *input, len(inputs), curationCfg.Protocol)
+		}
+		if *expectSHA256 != "" {
+			fatalf("-expect-sha256 needs a single input; %s matches %d files", *input, len(inputs))
+		}
+	}
+	if *appendOut && curationCfg.enabled() {
+		fatalf("-append supports only -curate-protocol %s", extractCurationProtocolNone)
+	}
+	if !*force && !*appendOut && fileExists(*output) {
 		fmt.Fprintf(os.Stderr, "Output exists, skipping: %s\n", *output)
 		return
 	}
 
-	totalRows := -1
-	if *progressOn {
-		count, err := RowCount(*input)
+	var rows int64
+	for _, path := range inputs {
+		n, err := checkInput(path, format, *expectSHA256)
 		if err != nil {
-			fatalf("count rows failed: %v", err)
+			fatalf("input check failed: %v", err)
 		}
-		totalRows = int(count)
+		rows += n
+	}
+	totalRows := -1
+	if *progressOn {
+		totalRows = inputProgressTotal(rows)
 	}
 
 	reportEvery := 0
@@ -51,22 +165,144 @@ func runExtract(args []string) {
 		reportEvery = 1
 	}
 
-	if _, err := buildTaxonkit(*input, *output, reportEvery, totalRows, curationCfg); err != nil {
+	opts := extractOptions{
+		EmitMarker:         *emitMarker,
+		EmitSubspecies:     *emitSubspecies,
+		EmitSource:         *emitSource,
+		FilterCountries:    splitList(*filterCountry),
+		FilterInstitutions: splitList(*filterInst),
+		InputFormat:        format,
+		NullMarker:         *preserveNull,
+		Append:             *appendOut,
+		Inputs:             inputs,
+	}
+	if _, err := buildTaxonkit(inputs[0], *output, reportEvery, totalRows, curationCfg, opts); err != nil {
 		fatalf("build failed: %v", err)
 	}
 }
 
-func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, curationCfg extractCurationConfig) (int, error) {
+// headerKey joins a header row's fields for comparison across inputs.
+func headerKey(fields [][]byte) string {
+	return string(bytes.Join(fields, []byte{'\t'}))
+}
+
+// withSameHeader checks the first row of path against the header already read
+// from first and passes the remaining rows to onRow.
+func withSameHeader(path, first string, header *string, onRow func(Row) error) func(Row) error {
+	seen := false
+	return func(row Row) error {
+		if !seen {
+			seen = true
+			if got := headerKey(row.Fields); got != *header {
+				return fmt.Errorf("header columns of %s differ from %s: %q vs %q", path, first, got, *header)
+			}
+			return nil
+		}
+		return onRow(row)
+	}
+}
+
+// loadTaxonkitProcessIDs returns the header line and processid set of an
+// existing taxonkit input TSV. A missing final newline is an error, since an
+// appended row would run into the last one.
+func loadTaxonkitProcessIDs(path string) (string, map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("open existing output: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("stat existing output: %w", err)
+	}
+	last := make([]byte, 1)
+	if info.Size() == 0 {
+		return "", nil, fmt.Errorf("existing output %s is empty", path)
+	}
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return "", nil, fmt.Errorf("read existing output: %w", err)
+	}
+	if last[0] != '\n' {
+		return "", nil, fmt.Errorf("existing output %s does not end in a newline", path)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	var header string
+	idx := -1
+	ids := make(map[string]struct{})
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx < 0 {
+			header = line
+			for i, col := range strings.Split(header, "\t") {
+				if col == "processid" {
+					idx = i
+				}
+			}
+			if idx < 0 {
+				return "", nil, fmt.Errorf("existing output %s has no processid column", path)
+			}
+			continue
+		}
+		cols := strings.SplitN(line, "\t", idx+2)
+		if idx < len(cols) {
+			ids[cols[idx]] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("scan existing output: %w", err)
+	}
+	return header, ids, nil
+}
+
+func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, curationCfg extractCurationConfig, extractOpts extractOptions) (int, error) {
+	inputs := extractOpts.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{inputPath}
+	}
+	if len(inputs) > 1 && curationCfg.enabled() {
+		return 0, fmt.Errorf("%s curation needs a single input, got %d", curationCfg.Protocol, len(inputs))
+	}
 	curator, err := newExtractCurator(curationCfg, inputPath)
 	if err != nil {
 		return 0, fmt.Errorf("create curation profile: %w", err)
 	}
 
-	out, err := os.Create(outputPath)
+	var existingHeader string
+	var existing map[string]struct{}
+	if extractOpts.Append && fileExists(outputPath) {
+		if curationCfg.enabled() {
+			return 0, fmt.Errorf("append supports only the %s curation protocol", extractCurationProtocolNone)
+		}
+		if existingHeader, existing, err = loadTaxonkitProcessIDs(outputPath); err != nil {
+			return 0, err
+		}
+	}
+
+	var out *os.File
+	var appendFrom int64
+	if existing != nil {
+		out, err = os.OpenFile(outputPath, os.O_WRONLY|os.O_APPEND, 0)
+		if err == nil {
+			appendFrom, err = out.Seek(0, io.SeekEnd)
+		}
+	} else {
+		out, err = createOutput(outputPath)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("create output: %w", err)
 	}
+	// An append that fails partway, interrupts included, truncates the file
+	// back to its original rows. Registered first so it runs after the
+	// deferred flush below.
+	appended := false
 	defer func() {
+		if existing != nil && !appended {
+			_ = out.Truncate(appendFrom)
+		}
 		_ = out.Close()
 	}()
 
@@ -76,13 +312,19 @@ func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, cur
 	}()
 
 	progress := newProgress(totalRows, reportEvery)
+	tokens := curationCfg.Tokens
 
 	opts := DefaultOptions()
 	opts.Progress = progress
 	opts.SkipProgressFirstRow = true
 
-	var rowCount int
+	var rowCount, filtered, appendSkipped int
+	var sourceFile, inputHeader string
+	countries := foldedSet(extractOpts.FilterCountries)
+	institutions := foldedSet(extractOpts.FilterInstitutions)
 	var (
+		idxCountry   = -1
+		idxInst      = -1
 		idxProcess   = -1
 		idxBin       = -1
 		idxKingdom   = -1
@@ -94,10 +336,13 @@ func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, cur
 		idxTribe     = -1
 		idxGenus     = -1
 		idxSpecies   = -1
+		idxMarker    = -1
+		idxSubsp     = -1
 	)
 
-	err = ParseRows(inputPath, opts, func(row Row) error {
+	onRow := func(row Row) error {
 		if idxProcess < 0 {
+			inputHeader = headerKey(row.Fields)
 			idxProcess = indexOfBytes(row.Fields, "processid")
 			idxBin = indexOfBytes(row.Fields, "bin_uri")
 			idxKingdom = indexOfBytes(row.Fields, "kingdom")
@@ -109,37 +354,86 @@ func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, cur
 			idxTribe = indexOfBytes(row.Fields, "tribe")
 			idxGenus = indexOfBytes(row.Fields, "genus")
 			idxSpecies = indexOfBytes(row.Fields, "species")
+			if extractOpts.EmitSubspecies {
+				idxSubsp = indexOfBytes(row.Fields, "subspecies")
+				if idxSubsp < 0 {
+					return errors.New("-emit-subspecies needs a subspecies column in input")
+				}
+			}
+			idxMarker = indexOfBytes(row.Fields, "marker_code")
+			if idxMarker < 0 {
+				idxMarker = indexOfBytes(row.Fields, "markercode")
+			}
 			if idxProcess < 0 || idxBin < 0 || idxKingdom < 0 || idxPhylum < 0 || idxClass < 0 ||
 				idxOrder < 0 || idxFamily < 0 || idxGenus < 0 || idxSpecies < 0 {
 				return errors.New("required headers missing in input")
 			}
-			_, err := writer.WriteString("kingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tprocessid\n")
+			idxCountry = indexOfBytes(row.Fields, "country/ocean")
+			idxInst = indexOfBytes(row.Fields, "inst")
+			if countries != nil && idxCountry < 0 {
+				return errors.New("-filter-country needs a country/ocean column in input")
+			}
+			if institutions != nil && idxInst < 0 {
+				return errors.New("-filter-institution needs an inst column in input")
+			}
+			header := "kingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tprocessid"
+			if idxSubsp >= 0 {
+				header += "\tsubspecies"
+			}
+			if extractOpts.EmitMarker {
+				header += "\tmarker_code"
+			}
+			if extractOpts.EmitSource {
+				header += "\tsource_file"
+			}
+			if existing != nil {
+				if header != existingHeader {
+					return fmt.Errorf("append: %s header %q does not match this run's %q", outputPath, existingHeader, header)
+				}
+				return nil
+			}
+			_, err := writer.WriteString(header + "\n")
 			return err
 		}
 
 		rowCount++
 		fields := row.Fields
+		if (countries != nil && !inFoldedSet(countries, fieldBytes(fields, idxCountry))) ||
+			(institutions != nil && !inFoldedSet(institutions, fieldBytes(fields, idxInst))) {
+			filtered++
+			return nil
+		}
+		if existing != nil {
+			if _, seen := existing[string(fieldBytes(fields, idxProcess))]; seen {
+				appendSkipped++
+				return nil
+			}
+		}
 
 		record := extractTaxonRecord{
-			ProcessID: string(fieldBytes(fields, idxProcess)),
-			BinURI:    string(fieldBytes(fields, idxBin)),
-			Kingdom:   string(normalizeBytes(fieldBytes(fields, idxKingdom))),
-			Phylum:    string(normalizeBytes(fieldBytes(fields, idxPhylum))),
-			Class:     string(normalizeBytes(fieldBytes(fields, idxClass))),
-			Order:     string(normalizeBytes(fieldBytes(fields, idxOrder))),
-			Family:    string(normalizeBytes(fieldBytes(fields, idxFamily))),
-			Subfamily: string(normalizeBytes(fieldBytes(fields, idxSubfamily))),
-			Tribe:     string(normalizeBytes(fieldBytes(fields, idxTribe))),
-			Genus:     string(normalizeBytes(fieldBytes(fields, idxGenus))),
-			Species:   string(normalizeBytes(fieldBytes(fields, idxSpecies))),
+			ProcessID:  string(fieldBytes(fields, idxProcess)),
+			BinURI:     string(fieldBytes(fields, idxBin)),
+			Kingdom:    string(tokens.normalizeBytes(fieldBytes(fields, idxKingdom))),
+			Phylum:     string(tokens.normalizeBytes(fieldBytes(fields, idxPhylum))),
+			Class:      string(tokens.normalizeBytes(fieldBytes(fields, idxClass))),
+			Order:      string(tokens.normalizeBytes(fieldBytes(fields, idxOrder))),
+			Family:     string(tokens.normalizeBytes(fieldBytes(fields, idxFamily))),
+			Subfamily:  string(tokens.normalizeBytes(fieldBytes(fields, idxSubfamily))),
+			Tribe:      string(tokens.normalizeBytes(fieldBytes(fields, idxTribe))),
+			Genus:      string(tokens.normalizeBytes(fieldBytes(fields, idxGenus))),
+			Species:    string(tokens.normalizeBytes(fieldBytes(fields, idxSpecies))),
+			Subspecies: string(tokens.normalizeBytes(fieldBytes(fields, idxSubsp))),
 		}
 		if err := curator.Curate(&record); err != nil {
 			return fmt.Errorf("line %d curation failed: %w", rowCount+1, err)
 		}
+		// Curators already drop a subspecies their species rewrite orphaned;
+		// this covers the none protocol's raw labels too.
+		record.Subspecies = consistentSubspecies(record.Species, record.Subspecies)
 
-		if record.Genus != "" && record.Species == "" {
+		if record.Genus != "" && record.Species == "" && !record.NoProvisional {
 			suffix := record.BinURI
-			if suffix == "" && !curationCfg.enabled() {
+			if suffix == "" && !curationCfg.rewrites() {
 				suffix = record.ProcessID
 			}
 			if suffix != "" {
@@ -147,23 +441,72 @@ func buildTaxonkit(inputPath, outputPath string, reportEvery, totalRows int, cur
 			}
 		}
 
-		line := strings.Join([]string{
+		cols := []string{
 			record.Kingdom, record.Phylum, record.Class, record.Order, record.Family,
 			record.Subfamily, record.Tribe, record.Genus, record.Species, record.ProcessID,
-		}, "\t")
+		}
+		if extractOpts.NullMarker != "" {
+			rankIdx := []int{idxKingdom, idxPhylum, idxClass, idxOrder, idxFamily, idxSubfamily, idxTribe, idxGenus, idxSpecies}
+			for i, idx := range rankIdx {
+				if cols[i] == "" && tokens.isNulled(fieldBytes(fields, idx)) {
+					cols[i] = extractOpts.NullMarker
+				}
+			}
+			if record.Subspecies == "" && tokens.isNulled(fieldBytes(fields, idxSubsp)) {
+				record.Subspecies = extractOpts.NullMarker
+			}
+		}
+		if idxSubsp >= 0 {
+			// taxonkit create-taxdump -A 10 takes every other column as a rank,
+			// so a trailing subspecies column becomes the rank below species.
+			cols = append(cols, record.Subspecies)
+		}
+		if extractOpts.EmitMarker {
+			marker := string(tokens.normalizeBytes(fieldBytes(fields, idxMarker)))
+			if marker == "" {
+				marker = "unknown"
+			}
+			cols = append(cols, marker)
+		}
+		if extractOpts.EmitSource {
+			cols = append(cols, sourceFile)
+		}
+		line := strings.Join(cols, "\t")
 		if _, err := writer.WriteString(line + "\n"); err != nil {
 			return fmt.Errorf("write row: %w", err)
 		}
+		if existing != nil {
+			// A processid repeated within the delta is appended once.
+			existing[record.ProcessID] = struct{}{}
+		}
 
 		return nil
-	})
-	if err != nil {
-		return 0, err
+	}
+	for i, path := range inputs {
+		sourceFile = filepath.Base(path)
+		fn := onRow
+		if i > 0 {
+			fn = withSameHeader(path, inputs[0], &inputHeader, onRow)
+		}
+		if err := ParseRowsAs(path, extractOpts.InputFormat, opts, fn); err != nil {
+			return 0, err
+		}
 	}
 
 	progress.finish()
+	if countries != nil || institutions != nil {
+		logf("extract: filtered %d of %d rows by country/institution", filtered, rowCount)
+	}
+	if existing != nil {
+		logf("extract: appended to %s, skipped %d rows already present", outputPath, appendSkipped)
+	}
 	if err := curator.Close(); err != nil {
 		return 0, fmt.Errorf("finalize curation profile: %w", err)
 	}
+	if err := writer.Flush(); err != nil {
+		return 0, fmt.Errorf("write output: %w", err)
+	}
+	appended = true
+	keepOutputs(outputPath, reportFilePath(curationCfg.AuditPath))
 	return rowCount, nil
 }
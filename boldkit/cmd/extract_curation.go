@@ -9,12 +9,52 @@ import (
 const (
 	extractCurationProtocolNone      = "none"
 	extractCurationProtocolBioscan5M = "bioscan-5m"
+	extractCurationProtocolGBIF      = "gbif-backbone"
+
+	defaultSubfamilyTemplate   = "{family} subfam. incertae sedis"
+	defaultProvisionalTemplate = "{genus} sp. {bin}"
 )
 
 type extractCurationConfig struct {
 	Protocol   string
 	ReportPath string
 	AuditPath  string
+	// GBIFBackbonePath is the GBIF backbone Taxon.tsv used by gbif-backbone.
+	GBIFBackbonePath string
+	// MaxBins caps how many BINs with a resolved species bioscan-5m priming
+	// holds: reaching it prunes by MinBinRecords, and priming aborts if the
+	// cap is still exceeded (0 disables).
+	MaxBins int
+	// MinBinRecords drops BINs with fewer resolved records than this, during
+	// priming as the resolver grows and once after it (<= 1 keeps all); see
+	// bioscan5MCurator.prime.
+	MinBinRecords int
+	// ProvisionalMinSupport withholds "Genus sp. BIN" labels for BINs seen
+	// in fewer input rows than this during priming (<= 1 disables).
+	ProvisionalMinSupport int
+	// BinMajority is the share of a BIN's resolved records its top species
+	// must exceed to become the BIN canonical species; 0 accepts any unique
+	// top species.
+	BinMajority float64
+	// BinMinCount is how many times the top species must be observed before
+	// a BIN adopts it (0 or 1 disables). Unlike MinBinRecords it counts only
+	// the winning species, and the BIN still counts as observed.
+	BinMinCount int
+	// ReportOnly runs bioscan-5m curation for its report, stats and audit but
+	// emits every record as the none protocol would.
+	ReportOnly bool
+	// SubfamilyTemplate is the bioscan-5m fill for a subfamily missing
+	// between a family and a tribe, with {family} replaced by the family;
+	// empty uses defaultSubfamilyTemplate.
+	SubfamilyTemplate string
+	// NoSubfamilyFill leaves a missing subfamily empty instead.
+	NoSubfamilyFill bool
+	// ProvisionalTemplate formats bioscan-5m provisional species labels from
+	// {genus}, {bin} and {processid}; empty uses defaultProvisionalTemplate.
+	ProvisionalTemplate string
+	// Tokens are the -null-tokens and -placeholder-tokens labels. Extract
+	// blanks them in every protocol; bioscan-5m adds bioscanPlaceholders.
+	Tokens labelTokens
 }
 
 func (c extractCurationConfig) normalized() extractCurationConfig {
@@ -24,6 +64,7 @@ func (c extractCurationConfig) normalized() extractCurationConfig {
 	}
 	c.ReportPath = strings.TrimSpace(c.ReportPath)
 	c.AuditPath = strings.TrimSpace(c.AuditPath)
+	c.GBIFBackbonePath = strings.TrimSpace(c.GBIFBackbonePath)
 	return c
 }
 
@@ -31,8 +72,12 @@ func (c extractCurationConfig) validate() error {
 	switch c.Protocol {
 	case extractCurationProtocolNone, extractCurationProtocolBioscan5M:
 		// known profile
+	case extractCurationProtocolGBIF:
+		if c.GBIFBackbonePath == "" {
+			return fmt.Errorf("protocol %s requires -gbif-backbone", extractCurationProtocolGBIF)
+		}
 	default:
-		return fmt.Errorf("unknown protocol %q (supported: %s,%s)", c.Protocol, extractCurationProtocolNone, extractCurationProtocolBioscan5M)
+		return fmt.Errorf("unknown protocol %q (supported: %s,%s,%s)", c.Protocol, extractCurationProtocolNone, extractCurationProtocolBioscan5M, extractCurationProtocolGBIF)
 	}
 	if c.ReportPath != "" && filepath.Clean(c.ReportPath) == "." {
 		return fmt.Errorf("invalid report path %q", c.ReportPath)
@@ -40,6 +85,40 @@ func (c extractCurationConfig) validate() error {
 	if c.AuditPath != "" && filepath.Clean(c.AuditPath) == "." {
 		return fmt.Errorf("invalid audit path %q", c.AuditPath)
 	}
+	if c.MaxBins < 0 {
+		return fmt.Errorf("max bins must be >= 0")
+	}
+	if c.MinBinRecords < 0 {
+		return fmt.Errorf("min bin records must be >= 0")
+	}
+	if c.ProvisionalMinSupport < 0 {
+		return fmt.Errorf("provisional min support must be >= 0")
+	}
+	if c.BinMajority < 0 || c.BinMajority >= 1 {
+		return fmt.Errorf("bin majority must be in [0, 1)")
+	}
+	if c.BinMinCount < 0 {
+		return fmt.Errorf("bin min count must be >= 0")
+	}
+	if tmpl := c.subfamilyTemplate(); tmpl != "" {
+		if !strings.Contains(tmpl, "{family}") {
+			return fmt.Errorf("subfamily template %q must contain {family}", tmpl)
+		}
+		if strings.ContainsAny(tmpl, "\t\r\n") {
+			return fmt.Errorf("subfamily template must not contain tabs or newlines")
+		}
+	}
+	if tmpl := c.ProvisionalTemplate; tmpl != "" {
+		if !strings.Contains(tmpl, "{genus}") || !(strings.Contains(tmpl, "{bin}") || strings.Contains(tmpl, "{processid}")) {
+			return fmt.Errorf("provisional template %q must contain {genus} and {bin} or {processid}", tmpl)
+		}
+		if strings.ContainsAny(tmpl, "\t\r\n") {
+			return fmt.Errorf("provisional template must not contain tabs or newlines")
+		}
+	}
+	if c.ReportOnly && c.Protocol != extractCurationProtocolBioscan5M {
+		return fmt.Errorf("report-only needs protocol %s", extractCurationProtocolBioscan5M)
+	}
 	return nil
 }
 
@@ -47,6 +126,31 @@ func (c extractCurationConfig) enabled() bool {
 	return c.Protocol != extractCurationProtocolNone
 }
 
+// subfamilyTemplate returns SubfamilyTemplate, the default when empty, or
+// "" under NoSubfamilyFill.
+func (c extractCurationConfig) subfamilyTemplate() string {
+	if c.NoSubfamilyFill {
+		return ""
+	}
+	if c.SubfamilyTemplate == "" {
+		return defaultSubfamilyTemplate
+	}
+	return c.SubfamilyTemplate
+}
+
+// provisionalTemplate returns ProvisionalTemplate, or the default when empty.
+func (c extractCurationConfig) provisionalTemplate() string {
+	if c.ProvisionalTemplate == "" {
+		return defaultProvisionalTemplate
+	}
+	return c.ProvisionalTemplate
+}
+
+// rewrites reports whether curation may change the emitted records.
+func (c extractCurationConfig) rewrites() bool {
+	return c.enabled() && !c.ReportOnly
+}
+
 type extractTaxonRecord struct {
 	ProcessID string
 	BinURI    string
@@ -59,6 +163,21 @@ type extractTaxonRecord struct {
 	Tribe     string
 	Genus     string
 	Species   string
+	// Subspecies is the full trinomial from an optional "subspecies" column.
+	Subspecies string
+	// NoProvisional is set by a curator that left Species empty on purpose;
+	// extract then skips its "Genus sp. <suffix>" fallback.
+	NoProvisional bool
+}
+
+// consistentSubspecies returns subspecies when it extends species (a
+// trinomial under the record's binomial) and "" otherwise, so a curator that
+// rewrites the species never leaves a dangling infraspecific label.
+func consistentSubspecies(species, subspecies string) string {
+	if species == "" || !strings.HasPrefix(subspecies, species+" ") {
+		return ""
+	}
+	return subspecies
 }
 
 type extractCurator interface {
@@ -72,6 +191,8 @@ func newExtractCurator(cfg extractCurationConfig, inputPath string) (extractCura
 		return &noopExtractCurator{}, nil
 	case extractCurationProtocolBioscan5M:
 		return newExtractBioscan5MCurator(cfg, inputPath)
+	case extractCurationProtocolGBIF:
+		return newExtractGBIFCurator(cfg, inputPath)
 	default:
 		return nil, fmt.Errorf("unsupported extraction curation protocol %q", cfg.Protocol)
 	}
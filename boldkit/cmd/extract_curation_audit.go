@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const curationAuditHeader = "processid\tbin_uri\tgenus_before\tspecies_before\tsubfamily_before\tgenus_after\tspecies_after\tsubfamily_after\trules\n"
+
+// curationAudit writes the per-row audit TSV shared by extraction curation
+// protocols. A nil *curationAudit discards rows, so protocols can call it
+// unconditionally when no audit path was requested.
+type curationAudit struct {
+	writer *reportWriter
+}
+
+func openCurationAudit(path string) (*curationAudit, error) {
+	if path == "" {
+		return nil, nil
+	}
+	w, err := createReport(path)
+	if err != nil {
+		return nil, fmt.Errorf("create audit file: %w", err)
+	}
+	a := &curationAudit{writer: w}
+	if _, err := a.writer.WriteString(curationAuditHeader); err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("write audit header: %w", err)
+	}
+	return a, nil
+}
+
+func (a *curationAudit) writeRow(before, after extractTaxonRecord, ruleSet map[string]struct{}) error {
+	if a == nil {
+		return nil
+	}
+	rules := sortedRuleSet(ruleSet)
+	line := strings.Join([]string{
+		auditField(after.ProcessID),
+		auditField(after.BinURI),
+		auditField(before.Genus),
+		auditField(before.Species),
+		auditField(before.Subfamily),
+		auditField(after.Genus),
+		auditField(after.Species),
+		auditField(after.Subfamily),
+		strings.Join(rules, ","),
+	}, "\t")
+	if _, err := a.writer.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("write audit row: %w", err)
+	}
+	return nil
+}
+
+func (a *curationAudit) close() error {
+	if a == nil {
+		return nil
+	}
+	if err := a.writer.Close(); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+// writeCurationReport writes a protocol report as indented JSON.
+func writeCurationReport(path, protocol string, report any) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create report dir: %w", err)
+	}
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logf("extract (%s): report -> %s", protocol, path)
+	return nil
+}
@@ -1,14 +1,13 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 )
 
 type bioscan5MCurator struct {
 	cfg            extractCurationConfig
+	tokens         labelTokens
 	inputPath      string
 	resolver       *bioscanBinSpeciesResolver
 	binCanonical   map[string]bioscanSpeciesInfo
@@ -16,29 +15,44 @@ type bioscan5MCurator struct {
 	binsCanonical  int
 	binsConflicted int
 	stats          bioscanCurationStats
-	auditFile      *os.File
-	auditWriter    *bufio.Writer
+	audit          *curationAudit
 }
 
 func newExtractBioscan5MCurator(cfg extractCurationConfig, inputPath string) (extractCurator, error) {
 	c := &bioscan5MCurator{
 		cfg:          cfg,
+		tokens:       cfg.Tokens.withPlaceholders(bioscanPlaceholders),
 		inputPath:    inputPath,
 		resolver:     newBioscanBinSpeciesResolver(),
 		binCanonical: make(map[string]bioscanSpeciesInfo),
 	}
-	if err := c.openAudit(); err != nil {
+	if cfg.ProvisionalMinSupport > 1 {
+		c.resolver.TrackSupport()
+	}
+	audit, err := openCurationAudit(cfg.AuditPath)
+	if err != nil {
 		return nil, err
 	}
+	c.audit = audit
 	if inputPath != "" {
 		if err := c.prime(inputPath); err != nil {
-			_ = c.closeAudit()
+			_ = c.audit.close()
 			return nil, err
 		}
 	}
 	return c, nil
 }
 
+// bioscanPrimePruneBins is how many resolved BINs priming holds before its
+// first -curate-min-bin-records prune when -curate-max-bins is unset.
+const bioscanPrimePruneBins = 1 << 20
+
+// prime counts resolved species per BIN over the whole input. With
+// MinBinRecords > 1 rare BINs are pruned during the pass, whenever the
+// resolver reaches MaxBins (or a doubling watermark when MaxBins is 0), so
+// MaxBins caps memory instead of only aborting. A BIN pruned early restarts
+// from zero, so one whose records are spread thinly across the input may
+// miss the threshold it would have reached in a single count.
 func (c *bioscan5MCurator) prime(inputPath string) error {
 	opts := DefaultOptions()
 	var (
@@ -46,6 +60,11 @@ func (c *bioscan5MCurator) prime(inputPath string) error {
 		idxGenus   = -1
 		idxSpecies = -1
 	)
+	dropped := 0
+	pruneAt := bioscanPrimePruneBins
+	if c.cfg.MaxBins > 0 {
+		pruneAt = c.cfg.MaxBins + 1
+	}
 
 	err := ParseRows(inputPath, opts, func(row Row) error {
 		if idxBin < 0 {
@@ -58,15 +77,27 @@ func (c *bioscan5MCurator) prime(inputPath string) error {
 			return nil
 		}
 
-		binURI := bioscanNormalizeLabel(string(fieldBytes(row.Fields, idxBin)))
-		genus := bioscanNormalizeLabel(string(fieldBytes(row.Fields, idxGenus)))
-		species := bioscanNormalizeLabel(string(fieldBytes(row.Fields, idxSpecies)))
+		binURI := c.tokens.normalizeLabel(string(fieldBytes(row.Fields, idxBin)))
+		genus := c.tokens.normalizeLabel(string(fieldBytes(row.Fields, idxGenus)))
+		species := c.tokens.normalizeLabel(string(fieldBytes(row.Fields, idxSpecies)))
 		c.resolver.Observe(binURI, genus, species)
+		if c.cfg.MinBinRecords > 1 && c.resolver.Bins() >= pruneAt {
+			dropped += c.resolver.Prune(c.cfg.MinBinRecords)
+			if c.cfg.MaxBins == 0 {
+				pruneAt = max(bioscanPrimePruneBins, 2*c.resolver.Bins())
+			}
+		}
+		if c.cfg.MaxBins > 0 && c.resolver.Bins() > c.cfg.MaxBins {
+			return fmt.Errorf("more than %d BINs with resolved species (raise -curate-max-bins or set -curate-min-bin-records)", c.cfg.MaxBins)
+		}
 		return nil
 	})
 	if err != nil {
 		return fmt.Errorf("bioscan prime: %w", err)
 	}
+	if dropped += c.resolver.Prune(c.cfg.MinBinRecords); dropped > 0 {
+		logf("extract (%s): dropped %d BINs with fewer than %d resolved records", extractCurationProtocolBioscan5M, dropped, c.cfg.MinBinRecords)
+	}
 	c.buildBinDecisions()
 	return nil
 }
@@ -78,7 +109,7 @@ func (c *bioscan5MCurator) buildBinDecisions() {
 	c.binCanonical = make(map[string]bioscanSpeciesInfo)
 	for bin := range c.resolver.counts {
 		c.binsObserved++
-		resolution := c.resolver.Resolve(bin)
+		resolution := c.resolver.Resolve(bin, c.cfg.BinMajority, c.cfg.BinMinCount)
 		if resolution.Accepted {
 			info := bioscanParseSpecies(resolution.Canonical)
 			if info.Kind == bioscanSpeciesResolved && info.Canonical != "" {
@@ -94,7 +125,7 @@ func (c *bioscan5MCurator) buildBinDecisions() {
 }
 
 func (c *bioscan5MCurator) canonicalForBin(binURI string) (bioscanSpeciesInfo, bool) {
-	bin := bioscanNormalizeLabel(binURI)
+	bin := c.tokens.normalizeLabel(binURI)
 	if bin == "" {
 		return bioscanSpeciesInfo{}, false
 	}
@@ -113,16 +144,17 @@ func (c *bioscan5MCurator) Curate(rec *extractTaxonRecord) error {
 	original := *rec
 	ruleSet := make(map[string]struct{})
 
-	rec.Kingdom = bioscanNormalizeLabel(rec.Kingdom)
-	rec.Phylum = bioscanNormalizeLabel(rec.Phylum)
-	rec.Class = bioscanNormalizeLabel(rec.Class)
-	rec.Order = bioscanNormalizeLabel(rec.Order)
-	rec.Family = bioscanNormalizeLabel(rec.Family)
-	rec.Subfamily = bioscanNormalizeLabel(rec.Subfamily)
-	rec.Tribe = bioscanNormalizeLabel(rec.Tribe)
-	rec.Genus = bioscanNormalizeLabel(rec.Genus)
-	rec.Species = bioscanNormalizeLabel(rec.Species)
-	rec.BinURI = bioscanNormalizeLabel(rec.BinURI)
+	rec.Kingdom = c.tokens.normalizeLabel(rec.Kingdom)
+	rec.Phylum = c.tokens.normalizeLabel(rec.Phylum)
+	rec.Class = c.tokens.normalizeLabel(rec.Class)
+	rec.Order = c.tokens.normalizeLabel(rec.Order)
+	rec.Family = c.tokens.normalizeLabel(rec.Family)
+	rec.Subfamily = c.tokens.normalizeLabel(rec.Subfamily)
+	rec.Tribe = c.tokens.normalizeLabel(rec.Tribe)
+	rec.Genus = c.tokens.normalizeLabel(rec.Genus)
+	rec.Species = c.tokens.normalizeLabel(rec.Species)
+	rec.Subspecies = c.tokens.normalizeLabel(rec.Subspecies)
+	rec.BinURI = c.tokens.normalizeLabel(rec.BinURI)
 	if rec.Kingdom != original.Kingdom || rec.Phylum != original.Phylum || rec.Class != original.Class ||
 		rec.Order != original.Order || rec.Family != original.Family || rec.Subfamily != original.Subfamily ||
 		rec.Tribe != original.Tribe || rec.Genus != original.Genus || rec.Species != original.Species ||
@@ -130,8 +162,8 @@ func (c *bioscan5MCurator) Curate(rec *extractTaxonRecord) error {
 		ruleSet[rulePlaceholderNormalize] = struct{}{}
 	}
 
-	if rec.Family != "" && rec.Tribe != "" && rec.Subfamily == "" {
-		rec.Subfamily = rec.Family + " subfam. incertae sedis"
+	if tmpl := c.cfg.subfamilyTemplate(); tmpl != "" && rec.Family != "" && rec.Tribe != "" && rec.Subfamily == "" {
+		rec.Subfamily = strings.ReplaceAll(tmpl, "{family}", rec.Family)
 		ruleSet[ruleSubfamilyFill] = struct{}{}
 	}
 
@@ -166,7 +198,7 @@ func (c *bioscan5MCurator) Curate(rec *extractTaxonRecord) error {
 			ruleSet[ruleBinCanonicalAdopt] = struct{}{}
 			break
 		}
-		species = bioscanProvisionalSpecies(genus, rec.BinURI)
+		species = c.provisionalSpecies(genus, rec.BinURI, rec.ProcessID, ruleSet)
 		ruleSet[ruleGenusSpeciesMismatchDemote] = struct{}{}
 
 	case bioscanSpeciesOpen, bioscanSpeciesEmpty:
@@ -184,18 +216,21 @@ func (c *bioscan5MCurator) Curate(rec *extractTaxonRecord) error {
 			break
 		}
 
-		species = bioscanProvisionalSpecies(genus, rec.BinURI)
+		species = c.provisionalSpecies(genus, rec.BinURI, rec.ProcessID, ruleSet)
 		ruleSet[ruleOpenToBinProvisional] = struct{}{}
 	default:
-		species = bioscanProvisionalSpecies(genus, rec.BinURI)
+		species = c.provisionalSpecies(genus, rec.BinURI, rec.ProcessID, ruleSet)
 		ruleSet[ruleOpenToBinProvisional] = struct{}{}
 	}
 
 	rec.Genus = genus
 	rec.Species = species
+	rec.Subspecies = consistentSubspecies(rec.Species, rec.Subspecies)
 	_, provisionalRule := ruleSet[ruleOpenToBinProvisional]
 	_, mismatchRule := ruleSet[ruleGenusSpeciesMismatchDemote]
-	if (provisionalRule || mismatchRule) && rec.Species == "" {
+	_, lowSupport := ruleSet[ruleProvisionalLowSupport]
+	rec.NoProvisional = lowSupport
+	if (provisionalRule || mismatchRule) && rec.Species == "" && !lowSupport {
 		ruleSet[ruleProvisionalDroppedNoBin] = struct{}{}
 	}
 	changed := original.Genus != rec.Genus || original.Species != rec.Species || original.Subfamily != rec.Subfamily ||
@@ -205,19 +240,39 @@ func (c *bioscan5MCurator) Curate(rec *extractTaxonRecord) error {
 		c.stats.RowsChanged++
 	}
 	c.stats.addRules(ruleSet)
-	if err := c.writeAuditRow(original, *rec, ruleSet, changed); err != nil {
-		return err
+	if changed {
+		if err := c.audit.writeRow(original, *rec, ruleSet); err != nil {
+			return err
+		}
+	}
+	if c.cfg.ReportOnly {
+		*rec = original
 	}
 	return nil
 }
 
+// provisionalSpecies is bioscanProvisionalLabel over the configured template,
+// gated on ProvisionalMinSupport: a BIN seen in too few rows during prime
+// yields "" and records ruleProvisionalLowSupport.
+func (c *bioscan5MCurator) provisionalSpecies(genus, binURI, processID string, ruleSet map[string]struct{}) string {
+	species := bioscanProvisionalLabel(c.cfg.provisionalTemplate(), genus, binURI, processID)
+	if species == "" || c.cfg.ProvisionalMinSupport <= 1 {
+		return species
+	}
+	if c.resolver.Support(binURI) < c.cfg.ProvisionalMinSupport {
+		ruleSet[ruleProvisionalLowSupport] = struct{}{}
+		return ""
+	}
+	return species
+}
+
 func (c *bioscan5MCurator) Close() error {
 	logf("extract (%s): bins-observed=%d bins-canonical=%d bins-conflicted=%d", extractCurationProtocolBioscan5M, c.binsObserved, c.binsCanonical, c.binsConflicted)
 	var firstErr error
 	if err := c.writeReport(); err != nil && firstErr == nil {
 		firstErr = err
 	}
-	if err := c.closeAudit(); err != nil && firstErr == nil {
+	if err := c.audit.close(); err != nil && firstErr == nil {
 		firstErr = err
 	}
 	return firstErr
@@ -21,18 +21,9 @@ type bioscanSpeciesInfo struct {
 	Epithet    string
 }
 
-var bioscanPlaceholderTokens = map[string]struct{}{
-	"":             {},
-	"-":            {},
-	"n/a":          {},
-	"na":           {},
-	"none":         {},
-	"null":         {},
-	"unclassified": {},
-	"undetermined": {},
-	"unidentified": {},
-	"unknown":      {},
-}
+// bioscanPlaceholders are the labels bioscan-5m always treats as empty; a
+// curator adds them to extractCurationConfig.Tokens.
+var bioscanPlaceholders = newPlaceholderSet("-,n/a,na,none,null,unclassified,undetermined,unidentified,unknown")
 
 var bioscanOpenNomenclatureTokens = map[string]struct{}{
 	"aff":         {},
@@ -49,19 +40,7 @@ var bioscanOpenNomenclatureTokens = map[string]struct{}{
 }
 
 func bioscanNormalizeLabel(value string) string {
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return ""
-	}
-	parts := strings.Fields(value)
-	if len(parts) == 0 {
-		return ""
-	}
-	value = strings.Join(parts, " ")
-	if _, ok := bioscanPlaceholderTokens[strings.ToLower(value)]; ok {
-		return ""
-	}
-	return value
+	return labelTokens{placeholders: bioscanPlaceholders}.normalizeLabel(value)
 }
 
 func bioscanNormalizeToken(token string) string {
@@ -176,17 +155,32 @@ func bioscanInferGenus(species string) string {
 	return ""
 }
 
-func bioscanProvisionalSpecies(genus, binURI string) string {
-	genus = bioscanNormalizeLabel(genus)
-	binURI = bioscanNormalizeLabel(binURI)
-	if genus == "" || binURI == "" {
-		return ""
+// bioscanProvisionalLabel fills tmpl's {genus}, {bin} and {processid}
+// placeholders; it returns "" when any placeholder tmpl uses has no value.
+func bioscanProvisionalLabel(tmpl, genus, binURI, processID string) string {
+	values := []struct{ placeholder, value string }{
+		{"{genus}", bioscanNormalizeLabel(genus)},
+		{"{bin}", bioscanNormalizeLabel(binURI)},
+		{"{processid}", strings.TrimSpace(processID)},
+	}
+	label := tmpl
+	for _, v := range values {
+		if !strings.Contains(tmpl, v.placeholder) {
+			continue
+		}
+		if v.value == "" {
+			return ""
+		}
+		label = strings.ReplaceAll(label, v.placeholder, v.value)
 	}
-	return genus + " sp. " + binURI
+	return label
 }
 
 type bioscanBinSpeciesResolver struct {
 	counts map[string]map[string]int
+	// seen counts every observed row per BIN, resolved species or not. It
+	// stays nil, and costs nothing per BIN, until TrackSupport.
+	seen map[string]int
 }
 
 type bioscanBinResolution struct {
@@ -201,6 +195,13 @@ func newBioscanBinSpeciesResolver() *bioscanBinSpeciesResolver {
 	}
 }
 
+// TrackSupport makes Observe count the rows of every BIN for Support.
+func (r *bioscanBinSpeciesResolver) TrackSupport() {
+	if r != nil && r.seen == nil {
+		r.seen = make(map[string]int)
+	}
+}
+
 func (r *bioscanBinSpeciesResolver) Observe(binURI, genus, species string) {
 	if r == nil {
 		return
@@ -209,6 +210,9 @@ func (r *bioscanBinSpeciesResolver) Observe(binURI, genus, species string) {
 	if bin == "" {
 		return
 	}
+	if r.seen != nil {
+		r.seen[bin]++
+	}
 	info := bioscanParseSpecies(species)
 	if info.Kind != bioscanSpeciesResolved || info.Canonical == "" {
 		return
@@ -227,7 +231,51 @@ func (r *bioscanBinSpeciesResolver) Observe(binURI, genus, species string) {
 	bySpecies[info.Canonical]++
 }
 
-func (r *bioscanBinSpeciesResolver) Resolve(binURI string) bioscanBinResolution {
+// Bins returns the number of BINs holding at least one resolved species.
+func (r *bioscanBinSpeciesResolver) Bins() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.counts)
+}
+
+// Support returns how many observed rows carried binURI, or 0 without
+// TrackSupport. Prune does not affect it.
+func (r *bioscanBinSpeciesResolver) Support(binURI string) int {
+	if r == nil {
+		return 0
+	}
+	return r.seen[bioscanNormalizeLabel(binURI)]
+}
+
+// Prune drops BINs with fewer than minRecords resolved observations and
+// returns how many were dropped. A pruned BIN resolves to nothing, so its rows
+// never adopt a BIN canonical species: resolved labels are kept as-is and
+// open or empty labels fall back to the provisional "Genus sp. BIN" form.
+func (r *bioscanBinSpeciesResolver) Prune(minRecords int) int {
+	if r == nil || minRecords <= 1 {
+		return 0
+	}
+	dropped := 0
+	for bin, bySpecies := range r.counts {
+		total := 0
+		for _, count := range bySpecies {
+			total += count
+		}
+		if total < minRecords {
+			delete(r.counts, bin)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Resolve picks the BIN's canonical species: the unique top species is
+// accepted when its share of the BIN's resolved records exceeds majority
+// (0 accepts any unique top species) and it was observed at least minCount
+// times. A BIN whose top species falls short of minCount resolves to
+// nothing rather than a conflict.
+func (r *bioscanBinSpeciesResolver) Resolve(binURI string, majority float64, minCount int) bioscanBinResolution {
 	if r == nil {
 		return bioscanBinResolution{}
 	}
@@ -242,7 +290,10 @@ func (r *bioscanBinSpeciesResolver) Resolve(binURI string) bioscanBinResolution
 	}
 
 	if len(bySpecies) == 1 {
-		for species := range bySpecies {
+		for species, count := range bySpecies {
+			if count < minCount {
+				return bioscanBinResolution{}
+			}
 			return bioscanBinResolution{
 				Canonical: species,
 				Accepted:  true,
@@ -267,8 +318,12 @@ func (r *bioscanBinSpeciesResolver) Resolve(binURI string) bioscanBinResolution
 		}
 	}
 
-	// Accept only when there is a strict majority and a unique top species.
-	if best != "" && bestCount > second && bestCount*2 > total {
+	if bestCount < minCount {
+		return bioscanBinResolution{}
+	}
+	// Accept only when the unique top species holds more than the majority
+	// fraction of the BIN.
+	if best != "" && bestCount > second && float64(bestCount) > majority*float64(total) {
 		return bioscanBinResolution{
 			Canonical: best,
 			Accepted:  true,
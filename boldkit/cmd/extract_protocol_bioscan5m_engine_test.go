@@ -53,11 +53,11 @@ func TestBioscanParseSpecies(t *testing.T) {
 }
 
 func TestBioscanProvisionalSpeciesNoProcessIDFallback(t *testing.T) {
-	if got := bioscanProvisionalSpecies("Canis", "BOLD:AAA1111"); got != "Canis sp. BOLD:AAA1111" {
-		t.Fatalf("bioscanProvisionalSpecies()=%q want %q", got, "Canis sp. BOLD:AAA1111")
+	if got := bioscanProvisionalLabel(defaultProvisionalTemplate, "Canis", "BOLD:AAA1111", ""); got != "Canis sp. BOLD:AAA1111" {
+		t.Fatalf("bioscanProvisionalLabel()=%q want %q", got, "Canis sp. BOLD:AAA1111")
 	}
-	if got := bioscanProvisionalSpecies("Canis", ""); got != "" {
-		t.Fatalf("bioscanProvisionalSpecies()=%q want empty", got)
+	if got := bioscanProvisionalLabel(defaultProvisionalTemplate, "Canis", "", ""); got != "" {
+		t.Fatalf("bioscanProvisionalLabel()=%q want empty", got)
 	}
 }
 
@@ -86,7 +86,7 @@ func TestBioscanBinSpeciesResolverCanonical(t *testing.T) {
 	resolver.Observe("BOLD:AAA1111", "Homo", "Homo sapiens")
 	resolver.Observe("BOLD:AAA1111", "Homo", "Homo erectus")
 
-	res := resolver.Resolve("BOLD:AAA1111")
+	res := resolver.Resolve("BOLD:AAA1111", 0.5, 0)
 	if !res.Accepted {
 		t.Fatalf("resolver.Resolve() returned !accepted")
 	}
@@ -101,7 +101,7 @@ func TestBioscanBinSpeciesResolverConflictOnTie(t *testing.T) {
 	resolver.Observe("BOLD:TIE0001", "Panthera", "Panthera leo")
 	resolver.Observe("BOLD:TIE0001", "Panthera", "Panthera onca")
 
-	res := resolver.Resolve("BOLD:TIE0001")
+	res := resolver.Resolve("BOLD:TIE0001", 0.5, 0)
 	if res.Accepted {
 		t.Fatalf("resolver.Resolve() unexpectedly accepted tie case")
 	}
@@ -117,7 +117,7 @@ func TestBioscanBinSpeciesResolverIgnoresUnresolvedAndMismatch(t *testing.T) {
 	resolver.Observe("BOLD:BAD0001", "Homo", "None")
 	resolver.Observe("BOLD:BAD0001", "Canis", "Homo sapiens")
 
-	res := resolver.Resolve("BOLD:BAD0001")
+	res := resolver.Resolve("BOLD:BAD0001", 0.5, 0)
 	if res.Accepted || res.Canonical != "" || res.Conflict {
 		t.Fatalf("resolver.Resolve()=%+v want empty unresolved state", res)
 	}
@@ -131,11 +131,53 @@ func TestBioscanBinSpeciesResolverConflictWithoutMajority(t *testing.T) {
 	resolver.Observe("BOLD:WEAK1", "Apis", "Apis dorsata")
 	resolver.Observe("BOLD:WEAK1", "Apis", "Apis mellifera")
 
-	res := resolver.Resolve("BOLD:WEAK1")
+	res := resolver.Resolve("BOLD:WEAK1", 0.5, 0)
 	if res.Accepted {
 		t.Fatalf("resolver.Resolve() unexpectedly accepted non-majority case")
 	}
 	if !res.Conflict {
 		t.Fatalf("resolver.Resolve() expected conflict=true for non-majority case")
 	}
+	// A majority of 0 takes the unique top species whatever its share.
+	if res := resolver.Resolve("BOLD:WEAK1", 0, 0); !res.Accepted || res.Canonical != "Apis mellifera" {
+		t.Fatalf("resolver.Resolve() at majority 0=%+v want Apis mellifera accepted", res)
+	}
+}
+
+func TestBioscanBinSpeciesResolverMajorityThreshold(t *testing.T) {
+	resolver := newBioscanBinSpeciesResolver()
+	for i := 0; i < 3; i++ {
+		resolver.Observe("BOLD:SPLIT1", "Apis", "Apis mellifera")
+	}
+	for i := 0; i < 2; i++ {
+		resolver.Observe("BOLD:SPLIT1", "Apis", "Apis cerana")
+	}
+
+	res := resolver.Resolve("BOLD:SPLIT1", 0.5, 0)
+	if !res.Accepted || res.Canonical != "Apis mellifera" {
+		t.Fatalf("majority 0.5: resolver.Resolve()=%+v want Apis mellifera accepted", res)
+	}
+	res = resolver.Resolve("BOLD:SPLIT1", 0.6, 0)
+	if res.Accepted || !res.Conflict {
+		t.Fatalf("majority 0.6: resolver.Resolve()=%+v want conflict on a 3:2 split", res)
+	}
+	res = resolver.Resolve("BOLD:SPLIT1", 0.5, 4)
+	if res.Accepted || res.Conflict {
+		t.Fatalf("min count 4: resolver.Resolve()=%+v want empty unresolved state", res)
+	}
+}
+
+func TestBioscanBinSpeciesResolverSupportOptIn(t *testing.T) {
+	resolver := newBioscanBinSpeciesResolver()
+	resolver.Observe("BOLD:AAA0001", "Apis", "")
+	if resolver.seen != nil || resolver.Support("BOLD:AAA0001") != 0 {
+		t.Fatalf("expected no support counts without TrackSupport, got %v", resolver.seen)
+	}
+
+	resolver.TrackSupport()
+	resolver.Observe("BOLD:AAA0001", "Apis", "")
+	resolver.Observe("BOLD:AAA0001", "Apis", "Apis mellifera")
+	if got := resolver.Support("BOLD:AAA0001"); got != 2 {
+		t.Fatalf("Support()=%d want 2", got)
+	}
 }
@@ -1,11 +1,6 @@
 package cmd
 
 import (
-	"bufio"
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 )
@@ -22,6 +17,7 @@ const (
 	ruleGenusSpeciesMismatchDemote = "genus_species_mismatch_demote"
 	ruleOpenToBinProvisional       = "open_or_empty_to_bin_provisional"
 	ruleProvisionalDroppedNoBin    = "provisional_dropped_missing_bin"
+	ruleProvisionalLowSupport      = "provisional_dropped_low_bin_support"
 )
 
 type bioscanCurationStats struct {
@@ -36,6 +32,7 @@ type bioscanCurationStats struct {
 	GenusSpeciesMismatchDemote int `json:"genus_species_mismatch_demote"`
 	OpenToBinProvisional       int `json:"open_or_empty_to_bin_provisional"`
 	ProvisionalDroppedNoBin    int `json:"provisional_dropped_missing_bin"`
+	ProvisionalLowSupport      int `json:"provisional_dropped_low_bin_support"`
 }
 
 func (s *bioscanCurationStats) addRules(ruleSet map[string]struct{}) {
@@ -59,6 +56,8 @@ func (s *bioscanCurationStats) addRules(ruleSet map[string]struct{}) {
 			s.OpenToBinProvisional++
 		case ruleProvisionalDroppedNoBin:
 			s.ProvisionalDroppedNoBin++
+		case ruleProvisionalLowSupport:
+			s.ProvisionalLowSupport++
 		}
 	}
 }
@@ -74,87 +73,18 @@ type bioscanCurationReport struct {
 	RulesetVersion string                    `json:"ruleset_version"`
 	InputPath      string                    `json:"input_path"`
 	AuditPath      string                    `json:"audit_path,omitempty"`
+	ReportOnly     bool                      `json:"report_only,omitempty"`
 	BinSummary     bioscanCurationBinSummary `json:"bin_summary"`
 	Stats          bioscanCurationStats      `json:"stats"`
 }
 
-func (c *bioscan5MCurator) openAudit() error {
-	if c.cfg.AuditPath == "" {
-		return nil
-	}
-	if err := os.MkdirAll(filepath.Dir(c.cfg.AuditPath), 0o755); err != nil {
-		return fmt.Errorf("create audit dir: %w", err)
-	}
-	f, err := os.Create(c.cfg.AuditPath)
-	if err != nil {
-		return fmt.Errorf("create audit file: %w", err)
-	}
-	c.auditFile = f
-	c.auditWriter = bufio.NewWriterSize(f, writerBufferSize)
-	if _, err := c.auditWriter.WriteString("processid\tbin_uri\tgenus_before\tspecies_before\tsubfamily_before\tgenus_after\tspecies_after\tsubfamily_after\trules\n"); err != nil {
-		return fmt.Errorf("write audit header: %w", err)
-	}
-	return nil
-}
-
-func (c *bioscan5MCurator) closeAudit() error {
-	if c.auditWriter != nil {
-		if err := c.auditWriter.Flush(); err != nil {
-			return fmt.Errorf("flush audit: %w", err)
-		}
-		c.auditWriter = nil
-	}
-	if c.auditFile != nil {
-		if err := c.auditFile.Close(); err != nil {
-			return fmt.Errorf("close audit: %w", err)
-		}
-		c.auditFile = nil
-	}
-	return nil
-}
-
-func (c *bioscan5MCurator) writeAuditRow(before, after extractTaxonRecord, ruleSet map[string]struct{}, changed bool) error {
-	if c.auditWriter == nil || !changed {
-		return nil
-	}
-	rules := sortedRuleSet(ruleSet)
-	line := strings.Join([]string{
-		auditField(after.ProcessID),
-		auditField(after.BinURI),
-		auditField(before.Genus),
-		auditField(before.Species),
-		auditField(before.Subfamily),
-		auditField(after.Genus),
-		auditField(after.Species),
-		auditField(after.Subfamily),
-		strings.Join(rules, ","),
-	}, "\t")
-	if _, err := c.auditWriter.WriteString(line + "\n"); err != nil {
-		return fmt.Errorf("write audit row: %w", err)
-	}
-	return nil
-}
-
 func (c *bioscan5MCurator) writeReport() error {
-	if c.cfg.ReportPath == "" {
-		return nil
-	}
-	if err := os.MkdirAll(filepath.Dir(c.cfg.ReportPath), 0o755); err != nil {
-		return fmt.Errorf("create report dir: %w", err)
-	}
-	f, err := os.Create(c.cfg.ReportPath)
-	if err != nil {
-		return fmt.Errorf("create report file: %w", err)
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-
 	report := bioscanCurationReport{
 		Protocol:       extractCurationProtocolBioscan5M,
 		RulesetVersion: bioscanRulesetVersion,
 		InputPath:      c.inputPath,
-		AuditPath:      c.cfg.AuditPath,
+		AuditPath:      reportFilePath(c.cfg.AuditPath),
+		ReportOnly:     c.cfg.ReportOnly,
 		BinSummary: bioscanCurationBinSummary{
 			Observed:   c.binsObserved,
 			Canonical:  c.binsCanonical,
@@ -162,13 +92,7 @@ func (c *bioscan5MCurator) writeReport() error {
 		},
 		Stats: c.stats,
 	}
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(report); err != nil {
-		return fmt.Errorf("write report: %w", err)
-	}
-	logf("extract (%s): report -> %s", extractCurationProtocolBioscan5M, c.cfg.ReportPath)
-	return nil
+	return writeCurationReport(c.cfg.ReportPath, extractCurationProtocolBioscan5M, report)
 }
 
 func sortedRuleSet(ruleSet map[string]struct{}) []string {
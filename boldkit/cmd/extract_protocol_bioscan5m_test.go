@@ -48,7 +48,7 @@ func TestBioscanCurateBinCanonicalAdoption(t *testing.T) {
 		t.Fatalf("write input: %v", err)
 	}
 
-	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized()); err != nil {
+	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized(), extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit failed: %v", err)
 	}
 	data, err := os.ReadFile(output)
@@ -74,7 +74,7 @@ func TestBioscanCurateGenusMismatchFallsBackToBinProvisional(t *testing.T) {
 		t.Fatalf("write input: %v", err)
 	}
 
-	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized()); err != nil {
+	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized(), extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit failed: %v", err)
 	}
 	data, err := os.ReadFile(output)
@@ -101,7 +101,7 @@ func TestBioscanCurateDoesNotAdoptConflictedBinSpecies(t *testing.T) {
 		t.Fatalf("write input: %v", err)
 	}
 
-	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized()); err != nil {
+	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized(), extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit failed: %v", err)
 	}
 	data, err := os.ReadFile(output)
@@ -138,7 +138,7 @@ func TestBioscanReportAndAuditOutputs(t *testing.T) {
 		ReportPath: report,
 		AuditPath:  audit,
 	}.normalized()
-	if _, err := buildTaxonkit(input, output, 0, -1, cfg); err != nil {
+	if _, err := buildTaxonkit(input, output, 0, -1, cfg, extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit failed: %v", err)
 	}
 
@@ -172,3 +172,223 @@ func TestBioscanReportAndAuditOutputs(t *testing.T) {
 		t.Fatalf("expected P2 change in audit, got:\n%s", string(auditBytes))
 	}
 }
+
+func TestBioscanCurateBinLimits(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens",
+		"P2\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens",
+		"P3\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\t",
+		"P4\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo erectus",
+		"P5\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\t",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	capped := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, MaxBins: 1}.normalized()
+	if _, err := buildTaxonkit(input, filepath.Join(tmp, "capped.tsv"), 0, -1, capped, extractOptions{}); err == nil {
+		t.Fatalf("expected -curate-max-bins 1 to fail with two BINs")
+	}
+
+	// With a min record count the cap prunes BIN2 mid-pass instead of failing.
+	cappedPruned := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, MaxBins: 1, MinBinRecords: 2}.normalized()
+	curator, err := newExtractBioscan5MCurator(cappedPruned, input)
+	if err != nil {
+		t.Fatalf("expected -curate-max-bins 1 with -curate-min-bin-records 2 to prune, got: %v", err)
+	}
+	if bins := curator.(*bioscan5MCurator).resolver.Bins(); bins != 1 {
+		t.Fatalf("resolver holds %d BINs after priming, want 1", bins)
+	}
+	_ = curator.Close()
+
+	output := filepath.Join(tmp, "output.tsv")
+	pruned := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, MinBinRecords: 2}.normalized()
+	if _, err := buildTaxonkit(input, output, 0, -1, pruned, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "Homo\tHomo sapiens\tP3\n") {
+		t.Fatalf("expected P3 to adopt BIN1 canonical species, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Homo\tHomo sp. BOLD:BIN2\tP5\n") {
+		t.Fatalf("expected P5 to stay provisional after BIN2 was pruned, got:\n%s", got)
+	}
+}
+
+func TestBioscanCurateProvisionalMinSupport(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	output := filepath.Join(tmp, "output.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\t",
+		"P2\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\t",
+		"P3\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\t",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	cfg := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, ProvisionalMinSupport: 2}.normalized()
+	if _, err := buildTaxonkit(input, output, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "Homo\tHomo sp. BOLD:BIN1\tP1\n") {
+		t.Fatalf("expected BIN1 (2 rows) to keep its provisional label, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Homo\t\tP3\n") {
+		t.Fatalf("expected singleton BIN2 to leave species empty, got:\n%s", got)
+	}
+}
+
+func TestBioscanCurateReportOnly(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens",
+		"P2\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sp. BOLD:BIN1",
+		"P3\tBOLD:BIN3\tAnimalia\tArthropoda\tInsecta\tLepidoptera\tCrambidae\t\tHaimbachiini\tHomo\tsapiens",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	noneOut := filepath.Join(tmp, "none.tsv")
+	if _, err := buildTaxonkit(input, noneOut, 0, -1, extractCurationConfig{}.normalized(), extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit none failed: %v", err)
+	}
+	reportOut := filepath.Join(tmp, "report_only.tsv")
+	reportPath := filepath.Join(tmp, "curation.json")
+	auditPath := filepath.Join(tmp, "audit.tsv")
+	cfg := extractCurationConfig{
+		Protocol:   extractCurationProtocolBioscan5M,
+		ReportPath: reportPath,
+		AuditPath:  auditPath,
+		ReportOnly: true,
+	}.normalized()
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if _, err := buildTaxonkit(input, reportOut, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit report-only failed: %v", err)
+	}
+
+	want, err := os.ReadFile(noneOut)
+	if err != nil {
+		t.Fatalf("read none output: %v", err)
+	}
+	got, err := os.ReadFile(reportOut)
+	if err != nil {
+		t.Fatalf("read report-only output: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("report-only output differs from none:\n%s\nwant:\n%s", got, want)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report bioscanCurationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if !report.ReportOnly {
+		t.Fatalf("report_only not set in report")
+	}
+	if report.Stats.RowsChanged == 0 || report.Stats.BinCanonicalAdopted == 0 || report.Stats.SubfamilyFilled == 0 {
+		t.Fatalf("expected nonzero rule counts, got %+v", report.Stats)
+	}
+	audit, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("read audit: %v", err)
+	}
+	if !strings.Contains(string(audit), "P2") {
+		t.Fatalf("audit missing P2 change:\n%s", audit)
+	}
+}
+
+func TestBioscanCurateSubfamilyTemplate(t *testing.T) {
+	cases := []struct {
+		name     string
+		tmpl     string
+		disabled bool
+		want     string
+	}{
+		{"custom", "{family}_incertae_sedis", false, "Crambidae_incertae_sedis"},
+		{"disabled", "", true, ""},
+	}
+	for _, tc := range cases {
+		cfg := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, SubfamilyTemplate: tc.tmpl, NoSubfamilyFill: tc.disabled}
+		if err := cfg.validate(); err != nil {
+			t.Fatalf("%s: validate: %v", tc.name, err)
+		}
+		curator, err := newExtractBioscan5MCurator(cfg, "")
+		if err != nil {
+			t.Fatalf("%s: newExtractBioscan5MCurator failed: %v", tc.name, err)
+		}
+		rec := &extractTaxonRecord{
+			Family:  "Crambidae",
+			Tribe:   "Haimbachiini",
+			Genus:   "Homo",
+			Species: "Homo sapiens",
+			BinURI:  "BOLD:AAA0001",
+		}
+		if err := curator.Curate(rec); err != nil {
+			t.Fatalf("%s: Curate failed: %v", tc.name, err)
+		}
+		if rec.Subfamily != tc.want {
+			t.Fatalf("%s: subfamily=%q want %q", tc.name, rec.Subfamily, tc.want)
+		}
+	}
+
+	if err := (extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, SubfamilyTemplate: "incertae sedis"}).validate(); err == nil {
+		t.Fatalf("expected a template without {family} to be rejected")
+	}
+}
+
+func TestBioscanCurateProvisionalTemplate(t *testing.T) {
+	cfg := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, ProvisionalTemplate: "{genus}_sp_{bin}"}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	curator, err := newExtractBioscan5MCurator(cfg, "")
+	if err != nil {
+		t.Fatalf("newExtractBioscan5MCurator failed: %v", err)
+	}
+	rec := &extractTaxonRecord{
+		ProcessID: "P1",
+		Family:    "Canidae",
+		Genus:     "Canis",
+		Species:   "",
+		BinURI:    "BOLD:AAA1111",
+	}
+	if err := curator.Curate(rec); err != nil {
+		t.Fatalf("Curate failed: %v", err)
+	}
+	if rec.Species != "Canis_sp_BOLD:AAA1111" {
+		t.Fatalf("species=%q want %q", rec.Species, "Canis_sp_BOLD:AAA1111")
+	}
+
+	if got := bioscanProvisionalLabel("{genus} sp. {processid}", "Canis", "", "P1"); got != "Canis sp. P1" {
+		t.Fatalf("processid label=%q want %q", got, "Canis sp. P1")
+	}
+	for _, tmpl := range []string{"{bin}", "{genus} sp."} {
+		if err := (extractCurationConfig{Protocol: extractCurationProtocolBioscan5M, ProvisionalTemplate: tmpl}).validate(); err == nil {
+			t.Fatalf("expected template %q to be rejected", tmpl)
+		}
+	}
+}
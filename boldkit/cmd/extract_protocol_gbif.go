@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	gbifRulesetVersion = "gbif-backbone.v1"
+
+	ruleGBIFSpeciesSynonym   = "gbif_species_synonym_to_accepted"
+	ruleGBIFGenusSynonym     = "gbif_genus_synonym_to_accepted"
+	ruleGBIFGenusFromSpecies = "gbif_genus_from_accepted_species"
+	ruleGBIFSpeciesUnmatched = "gbif_species_unmatched"
+	ruleGBIFGenusUnmatched   = "gbif_genus_unmatched"
+)
+
+type gbifCurationStats struct {
+	RowsTotal        int `json:"rows_total"`
+	RowsChanged      int `json:"rows_changed"`
+	SpeciesSynonym   int `json:"gbif_species_synonym_to_accepted"`
+	GenusSynonym     int `json:"gbif_genus_synonym_to_accepted"`
+	GenusFromSpecies int `json:"gbif_genus_from_accepted_species"`
+	SpeciesUnmatched int `json:"gbif_species_unmatched"`
+	GenusUnmatched   int `json:"gbif_genus_unmatched"`
+}
+
+func (s *gbifCurationStats) addRules(ruleSet map[string]struct{}) {
+	for rule := range ruleSet {
+		switch rule {
+		case ruleGBIFSpeciesSynonym:
+			s.SpeciesSynonym++
+		case ruleGBIFGenusSynonym:
+			s.GenusSynonym++
+		case ruleGBIFGenusFromSpecies:
+			s.GenusFromSpecies++
+		case ruleGBIFSpeciesUnmatched:
+			s.SpeciesUnmatched++
+		case ruleGBIFGenusUnmatched:
+			s.GenusUnmatched++
+		}
+	}
+}
+
+type gbifBackboneSummary struct {
+	Names    int `json:"names"`
+	Accepted int `json:"accepted"`
+	Synonyms int `json:"synonyms"`
+}
+
+type gbifCurationReport struct {
+	Protocol        string              `json:"protocol"`
+	RulesetVersion  string              `json:"ruleset_version"`
+	InputPath       string              `json:"input_path"`
+	BackbonePath    string              `json:"backbone_path"`
+	AuditPath       string              `json:"audit_path,omitempty"`
+	BackboneSummary gbifBackboneSummary `json:"backbone_summary"`
+	Stats           gbifCurationStats   `json:"stats"`
+}
+
+// gbifBackbone maps every backbone name to the canonical name it should be
+// reported as: accepted names map to themselves, synonyms to their accepted
+// name.
+type gbifBackbone struct {
+	accepted map[string]string
+	summary  gbifBackboneSummary
+}
+
+func (b *gbifBackbone) resolve(name string) (string, bool) {
+	accepted, ok := b.accepted[name]
+	return accepted, ok
+}
+
+// loadGBIFBackbone reads a GBIF backbone Taxon.tsv (Darwin Core headers
+// taxonID, acceptedNameUsageID, taxonomicStatus and canonicalName, falling
+// back to scientificName). When a name is both accepted and a synonym of
+// something else, the accepted usage wins.
+func loadGBIFBackbone(path string) (*gbifBackbone, error) {
+	type usage struct {
+		name       string
+		acceptedID string
+		accepted   bool
+	}
+	var (
+		idxID       = -1
+		idxAccepted = -1
+		idxStatus   = -1
+		idxName     = -1
+		nameByID    = make(map[string]string)
+		usages      []usage
+		header      = true
+	)
+
+	err := ParseRows(path, DefaultOptions(), func(row Row) error {
+		if header {
+			header = false
+			idxID = indexOfBytes(row.Fields, "taxonID")
+			idxAccepted = indexOfBytes(row.Fields, "acceptedNameUsageID")
+			idxStatus = indexOfBytes(row.Fields, "taxonomicStatus")
+			idxName = indexOfBytes(row.Fields, "canonicalName")
+			if idxName < 0 {
+				idxName = indexOfBytes(row.Fields, "scientificName")
+			}
+			if idxID < 0 || idxAccepted < 0 || idxStatus < 0 || idxName < 0 {
+				return fmt.Errorf("required headers missing in backbone (taxonID, acceptedNameUsageID, taxonomicStatus, canonicalName)")
+			}
+			return nil
+		}
+
+		name := bioscanNormalizeLabel(string(fieldBytes(row.Fields, idxName)))
+		if name == "" {
+			return nil
+		}
+		id := strings.TrimSpace(string(fieldBytes(row.Fields, idxID)))
+		acceptedID := strings.TrimSpace(string(fieldBytes(row.Fields, idxAccepted)))
+		status := strings.ToLower(strings.TrimSpace(string(fieldBytes(row.Fields, idxStatus))))
+		if id != "" {
+			nameByID[id] = name
+		}
+		usages = append(usages, usage{
+			name:       name,
+			acceptedID: acceptedID,
+			accepted:   acceptedID == "" || acceptedID == id || !strings.Contains(status, "synonym"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gbif backbone: %w", err)
+	}
+
+	b := &gbifBackbone{accepted: make(map[string]string, len(usages))}
+	for _, u := range usages {
+		if u.accepted {
+			b.accepted[u.name] = u.name
+		}
+	}
+	for _, u := range usages {
+		if _, ok := b.accepted[u.name]; ok {
+			continue
+		}
+		target, ok := nameByID[u.acceptedID]
+		if !ok {
+			// Dangling synonym: keep the name as reported.
+			target = u.name
+		}
+		b.accepted[u.name] = target
+	}
+	b.summary.Names = len(b.accepted)
+	for name, target := range b.accepted {
+		if name == target {
+			b.summary.Accepted++
+		} else {
+			b.summary.Synonyms++
+		}
+	}
+	return b, nil
+}
+
+type gbifCurator struct {
+	cfg       extractCurationConfig
+	inputPath string
+	backbone  *gbifBackbone
+	stats     gbifCurationStats
+	audit     *curationAudit
+}
+
+func newExtractGBIFCurator(cfg extractCurationConfig, inputPath string) (extractCurator, error) {
+	backbone, err := loadGBIFBackbone(cfg.GBIFBackbonePath)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := openCurationAudit(cfg.AuditPath)
+	if err != nil {
+		return nil, err
+	}
+	return &gbifCurator{
+		cfg:       cfg,
+		inputPath: inputPath,
+		backbone:  backbone,
+		audit:     audit,
+	}, nil
+}
+
+// Curate rewrites synonym species and genus names to their accepted backbone
+// names. Names absent from the backbone are left unchanged but recorded in
+// the audit so they can be reviewed.
+func (c *gbifCurator) Curate(rec *extractTaxonRecord) error {
+	if rec == nil {
+		return nil
+	}
+	c.stats.RowsTotal++
+	original := *rec
+	ruleSet := make(map[string]struct{})
+
+	genus := rec.Genus
+	species := rec.Species
+	genusFromSpecies := false
+	if species != "" {
+		if accepted, ok := c.backbone.resolve(species); ok {
+			if accepted != species {
+				species = accepted
+				ruleSet[ruleGBIFSpeciesSynonym] = struct{}{}
+				if g, _, found := strings.Cut(accepted, " "); found && g != genus {
+					genus = g
+					genusFromSpecies = true
+					ruleSet[ruleGBIFGenusFromSpecies] = struct{}{}
+				}
+			}
+		} else {
+			ruleSet[ruleGBIFSpeciesUnmatched] = struct{}{}
+		}
+	}
+	if genus != "" && !genusFromSpecies {
+		if accepted, ok := c.backbone.resolve(genus); ok {
+			if accepted != genus {
+				genus = accepted
+				ruleSet[ruleGBIFGenusSynonym] = struct{}{}
+			}
+		} else {
+			ruleSet[ruleGBIFGenusUnmatched] = struct{}{}
+		}
+	}
+
+	rec.Genus = genus
+	rec.Species = species
+	rec.Subspecies = consistentSubspecies(rec.Species, rec.Subspecies)
+	changed := original.Genus != rec.Genus || original.Species != rec.Species
+	if changed {
+		c.stats.RowsChanged++
+	}
+	c.stats.addRules(ruleSet)
+	if len(ruleSet) > 0 {
+		if err := c.audit.writeRow(original, *rec, ruleSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *gbifCurator) Close() error {
+	logf("extract (%s): backbone-names=%d synonyms=%d species-unmatched=%d genus-unmatched=%d",
+		extractCurationProtocolGBIF, c.backbone.summary.Names, c.backbone.summary.Synonyms, c.stats.SpeciesUnmatched, c.stats.GenusUnmatched)
+	var firstErr error
+	report := gbifCurationReport{
+		Protocol:        extractCurationProtocolGBIF,
+		RulesetVersion:  gbifRulesetVersion,
+		InputPath:       c.inputPath,
+		BackbonePath:    c.cfg.GBIFBackbonePath,
+		AuditPath:       reportFilePath(c.cfg.AuditPath),
+		BackboneSummary: c.backbone.summary,
+		Stats:           c.stats,
+	}
+	if err := writeCurationReport(c.cfg.ReportPath, extractCurationProtocolGBIF, report); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := c.audit.close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGBIFCurateSynonymAndUnmatched(t *testing.T) {
+	tmp := t.TempDir()
+	backbone := filepath.Join(tmp, "Taxon.tsv")
+	input := filepath.Join(tmp, "input.tsv")
+	output := filepath.Join(tmp, "output.tsv")
+	reportPath := filepath.Join(tmp, "report.json")
+	auditPath := filepath.Join(tmp, "audit.tsv")
+
+	backboneContent := strings.Join([]string{
+		"taxonID\tacceptedNameUsageID\ttaxonomicStatus\ttaxonRank\tcanonicalName",
+		"10\t\taccepted\tgenus\tPanthera",
+		"11\t\taccepted\tspecies\tPanthera leo",
+		"12\t11\tsynonym\tspecies\tFelis leo",
+		"13\t\taccepted\tgenus\tFelis",
+	}, "\n") + "\n"
+	if err := os.WriteFile(backbone, []byte(backboneContent), 0o644); err != nil {
+		t.Fatalf("write backbone: %v", err)
+	}
+	inputContent := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tCarnivora\tFelidae\t\t\tFelis\tFelis leo",
+		"P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tCarnivora\tFelidae\t\t\tPanthera\tPanthera novus",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(inputContent), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	cfg := extractCurationConfig{
+		Protocol:         extractCurationProtocolGBIF,
+		ReportPath:       reportPath,
+		AuditPath:        auditPath,
+		GBIFBackbonePath: backbone,
+	}.normalized()
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if _, err := buildTaxonkit(input, output, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "Panthera\tPanthera leo\tP1\n") {
+		t.Fatalf("expected P1 synonym rewritten to accepted name, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Panthera\tPanthera novus\tP2\n") {
+		t.Fatalf("expected P2 unmatched species left unchanged, got:\n%s", got)
+	}
+
+	auditData, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("read audit: %v", err)
+	}
+	audit := string(auditData)
+	if !strings.Contains(audit, "P1\tBOLD:BIN1\tFelis\tFelis leo\t\tPanthera\tPanthera leo\t\t"+ruleGBIFGenusFromSpecies+","+ruleGBIFSpeciesSynonym+"\n") {
+		t.Fatalf("missing P1 audit row, got:\n%s", audit)
+	}
+	if !strings.Contains(audit, "P2\tBOLD:BIN2\tPanthera\tPanthera novus\t\tPanthera\tPanthera novus\t\t"+ruleGBIFSpeciesUnmatched+"\n") {
+		t.Fatalf("missing P2 unmatched audit row, got:\n%s", audit)
+	}
+
+	reportData, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report gbifCurationReport
+	if err := json.Unmarshal(reportData, &report); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if report.RulesetVersion != gbifRulesetVersion {
+		t.Fatalf("ruleset_version=%q want %q", report.RulesetVersion, gbifRulesetVersion)
+	}
+	if report.Stats.RowsChanged != 1 || report.Stats.SpeciesSynonym != 1 || report.Stats.SpeciesUnmatched != 1 {
+		t.Fatalf("unexpected stats: %+v", report.Stats)
+	}
+	if report.BackboneSummary.Synonyms != 1 {
+		t.Fatalf("backbone synonyms=%d want 1", report.BackboneSummary.Synonyms)
+	}
+}
+
+func TestGBIFCurationRequiresBackbone(t *testing.T) {
+	cfg := extractCurationConfig{Protocol: extractCurationProtocolGBIF}.normalized()
+	if err := cfg.validate(); err == nil {
+		t.Fatalf("expected validate to fail without -gbif-backbone")
+	}
+}
@@ -21,7 +21,7 @@ func TestBuildTaxonkitProcessIDFallbackByProtocol(t *testing.T) {
 		t.Fatalf("write input: %v", err)
 	}
 
-	if _, err := buildTaxonkit(input, outputNone, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolNone}.normalized()); err != nil {
+	if _, err := buildTaxonkit(input, outputNone, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolNone}.normalized(), extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit none failed: %v", err)
 	}
 	dataNone, err := os.ReadFile(outputNone)
@@ -32,7 +32,7 @@ func TestBuildTaxonkitProcessIDFallbackByProtocol(t *testing.T) {
 		t.Fatalf("expected PROCESSID fallback in none mode, got:\n%s", string(dataNone))
 	}
 
-	if _, err := buildTaxonkit(input, outputBioscan, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized()); err != nil {
+	if _, err := buildTaxonkit(input, outputBioscan, 0, -1, extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized(), extractOptions{}); err != nil {
 		t.Fatalf("buildTaxonkit bioscan failed: %v", err)
 	}
 	dataBioscan, err := os.ReadFile(outputBioscan)
@@ -46,3 +46,299 @@ func TestBuildTaxonkitProcessIDFallbackByProtocol(t *testing.T) {
 		t.Fatalf("expected empty species in bioscan mode when BIN is missing, got:\n%s", string(dataBioscan))
 	}
 }
+
+func TestExtraPlaceholderTokens(t *testing.T) {
+	tokens := newLabelTokens("", "Environmental Sample, incertae  sedis")
+	if got := tokens.normalizeBytes([]byte("environmental   sample")); got != nil {
+		t.Fatalf("normalizeBytes=%q want empty", got)
+	}
+	if got := string(tokens.normalizeBytes([]byte("Panthera leo"))); got != "Panthera leo" {
+		t.Fatalf("normalizeBytes=%q want unchanged", got)
+	}
+
+	curator, err := newExtractBioscan5MCurator(extractCurationConfig{Tokens: tokens}, "")
+	if err != nil {
+		t.Fatalf("newExtractBioscan5MCurator failed: %v", err)
+	}
+	bioscan := curator.(*bioscan5MCurator).tokens
+	if got := bioscan.normalizeLabel("  Environmental sample "); got != "" {
+		t.Fatalf("normalizeLabel=%q want empty", got)
+	}
+	if got := bioscan.normalizeLabel("Environmental samples"); got != "Environmental samples" {
+		t.Fatalf("normalizeLabel=%q want unchanged (exact match only)", got)
+	}
+	if got := bioscan.normalizeLabel("unknown"); got != "" {
+		t.Fatalf("normalizeLabel=%q want empty (built-in bioscan placeholder)", got)
+	}
+	if got := bioscanNormalizeLabel("Environmental sample"); got != "Environmental sample" {
+		t.Fatalf("bioscanNormalizeLabel=%q want unchanged (no config set)", got)
+	}
+}
+
+func TestNullTokensSharedWithTaxonkit(t *testing.T) {
+	tokens := newLabelTokens(defaultNullTokens, "")
+	for _, token := range []string{"None", "NULL", "NA"} {
+		if got := tokens.normalizeBytes([]byte(token)); got != nil {
+			t.Fatalf("normalizeBytes(%q)=%q want empty", token, got)
+		}
+	}
+	// taxonkit --null is case-sensitive, so extract keeps what it keeps.
+	for _, token := range []string{"null", "Na"} {
+		if got := string(tokens.normalizeBytes([]byte(token))); got != token {
+			t.Fatalf("normalizeBytes(%q)=%q want unchanged", token, got)
+		}
+	}
+	args := taxonkitCreateArgs("in.tsv", "out", parseNullTokens(" None, NULL ,NA,"))
+	got := strings.Join(args, " ")
+	if !strings.Contains(got, "--null None,NULL,NA") {
+		t.Fatalf("create-taxdump args=%q want --null None,NULL,NA", got)
+	}
+	if args := taxonkitCreateArgs("in.tsv", "out", nil); strings.Contains(strings.Join(args, " "), "--null") {
+		t.Fatalf("expected no --null without tokens, got %v", args)
+	}
+}
+
+func TestBuildTaxonkitEmitMarker(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tmarker_code",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens\tCOI-5P",
+		"P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens\t",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	cfg := extractCurationConfig{}.normalized()
+	plain := filepath.Join(tmp, "plain.tsv")
+	if _, err := buildTaxonkit(input, plain, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if strings.Contains(string(data), "marker_code") || strings.Contains(string(data), "COI-5P") {
+		t.Fatalf("marker column emitted without -emit-marker:\n%s", data)
+	}
+
+	withMarker := filepath.Join(tmp, "marker.tsv")
+	if _, err := buildTaxonkit(input, withMarker, 0, -1, cfg, extractOptions{EmitMarker: true}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err = os.ReadFile(withMarker)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.HasPrefix(got, "kingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tprocessid\tmarker_code\n") {
+		t.Fatalf("missing marker_code header:\n%s", got)
+	}
+	if !strings.Contains(got, "\tP1\tCOI-5P\n") || !strings.Contains(got, "\tP2\tunknown\n") {
+		t.Fatalf("unexpected marker values:\n%s", got)
+	}
+
+	withSource := filepath.Join(tmp, "source.tsv")
+	if _, err := buildTaxonkit(input, withSource, 0, -1, cfg, extractOptions{EmitMarker: true, EmitSource: true}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err = os.ReadFile(withSource)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got = string(data)
+	base := filepath.Base(input)
+	if !strings.Contains(got, "\tprocessid\tmarker_code\tsource_file\n") || !strings.Contains(got, "\tP1\tCOI-5P\t"+base+"\n") {
+		t.Fatalf("unexpected source_file column:\n%s", got)
+	}
+}
+
+func TestBuildTaxonkitPreserveNullMarker(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tNone\t\t\tHomo\tHomo sapiens",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	cfg := extractCurationConfig{Tokens: newLabelTokens(defaultNullTokens, "")}.normalized()
+	plain := filepath.Join(tmp, "plain.tsv")
+	if _, err := buildTaxonkit(input, plain, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.Contains(string(data), "Primates\t\t\t\tHomo\t") {
+		t.Fatalf("null family not blanked by default:\n%s", data)
+	}
+
+	marked := filepath.Join(tmp, "marked.tsv")
+	if _, err := buildTaxonkit(input, marked, 0, -1, cfg, extractOptions{NullMarker: "__null__"}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err = os.ReadFile(marked)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	// family was "None"; subfamily and tribe were empty and stay empty.
+	if !strings.Contains(string(data), "Primates\t__null__\t\t\tHomo\t") {
+		t.Fatalf("null family not marked:\n%s", data)
+	}
+}
+
+func TestBuildTaxonkitCountryFilter(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	output := filepath.Join(tmp, "output.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tcountry/ocean\tinst",
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens\tCanada\tCBG",
+		"P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\t\t\tCanis\tCanis lupus\tKenya\tCBG",
+		"P3\tBOLD:BIN3\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\t\t\tCanis\tCanis lupus\tcanada\tNHM",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	opts := extractOptions{FilterCountries: []string{"CANADA"}}
+	if _, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{}.normalized(), opts); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "\tP1\n") || !strings.Contains(got, "\tP3\n") || strings.Contains(got, "\tP2\n") {
+		t.Fatalf("expected only Canadian rows P1 and P3, got:\n%s", got)
+	}
+}
+
+func TestBuildTaxonkitAppend(t *testing.T) {
+	tmp := t.TempDir()
+	header := "processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies"
+	first := filepath.Join(tmp, "first.tsv")
+	content := strings.Join([]string{
+		header,
+		"P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens",
+		"P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\t\t\tCanis\tCanis lupus",
+	}, "\n") + "\n"
+	if err := os.WriteFile(first, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	cfg := extractCurationConfig{}.normalized()
+	output := filepath.Join(tmp, "taxonkit_input.tsv")
+	if _, err := buildTaxonkit(first, output, 0, -1, cfg, extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	before, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+
+	delta := filepath.Join(tmp, "delta.tsv")
+	content = strings.Join([]string{
+		header,
+		"P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\t\t\tCanis\tCanis lupus",
+		"P3\tBOLD:BIN3\tAnimalia\tChordata\tMammalia\tCarnivora\tFelidae\t\t\tFelis\tFelis catus",
+		"P3\tBOLD:BIN3\tAnimalia\tChordata\tMammalia\tCarnivora\tFelidae\t\t\tFelis\tFelis catus",
+	}, "\n") + "\n"
+	if err := os.WriteFile(delta, []byte(content), 0o644); err != nil {
+		t.Fatalf("write delta: %v", err)
+	}
+	if _, err := buildTaxonkit(delta, output, 0, -1, cfg, extractOptions{Append: true}); err != nil {
+		t.Fatalf("buildTaxonkit append failed: %v", err)
+	}
+	after, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := string(before) + "Animalia\tChordata\tMammalia\tCarnivora\tFelidae\t\t\tFelis\tFelis catus\tP3\n"
+	if string(after) != want {
+		t.Fatalf("appended output=%q want %q", after, want)
+	}
+
+	bioscan := extractCurationConfig{Protocol: extractCurationProtocolBioscan5M}.normalized()
+	if _, err := buildTaxonkit(delta, output, 0, -1, bioscan, extractOptions{Append: true}); err == nil {
+		t.Fatalf("expected -append with bioscan-5m to fail")
+	}
+
+	// A later shard whose header differs fails after the first shard's rows
+	// were written; the output must be back to what it held before.
+	other := filepath.Join(tmp, "delta2.tsv")
+	if err := os.WriteFile(other, []byte("processid\tbin_uri\n"), 0o644); err != nil {
+		t.Fatalf("write delta2: %v", err)
+	}
+	fresh := filepath.Join(tmp, "delta3.tsv")
+	content = header + "\nP4\tBOLD:BIN4\tAnimalia\tChordata\tMammalia\tCarnivora\tUrsidae\t\t\tUrsus\tUrsus arctos\n"
+	if err := os.WriteFile(fresh, []byte(content), 0o644); err != nil {
+		t.Fatalf("write delta3: %v", err)
+	}
+	_, err = buildTaxonkit(fresh, output, 0, -1, cfg, extractOptions{Append: true, Inputs: []string{fresh, other}})
+	if err == nil || !strings.Contains(err.Error(), "header columns") {
+		t.Fatalf("expected header mismatch error, got %v", err)
+	}
+	rolledBack, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(rolledBack) != want {
+		t.Fatalf("failed append left output=%q want %q", rolledBack, want)
+	}
+}
+
+func TestBuildTaxonkitGlobInputs(t *testing.T) {
+	tmp := t.TempDir()
+	header := "processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies"
+	shards := map[string]string{
+		"BOLD_Public.part1.tsv": "P1\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens",
+		"BOLD_Public.part2.tsv": "P2\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\t\t\tCanis\tCanis lupus",
+	}
+	for name, row := range shards {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(header+"\n"+row+"\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	inputs, err := expandInputs(filepath.Join(tmp, "BOLD_Public.*.tsv"))
+	if err != nil {
+		t.Fatalf("expandInputs: %v", err)
+	}
+	if len(inputs) != 2 {
+		t.Fatalf("inputs=%v want 2 files", inputs)
+	}
+
+	output := filepath.Join(tmp, "taxonkit_input.tsv")
+	cfg := extractCurationConfig{}.normalized()
+	rows, err := buildTaxonkit(inputs[0], output, 0, -1, cfg, extractOptions{Inputs: inputs, EmitSource: true})
+	if err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("rows=%d want 2", rows)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines=%d want header + 2 rows:\n%s", len(lines), data)
+	}
+	if !strings.HasSuffix(lines[1], "\tP1\tBOLD_Public.part1.tsv") || !strings.HasSuffix(lines[2], "\tP2\tBOLD_Public.part2.tsv") {
+		t.Fatalf("unexpected merged rows:\n%s", data)
+	}
+
+	other := filepath.Join(tmp, "BOLD_Public.part3.tsv")
+	if err := os.WriteFile(other, []byte("processid\tbin_uri\n"), 0o644); err != nil {
+		t.Fatalf("write part3: %v", err)
+	}
+	_, err = buildTaxonkit(inputs[0], output, 0, -1, cfg, extractOptions{Inputs: append(inputs, other)})
+	if err == nil || !strings.Contains(err.Error(), "header columns") {
+		t.Fatalf("expected header mismatch error, got %v", err)
+	}
+}
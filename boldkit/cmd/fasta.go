@@ -5,12 +5,18 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 )
 
 type fastaRecord struct {
 	id  string
 	seq []byte
+	// qual holds Phred+33 quality bytes for FASTQ input; nil for FASTA.
+	qual []byte
+	// header is the full header line without '>', for formats that carry
+	// data after the ID (SINTAX tax=, RDP lineage).
+	header string
 }
 
 func parseFasta(r io.Reader, onRecord func(fastaRecord) error) error {
@@ -25,8 +31,9 @@ func parseFasta(r io.Reader, onRecord func(fastaRecord) error) error {
 			return nil
 		}
 		rec := fastaRecord{
-			id:  fastaID(header),
-			seq: append([]byte(nil), seq.Bytes()...),
+			id:     fastaID(header),
+			seq:    append([]byte(nil), seq.Bytes()...),
+			header: header,
 		}
 		seq.Reset()
 		header = ""
@@ -39,6 +46,9 @@ func parseFasta(r io.Reader, onRecord func(fastaRecord) error) error {
 			if err := emit(); err != nil {
 				return err
 			}
+			if err := interrupted(); err != nil {
+				return err
+			}
 			header = strings.TrimSpace(line[1:])
 			continue
 		}
@@ -89,3 +99,96 @@ func sanitizeTaxon(name string) string {
 	}
 	return b.String()
 }
+
+// Policies for records whose sequence is empty (-on-empty-seq).
+const (
+	emptySeqSkip  = "skip"
+	emptySeqKeep  = "keep"
+	emptySeqError = "error"
+)
+
+func normalizeEmptySeqPolicy(policy string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+	case "", emptySeqSkip:
+		return emptySeqSkip, nil
+	case emptySeqKeep, emptySeqError:
+		return p, nil
+	}
+	return "", fmt.Errorf("on-empty-seq must be one of skip, keep, error (got %q)", policy)
+}
+
+// Policies for repeated record IDs (-on-duplicate-id).
+const (
+	duplicateIDError  = "error"
+	duplicateIDSkip   = "skip"
+	duplicateIDRename = "rename"
+)
+
+func normalizeDuplicateIDPolicy(policy string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+	case "", duplicateIDError:
+		return duplicateIDError, nil
+	case duplicateIDSkip, duplicateIDRename:
+		return p, nil
+	}
+	return "", fmt.Errorf("on-duplicate-id must be one of error, skip, rename (got %q)", policy)
+}
+
+// withDuplicateIDPolicy wraps onRecord so a repeated record ID fails
+// ("error", the default), is dropped and counted in handled ("skip"), or is
+// renamed to <id>_<n> with the first free n >= 2 and counted ("rename").
+// A non-nil renamed map receives new ID -> original ID. Each call tracks its
+// own IDs, so every pass over the same input makes the same choices.
+func withDuplicateIDPolicy(policy string, handled *int, renamed map[string]string, onRecord func(fastaRecord) error) func(fastaRecord) error {
+	seen := make(map[string]struct{})
+	return func(rec fastaRecord) error {
+		if _, dup := seen[rec.id]; dup {
+			switch policy {
+			case duplicateIDSkip:
+				if handled != nil {
+					*handled++
+				}
+				return nil
+			case duplicateIDRename:
+				id := rec.id
+				for n := 2; ; n++ {
+					rec.id = id + "_" + strconv.Itoa(n)
+					if _, taken := seen[rec.id]; !taken {
+						break
+					}
+				}
+				if handled != nil {
+					*handled++
+				}
+				if renamed != nil {
+					renamed[rec.id] = id
+				}
+			default:
+				return fmt.Errorf("duplicate processid in input FASTA: %s", rec.id)
+			}
+		}
+		seen[rec.id] = struct{}{}
+		return onRecord(rec)
+	}
+}
+
+// withEmptySeqPolicy wraps onRecord so empty-sequence records are dropped and
+// counted in skipped ("skip", the default), rejected with an error ("error"),
+// or passed through unchanged ("keep").
+func withEmptySeqPolicy(policy string, skipped *int, onRecord func(fastaRecord) error) func(fastaRecord) error {
+	if policy == emptySeqKeep {
+		return onRecord
+	}
+	return func(rec fastaRecord) error {
+		if len(rec.seq) == 0 {
+			if policy == emptySeqError {
+				return fmt.Errorf("record %q has an empty sequence", rec.id)
+			}
+			if skipped != nil {
+				*skipped++
+			}
+			return nil
+		}
+		return onRecord(rec)
+	}
+}
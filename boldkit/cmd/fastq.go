@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseSequences sniffs the first non-blank byte of r and dispatches to
+// parseFastq for '@' records, otherwise parseFasta.
+func parseSequences(r io.Reader, onRecord func(fastaRecord) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("read input: %w", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+			continue
+		case '@':
+			return parseFastq(br, onRecord)
+		}
+		return parseFasta(br, onRecord)
+	}
+}
+
+// parseFastq reads four-line FASTQ records (header, sequence, '+' separator,
+// quality) and yields them as fastaRecords with qual set. Blank lines are
+// skipped only between records; inside one the lines are positional, so an
+// empty sequence with its empty quality line reads back.
+func parseFastq(r io.Reader, onRecord func(fastaRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	line := 0
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		line++
+		return strings.TrimSpace(scanner.Text()), true
+	}
+
+	for {
+		if err := interrupted(); err != nil {
+			return err
+		}
+		header, ok := next()
+		for ok && header == "" {
+			header, ok = next()
+		}
+		if !ok {
+			break
+		}
+		if !strings.HasPrefix(header, "@") {
+			return fmt.Errorf("fastq line %d: expected '@' header", line)
+		}
+		seq, ok := next()
+		if !ok {
+			return fmt.Errorf("fastq line %d: truncated record %s", line, header)
+		}
+		sep, ok := next()
+		if !ok || !strings.HasPrefix(sep, "+") {
+			return fmt.Errorf("fastq line %d: expected '+' separator", line)
+		}
+		qual, ok := next()
+		if !ok {
+			return fmt.Errorf("fastq line %d: truncated record %s", line, header)
+		}
+		if len(qual) != len(seq) {
+			return fmt.Errorf("fastq line %d: quality length %d does not match sequence length %d", line, len(qual), len(seq))
+		}
+		rec := fastaRecord{
+			id:   fastaID(header[1:]),
+			seq:  []byte(seq),
+			qual: []byte(qual),
+		}
+		if err := onRecord(rec); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan fastq: %w", err)
+	}
+	return nil
+}
+
+// meanPhred returns the mean Phred+33 quality of qual (0 when empty).
+func meanPhred(qual []byte) float64 {
+	if len(qual) == 0 {
+		return 0
+	}
+	total := 0
+	for _, q := range qual {
+		total += int(q) - 33
+	}
+	return float64(total) / float64(len(qual))
+}
+
+// Sequence output formats (-output-format).
+const (
+	outputFormatFasta = "fasta"
+	outputFormatFastq = "fastq"
+)
+
+// defaultFastqQual is Phred 40 in Phred+33.
+const defaultFastqQual = "I"
+
+// parseFastqQual validates -output-format and -fastq-qual and returns the
+// quality byte FASTQ records are written with, or 0 for FASTA. The qualities
+// are synthetic: every base gets the same printable Phred+33 character.
+func parseFastqQual(format, qual string) (byte, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", outputFormatFasta:
+		return 0, nil
+	case outputFormatFastq:
+	default:
+		return 0, fmt.Errorf("output-format must be fasta or fastq (got %q)", format)
+	}
+	if len(qual) != 1 || qual[0] < '!' || qual[0] > '~' {
+		return 0, fmt.Errorf("fastq-qual must be one printable Phred+33 character ('!'..'~'), got %q", qual)
+	}
+	return qual[0], nil
+}
+
+// seqFileExt is the extension of sequence outputs written with fastqQual.
+func seqFileExt(fastqQual byte) string {
+	if fastqQual != 0 {
+		return ".fastq"
+	}
+	return ".fasta"
+}
+
+// writeSeqRecord writes a FASTA record, or a FASTQ record with a constant
+// fastqQual quality string when fastqQual is non-zero.
+func writeSeqRecord(w *bufio.Writer, header string, seq []byte, fastqQual byte) error {
+	if fastqQual == 0 {
+		return writeFasta(w, header, seq)
+	}
+	if _, err := w.WriteString("@" + header + "\n"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err := w.Write(seq); err != nil {
+		return fmt.Errorf("write seq: %w", err)
+	}
+	if _, err := w.WriteString("\n+\n"); err != nil {
+		return fmt.Errorf("write separator: %w", err)
+	}
+	for range seq {
+		if err := w.WriteByte(fastqQual); err != nil {
+			return fmt.Errorf("write quality: %w", err)
+		}
+	}
+	if err := w.WriteByte('\n'); err != nil {
+		return fmt.Errorf("write newline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSequencesFastq(t *testing.T) {
+	input := "@R1 sample\nACGT\n+\nIIII\n@R2\nTTGA\n+R2\n$$$$\n"
+	var recs []fastaRecord
+	err := parseSequences(strings.NewReader(input), func(rec fastaRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("parseSequences failed: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records want 2", len(recs))
+	}
+	if recs[0].id != "R1" || string(recs[0].seq) != "ACGT" || string(recs[0].qual) != "IIII" {
+		t.Fatalf("unexpected first record: %+v", recs[0])
+	}
+	if got := meanPhred(recs[1].qual); got != 3 {
+		t.Fatalf("mean phred=%v want 3", got)
+	}
+}
+
+func TestFastqEmptyRecordRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	for _, rec := range []struct{ id, seq string }{{"A", ""}, {"B", "ACGT"}} {
+		if err := writeSeqRecord(w, rec.id, []byte(rec.seq), 'I'); err != nil {
+			t.Fatalf("writeSeqRecord failed: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var recs []fastaRecord
+	err := parseSequences(bytes.NewReader(buf.Bytes()), func(rec fastaRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("parseSequences of %q failed: %v", buf.String(), err)
+	}
+	if len(recs) != 2 || recs[0].id != "A" || len(recs[0].seq) != 0 || len(recs[0].qual) != 0 || string(recs[1].seq) != "ACGT" {
+		t.Fatalf("unexpected records: %+v", recs)
+	}
+}
+
+func TestQCFastqMeanQuality(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "reads.fastq")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	if err := os.WriteFile(input, []byte("@GOOD\nACGTACGT\n+\nIIIIIIII\n@BAD\nTTGATTGA\n+\n########\n"), 0o644); err != nil {
+		t.Fatalf("write fastq: %v", err)
+	}
+
+	err := qcFasta(input, QCConfig{
+		MaxN:        -1,
+		MaxAmbig:    -1,
+		MinMeanQual: 20,
+		OutputPath:  output,
+		ReportPath:  report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(kept) != ">GOOD\nACGTACGT\n" {
+		t.Fatalf("unexpected QC output:\n%s", string(kept))
+	}
+	if stats := readQCReport(t, report); stats.LowQuality != 1 {
+		t.Fatalf("low_quality=%d want 1", stats.LowQuality)
+	}
+}
+
+func TestQCFastqMeanQualityAfterTrim(t *testing.T) {
+	// Both reads carry low-quality ends that trimming removes; the mean
+	// quality filter must see only the kept bases.
+	reads := "@NPAD\nNNACGTACGTNN\n+\n!!IIIIIIII!!\n" +
+		"@PRIMER\nGGGGTTGATTGA\n+\n!!!!IIIIIIII\n"
+	var out bytes.Buffer
+	stats, err := QCStream(strings.NewReader(reads), &out, QCConfig{
+		MaxN:          -1,
+		MaxAmbig:      -1,
+		MinMeanQual:   30,
+		TrimTerminalN: true,
+		ForwardPrimer: "GGGG",
+	})
+	if err != nil {
+		t.Fatalf("QCStream failed: %v", err)
+	}
+	if stats.LowQuality != 0 {
+		t.Fatalf("low_quality=%d want 0 once the trimmed ends are dropped", stats.LowQuality)
+	}
+	if out.String() != ">NPAD\nACGTACGT\n>PRIMER\nTTGATTGA\n" {
+		t.Fatalf("unexpected QC output:\n%s", out.String())
+	}
+}
+
+func TestFormatOutputFastq(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">P1\nACGT\n")
+
+	fastqQual, err := parseFastqQual("fastq", "5")
+	if err != nil {
+		t.Fatalf("parseFastqQual: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	err = formatFasta(formatConfig{
+		Classifiers:  []string{"blast"},
+		RequireRanks: []string{"kingdom", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+		FastqQual:    fastqQual,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "blast.fastq"))
+	if err != nil {
+		t.Fatalf("read blast.fastq: %v", err)
+	}
+	if string(data) != "@P1\nACGT\n+\n5555\n" {
+		t.Fatalf("blast.fastq=%q", string(data))
+	}
+	if !fileExists(filepath.Join(outDir, "blast_seqid2taxid.map")) {
+		t.Fatalf("map output should keep its name")
+	}
+
+	for _, bad := range [][2]string{{"fastq", ""}, {"fastq", "II"}, {"fastq", " "}, {"bam", "I"}} {
+		if _, err := parseFastqQual(bad[0], bad[1]); err == nil {
+			t.Fatalf("parseFastqQual(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
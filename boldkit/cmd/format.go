@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"bufio"
+	"crypto/md5"
+	"encoding/hex"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -20,25 +22,95 @@ type formatConfig struct {
 	TaxidMapPath string
 	ReportPath   string
 	Progress     bool
+	// Subdirs writes each classifier's files under OutDir/<classifier>/.
+	Subdirs bool
+	// ConsistencyCheck fails the run when any classifier output saw a
+	// different record count than formatStats.Written.
+	ConsistencyCheck bool
+	// Kraken2IncludeLineage appends the lineage as a header description.
+	Kraken2IncludeLineage bool
+	// OnEmptySeq is the empty-sequence policy (skip, keep, error).
+	OnEmptySeq string
+	// RejectedIDsPath streams one processid\treason\ttaxid line per record
+	// dropped for taxonomy.
+	RejectedIDsPath string
+	// EmitSeqHashes writes blast_seqid2md5.tsv next to the blast map, one
+	// seqid\tmd5 line per written sequence.
+	EmitSeqHashes bool
+	// OutputRankNames relabels canonical ranks where outputs name them (the
+	// RDP taxonomy file and taxid_lineage.tsv). RequireRanks stays canonical.
+	OutputRankNames map[string]string
+	// LineageTSV is a processid + rank-column TSV used instead of the
+	// taxdump and taxid.map. Only taxid-free classifiers are allowed.
+	LineageTSV string
+	// FastqQual, when non-zero, writes the sequence outputs as .fastq with
+	// this synthetic quality on every base (see parseFastqQual).
+	FastqQual byte
+	// Dedupe writes only the first record of each (sequence, lineage) pair;
+	// the collapsed processids go to format_dedupe_map.tsv.
+	Dedupe bool
+	// NoReport disables the default <OutDir>/format_report.json written when
+	// ReportPath is empty.
+	NoReport bool
+	// CombinedLineage writes lineage.tsv: processid, taxid and one column
+	// per RequireRanks entry for every written record.
+	CombinedLineage bool
 }
 
+// formatReportName is the report written to the output directory when no
+// -report path is given, like split_report.json for split.
+const formatReportName = "format_report.json"
+
+// formatReasonUnknownTaxID marks a mapped taxid that is absent from the
+// taxdump. It is counted under MissingRanks but reported separately in
+// -rejected-ids so the map can be fixed.
+const formatReasonUnknownTaxID = "unknown_taxid"
+
+// formatReasonDeletedTaxID marks a mapped taxid listed in delnodes.dmp.
+const formatReasonDeletedTaxID = "deleted_taxid"
+
+// formatReasonMissingLineage marks a record absent from -lineage-tsv. It is
+// counted under MissingTaxID.
+const formatReasonMissingLineage = "missing_lineage"
+
 type formatStats struct {
 	Total        int
 	Written      int
 	MissingTaxID int
 	MissingRanks int
+	EmptySeq     int
+	Collapsed    int
 }
 
+// formatClassifiers lists the classifiers openFormatWriters knows, in the
+// order classifierHandles builds them; help and error text are built from it.
+var formatClassifiers = []string{"blast", "kraken2", "sintax", "rdp", "idtaxa", "protax", "qiime2", "dada2", "mothur", "taxidlineage"}
+
 func runFormat(args []string) {
 	fs := flag.NewFlagSet("format", flag.ExitOnError)
-	input := fs.String("input", "", "Input FASTA/FASTA.gz")
+	input := fs.String("input", "", "Input FASTA/FASTQ (optionally .gz)")
 	outDir := fs.String("outdir", "formatted", "Output directory")
-	classifiers := fs.String("classifier", "blast,kraken2,sintax", "Comma-separated classifiers (blast,kraken2,sintax,rdp,idtaxa,protax,dnasketch)")
+	classifiers := fs.String("classifier", "blast,kraken2,sintax", "Comma-separated classifiers ("+strings.Join(formatClassifiers, ",")+")")
 	requireRanks := fs.String("require-ranks", "kingdom,phylum,class,order,family,genus,species", "Comma-separated ranks required to keep a sequence (empty disables)")
 	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory with nodes.dmp/names.dmp/taxid.map")
 	taxidMap := fs.String("taxid-map", "", "Optional taxid.map override")
 	progressOn := fs.Bool("progress", true, "Show progress bar (approximate)")
-	report := fs.String("report", "", "Optional JSON report output path")
+	report := fs.String("report", "", "JSON report output path (default: <outdir>/"+formatReportName+")")
+	noReport := fs.Bool("no-report", false, "Do not write the default JSON report when -report is empty")
+	consistency := fs.Bool("consistency-check", false, "Fail if any classifier output wrote a different record count than the shared filter kept")
+	krakenLineage := fs.Bool("kraken2-include-lineage", false, "Append the lineage to kraken2 headers as a description (ignored by kraken2-build)")
+	rankAvail := fs.Bool("rank-availability", false, "Preflight only: report per-rank coverage and require-ranks pass rates, then exit")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
+	subdirs := fs.Bool("subdirs", false, "Write each classifier's outputs to its own subdirectory of -outdir")
+	combinedLineage := fs.Bool("combined-lineage", false, "Also write lineage.tsv with processid, taxid and one column per -require-ranks rank")
+	dedupe := fs.Bool("dedupe", false, "Write one record per identical sequence+lineage pair; collapsed processids go to format_dedupe_map.tsv")
+	rejectedIDs := fs.String("rejected-ids", "", "Optional TSV of processids dropped for missing taxid/ranks, with reason and taxid")
+	emitSeqHashes := fs.Bool("emit-seq-hashes", false, "Write blast_seqid2md5.tsv with the md5 of each blast sequence (requires blast)")
+	strictRanks := fs.Bool("strict-ranks", false, "Fail instead of warning when -require-ranks is not a contiguous prefix of kingdom..subspecies")
+	lineageTSV := fs.String("lineage-tsv", "", "Optional processid + rank-column TSV used instead of -taxdump-dir/-taxid-map ("+strings.Join(convertTargetNames(), ", ")+" only)")
+	outputFormat := fs.String("output-format", outputFormatFasta, "Sequence output format: fasta, or fastq with synthetic constant qualities (maps and taxonomy files are unchanged)")
+	fastqQual := fs.String("fastq-qual", defaultFastqQual, "Phred+33 character used for every base with -output-format fastq")
+	outputRankNames := fs.String("output-rank-names", "", "Comma-separated rank=label pairs used where outputs name ranks, e.g. kingdom=domain,class=clade")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
@@ -46,14 +118,52 @@ func runFormat(args []string) {
 		fatalf("input is required")
 	}
 	cfg := formatConfig{
-		Classifiers:  splitList(*classifiers),
-		RequireRanks: splitList(*requireRanks),
-		Input:        *input,
-		OutDir:       *outDir,
-		TaxdumpDir:   *taxdumpDir,
-		TaxidMapPath: *taxidMap,
-		ReportPath:   *report,
-		Progress:     *progressOn,
+		Classifiers:           splitList(*classifiers),
+		RequireRanks:          splitList(*requireRanks),
+		Input:                 *input,
+		OutDir:                *outDir,
+		TaxdumpDir:            *taxdumpDir,
+		TaxidMapPath:          *taxidMap,
+		ReportPath:            *report,
+		Progress:              *progressOn,
+		Subdirs:               *subdirs,
+		ConsistencyCheck:      *consistency,
+		Kraken2IncludeLineage: *krakenLineage,
+		RejectedIDsPath:       *rejectedIDs,
+		EmitSeqHashes:         *emitSeqHashes,
+		LineageTSV:            *lineageTSV,
+		Dedupe:                *dedupe,
+		CombinedLineage:       *combinedLineage,
+		NoReport:              *noReport,
+	}
+	policy, err := normalizeEmptySeqPolicy(*onEmptySeq)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	cfg.OnEmptySeq = policy
+	if cfg.FastqQual, err = parseFastqQual(*outputFormat, *fastqQual); err != nil {
+		fatalf("%v", err)
+	}
+	if err := checkRankPrefix("format", cfg.RequireRanks, *strictRanks); err != nil {
+		fatalf("%v", err)
+	}
+	rankNames, err := parseOutputRankNames(*outputRankNames)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	cfg.OutputRankNames = rankNames
+	if *rankAvail {
+		report, err := rankAvailability(cfg)
+		if err != nil {
+			fatalf("rank availability failed: %v", err)
+		}
+		logRankAvailability(report)
+		if cfg.ReportPath != "" {
+			if err := writeJSONReport(cfg.ReportPath, report); err != nil {
+				fatalf("%v", err)
+			}
+		}
+		return
 	}
 	if len(cfg.Classifiers) == 0 {
 		fatalf("classifier must not be empty")
@@ -64,13 +174,33 @@ func runFormat(args []string) {
 }
 
 type writerHandle struct {
-	w *bufio.Writer
-	f *os.File
+	w    *bufio.Writer
+	f    *os.File
+	name string
+	// records counts per-sequence lines written, for consistency checks.
+	records int
+	// fastqQual is set on sequence outputs opened for FASTQ.
+	fastqQual byte
+}
+
+// writeSeq writes one sequence record in the handle's format.
+func (h *writerHandle) writeSeq(header string, seq []byte) error {
+	return writeSeqRecord(h.w, header, seq, h.fastqQual)
+}
+
+// formatReport is the format JSON report: the shared filter counters plus
+// the record count each enabled classifier actually wrote.
+type formatReport struct {
+	QCStats
+	ClassifierWritten map[string]int `json:"classifier_written"`
+	// DedupeCollapsed counts records dropped by -dedupe.
+	DedupeCollapsed int `json:"dedupe_collapsed,omitempty"`
 }
 
 type formatWriters struct {
 	blastFasta    writerHandle
 	blastMap      writerHandle
+	blastHashes   writerHandle
 	krakenFasta   writerHandle
 	sintaxFasta   writerHandle
 	rdpTrainFasta writerHandle
@@ -79,9 +209,34 @@ type formatWriters struct {
 	idtaxaLineage writerHandle
 	protaxFasta   writerHandle
 	protaxMap     writerHandle
+	qiime2Fasta   writerHandle
+	qiime2Tax     writerHandle
+	dada2Train    writerHandle
+	dada2Species  writerHandle
+	mothurFasta   writerHandle
+	mothurTax     writerHandle
+	taxidLineage  writerHandle
+	dedupeMap     writerHandle
+	lineageTable  writerHandle
+	// sintaxPrefixes label each lineage level in sintax headers; nil uses
+	// the positional defaults in sintaxPrefixes.
+	sintaxPrefixes []string
+	// qiime2Prefixes are the Greengenes-style level prefixes; nil uses
+	// qiime2DefaultPrefixes.
+	qiime2Prefixes []string
+	// lineageRanks names the levels of each lineage; dada2_species.fasta
+	// takes the species level from it, or the last level when it is nil.
+	lineageRanks []string
 }
 
 func formatFasta(cfg formatConfig) error {
+	if len(cfg.RequireRanks) == 0 {
+		for _, c := range cfg.Classifiers {
+			if strings.EqualFold(strings.TrimSpace(c), "taxidlineage") {
+				return fmt.Errorf("taxidlineage needs -require-ranks")
+			}
+		}
+	}
 	in, counter, err := openInputWithCounter(cfg.Input)
 	if err != nil {
 		return fmt.Errorf("open input: %w", err)
@@ -93,164 +248,481 @@ func formatFasta(cfg formatConfig) error {
 	var bar *byteProgress
 	var lastCount int64
 	if cfg.Progress {
-		total := fileSize(cfg.Input)
-		bar = newByteProgress(total, "format (approx)")
+		bar = newInputProgress(cfg.Input, "format (approx)")
 	}
 
 	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
 		return fmt.Errorf("create outdir: %w", err)
 	}
 
-	taxidPath := cfg.TaxidMapPath
-	if taxidPath == "" {
-		taxidPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
-	}
-	taxidMap, err := loadTaxidMap(taxidPath)
-	if err != nil {
-		return err
-	}
+	var (
+		taxidMap map[string]int
+		dump     *taxDump
+		lineages map[string]map[string]string
+	)
+	if cfg.LineageTSV != "" {
+		if err := checkLineageTSVClassifiers(cfg); err != nil {
+			return err
+		}
+		lineages, err = loadLineageTSV(cfg.LineageTSV, cfg.RequireRanks)
+		if err != nil {
+			return err
+		}
+	} else {
+		taxidPath := cfg.TaxidMapPath
+		if taxidPath == "" {
+			taxidPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+		}
+		taxidMap, err = loadTaxidMap(taxidPath)
+		if err != nil {
+			return err
+		}
 
-	nodesPath := filepath.Join(cfg.TaxdumpDir, "nodes.dmp")
-	namesPath := filepath.Join(cfg.TaxdumpDir, "names.dmp")
-	dump, err := loadTaxDump(nodesPath, namesPath)
-	if err != nil {
-		return err
+		nodesPath := filepath.Join(cfg.TaxdumpDir, "nodes.dmp")
+		namesPath := filepath.Join(cfg.TaxdumpDir, "names.dmp")
+		dump, err = loadTaxDump(nodesPath, namesPath)
+		if err != nil {
+			return err
+		}
 	}
 
-	writers, err := openFormatWriters(cfg.OutDir, cfg.Classifiers)
+	writers, err := openFormatWriters(cfg.OutDir, cfg.Classifiers, cfg.Subdirs, cfg.FastqQual)
 	if err != nil {
 		return err
 	}
 	defer closeFormatWriters(writers)
+	writers.sintaxPrefixes = sintaxPrefixesFor(cfg.RequireRanks)
+	writers.qiime2Prefixes = qiime2PrefixesFor(cfg.RequireRanks)
+	writers.lineageRanks = cfg.RequireRanks
+	if cfg.EmitSeqHashes {
+		if writers.blastFasta.w == nil {
+			return fmt.Errorf("emit-seq-hashes requires the blast classifier")
+		}
+		hw, err := openFormatHandle(cfg.OutDir, cfg.Subdirs, "blast", "blast_seqid2md5.tsv")
+		if err != nil {
+			return err
+		}
+		writers.blastHashes = hw
+	}
+	if cfg.CombinedLineage {
+		if len(cfg.RequireRanks) == 0 {
+			return fmt.Errorf("combined-lineage needs -require-ranks")
+		}
+		lw, err := openFormatHandle(cfg.OutDir, false, "", "lineage.tsv")
+		if err != nil {
+			return err
+		}
+		writers.lineageTable = lw
+		header := []string{"processid", "taxid"}
+		for _, rank := range cfg.RequireRanks {
+			header = append(header, outputRankName(rank, cfg.OutputRankNames))
+		}
+		if _, err := lw.w.WriteString(strings.Join(header, "\t") + "\n"); err != nil {
+			return fmt.Errorf("write lineage table: %w", err)
+		}
+	}
+	// seenPairs maps each kept (sequence, lineage) pair to its processid;
+	// collapsed holds the input indexes of records dropped as duplicates of
+	// one, so a repeated processid does not drop its kept copy too.
+	var (
+		seenPairs map[formatDedupeKey]string
+		collapsed map[int]struct{}
+	)
+	if cfg.Dedupe {
+		mw, err := openFormatHandle(cfg.OutDir, false, "", "format_dedupe_map.tsv")
+		if err != nil {
+			return err
+		}
+		writers.dedupeMap = mw
+		if _, err := mw.w.WriteString("processid\tcollapsed_into\n"); err != nil {
+			return fmt.Errorf("write dedupe map: %w", err)
+		}
+		seenPairs = make(map[formatDedupeKey]string, 1<<16)
+		collapsed = make(map[int]struct{})
+	}
+
+	var rejected *reportWriter
+	if cfg.RejectedIDsPath != "" {
+		rejected, err = createReport(cfg.RejectedIDsPath)
+		if err != nil {
+			return fmt.Errorf("create rejected-ids: %w", err)
+		}
+		defer func() {
+			_ = rejected.Close()
+		}()
+		if _, err := rejected.WriteString("processid\treason\ttaxid\n"); err != nil {
+			return fmt.Errorf("write rejected-ids: %w", err)
+		}
+	}
+	writeRejected := func(id, reason string, taxid int) error {
+		if rejected == nil {
+			return nil
+		}
+		tid := ""
+		if taxid > 0 {
+			tid = strconv.Itoa(taxid)
+		}
+		if _, err := rejected.WriteString(id + "\t" + reason + "\t" + tid + "\n"); err != nil {
+			return fmt.Errorf("write rejected-ids: %w", err)
+		}
+		return nil
+	}
 
 	stats := formatStats{}
-	err = parseFasta(in, func(rec fastaRecord) error {
+	err = parseSequences(in, withEmptySeqPolicy(cfg.OnEmptySeq, &stats.EmptySeq, func(rec fastaRecord) error {
 		stats.Total++
 		if rec.id == "" {
 			stats.MissingTaxID++
 			updateByteProgress(bar, counter, &lastCount)
 			return nil
 		}
-		taxid, ok := taxidMap[rec.id]
-		if !ok {
-			stats.MissingTaxID++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		var (
+			taxid   int
+			lineage map[string]string
+			rankIDs map[string]int
+		)
+		if lineages != nil {
+			var ok bool
+			if lineage, ok = lineages[rec.id]; !ok {
+				stats.MissingTaxID++
+				updateByteProgress(bar, counter, &lastCount)
+				return writeRejected(rec.id, formatReasonMissingLineage, 0)
+			}
+		} else {
+			var ok bool
+			if taxid, ok = taxidMap[rec.id]; !ok {
+				stats.MissingTaxID++
+				updateByteProgress(bar, counter, &lastCount)
+				return writeRejected(rec.id, qcReasonMissingTaxID, 0)
+			}
+			lineage, rankIDs = dump.lineageWithIDs(taxid)
+			if len(lineage) == 0 {
+				stats.MissingRanks++
+				updateByteProgress(bar, counter, &lastCount)
+				reason := formatReasonUnknownTaxID
+				if dump.isDeleted(dump.resolve(taxid)) {
+					reason = formatReasonDeletedTaxID
+				}
+				return writeRejected(rec.id, reason, taxid)
+			}
 		}
-		lineage := dump.lineage(taxid)
 		if !hasAllRanks(lineage, cfg.RequireRanks) {
 			stats.MissingRanks++
 			updateByteProgress(bar, counter, &lastCount)
-			return nil
+			return writeRejected(rec.id, qcReasonMissingRanks, taxid)
 		}
 
 		names := buildLineage(lineage, cfg.RequireRanks)
 		if len(names) == 0 {
 			stats.MissingRanks++
 			updateByteProgress(bar, counter, &lastCount)
-			return nil
+			return writeRejected(rec.id, qcReasonMissingRanks, taxid)
 		}
 		seq := rec.seq
+		if seenPairs != nil {
+			key := formatDedupeKey{hash: md5.Sum(seq), lineage: strings.Join(names, ";")}
+			if kept, dup := seenPairs[key]; dup {
+				stats.Collapsed++
+				collapsed[stats.Total-1] = struct{}{}
+				updateByteProgress(bar, counter, &lastCount)
+				if _, err := writers.dedupeMap.w.WriteString(rec.id + "\t" + kept + "\n"); err != nil {
+					return fmt.Errorf("write dedupe map: %w", err)
+				}
+				return nil
+			}
+			seenPairs[key] = rec.id
+		}
 
 		if writers.blastFasta.w != nil {
-			if err := writeFasta(writers.blastFasta.w, rec.id, seq); err != nil {
+			if err := writers.blastFasta.writeSeq(rec.id, seq); err != nil {
 				return err
 			}
+			writers.blastFasta.records++
 		}
 		if writers.blastMap.w != nil {
 			if _, err := writers.blastMap.w.WriteString(rec.id + "\t" + strconv.Itoa(taxid) + "\n"); err != nil {
 				return fmt.Errorf("write blast map: %w", err)
 			}
+			writers.blastMap.records++
+		}
+		if writers.blastHashes.w != nil {
+			sum := md5.Sum(seq)
+			if _, err := writers.blastHashes.w.WriteString(rec.id + "\t" + hex.EncodeToString(sum[:]) + "\n"); err != nil {
+				return fmt.Errorf("write blast md5 map: %w", err)
+			}
+			writers.blastHashes.records++
 		}
 		if writers.krakenFasta.w != nil {
 			header := rec.id + "|kraken:taxid|" + strconv.Itoa(taxid)
-			if err := writeFasta(writers.krakenFasta.w, header, seq); err != nil {
-				return err
+			if cfg.Kraken2IncludeLineage {
+				// kraken2-build only reads the first word; the lineage is for
+				// people inspecting the library by hand.
+				header += " " + strings.Join(names, ";")
 			}
-		}
-		if writers.sintaxFasta.w != nil {
-			header := rec.id + ";tax=" + sintaxLineage(names)
-			if err := writeFasta(writers.sintaxFasta.w, header, seq); err != nil {
+			if err := writers.krakenFasta.writeSeq(header, seq); err != nil {
 				return err
 			}
+			writers.krakenFasta.records++
 		}
 		// RDP is handled separately in formatFastaRdp
-		if writers.idtaxaFasta.w != nil {
-			if err := writeFasta(writers.idtaxaFasta.w, rec.id, seq); err != nil {
-				return err
-			}
+		if err := writers.writeLineageOutputs(rec.id, names, seq); err != nil {
+			return err
 		}
-		if writers.idtaxaLineage.w != nil {
-			lineageStr := "Root;" + strings.Join(names, ";")
-			if _, err := writers.idtaxaLineage.w.WriteString(rec.id + "\t" + lineageStr + "\n"); err != nil {
-				return fmt.Errorf("write idtaxa lineage: %w", err)
+		if writers.taxidLineage.w != nil {
+			if _, err := writers.taxidLineage.w.WriteString(rec.id + "\t" + taxidLineageString(rankIDs, cfg.RequireRanks, cfg.OutputRankNames) + "\n"); err != nil {
+				return fmt.Errorf("write taxid lineage: %w", err)
 			}
+			writers.taxidLineage.records++
 		}
-		if writers.protaxFasta.w != nil {
-			if err := writeFasta(writers.protaxFasta.w, rec.id, seq); err != nil {
-				return err
+		if writers.lineageTable.w != nil {
+			tid := ""
+			if taxid > 0 {
+				tid = strconv.Itoa(taxid)
 			}
-		}
-		if writers.protaxMap.w != nil {
-			lineageStr := strings.Join(names, ";")
-			if _, err := writers.protaxMap.w.WriteString(rec.id + "\t" + lineageStr + "\n"); err != nil {
-				return fmt.Errorf("write protax map: %w", err)
+			if _, err := writers.lineageTable.w.WriteString(rec.id + "\t" + tid + "\t" + strings.Join(names, "\t") + "\n"); err != nil {
+				return fmt.Errorf("write lineage table: %w", err)
 			}
+			writers.lineageTable.records++
 		}
 
 		stats.Written++
 		updateByteProgress(bar, counter, &lastCount)
 		return nil
-	})
+	}))
 	if err != nil {
 		return err
 	}
-	updateByteProgress(bar, counter, &lastCount)
-	if bar != nil {
-		bar.Finish()
+	finishByteProgress(bar, counter, &lastCount)
+	if err := rejected.Close(); err != nil {
+		return fmt.Errorf("rejected-ids: %w", err)
 	}
 
 	// Handle RDP separately with two-pass approach
 	if writers.rdpTrainFasta.w != nil {
-		if err := formatFastaRdp(cfg, taxidMap, dump, writers); err != nil {
+		lineageOf := func(idx int, id string) map[string]string {
+			if _, dup := collapsed[idx]; dup {
+				return nil
+			}
+			if lineages != nil {
+				return lineages[id]
+			}
+			taxid, ok := taxidMap[id]
+			if !ok {
+				return nil
+			}
+			return dump.lineage(taxid)
+		}
+		if err := formatFastaRdp(cfg, lineageOf, writers); err != nil {
 			return fmt.Errorf("rdp format: %w", err)
 		}
 	}
 
-	if cfg.ReportPath != "" {
-		if err := writeQCReport(cfg.ReportPath, qcStats{
-			Total:        stats.Total,
-			Written:      stats.Written,
-			MissingTaxID: stats.MissingTaxID,
-			MissingRanks: stats.MissingRanks,
-		}); err != nil {
+	handles := writers.classifierHandles()
+	reportPath := cfg.ReportPath
+	if reportPath == "" && !cfg.NoReport {
+		reportPath = filepath.Join(cfg.OutDir, formatReportName)
+	}
+	if reportPath != "" {
+		report := formatReport{
+			QCStats: QCStats{
+				Total:        stats.Total,
+				Written:      stats.Written,
+				MissingTaxID: stats.MissingTaxID,
+				MissingRanks: stats.MissingRanks,
+			},
+			ClassifierWritten: make(map[string]int, len(handles)),
+			DedupeCollapsed:   stats.Collapsed,
+		}
+		for name, hs := range handles {
+			report.ClassifierWritten[name] = hs[0].records
+		}
+		if err := writeJSONReport(reportPath, report); err != nil {
 			return err
 		}
 	}
 	logf("format: total=%d kept=%d missing-taxid=%d missing-ranks=%d", stats.Total, stats.Written, stats.MissingTaxID, stats.MissingRanks)
+	countWarning("missing_taxid", stats.MissingTaxID)
+	countWarning("missing_ranks", stats.MissingRanks)
+	if stats.EmptySeq > 0 {
+		logf("format: skipped %d empty-sequence records", stats.EmptySeq)
+	}
+	if cfg.Dedupe {
+		logf("format: dedupe collapsed %d records into an identical sequence+lineage", stats.Collapsed)
+	}
+	if cfg.ConsistencyCheck {
+		if err := checkFormatConsistency(handles, stats.Written); err != nil {
+			return err
+		}
+	}
+	writers.keep()
+	if rejected != nil {
+		keepOutputs(rejected.path)
+	}
 	return nil
 }
 
-// formatFastaRdp handles RDP-native output with two-pass processing
-func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, writers *formatWriters) error {
+// formatDedupeKey identifies a record for -dedupe: its sequence md5 and its
+// ";"-joined lineage names.
+type formatDedupeKey struct {
+	hash    [16]byte
+	lineage string
+}
+
+// checkFormatConsistency verifies that every per-record output of every
+// enabled classifier holds exactly written records.
+func checkFormatConsistency(handles map[string][]*writerHandle, written int) error {
+	names := make([]string, 0, len(handles))
+	for name := range handles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		for _, h := range handles[name] {
+			if h.records != written {
+				problems = append(problems, fmt.Sprintf("%s (%s) wrote %d", name, h.name, h.records))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("consistency check failed: expected %d records, %s", written, strings.Join(problems, ", "))
+	}
+	logf("format: consistency check passed (%d classifiers, %d records each)", len(names), written)
+	return nil
+}
+
+// writeLineageOutputs writes one record to the name-lineage outputs (sintax,
+// idtaxa, protax, qiime2, dada2, mothur) that are open. These need no taxids, so convert
+// shares them.
+func (w *formatWriters) writeLineageOutputs(id string, names []string, seq []byte) error {
+	if w.sintaxFasta.w != nil {
+		header := id + ";tax=" + sintaxLineage(names, w.sintaxPrefixes)
+		if err := w.sintaxFasta.writeSeq(header, seq); err != nil {
+			return err
+		}
+		w.sintaxFasta.records++
+	}
+	if w.idtaxaFasta.w != nil {
+		if err := w.idtaxaFasta.writeSeq(id, seq); err != nil {
+			return err
+		}
+		w.idtaxaFasta.records++
+	}
+	if w.idtaxaLineage.w != nil {
+		lineageStr := "Root;" + strings.Join(names, ";")
+		if _, err := w.idtaxaLineage.w.WriteString(id + "\t" + lineageStr + "\n"); err != nil {
+			return fmt.Errorf("write idtaxa lineage: %w", err)
+		}
+		w.idtaxaLineage.records++
+	}
+	if w.protaxFasta.w != nil {
+		if err := w.protaxFasta.writeSeq(id, seq); err != nil {
+			return err
+		}
+		w.protaxFasta.records++
+	}
+	if w.protaxMap.w != nil {
+		lineageStr := strings.Join(names, ";")
+		if _, err := w.protaxMap.w.WriteString(id + "\t" + lineageStr + "\n"); err != nil {
+			return fmt.Errorf("write protax map: %w", err)
+		}
+		w.protaxMap.records++
+	}
+	if w.qiime2Fasta.w != nil {
+		if err := w.qiime2Fasta.writeSeq(id, seq); err != nil {
+			return err
+		}
+		w.qiime2Fasta.records++
+	}
+	if w.qiime2Tax.w != nil {
+		if _, err := w.qiime2Tax.w.WriteString(id + "\t" + qiime2Taxonomy(names, w.qiime2Prefixes) + "\n"); err != nil {
+			return fmt.Errorf("write qiime2 taxonomy: %w", err)
+		}
+		w.qiime2Tax.records++
+	}
+	if w.dada2Train.w != nil {
+		if err := w.dada2Train.writeSeq(strings.Join(names, ";")+";", seq); err != nil {
+			return err
+		}
+		w.dada2Train.records++
+	}
+	if w.dada2Species.w != nil {
+		species := strings.ReplaceAll(names[dada2SpeciesLevel(w.lineageRanks, len(names))], "_", " ")
+		if err := w.dada2Species.writeSeq(id+" "+species, seq); err != nil {
+			return err
+		}
+		w.dada2Species.records++
+	}
+	if w.mothurFasta.w != nil {
+		if err := w.mothurFasta.writeSeq(id, seq); err != nil {
+			return err
+		}
+		w.mothurFasta.records++
+	}
+	if w.mothurTax.w != nil {
+		// names come from sanitizeTaxon, so spaces are already underscores.
+		if _, err := w.mothurTax.w.WriteString(id + "\t" + strings.Join(names, ";") + ";\n"); err != nil {
+			return fmt.Errorf("write mothur taxonomy: %w", err)
+		}
+		w.mothurTax.records++
+	}
+	return nil
+}
+
+// dada2SpeciesLevel is the index of the species level in a lineage of n
+// names over ranks, falling back to the last level.
+func dada2SpeciesLevel(ranks []string, n int) int {
+	for i, rank := range ranks {
+		if rank == "species" && i < n {
+			return i
+		}
+	}
+	return n - 1
+}
+
+// formatFastaRdp handles RDP-native output with two-pass processing.
+// lineageOf returns the rank names of the idx-th input record, nil when it
+// has none.
+func formatFastaRdp(cfg formatConfig, lineageOf func(idx int, id string) map[string]string, writers *formatWriters) error {
+	idx := -1
+	return writeRdpFromFasta(cfg.Input, cfg.OnEmptySeq, cfg.RequireRanks, cfg.OutputRankNames, writers, func(rec fastaRecord) (string, []string, error) {
+		idx++
+		if rec.id == "" {
+			return "", nil, nil
+		}
+		lineage := lineageOf(idx, rec.id)
+		if lineage == nil || !hasAllRanks(lineage, cfg.RequireRanks) {
+			return "", nil, nil
+		}
+		return rec.id, buildLineage(lineage, cfg.RequireRanks), nil
+	})
+}
+
+// rdpKeySep separates resolved node keys in the RDP temp file.
+const rdpKeySep = "\x1f"
+
+// writeRdpFromFasta builds the RDP taxonomy and training FASTA from input.
+// namesFor returns the record ID and its rank-ordered names; nil names skip
+// the record. rankNames overrides the rank labels in the taxonomy file.
+func writeRdpFromFasta(input, onEmptySeq string, ranks []string, rankNames map[string]string, writers *formatWriters, namesFor func(fastaRecord) (string, []string, error)) error {
 	// Create temp file for sequences
-	tmpFasta, err := os.CreateTemp("", "rdp_seqs_*.fasta")
+	tmpFasta, err := createTempFile("", "rdp")
 	if err != nil {
-		return fmt.Errorf("create temp file: %w", err)
+		return err
 	}
 	tmpPath := tmpFasta.Name()
 	defer func() {
 		_ = tmpFasta.Close()
-		_ = os.Remove(tmpPath)
+		removeTempFile(tmpPath)
 	}()
 
 	tmpWriter := bufio.NewWriterSize(tmpFasta, writerBufferSize)
 
 	// Pass 1: collect lineages and write sequences to temp file
-	builder := newRdpTaxonomyBuilder(cfg.RequireRanks)
+	builder := newRdpTaxonomyBuilder(ranks, rankNames)
 	var seqCount int
 
-	in, err := openInput(cfg.Input)
+	in, err := openInput(input)
 	if err != nil {
 		return fmt.Errorf("open input for rdp: %w", err)
 	}
@@ -258,20 +730,11 @@ func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, wr
 		_ = in.Close()
 	}()
 
-	err = parseFasta(in, func(rec fastaRecord) error {
-		if rec.id == "" {
-			return nil
-		}
-		taxid, ok := taxidMap[rec.id]
-		if !ok {
-			return nil
-		}
-		lineage := dump.lineage(taxid)
-		if !hasAllRanks(lineage, cfg.RequireRanks) {
-			return nil
+	err = parseSequences(in, withEmptySeqPolicy(onEmptySeq, nil, func(rec fastaRecord) error {
+		id, names, err := namesFor(rec)
+		if err != nil {
+			return err
 		}
-
-		names := buildLineage(lineage, cfg.RequireRanks)
 		if len(names) == 0 {
 			return nil
 		}
@@ -283,13 +746,14 @@ func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, wr
 		}
 
 		// Write to temp file: seqid\tlineage_keys\tsequence
-		lineageStr := strings.Join(resolved, "|")
-		if _, err := tmpWriter.WriteString(rec.id + "\t" + lineageStr + "\t" + string(rec.seq) + "\n"); err != nil {
+		// Node keys are "name|rank", so join them on a byte names never hold.
+		lineageStr := strings.Join(resolved, rdpKeySep)
+		if _, err := tmpWriter.WriteString(id + "\t" + lineageStr + "\t" + string(rec.seq) + "\n"); err != nil {
 			return fmt.Errorf("write temp: %w", err)
 		}
 		seqCount++
 		return nil
-	})
+	}))
 	if err != nil {
 		return err
 	}
@@ -317,7 +781,7 @@ func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, wr
 		_ = tmpIn.Close()
 	}()
 
-	scanner := bufio.NewScanner(tmpIn)
+	scanner := bufio.NewScanner(contextReader(runCtx, tmpIn))
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 
@@ -328,15 +792,16 @@ func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, wr
 			continue
 		}
 		seqID := parts[0]
-		keys := strings.Split(parts[1], "|")
+		keys := strings.Split(parts[1], rdpKeySep)
 		seq := parts[2]
 
 		// Build lineage string from resolved keys
 		lineageNames := builder.getLineageString(keys)
 		header := seqID + "\t" + lineageNames
-		if err := writeFasta(writers.rdpTrainFasta.w, header, []byte(seq)); err != nil {
+		if err := writers.rdpTrainFasta.writeSeq(header, []byte(seq)); err != nil {
 			return err
 		}
+		writers.rdpTrainFasta.records++
 	}
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("scan temp: %w", err)
@@ -345,7 +810,9 @@ func formatFastaRdp(cfg formatConfig, taxidMap map[string]int, dump *taxDump, wr
 	return nil
 }
 
-func openFormatWriters(outDir string, classifiers []string) (*formatWriters, error) {
+// openFormatWriters opens the outputs of each classifier. With a non-zero
+// fastqQual the .fasta sequence outputs become .fastq.
+func openFormatWriters(outDir string, classifiers []string, subdirs bool, fastqQual byte) (*formatWriters, error) {
 	w := &formatWriters{}
 	needs := make(map[string]struct{})
 	for _, c := range classifiers {
@@ -356,21 +823,21 @@ func openFormatWriters(outDir string, classifiers []string) (*formatWriters, err
 		needs[name] = struct{}{}
 	}
 
-	openFasta := func(name string) (writerHandle, error) {
-		path := filepath.Join(outDir, name)
-		f, err := os.Create(path)
-		if err != nil {
-			return writerHandle{}, fmt.Errorf("create %s: %w", path, err)
+	openFasta := func(classifier, name string) (writerHandle, error) {
+		if fastqQual != 0 && strings.HasSuffix(name, ".fasta") {
+			h, err := openFormatHandle(outDir, subdirs, classifier, strings.TrimSuffix(name, ".fasta")+".fastq")
+			h.fastqQual = fastqQual
+			return h, err
 		}
-		return writerHandle{w: bufio.NewWriterSize(f, writerBufferSize), f: f}, nil
+		return openFormatHandle(outDir, subdirs, classifier, name)
 	}
 
 	if _, ok := needs["blast"]; ok {
-		bw, err := openFasta("blast.fasta")
+		bw, err := openFasta("blast", "blast.fasta")
 		if err != nil {
 			return nil, err
 		}
-		mw, err := openFasta("blast_seqid2taxid.map")
+		mw, err := openFasta("blast", "blast_seqid2taxid.map")
 		if err != nil {
 			return nil, err
 		}
@@ -378,25 +845,25 @@ func openFormatWriters(outDir string, classifiers []string) (*formatWriters, err
 		w.blastMap = mw
 	}
 	if _, ok := needs["kraken2"]; ok {
-		bw, err := openFasta("kraken2.fasta")
+		bw, err := openFasta("kraken2", "kraken2.fasta")
 		if err != nil {
 			return nil, err
 		}
 		w.krakenFasta = bw
 	}
 	if _, ok := needs["sintax"]; ok {
-		bw, err := openFasta("sintax.fasta")
+		bw, err := openFasta("sintax", "sintax.fasta")
 		if err != nil {
 			return nil, err
 		}
 		w.sintaxFasta = bw
 	}
 	if _, ok := needs["rdp"]; ok {
-		bw, err := openFasta("rdp_train_seqs.fasta")
+		bw, err := openFasta("rdp", "rdp_train_seqs.fasta")
 		if err != nil {
 			return nil, err
 		}
-		tw, err := openFasta("rdp_taxonomy.txt")
+		tw, err := openFasta("rdp", "rdp_taxonomy.txt")
 		if err != nil {
 			return nil, err
 		}
@@ -404,11 +871,11 @@ func openFormatWriters(outDir string, classifiers []string) (*formatWriters, err
 		w.rdpTaxonomy = tw
 	}
 	if _, ok := needs["idtaxa"]; ok {
-		bw, err := openFasta("idtaxa_seqs.fasta")
+		bw, err := openFasta("idtaxa", "idtaxa_seqs.fasta")
 		if err != nil {
 			return nil, err
 		}
-		tw, err := openFasta("idtaxa_lineage.tsv")
+		tw, err := openFasta("idtaxa", "idtaxa_lineage.tsv")
 		if err != nil {
 			return nil, err
 		}
@@ -416,40 +883,138 @@ func openFormatWriters(outDir string, classifiers []string) (*formatWriters, err
 		w.idtaxaLineage = tw
 	}
 	if _, ok := needs["protax"]; ok {
-		bw, err := openFasta("protax_seqs.fasta")
+		bw, err := openFasta("protax", "protax_seqs.fasta")
 		if err != nil {
 			return nil, err
 		}
-		tw, err := openFasta("protax_seqid2tax.tsv")
+		tw, err := openFasta("protax", "protax_seqid2tax.tsv")
 		if err != nil {
 			return nil, err
 		}
 		w.protaxFasta = bw
 		w.protaxMap = tw
 	}
+	if _, ok := needs["qiime2"]; ok {
+		bw, err := openFasta("qiime2", "qiime2_seqs.fasta")
+		if err != nil {
+			return nil, err
+		}
+		tw, err := openFasta("qiime2", "qiime2_taxonomy.tsv")
+		if err != nil {
+			return nil, err
+		}
+		w.qiime2Fasta = bw
+		w.qiime2Tax = tw
+	}
+	if _, ok := needs["dada2"]; ok {
+		bw, err := openFasta("dada2", "dada2_train.fasta")
+		if err != nil {
+			return nil, err
+		}
+		sw, err := openFasta("dada2", "dada2_species.fasta")
+		if err != nil {
+			return nil, err
+		}
+		w.dada2Train = bw
+		w.dada2Species = sw
+	}
+	if _, ok := needs["mothur"]; ok {
+		bw, err := openFasta("mothur", "mothur.fasta")
+		if err != nil {
+			return nil, err
+		}
+		tw, err := openFasta("mothur", "mothur.taxonomy")
+		if err != nil {
+			return nil, err
+		}
+		w.mothurFasta = bw
+		w.mothurTax = tw
+	}
+	if _, ok := needs["taxidlineage"]; ok {
+		tw, err := openFasta("taxidlineage", "taxid_lineage.tsv")
+		if err != nil {
+			return nil, err
+		}
+		w.taxidLineage = tw
+	}
 	return w, nil
 }
 
+// openFormatHandle creates name under outDir, or outDir/<classifier> with
+// subdirs.
+func openFormatHandle(outDir string, subdirs bool, classifier, name string) (writerHandle, error) {
+	dir := outDir
+	if subdirs {
+		dir = filepath.Join(outDir, classifier)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return writerHandle{}, fmt.Errorf("create %s: %w", dir, err)
+		}
+	}
+	path := filepath.Join(dir, name)
+	f, err := createOutput(path)
+	if err != nil {
+		return writerHandle{}, fmt.Errorf("create %s: %w", path, err)
+	}
+	return writerHandle{w: bufio.NewWriterSize(f, writerBufferSize), f: f, name: name}, nil
+}
+
+// classifierHandles maps each enabled classifier to its open per-record
+// writers, primary output first. rdp_taxonomy.txt is per-taxon, not
+// per-record, so it is left out.
+func (w *formatWriters) classifierHandles() map[string][]*writerHandle {
+	out := make(map[string][]*writerHandle)
+	add := func(name string, hs ...*writerHandle) {
+		if hs[0].w == nil {
+			return
+		}
+		out[name] = hs
+	}
+	if w.blastHashes.w != nil {
+		add("blast", &w.blastFasta, &w.blastMap, &w.blastHashes)
+	} else {
+		add("blast", &w.blastFasta, &w.blastMap)
+	}
+	add("kraken2", &w.krakenFasta)
+	add("sintax", &w.sintaxFasta)
+	add("rdp", &w.rdpTrainFasta)
+	add("idtaxa", &w.idtaxaFasta, &w.idtaxaLineage)
+	add("protax", &w.protaxFasta, &w.protaxMap)
+	add("qiime2", &w.qiime2Fasta, &w.qiime2Tax)
+	add("dada2", &w.dada2Train, &w.dada2Species)
+	add("mothur", &w.mothurFasta, &w.mothurTax)
+	add("taxidlineage", &w.taxidLineage)
+	return out
+}
+
+func (w *formatWriters) all() []*writerHandle {
+	return []*writerHandle{
+		&w.blastFasta, &w.blastMap, &w.blastHashes, &w.krakenFasta, &w.sintaxFasta,
+		&w.rdpTrainFasta, &w.rdpTaxonomy, &w.idtaxaFasta, &w.idtaxaLineage,
+		&w.protaxFasta, &w.protaxMap, &w.qiime2Fasta, &w.qiime2Tax,
+		&w.dada2Train, &w.dada2Species, &w.mothurFasta, &w.mothurTax, &w.taxidLineage,
+		&w.dedupeMap, &w.lineageTable,
+	}
+}
+
+// keep marks every open output complete; see keepOutputs.
+func (w *formatWriters) keep() {
+	for _, h := range w.all() {
+		if h.f != nil {
+			keepOutputs(h.f.Name())
+		}
+	}
+}
+
 func closeFormatWriters(w *formatWriters) {
-	flush := func(h writerHandle) {
+	for _, h := range w.all() {
 		if h.w == nil {
-			return
+			continue
 		}
 		_ = h.w.Flush()
 		if h.f != nil {
 			_ = h.f.Close()
 		}
 	}
-	flush(w.blastFasta)
-	flush(w.blastMap)
-	flush(w.krakenFasta)
-	flush(w.sintaxFasta)
-	flush(w.rdpTrainFasta)
-	flush(w.rdpTaxonomy)
-	flush(w.idtaxaFasta)
-	flush(w.idtaxaLineage)
-	flush(w.protaxFasta)
-	flush(w.protaxMap)
 }
 
 func writeFasta(w *bufio.Writer, header string, seq []byte) error {
@@ -480,8 +1045,143 @@ func buildLineage(lineage map[string]string, ranks []string) []string {
 	return out
 }
 
-func sintaxLineage(names []string) string {
-	prefixes := []string{"d", "p", "c", "o", "f", "g", "s"}
+// parseOutputRankNames parses -output-rank-names ("kingdom=domain,...").
+// Keys must be ranks format knows; labels may not contain the separators
+// the outputs use around rank names.
+func parseOutputRankNames(spec string) (map[string]string, error) {
+	entries := splitList(spec)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]struct{}, len(canonicalRanks)+1)
+	for _, rank := range append(append([]string(nil), canonicalRanks...), "subspecies") {
+		known[rank] = struct{}{}
+	}
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		rank, label, ok := strings.Cut(entry, "=")
+		rank = strings.TrimSpace(rank)
+		label = strings.TrimSpace(label)
+		if !ok || rank == "" || label == "" {
+			return nil, fmt.Errorf("output-rank-names: %q is not rank=label", entry)
+		}
+		if _, ok := known[rank]; !ok {
+			return nil, fmt.Errorf("output-rank-names: unknown rank %q", rank)
+		}
+		if strings.ContainsAny(label, "*:;\t") {
+			return nil, fmt.Errorf("output-rank-names: label %q for %s contains a reserved character", label, rank)
+		}
+		if _, dup := names[rank]; dup {
+			return nil, fmt.Errorf("output-rank-names: %s given more than once", rank)
+		}
+		names[rank] = label
+	}
+	return names, nil
+}
+
+// outputRankName is the label written for rank.
+func outputRankName(rank string, rankNames map[string]string) string {
+	if name, ok := rankNames[rank]; ok {
+		return name
+	}
+	return rank
+}
+
+// taxidLineageString renders rank:taxid pairs in rank order, tab-separated,
+// labelling each rank through rankNames.
+func taxidLineageString(rankIDs map[string]int, ranks []string, rankNames map[string]string) string {
+	parts := make([]string, 0, len(ranks))
+	for _, rank := range ranks {
+		parts = append(parts, outputRankName(rank, rankNames)+":"+strconv.Itoa(rankIDs[rank]))
+	}
+	return strings.Join(parts, "\t")
+}
+
+// sintaxPrefixes are the SINTAX rank prefixes in lineage order.
+var sintaxPrefixes = []string{"d", "p", "c", "o", "f", "g", "s", "t"}
+
+// sintaxRankPrefixes are the SINTAX prefixes of the standard ranks.
+var sintaxRankPrefixes = map[string]string{
+	"superkingdom": "d",
+	"kingdom":      "d",
+	"domain":       "d",
+	"phylum":       "p",
+	"class":        "c",
+	"order":        "o",
+	"family":       "f",
+	"genus":        "g",
+	"species":      "s",
+	"subspecies":   "t",
+}
+
+// sintaxPrefixesFor maps each rank to its SINTAX prefix by name, not
+// position. A rank without a standard prefix gets the first letter of its
+// name that no standard rank owns and ranks has not taken (subfamily -> u,
+// tribe -> r), falling back to any free letter. The standard prefixes stay
+// reserved even for absent ranks, so t always means subspecies.
+func sintaxPrefixesFor(ranks []string) []string {
+	if len(ranks) == 0 {
+		return nil
+	}
+	used := make(map[string]struct{}, len(sintaxPrefixes)+len(ranks))
+	for _, p := range sintaxPrefixes {
+		used[p] = struct{}{}
+	}
+	prefixes := make([]string, len(ranks))
+	for i, rank := range ranks {
+		if p, ok := sintaxRankPrefixes[rank]; ok {
+			prefixes[i] = p
+			continue
+		}
+		for _, c := range strings.ToLower(rank) + "abcdefghijklmnopqrstuvwxyz" {
+			p := string(c)
+			if _, taken := used[p]; taken || c < 'a' || c > 'z' {
+				continue
+			}
+			used[p] = struct{}{}
+			prefixes[i] = p
+			break
+		}
+	}
+	return prefixes
+}
+
+// qiime2DefaultPrefixes are the Greengenes level prefixes in lineage order.
+var qiime2DefaultPrefixes = []string{"k", "p", "c", "o", "f", "g", "s", "t"}
+
+// qiime2PrefixesFor is sintaxPrefixesFor with Greengenes' k for kingdom.
+func qiime2PrefixesFor(ranks []string) []string {
+	prefixes := sintaxPrefixesFor(ranks)
+	for i, rank := range ranks {
+		if rank == "kingdom" || rank == "superkingdom" || rank == "domain" {
+			prefixes[i] = "k"
+		}
+	}
+	return prefixes
+}
+
+// qiime2Taxonomy renders the QIIME 2 taxonomy column "k__A; p__B; ...",
+// or with the positional qiime2DefaultPrefixes when prefixes is nil.
+func qiime2Taxonomy(names []string, prefixes []string) string {
+	if prefixes == nil {
+		prefixes = qiime2DefaultPrefixes
+	}
+	parts := make([]string, 0, len(names))
+	for i, name := range names {
+		if i >= len(prefixes) {
+			break
+		}
+		parts = append(parts, prefixes[i]+"__"+name)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// sintaxLineage renders "d:A,p:B,..." with prefixes per level, or the
+// positional sintaxPrefixes when prefixes is nil.
+func sintaxLineage(names []string, prefixes []string) string {
+	if prefixes == nil {
+		prefixes = sintaxPrefixes
+	}
 	parts := make([]string, 0, len(names))
 	for i, name := range names {
 		if i >= len(prefixes) {
@@ -490,7 +1190,77 @@ func sintaxLineage(names []string) string {
 		parts = append(parts, prefixes[i]+":"+name)
 	}
 	if len(names) > len(prefixes) {
-		log.Printf("sintax: dropping %d ranks beyond species for %v", len(names)-len(prefixes), names)
+		warnf("sintax_dropped_ranks", 1, "sintax: dropping %d ranks beyond subspecies for %v", len(names)-len(prefixes), names)
 	}
 	return strings.Join(parts, ",")
 }
+
+// checkLineageTSVClassifiers rejects -lineage-tsv runs that need taxids or
+// have no ranks to read.
+func checkLineageTSVClassifiers(cfg formatConfig) error {
+	if len(cfg.RequireRanks) == 0 {
+		return fmt.Errorf("lineage-tsv requires -require-ranks to name its rank columns")
+	}
+	for _, c := range cfg.Classifiers {
+		if _, ok := convertTargets[strings.ToLower(strings.TrimSpace(c))]; !ok {
+			return fmt.Errorf("classifier %q needs taxids and cannot be built from -lineage-tsv (use %s)", c, strings.Join(convertTargetNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// loadLineageTSV reads a processid\t<rank>... TSV into per-record rank
+// names, keeping only ranks. The header must name processid and every rank.
+// Empty and null cells are left out, so those records fail require-ranks.
+func loadLineageTSV(path string, ranks []string) (map[string]map[string]string, error) {
+	in, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("open lineage-tsv: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	idxProcess := -1
+	idxRanks := make([]int, len(ranks))
+	lineages := make(map[string]map[string]string, 1<<16)
+	err = ParseTSV(in, DefaultOptions(), func(row Row) error {
+		if idxProcess < 0 {
+			idxProcess = indexOfBytes(row.Fields, "processid")
+			var missing []string
+			if idxProcess < 0 {
+				missing = append(missing, "processid")
+			}
+			for i, rank := range ranks {
+				idxRanks[i] = indexOfBytes(row.Fields, rank)
+				if idxRanks[i] < 0 {
+					missing = append(missing, rank)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("lineage-tsv %s: missing columns %s", path, strings.Join(missing, ","))
+			}
+			return nil
+		}
+		if idxProcess >= len(row.Fields) || len(row.Fields[idxProcess]) == 0 {
+			return nil
+		}
+		lineage := make(map[string]string, len(ranks))
+		for i, rank := range ranks {
+			idx := idxRanks[i]
+			if idx >= len(row.Fields) || len(row.Fields[idx]) == 0 || isNone(row.Fields[idx]) {
+				continue
+			}
+			lineage[rank] = string(row.Fields[idx])
+		}
+		lineages[string(row.Fields[idxProcess])] = lineage
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if idxProcess < 0 {
+		return nil, fmt.Errorf("lineage-tsv %s is empty", path)
+	}
+	return lineages, nil
+}
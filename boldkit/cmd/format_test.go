@@ -0,0 +1,1001 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestTaxdump writes a small two-species taxonomy plus taxid.map:
+// P1 -> Homo sapiens (9606), P2 -> Canis lupus (9615).
+func writeTestTaxdump(t *testing.T, dir string) {
+	t.Helper()
+	nodes := strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tkingdom\t|",
+		"3\t|\t2\t|\tphylum\t|",
+		"4\t|\t3\t|\tclass\t|",
+		"5\t|\t4\t|\torder\t|",
+		"6\t|\t5\t|\tfamily\t|",
+		"7\t|\t6\t|\tgenus\t|",
+		"9606\t|\t7\t|\tspecies\t|",
+		"15\t|\t4\t|\torder\t|",
+		"16\t|\t15\t|\tfamily\t|",
+		"17\t|\t16\t|\tgenus\t|",
+		"9615\t|\t17\t|\tspecies\t|",
+	}, "\n") + "\n"
+	names := strings.Join([]string{
+		"1\t|\troot\t|\t\t|\tscientific name\t|",
+		"2\t|\tAnimalia\t|\t\t|\tscientific name\t|",
+		"3\t|\tChordata\t|\t\t|\tscientific name\t|",
+		"4\t|\tMammalia\t|\t\t|\tscientific name\t|",
+		"5\t|\tPrimates\t|\t\t|\tscientific name\t|",
+		"6\t|\tHominidae\t|\t\t|\tscientific name\t|",
+		"7\t|\tHomo\t|\t\t|\tscientific name\t|",
+		"9606\t|\tHomo sapiens\t|\t\t|\tscientific name\t|",
+		"15\t|\tCarnivora\t|\t\t|\tscientific name\t|",
+		"16\t|\tCanidae\t|\t\t|\tscientific name\t|",
+		"17\t|\tCanis\t|\t\t|\tscientific name\t|",
+		"9615\t|\tCanis lupus\t|\t\t|\tscientific name\t|",
+	}, "\n") + "\n"
+	taxid := "P1\t9606\nP2\t9615\n"
+	files := map[string]string{
+		"nodes.dmp": nodes,
+		"names.dmp": names,
+		"taxid.map": taxid,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestFormatTaxidLineage(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"taxidlineage"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "taxid_lineage.tsv"))
+	if err != nil {
+		t.Fatalf("read taxid lineage: %v", err)
+	}
+	want := "P1\tkingdom:2\tphylum:3\tclass:4\torder:5\tfamily:6\tgenus:7\tspecies:9606\n" +
+		"P2\tkingdom:2\tphylum:3\tclass:4\torder:15\tfamily:16\tgenus:17\tspecies:9615\n"
+	if string(data) != want {
+		t.Fatalf("taxid lineage mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+}
+
+func TestFormatTaxidLineageNoRequireRanks(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers: []string{"taxidlineage"},
+		Input:       input,
+		OutDir:      outDir,
+		TaxdumpDir:  tmp,
+	})
+	if err == nil || !strings.Contains(err.Error(), "require-ranks") {
+		t.Fatalf("expected taxidlineage without -require-ranks to fail, got %v", err)
+	}
+	if fileExists(filepath.Join(outDir, "taxid_lineage.tsv")) {
+		t.Fatalf("taxid_lineage.tsv written despite the error")
+	}
+}
+
+func TestFormatQiime2(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"qiime2"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "qiime2_taxonomy.tsv"))
+	if err != nil {
+		t.Fatalf("read qiime2 taxonomy: %v", err)
+	}
+	want := "P1\tk__Animalia; p__Chordata; c__Mammalia; o__Primates; f__Hominidae; g__Homo; s__Homo_sapiens\n" +
+		"P2\tk__Animalia; p__Chordata; c__Mammalia; o__Carnivora; f__Canidae; g__Canis; s__Canis_lupus\n"
+	if string(data) != want {
+		t.Fatalf("qiime2 taxonomy mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+	seqs, err := os.ReadFile(filepath.Join(outDir, "qiime2_seqs.fasta"))
+	if err != nil {
+		t.Fatalf("read qiime2 seqs: %v", err)
+	}
+	if string(seqs) != ">P1\nACGT\n>P2\nTTGA\n" {
+		t.Fatalf("qiime2 seqs=%q", string(seqs))
+	}
+}
+
+func TestFormatDada2(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"dada2"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	train, err := os.ReadFile(filepath.Join(outDir, "dada2_train.fasta"))
+	if err != nil {
+		t.Fatalf("read dada2 train: %v", err)
+	}
+	if want := ">Animalia;Chordata;Mammalia;Primates;Hominidae;Homo;Homo_sapiens;\nACGT\n"; string(train) != want {
+		t.Fatalf("dada2 train=%q want %q", string(train), want)
+	}
+	species, err := os.ReadFile(filepath.Join(outDir, "dada2_species.fasta"))
+	if err != nil {
+		t.Fatalf("read dada2 species: %v", err)
+	}
+	if want := ">P1 Homo sapiens\nACGT\n"; string(species) != want {
+		t.Fatalf("dada2 species=%q want %q", string(species), want)
+	}
+}
+
+func TestFormatMothur(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"mothur"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "mothur.taxonomy"))
+	if err != nil {
+		t.Fatalf("read mothur taxonomy: %v", err)
+	}
+	if want := "P1\tAnimalia;Chordata;Mammalia;Primates;Hominidae;Homo;Homo_sapiens;\n"; string(data) != want {
+		t.Fatalf("mothur taxonomy=%q want %q", string(data), want)
+	}
+	if !fileExists(filepath.Join(outDir, "mothur.fasta")) {
+		t.Fatalf("mothur.fasta not written")
+	}
+}
+
+func TestFormatDedupe(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	taxidMap := filepath.Join(tmp, "dedupe_taxid.map")
+	if err := os.WriteFile(taxidMap, []byte("A\t9606\nB\t9606\nC\t9606\nD\t9615\n"), 0o644); err != nil {
+		t.Fatalf("write taxid map: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">A\nACGT\n>B\nACGT\n>C\nACGT\n>D\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	report := filepath.Join(tmp, "report.json")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"blast", "rdp"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+		TaxidMapPath: taxidMap,
+		ReportPath:   report,
+		Dedupe:       true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	blast, err := os.ReadFile(filepath.Join(outDir, "blast.fasta"))
+	if err != nil {
+		t.Fatalf("read blast: %v", err)
+	}
+	// D shares the sequence but not the lineage, so it stays.
+	if string(blast) != ">A\nACGT\n>D\nACGT\n" {
+		t.Fatalf("blast=%q want A and D only", string(blast))
+	}
+	rdp, err := os.ReadFile(filepath.Join(outDir, "rdp_train_seqs.fasta"))
+	if err != nil {
+		t.Fatalf("read rdp: %v", err)
+	}
+	if strings.Count(string(rdp), ">") != 2 || strings.Contains(string(rdp), ">B") {
+		t.Fatalf("rdp kept collapsed records:\n%s", rdp)
+	}
+	dedupeMap, err := os.ReadFile(filepath.Join(outDir, "format_dedupe_map.tsv"))
+	if err != nil {
+		t.Fatalf("read dedupe map: %v", err)
+	}
+	if want := "processid\tcollapsed_into\nB\tA\nC\tA\n"; string(dedupeMap) != want {
+		t.Fatalf("dedupe map=%q want %q", string(dedupeMap), want)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if !strings.Contains(string(data), `"dedupe_collapsed": 2`) {
+		t.Fatalf("report missing dedupe_collapsed:\n%s", data)
+	}
+}
+
+func TestFormatDedupeRepeatedProcessID(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	taxidMap := filepath.Join(tmp, "dedupe_taxid.map")
+	if err := os.WriteFile(taxidMap, []byte("A\t9606\n"), 0o644); err != nil {
+		t.Fatalf("write taxid map: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">A\nACGT\n>A\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"blast", "rdp"},
+		RequireRanks: []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+		TaxidMapPath: taxidMap,
+		Dedupe:       true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	blast, err := os.ReadFile(filepath.Join(outDir, "blast.fasta"))
+	if err != nil {
+		t.Fatalf("read blast: %v", err)
+	}
+	rdp, err := os.ReadFile(filepath.Join(outDir, "rdp_train_seqs.fasta"))
+	if err != nil {
+		t.Fatalf("read rdp: %v", err)
+	}
+	// The second A collapses into the first; the first must survive in rdp.
+	if strings.Count(string(blast), ">") != 1 || strings.Count(string(rdp), ">") != 1 {
+		t.Fatalf("want one kept A in each output, blast:\n%s\nrdp:\n%s", blast, rdp)
+	}
+}
+
+func TestFormatCombinedLineage(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n>P3\nGGCC\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:     []string{"sintax", "blast"},
+		RequireRanks:    []string{"kingdom", "order", "species"},
+		Input:           input,
+		OutDir:          outDir,
+		TaxdumpDir:      tmp,
+		CombinedLineage: true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "lineage.tsv"))
+	if err != nil {
+		t.Fatalf("read lineage.tsv: %v", err)
+	}
+	// P3 has no taxid and is not written.
+	want := "processid\ttaxid\tkingdom\torder\tspecies\n" +
+		"P1\t9606\tAnimalia\tPrimates\tHomo_sapiens\n" +
+		"P2\t9615\tAnimalia\tCarnivora\tCanis_lupus\n"
+	if string(data) != want {
+		t.Fatalf("lineage.tsv mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+}
+
+func TestFormatRequireNonStandardRank(t *testing.T) {
+	tmp := t.TempDir()
+	nodes := strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tkingdom\t|",
+		"5\t|\t2\t|\torder\t|",
+		"50\t|\t5\t|\tsuperfamily\t|",
+		"6\t|\t50\t|\tfamily\t|",
+		"9606\t|\t6\t|\tspecies\t|",
+		"15\t|\t2\t|\torder\t|",
+		"16\t|\t15\t|\tfamily\t|",
+		"9615\t|\t16\t|\tspecies\t|",
+	}, "\n") + "\n"
+	names := strings.Join([]string{
+		"1\t|\troot\t|\t\t|\tscientific name\t|",
+		"2\t|\tAnimalia\t|\t\t|\tscientific name\t|",
+		"5\t|\tPrimates\t|\t\t|\tscientific name\t|",
+		"50\t|\tHominoidea\t|\t\t|\tscientific name\t|",
+		"6\t|\tHominidae\t|\t\t|\tscientific name\t|",
+		"9606\t|\tHomo sapiens\t|\t\t|\tscientific name\t|",
+		"15\t|\tCarnivora\t|\t\t|\tscientific name\t|",
+		"16\t|\tCanidae\t|\t\t|\tscientific name\t|",
+		"9615\t|\tCanis lupus\t|\t\t|\tscientific name\t|",
+	}, "\n") + "\n"
+	files := map[string]string{"nodes.dmp": nodes, "names.dmp": names, "taxid.map": "P1\t9606\nP2\t9615\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:     []string{"blast"},
+		RequireRanks:    []string{"kingdom", "order", "superfamily", "family", "species"},
+		Input:           input,
+		OutDir:          outDir,
+		TaxdumpDir:      tmp,
+		CombinedLineage: true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "lineage.tsv"))
+	if err != nil {
+		t.Fatalf("read lineage.tsv: %v", err)
+	}
+	// P2 has no superfamily and is dropped.
+	want := "processid\ttaxid\tkingdom\torder\tsuperfamily\tfamily\tspecies\n" +
+		"P1\t9606\tAnimalia\tPrimates\tHominoidea\tHominidae\tHomo_sapiens\n"
+	if string(data) != want {
+		t.Fatalf("lineage.tsv mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+}
+
+func TestFormatDefaultReport(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P9\nTTGA\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	cfg := formatConfig{
+		Classifiers:  []string{"blast"},
+		RequireRanks: []string{"kingdom", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	}
+	if err := formatFasta(cfg); err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, formatReportName))
+	if err != nil {
+		t.Fatalf("read default report: %v", err)
+	}
+	var report formatReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if report.Total != 2 || report.Written != 1 || report.MissingTaxID != 1 {
+		t.Fatalf("report=%+v want total=2 written=1 missing_taxid=1", report.QCStats)
+	}
+
+	noReportDir := filepath.Join(tmp, "quiet")
+	cfg.OutDir = noReportDir
+	cfg.NoReport = true
+	if err := formatFasta(cfg); err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	if fileExists(filepath.Join(noReportDir, formatReportName)) {
+		t.Fatalf("-no-report still wrote %s", formatReportName)
+	}
+}
+
+func TestFormatSubdirs(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"blast", "kraken2"},
+		RequireRanks: []string{"genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+		Subdirs:      true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	for _, rel := range []string{"blast/blast.fasta", "blast/blast_seqid2taxid.map", "kraken2/kraken2.fasta"} {
+		if _, err := os.Stat(filepath.Join(outDir, rel)); err != nil {
+			t.Fatalf("expected %s: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "blast.fasta")); !os.IsNotExist(err) {
+		t.Fatalf("expected no flat blast.fasta with subdirs, err=%v", err)
+	}
+}
+
+func TestFormatClassifiersMatchWriters(t *testing.T) {
+	w, err := openFormatWriters(t.TempDir(), formatClassifiers, false, 0)
+	if err != nil {
+		t.Fatalf("openFormatWriters failed: %v", err)
+	}
+	defer closeFormatWriters(w)
+	handles := w.classifierHandles()
+	if len(handles) != len(formatClassifiers) {
+		t.Fatalf("formatClassifiers has %d names, writers opened %d", len(formatClassifiers), len(handles))
+	}
+	for _, name := range formatClassifiers {
+		if _, ok := handles[name]; !ok {
+			t.Fatalf("classifier %q opened no writers", name)
+		}
+	}
+	for _, name := range convertTargetNames() {
+		if _, ok := handles[name]; !ok {
+			t.Fatalf("convert target %q is not a format classifier", name)
+		}
+	}
+	if len(convertTargetNames()) != len(convertTargets) {
+		t.Fatalf("convertTargets has names outside formatClassifiers: %v", convertTargetNames())
+	}
+}
+
+func TestFormatReportClassifierCounts(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n>P3\nGGGG\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	report := filepath.Join(tmp, "report.json")
+
+	err := formatFasta(formatConfig{
+		Classifiers:      []string{"blast", "sintax", "rdp"},
+		RequireRanks:     []string{"genus", "species"},
+		Input:            input,
+		OutDir:           filepath.Join(tmp, "out"),
+		TaxdumpDir:       tmp,
+		ReportPath:       report,
+		ConsistencyCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var got struct {
+		Written           int            `json:"written"`
+		ClassifierWritten map[string]int `json:"classifier_written"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	if got.Written != 2 {
+		t.Fatalf("written=%d want 2", got.Written)
+	}
+	for _, name := range []string{"blast", "sintax", "rdp"} {
+		if got.ClassifierWritten[name] != 2 {
+			t.Fatalf("classifier_written[%s]=%d want 2 (report: %s)", name, got.ClassifierWritten[name], string(data))
+		}
+	}
+}
+
+func TestFormatConsistencyMismatch(t *testing.T) {
+	handles := map[string][]*writerHandle{
+		"kraken2": {{name: "kraken2.fasta", records: 1}},
+	}
+	if err := checkFormatConsistency(handles, 2); err == nil || !strings.Contains(err.Error(), "kraken2") {
+		t.Fatalf("expected kraken2 mismatch error, got %v", err)
+	}
+}
+
+func TestFormatKraken2IncludeLineage(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:           []string{"kraken2"},
+		RequireRanks:          []string{"kingdom", "genus", "species"},
+		Input:                 input,
+		OutDir:                outDir,
+		TaxdumpDir:            tmp,
+		Kraken2IncludeLineage: true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "kraken2.fasta"))
+	if err != nil {
+		t.Fatalf("read kraken2 fasta: %v", err)
+	}
+	want := ">P1|kraken:taxid|9606 Animalia;Homo;Homo_sapiens\nACGT\n"
+	if string(data) != want {
+		t.Fatalf("kraken2 mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+}
+
+func TestRankAvailability(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	taxidMap := filepath.Join(tmp, "avail_taxid.map")
+	// P1 species-level, P2 genus-level only, P3 unmapped.
+	if err := os.WriteFile(taxidMap, []byte("P1\t9606\nP2\t17\n"), 0o644); err != nil {
+		t.Fatalf("write taxid map: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nACGT\n>P3\nACGT\n>P4\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	report, err := rankAvailability(formatConfig{
+		RequireRanks: []string{"family", "genus", "species"},
+		Input:        input,
+		TaxdumpDir:   tmp,
+		TaxidMapPath: taxidMap,
+	})
+	if err != nil {
+		t.Fatalf("rankAvailability failed: %v", err)
+	}
+	if report.Records != 4 || report.MissingTaxID != 2 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	if got := report.Ranks[2]; got.Rank != "species" || got.Records != 1 || got.Fraction != 0.25 {
+		t.Fatalf("unexpected species coverage: %+v", got)
+	}
+	if got := report.RankSets[1]; strings.Join(got.Ranks, ",") != "family,genus" || got.Records != 2 || got.Fraction != 0.5 {
+		t.Fatalf("unexpected family,genus set: %+v", got)
+	}
+}
+
+func TestSubspeciesFlowsThroughExtractAndSintax(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.tsv")
+	taxonkitOut := filepath.Join(tmp, "taxonkit_input.tsv")
+	content := strings.Join([]string{
+		"processid\tbin_uri\tkingdom\tphylum\tclass\torder\tfamily\tsubfamily\ttribe\tgenus\tspecies\tsubspecies",
+		"P3\tBOLD:BIN1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo sapiens\tHomo sapiens idaltu",
+		"P4\tBOLD:BIN2\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\t\t\tHomo\tHomo erectus\tHomo sapiens idaltu",
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	plain := filepath.Join(tmp, "plain.tsv")
+	if _, err := buildTaxonkit(input, plain, 0, -1, extractCurationConfig{}.normalized(), extractOptions{}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatalf("read taxonkit output: %v", err)
+	}
+	if strings.Contains(string(data), "subspecies") || strings.Contains(string(data), "idaltu") {
+		t.Fatalf("subspecies column emitted without -emit-subspecies:\n%s", data)
+	}
+
+	if _, err := buildTaxonkit(input, taxonkitOut, 0, -1, extractCurationConfig{}.normalized(), extractOptions{EmitSubspecies: true}); err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	data, err = os.ReadFile(taxonkitOut)
+	if err != nil {
+		t.Fatalf("read taxonkit output: %v", err)
+	}
+	if !strings.Contains(string(data), "\tprocessid\tsubspecies\n") || !strings.Contains(string(data), "\tP3\tHomo sapiens idaltu\n") {
+		t.Fatalf("expected trailing subspecies column, got:\n%s", data)
+	}
+	// The none protocol drops a subspecies that does not extend the species.
+	if !strings.Contains(string(data), "\tHomo erectus\tP4\t\n") {
+		t.Fatalf("expected P4's inconsistent subspecies to be dropped, got:\n%s", data)
+	}
+
+	// Stand in for taxonkit create-taxdump: add the subspecies node it would build.
+	writeTestTaxdump(t, tmp)
+	appendFile := func(name, line string) {
+		f, err := os.OpenFile(filepath.Join(tmp, name), os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer func() { _ = f.Close() }()
+		if _, err := f.WriteString(line); err != nil {
+			t.Fatalf("append %s: %v", name, err)
+		}
+	}
+	appendFile("nodes.dmp", "96061\t|\t9606\t|\tsubspecies\t|\n")
+	appendFile("names.dmp", "96061\t|\tHomo sapiens idaltu\t|\t\t|\tscientific name\t|\n")
+	appendFile("taxid.map", "P3\t96061\n")
+
+	fasta := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(fasta, []byte(">P3\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write fasta: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	err = formatFasta(formatConfig{
+		Classifiers:  []string{"sintax"},
+		RequireRanks: append(append([]string(nil), canonicalRanks...), "subspecies"),
+		Input:        fasta,
+		OutDir:       outDir,
+		TaxdumpDir:   tmp,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	sintax, err := os.ReadFile(filepath.Join(outDir, "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	want := ">P3;tax=d:Animalia,p:Chordata,c:Mammalia,o:Primates,f:Hominidae,g:Homo,s:Homo_sapiens,t:Homo_sapiens_idaltu\n"
+	if !strings.HasPrefix(string(sintax), want) {
+		t.Fatalf("sintax header=%q want prefix %q", sintax, want)
+	}
+}
+
+func TestFormatRejectedIDs(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "taxid.map"), []byte("P1\t9606\nP2\t7\nP4\t424242\n"), 0o644); err != nil {
+		t.Fatalf("write taxid.map: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n>P3\nGGGG\n>P4\nCCCC\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	rejected := filepath.Join(tmp, "rejected.tsv")
+
+	err := formatFasta(formatConfig{
+		Classifiers:     []string{"blast"},
+		RequireRanks:    canonicalRanks,
+		Input:           input,
+		OutDir:          filepath.Join(tmp, "out"),
+		TaxdumpDir:      tmp,
+		RejectedIDsPath: rejected,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(rejected)
+	if err != nil {
+		t.Fatalf("read rejected-ids: %v", err)
+	}
+	want := "processid\treason\ttaxid\n" +
+		"P2\tmissing_ranks\t7\n" +
+		"P3\tmissing_taxid\t\n" +
+		"P4\tunknown_taxid\t424242\n"
+	if string(data) != want {
+		t.Fatalf("rejected-ids=%q want %q", data, want)
+	}
+}
+
+func TestFormatEmitSeqHashes(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nGGCC\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+
+	err := formatFasta(formatConfig{
+		Classifiers:      []string{"blast"},
+		RequireRanks:     []string{"genus", "species"},
+		Input:            input,
+		OutDir:           outDir,
+		TaxdumpDir:       tmp,
+		EmitSeqHashes:    true,
+		ConsistencyCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "blast_seqid2md5.tsv"))
+	if err != nil {
+		t.Fatalf("read md5 map: %v", err)
+	}
+	want := "P1\tf1f8f4bf413b16ad135722aa4591043e\nP2\t1ab520b9a89ee12d10dfc2391db04ff4\n"
+	if string(data) != want {
+		t.Fatalf("md5 map=%q want %q", data, want)
+	}
+	taxidMap, err := os.ReadFile(filepath.Join(outDir, "blast_seqid2taxid.map"))
+	if err != nil {
+		t.Fatalf("read taxid map: %v", err)
+	}
+	if string(taxidMap) != "P1\t9606\nP2\t9615\n" {
+		t.Fatalf("taxid map should stay two-column: %q", taxidMap)
+	}
+
+	err = formatFasta(formatConfig{
+		Classifiers:   []string{"sintax"},
+		RequireRanks:  []string{"genus", "species"},
+		Input:         input,
+		OutDir:        outDir,
+		TaxdumpDir:    tmp,
+		EmitSeqHashes: true,
+	})
+	if err == nil {
+		t.Fatalf("expected -emit-seq-hashes without blast to fail")
+	}
+}
+
+func TestFormatOutputRankNames(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	rankNames, err := parseOutputRankNames("kingdom=domain, class=clade")
+	if err != nil {
+		t.Fatalf("parseOutputRankNames: %v", err)
+	}
+
+	err = formatFasta(formatConfig{
+		Classifiers:     []string{"rdp", "taxidlineage"},
+		RequireRanks:    canonicalRanks,
+		Input:           input,
+		OutDir:          outDir,
+		TaxdumpDir:      tmp,
+		OutputRankNames: rankNames,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	lineage, err := os.ReadFile(filepath.Join(outDir, "taxid_lineage.tsv"))
+	if err != nil {
+		t.Fatalf("read taxid lineage: %v", err)
+	}
+	want := "P1\tdomain:2\tphylum:3\tclade:4\torder:5\tfamily:6\tgenus:7\tspecies:9606\n"
+	if string(lineage) != want {
+		t.Fatalf("taxid lineage=%q want %q", string(lineage), want)
+	}
+	taxonomy, err := os.ReadFile(filepath.Join(outDir, "rdp_taxonomy.txt"))
+	if err != nil {
+		t.Fatalf("read rdp taxonomy: %v", err)
+	}
+	if !strings.Contains(string(taxonomy), "*Mammalia*") || !strings.Contains(string(taxonomy), "*clade\n") || strings.Contains(string(taxonomy), "*class\n") {
+		t.Fatalf("rdp taxonomy should label class as clade:\n%s", taxonomy)
+	}
+
+	for _, bad := range []string{"realm=domain", "kingdom", "class=a:b"} {
+		if _, err := parseOutputRankNames(bad); err == nil {
+			t.Fatalf("parseOutputRankNames(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRankPrefixSuggestion(t *testing.T) {
+	cases := []struct {
+		ranks   []string
+		ok      bool
+		suggest string
+	}{
+		{nil, true, ""},
+		{canonicalRanks, true, strings.Join(canonicalRanks, ",")},
+		{[]string{"kingdom", "phylum", "class"}, true, "kingdom,phylum,class"},
+		{[]string{"kingdom", "family"}, false, "kingdom,phylum,class,order,family"},
+		{[]string{"phylum", "class"}, false, "kingdom,phylum,class"},
+		{[]string{"kingdom", "class", "phylum"}, false, "kingdom,phylum,class"},
+		{[]string{"kingdom", "phylum", "class", "order", "superfamily", "family"}, true, "kingdom,phylum,class,order,family"},
+	}
+	for _, tc := range cases {
+		suggest, ok := rankPrefixSuggestion(tc.ranks)
+		if ok != tc.ok || strings.Join(suggest, ",") != tc.suggest {
+			t.Fatalf("rankPrefixSuggestion(%v)=%v,%v want %s,%v", tc.ranks, suggest, ok, tc.suggest, tc.ok)
+		}
+	}
+	if err := checkRankPrefix("format", []string{"kingdom", "family"}, true); err == nil || !strings.Contains(err.Error(), "did you mean -require-ranks kingdom,phylum,class,order,family") {
+		t.Fatalf("strict check err=%v", err)
+	}
+}
+
+func TestFormatRejectedIDsReportGzip(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P9\nTTGA\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	globalOpts.ReportGzip = true
+	defer func() {
+		globalOpts.ReportGzip = false
+	}()
+
+	rejected := filepath.Join(tmp, "rejected.tsv")
+	err := formatFasta(formatConfig{
+		Classifiers:     []string{"blast"},
+		RequireRanks:    canonicalRanks,
+		Input:           input,
+		OutDir:          filepath.Join(tmp, "out"),
+		TaxdumpDir:      tmp,
+		RejectedIDsPath: rejected,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	if fileExists(rejected) {
+		t.Fatalf("-report-gzip should not write the plain %s", rejected)
+	}
+	f, err := os.Open(rejected + ".gz")
+	if err != nil {
+		t.Fatalf("open gzipped rejected-ids: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("read rejected-ids: %v", err)
+	}
+	if string(data) != "processid\treason\ttaxid\nP9\tmissing_taxid\t\n" {
+		t.Fatalf("rejected-ids=%q", string(data))
+	}
+}
+
+func TestFormatSintaxFromLineageTSV(t *testing.T) {
+	tmp := t.TempDir()
+	lineage := filepath.Join(tmp, "lineage.tsv")
+	content := "processid\tkingdom\tphylum\tclass\torder\tfamily\tgenus\tspecies\textra\n" +
+		"P1\tAnimalia\tChordata\tMammalia\tPrimates\tHominidae\tHomo\tHomo sapiens\tx\n" +
+		"P2\tAnimalia\tChordata\tMammalia\tCarnivora\tCanidae\tCanis\tNone\tx\n"
+	if err := os.WriteFile(lineage, []byte(content), 0o644); err != nil {
+		t.Fatalf("write lineage: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n>P2\nTTGA\n>P3\nGGCC\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	rejected := filepath.Join(tmp, "rejected.tsv")
+
+	// No taxdump in tmp: format must not try to load one.
+	err := formatFasta(formatConfig{
+		Classifiers:     []string{"sintax"},
+		RequireRanks:    canonicalRanks,
+		Input:           input,
+		OutDir:          outDir,
+		TaxdumpDir:      tmp,
+		LineageTSV:      lineage,
+		RejectedIDsPath: rejected,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	sintax, err := os.ReadFile(filepath.Join(outDir, "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	want := ">P1;tax=d:Animalia,p:Chordata,c:Mammalia,o:Primates,f:Hominidae,g:Homo,s:Homo_sapiens\nACGT\n"
+	if string(sintax) != want {
+		t.Fatalf("sintax=%q want %q", sintax, want)
+	}
+	data, err := os.ReadFile(rejected)
+	if err != nil {
+		t.Fatalf("read rejected-ids: %v", err)
+	}
+	if string(data) != "processid\treason\ttaxid\nP2\tmissing_ranks\t\nP3\tmissing_lineage\t\n" {
+		t.Fatalf("rejected-ids=%q", string(data))
+	}
+
+	err = formatFasta(formatConfig{
+		Classifiers:  []string{"sintax"},
+		RequireRanks: append(append([]string(nil), canonicalRanks...), "subspecies"),
+		Input:        input,
+		OutDir:       outDir,
+		LineageTSV:   lineage,
+	})
+	if err == nil || !strings.Contains(err.Error(), "missing columns subspecies") {
+		t.Fatalf("missing rank column err=%v", err)
+	}
+	err = formatFasta(formatConfig{
+		Classifiers:  []string{"blast"},
+		RequireRanks: canonicalRanks,
+		Input:        input,
+		OutDir:       outDir,
+		LineageTSV:   lineage,
+	})
+	if err == nil || !strings.Contains(err.Error(), "needs taxids") {
+		t.Fatalf("blast from lineage-tsv err=%v", err)
+	}
+}
+
+func TestFormatSintaxPrefixesFollowRanks(t *testing.T) {
+	tmp := t.TempDir()
+	lineage := filepath.Join(tmp, "lineage.tsv")
+	content := "processid\tkingdom\tfamily\tsubfamily\ttribe\tgenus\tspecies\n" +
+		"P1\tAnimalia\tNymphalidae\tSatyrinae\tSatyrini\tErebia\tErebia aethiops\n"
+	if err := os.WriteFile(lineage, []byte(content), 0o644); err != nil {
+		t.Fatalf("write lineage: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	if err := os.WriteFile(input, []byte(">P1\nACGT\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	err := formatFasta(formatConfig{
+		Classifiers:  []string{"sintax"},
+		RequireRanks: []string{"kingdom", "family", "subfamily", "tribe", "genus", "species"},
+		Input:        input,
+		OutDir:       outDir,
+		LineageTSV:   lineage,
+	})
+	if err != nil {
+		t.Fatalf("formatFasta failed: %v", err)
+	}
+	sintax, err := os.ReadFile(filepath.Join(outDir, "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	want := ">P1;tax=d:Animalia,f:Nymphalidae,u:Satyrinae,r:Satyrini,g:Erebia,s:Erebia_aethiops\n"
+	if !strings.HasPrefix(string(sintax), want) {
+		t.Fatalf("sintax header=%q want prefix %q", sintax, want)
+	}
+
+	got := strings.Join(sintaxPrefixesFor([]string{"kingdom", "subfamily", "tribe", "species", "subspecies"}), ",")
+	if got != "d,u,r,s,t" {
+		t.Fatalf("sintaxPrefixesFor=%s want d,u,r,s,t", got)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import "sort"
+
+// lengthStats summarises a set of sequence lengths. When BucketWidth > 0 the
+// histogram is bucketed: Min, Max, Count and TotalBases stay exact, while
+// Q1/Median/Q3 and N50 are reported as bucket midpoints and so are within
+// BucketWidth/2 of the exact value. L50 is computed from those midpoints and
+// can drift by the number of records sharing the N50 bucket.
+type lengthStats struct {
+	Count       int   `json:"count"`
+	TotalBases  int64 `json:"total_bases"`
+	Min         int   `json:"min"`
+	Q1          int   `json:"q1"`
+	Median      int   `json:"median"`
+	Q3          int   `json:"q3"`
+	Max         int   `json:"max"`
+	N50         int   `json:"n50"`
+	L50         int   `json:"l50"`
+	BucketWidth int   `json:"bucket_width,omitempty"`
+}
+
+// lengthHistogram counts lengths per bucket. Memory is bounded by the number
+// of distinct buckets rather than the number of records; width <= 1 keeps
+// one bucket per exact length.
+type lengthHistogram struct {
+	width  int
+	counts map[int]int
+	n      int
+	total  int64
+	min    int
+	max    int
+}
+
+func newLengthHistogram(width int) *lengthHistogram {
+	if width < 1 {
+		width = 1
+	}
+	return &lengthHistogram{width: width, counts: make(map[int]int)}
+}
+
+func (h *lengthHistogram) add(length int) {
+	if h.n == 0 || length < h.min {
+		h.min = length
+	}
+	if length > h.max {
+		h.max = length
+	}
+	h.n++
+	h.total += int64(length)
+	h.counts[length/h.width]++
+}
+
+// value returns the representative length of a bucket, clamped to the
+// observed range so exact extremes are never exceeded.
+func (h *lengthHistogram) value(bucket int) int {
+	v := bucket * h.width
+	if h.width > 1 {
+		v += h.width / 2
+	}
+	if v < h.min {
+		v = h.min
+	}
+	if v > h.max {
+		v = h.max
+	}
+	return v
+}
+
+func (h *lengthHistogram) summary() lengthStats {
+	stats := lengthStats{Count: h.n, TotalBases: h.total, Min: h.min, Max: h.max}
+	if h.width > 1 {
+		stats.BucketWidth = h.width
+	}
+	if h.n == 0 {
+		return stats
+	}
+	buckets := make([]int, 0, len(h.counts))
+	for b := range h.counts {
+		buckets = append(buckets, b)
+	}
+	sort.Ints(buckets)
+
+	// rank r (1-based) in ascending order.
+	at := func(r int) int {
+		seen := 0
+		for _, b := range buckets {
+			seen += h.counts[b]
+			if seen >= r {
+				return h.value(b)
+			}
+		}
+		return h.max
+	}
+	quantile := func(num, den int) int {
+		r := (h.n*num + den - 1) / den
+		if r < 1 {
+			r = 1
+		}
+		return at(r)
+	}
+	stats.Q1 = quantile(1, 4)
+	stats.Median = quantile(1, 2)
+	stats.Q3 = quantile(3, 4)
+
+	var half, acc int64
+	half = (h.total + 1) / 2
+	records := 0
+	for i := len(buckets) - 1; i >= 0; i-- {
+		b := buckets[i]
+		v := int64(h.value(b))
+		c := h.counts[b]
+		if v > 0 && acc+v*int64(c) >= half {
+			need := (half - acc + v - 1) / v
+			stats.N50 = int(v)
+			stats.L50 = records + int(need)
+			break
+		}
+		acc += v * int64(c)
+		records += c
+	}
+	return stats
+}
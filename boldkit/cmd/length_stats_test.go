@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestLengthHistogramExact(t *testing.T) {
+	h := newLengthHistogram(0)
+	for _, l := range []int{2, 3, 4, 5, 6, 7, 8, 9, 10} {
+		h.add(l)
+	}
+	got := h.summary()
+	want := lengthStats{Count: 9, TotalBases: 54, Min: 2, Q1: 4, Median: 6, Q3: 8, Max: 10, N50: 8, L50: 3}
+	if got != want {
+		t.Fatalf("summary mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestLengthHistogramApproxWithinBound(t *testing.T) {
+	exact := newLengthHistogram(0)
+	approx := newLengthHistogram(10)
+	for i := 0; i < 1000; i++ {
+		l := 500 + (i*37)%200
+		exact.add(l)
+		approx.add(l)
+	}
+	e, a := exact.summary(), approx.summary()
+	if a.Min != e.Min || a.Max != e.Max || a.Count != e.Count || a.TotalBases != e.TotalBases {
+		t.Fatalf("exact fields drifted: exact=%+v approx=%+v", e, a)
+	}
+	within := func(name string, x, y int) {
+		d := x - y
+		if d < 0 {
+			d = -d
+		}
+		if d > 5 {
+			t.Fatalf("%s off by %d (exact=%d approx=%d)", name, d, x, y)
+		}
+	}
+	within("median", e.Median, a.Median)
+	within("n50", e.N50, a.N50)
+}
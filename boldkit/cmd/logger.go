@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats accepted by the global -log-format flag.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logger renders logf/debugf lines. Progress bars write to stderr directly
+// and are never wrapped.
+type logger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	format  string
+	command string
+	now     func() time.Time
+}
+
+type logEntry struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Command   string `json:"command,omitempty"`
+	Message   string `json:"message"`
+}
+
+var appLogger = &logger{out: os.Stderr, format: logFormatText, now: time.Now}
+
+func normalizeLogFormat(format string) (string, error) {
+	switch format {
+	case "", logFormatText:
+		return logFormatText, nil
+	case logFormatJSON:
+		return logFormatJSON, nil
+	}
+	return "", fmt.Errorf("log-format must be text or json (got %q)", format)
+}
+
+func (l *logger) log(level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.format != logFormatJSON {
+		fmt.Fprintf(l.out, "[boldkit] %s\n", msg)
+		return
+	}
+	line, err := json.Marshal(logEntry{
+		Timestamp: l.now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Level:     level,
+		Command:   l.command,
+		Message:   msg,
+	})
+	if err != nil {
+		fmt.Fprintf(l.out, "[boldkit] %s\n", msg)
+		return
+	}
+	_, _ = l.out.Write(append(line, '\n'))
+}
+
+func logf(format string, args ...any) {
+	appLogger.log("info", fmt.Sprintf(format, args...))
+}
+
+// debugf logs only when the global -verbose flag is set.
+func debugf(format string, args ...any) {
+	if !globalOpts.Verbose {
+		return
+	}
+	appLogger.log("debug", fmt.Sprintf(format, args...))
+}
+
+// warningCounter tallies warnings by category so -fail-on-warnings can turn
+// them into a nonzero exit once the command finishes.
+type warningCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+var appWarnings = &warningCounter{counts: make(map[string]int)}
+
+func (w *warningCounter) add(category string, n int) {
+	if n <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.counts[category] += n
+}
+
+// summary renders "category=count" pairs sorted by category; "" when no
+// warnings were recorded.
+func (w *warningCounter) summary() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	parts := make([]string, 0, len(w.counts))
+	for category, n := range w.counts {
+		parts = append(parts, fmt.Sprintf("%s=%d", category, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// warnf logs a warning and records n occurrences under category.
+func warnf(category string, n int, format string, args ...any) {
+	appWarnings.add(category, n)
+	appLogger.log("warn", fmt.Sprintf(format, args...))
+}
+
+// countWarning records n occurrences under category without logging, for
+// counts already reported in a summary line.
+func countWarning(category string, n int) {
+	appWarnings.add(category, n)
+}
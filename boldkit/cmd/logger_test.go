@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLoggerFormats(t *testing.T) {
+	var buf bytes.Buffer
+	now := func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC) }
+
+	text := &logger{out: &buf, format: logFormatText, now: now}
+	text.log("info", "format: total=2")
+	if got := buf.String(); got != "[boldkit] format: total=2\n" {
+		t.Fatalf("text log=%q", got)
+	}
+
+	buf.Reset()
+	js := &logger{out: &buf, format: logFormatJSON, command: "format", now: now}
+	js.log("debug", "temp file created")
+	var entry logEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("json log not parseable: %v (%q)", err, buf.String())
+	}
+	want := logEntry{Timestamp: "2024-05-01T12:30:00.000Z", Level: "debug", Command: "format", Message: "temp file created"}
+	if entry != want {
+		t.Fatalf("json log=%+v want %+v", entry, want)
+	}
+
+	if _, err := normalizeLogFormat("yaml"); err == nil {
+		t.Fatalf("expected unknown log format to fail")
+	}
+}
+
+func TestWarningCounterSummary(t *testing.T) {
+	w := &warningCounter{counts: make(map[string]int)}
+	if got := w.summary(); got != "" {
+		t.Fatalf("empty summary=%q", got)
+	}
+	w.add("missing_ranks", 3)
+	w.add("conflicted_barcodes", 1)
+	w.add("missing_ranks", 2)
+	w.add("missing_taxid", 0)
+	if got, want := w.summary(), "conflicted_barcodes=1 missing_ranks=5"; got != want {
+		t.Fatalf("summary=%q want %q", got, want)
+	}
+}
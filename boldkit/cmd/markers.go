@@ -2,37 +2,109 @@ package cmd
 
 import (
 	"bufio"
+	"crypto/md5"
 	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/klauspost/pgzip"
 )
 
 type markerWriter struct {
+	path string
 	file *os.File
 	buf  *bufio.Writer
 	gz   io.Closer
+	// records and bases count what this run wrote, for -marker-summary.
+	records int
+	bases   int
+	// minLen and maxLen bound the written sequence lengths (markers_stats.json).
+	minLen int
+	maxLen int
+	// derepSeen maps sequence md5 to the processid kept for it (-derep);
+	// derepBuf writes the <marker>.derep.tsv map next to the FASTA.
+	derepSeen map[[16]byte]string
+	derepPath string
+	derepFile *os.File
+	derepBuf  *bufio.Writer
+	collapsed int
+	// shard is the markerShards goroutine that writes buf; see markerShard.
+	shard int
+}
+
+type markerConfig struct {
+	GzipOut     bool
+	ReportEvery int
+	TotalRows   int
+	// Workers sizes both the row parser and the marker writer shards.
+	Workers int
+	// DedupeGlobal keeps one record per sequence md5 across all markers; the
+	// first occurrence in input order wins.
+	DedupeGlobal bool
+	// Resume skips markers whose output has a .done sentinel from an earlier
+	// run and writes sentinels for the markers this run completes.
+	Resume bool
+	// Markers, when non-empty, is an allowlist of marker codes matched
+	// case-insensitively; records of other markers are skipped.
+	Markers []string
+	// SummaryPath receives a per-marker TSV (marker, records, bases, gzip,
+	// path) of the markers this run wrote, sorted by marker.
+	SummaryPath string
+	// GzipLevel is the pgzip level of .fasta.gz outputs; 0 keeps
+	// pgzip.DefaultCompression.
+	GzipLevel int
+	// Derep collapses exact-duplicate sequences within each marker, keeping
+	// the first processid, and maps every processid to its representative.
+	Derep bool
+}
+
+// markerDoneSuffix marks a marker FASTA that was fully written and closed.
+const markerDoneSuffix = ".done"
+
+// markerDerepSuffix names the per-marker -derep map, <marker>.derep.tsv.
+const markerDerepSuffix = ".derep.tsv"
+
+// isMarkerBuildArtifact reports whether a file in the marker directory is
+// markers build state or a side report (.done sentinels, markers_stats.json,
+// -derep maps) rather than a marker FASTA. Release archives leave these out.
+func isMarkerBuildArtifact(name string) bool {
+	return strings.HasSuffix(name, markerDoneSuffix) ||
+		strings.HasSuffix(name, markerDerepSuffix) ||
+		name == markerStatsName
 }
 
 func runMarkers(args []string) {
 	fs := flag.NewFlagSet("markers", flag.ExitOnError)
-	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV or Parquet)")
+	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV, CSV or Parquet)")
 	outDir := fs.String("outdir", "marker_fastas", "Output directory for marker FASTAs")
 	progressOn := fs.Bool("progress", true, "Show progress bar")
 	gzipOut := fs.Bool("gzip", true, "Compress FASTA outputs to .fasta.gz")
 	force := fs.Bool("force", false, "Overwrite existing outputs")
 	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "Parser worker goroutines (<=0 defaults to GOMAXPROCS)")
+	dedupeGlobal := fs.Bool("dedupe-global", false, "Keep one record per sequence across all markers (first occurrence wins)")
+	resume := fs.Bool("resume", false, "Skip markers with a completed .done sentinel and rebuild the rest")
+	markers := fs.String("markers", "", "Comma-separated marker codes to write, case-insensitive (empty writes every marker)")
+	summary := fs.String("marker-summary", "", "Optional TSV of marker, records, bases, gzip and output path per marker written")
+	gzipLevel := fs.Int("gzip-level", 0, "Gzip level 1-9 for .fasta.gz outputs (0 keeps the pgzip default)")
+	derep := fs.Bool("derep", false, "Collapse exact-duplicate sequences within each marker (first processid wins) and write <marker>.derep.tsv")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
 
-	if !*force && outputsExist(*outDir) {
+	if err := validateGzipLevel(*gzipLevel); err != nil {
+		fatalf("%v", err)
+	}
+
+	if !*force && !*resume && outputsExist(*outDir) {
 		fmt.Fprintf(os.Stderr, "Marker FASTAs already exist, skipping: %s\n", *outDir)
 		return
 	}
@@ -43,11 +115,11 @@ func runMarkers(args []string) {
 
 	totalRows := -1
 	if *progressOn {
-		count, err := RowCount(*input)
+		count, err := progressRows(*input, "")
 		if err != nil {
 			fatalf("count rows failed: %v", err)
 		}
-		totalRows = int(count)
+		totalRows = count
 	}
 
 	reportEvery := 0
@@ -55,24 +127,49 @@ func runMarkers(args []string) {
 		reportEvery = 1
 	}
 
-	if err := buildMarkerFastas(*input, *outDir, *gzipOut, reportEvery, totalRows, *workers); err != nil {
+	cfg := markerConfig{
+		GzipOut:      *gzipOut,
+		ReportEvery:  reportEvery,
+		TotalRows:    totalRows,
+		Workers:      *workers,
+		DedupeGlobal: *dedupeGlobal,
+		Resume:       *resume,
+		SummaryPath:  *summary,
+		Markers:      splitList(*markers),
+		Derep:        *derep,
+		GzipLevel:    *gzipLevel,
+	}
+	if err := buildMarkerFastas(*input, *outDir, cfg); err != nil {
 		fatalf("build failed: %v", err)
 	}
 }
 
-func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, totalRows, workers int) error {
+func buildMarkerFastas(inputPath, outDir string, cfg markerConfig) error {
 	writers := make(map[string]*markerWriter)
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	recordPool := sync.Pool{
+		New: func() any {
+			buf := make([]byte, 0, 4096)
+			return &buf
+		},
+	}
+	shards := newMarkerShards(workers, &recordPool)
 	defer func() {
+		_ = shards.wait()
 		for _, w := range writers {
 			_ = w.buf.Flush()
 			if w.gz != nil {
 				_ = w.gz.Close()
 			}
 			_ = w.file.Close()
+			_ = w.closeDerep()
 		}
 	}()
 
-	progress := newProgress(totalRows, reportEvery)
+	progress := newProgress(cfg.TotalRows, cfg.ReportEvery)
 	var (
 		idxProcess = -1
 		idxMarker  = -1
@@ -82,9 +179,6 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 	opts := DefaultOptions()
 	opts.StrictColumns = true
 	opts.BatchLines = 2048
-	if workers <= 0 {
-		workers = runtime.GOMAXPROCS(0)
-	}
 	gzipWorkers := workers
 	opts.Workers = workers
 	opts.Progress = progress
@@ -96,12 +190,6 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 			return &buf
 		},
 	}
-	recordPool := sync.Pool{
-		New: func() any {
-			buf := make([]byte, 0, 4096)
-			return &buf
-		},
-	}
 	markerBufPool := sync.Pool{
 		New: func() any {
 			buf := make([]byte, 0, 32)
@@ -109,6 +197,30 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 		},
 	}
 
+	// seenSeqs maps sequence md5 to the marker that kept it (DedupeGlobal).
+	var seenSeqs map[[16]byte]string
+	var crossDupes, sameDupes int
+	if cfg.DedupeGlobal {
+		seenSeqs = make(map[[16]byte]string, 1<<20)
+	}
+
+	allowed := foldedSet(cfg.Markers)
+	var skipped int
+	// emptyDropped counts per marker the rows whose sequence was empty after
+	// filtering, for markers_stats.json.
+	emptyDropped := make(map[string]int)
+
+	var done map[string]struct{}
+	if cfg.Resume {
+		var err error
+		if done, err = completedMarkers(outDir, cfg.GzipOut); err != nil {
+			return err
+		}
+		if len(done) > 0 {
+			logf("markers: resume skipping %d completed markers", len(done))
+		}
+	}
+
 	err := ParseRows(inputPath, opts, func(row Row) error {
 		if idxProcess < 0 {
 			idxProcess = indexOfBytes(row.Fields, "processid")
@@ -125,8 +237,24 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 			return fmt.Errorf("line %d: expected at least %d fields", row.Line, maxIndex(idxProcess, idxMarker, idxNuc)+1)
 		}
 
+		markerVal := normalizeBytes(fields[idxMarker])
+		if len(markerVal) == 0 {
+			markerVal = []byte("UNKNOWN")
+		}
+		if allowed != nil && !inFoldedSet(allowed, markerVal) {
+			skipped++
+			return nil
+		}
+
+		markerScratchPtr := markerBufPool.Get().(*[]byte)
+		markerScratch := *markerScratchPtr
+		sanitizedMarker := sanitizeMarkerBytes(markerScratch, markerVal)
+		*markerScratchPtr = markerScratch[:0]
+		markerBufPool.Put(markerScratchPtr)
+
 		nuc := fields[idxNuc]
 		if len(nuc) == 0 || isNone(nuc) {
+			emptyDropped[sanitizedMarker]++
 			return nil
 		}
 
@@ -134,29 +262,52 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 		seqBuf := *seqBufPtr
 		seq := filterSeqBytes(seqBuf, nuc)
 		if len(seq) == 0 {
+			emptyDropped[sanitizedMarker]++
 			*seqBufPtr = seq[:0]
 			seqPool.Put(seqBufPtr)
 			return nil
 		}
 
-		markerVal := normalizeBytes(fields[idxMarker])
-		if len(markerVal) == 0 {
-			markerVal = []byte("UNKNOWN")
+		if seenSeqs != nil {
+			hash := md5.Sum(seq)
+			if first, dup := seenSeqs[hash]; dup {
+				if first != sanitizedMarker {
+					crossDupes++
+				} else {
+					sameDupes++
+				}
+				*seqBufPtr = seq[:0]
+				seqPool.Put(seqBufPtr)
+				return nil
+			}
+			seenSeqs[hash] = sanitizedMarker
+		}
+		if _, skip := done[sanitizedMarker]; skip {
+			*seqBufPtr = seq[:0]
+			seqPool.Put(seqBufPtr)
+			return nil
 		}
-
-		markerScratchPtr := markerBufPool.Get().(*[]byte)
-		markerScratch := *markerScratchPtr
-		sanitizedMarker := sanitizeMarkerBytes(markerScratch, markerVal)
-		*markerScratchPtr = markerScratch[:0]
-		markerBufPool.Put(markerScratchPtr)
 
 		pid := fields[idxProcess]
-		w, err := getMarkerWriter(outDir, sanitizedMarker, gzipOut, gzipWorkers, writers)
+		w, err := getMarkerWriter(outDir, sanitizedMarker, cfg.GzipOut, cfg.GzipLevel, gzipWorkers, len(shards.chans), writers)
 		if err != nil {
 			*seqBufPtr = seq[:0]
 			seqPool.Put(seqBufPtr)
 			return err
 		}
+		if cfg.Derep {
+			dup, err := w.derepRecord(outDir, sanitizedMarker, pid, seq)
+			if err != nil {
+				*seqBufPtr = seq[:0]
+				seqPool.Put(seqBufPtr)
+				return err
+			}
+			if dup {
+				*seqBufPtr = seq[:0]
+				seqPool.Put(seqBufPtr)
+				return nil
+			}
+		}
 
 		recordPtr := recordPool.Get().(*[]byte)
 		record := *recordPtr
@@ -165,17 +316,23 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 		record = append(record, '\n')
 		record = append(record, seq...)
 		record = append(record, '\n')
+		*recordPtr = record
 
-		if _, err := w.buf.Write(record); err != nil {
-			*recordPtr = record[:0]
-			recordPool.Put(recordPtr)
+		if err := shards.send(w, recordPtr); err != nil {
 			*seqBufPtr = seq[:0]
 			seqPool.Put(seqBufPtr)
-			return fmt.Errorf("write marker %s: %w", sanitizedMarker, err)
+			return err
+		}
+
+		w.records++
+		w.bases += len(seq)
+		if w.records == 1 || len(seq) < w.minLen {
+			w.minLen = len(seq)
+		}
+		if len(seq) > w.maxLen {
+			w.maxLen = len(seq)
 		}
 
-		*recordPtr = record[:0]
-		recordPool.Put(recordPtr)
 		*seqBufPtr = seq[:0]
 		seqPool.Put(seqBufPtr)
 		return nil
@@ -183,21 +340,215 @@ func buildMarkerFastas(inputPath, outDir string, gzipOut bool, reportEvery, tota
 	if err != nil {
 		return err
 	}
+	if err := shards.wait(); err != nil {
+		return err
+	}
 
 	progress.finish()
+	var collapsed int
+	for _, w := range writers {
+		keepOutputs(w.path)
+		if w.derepBuf != nil {
+			keepOutputs(w.derepPath)
+		}
+		collapsed += w.collapsed
+	}
+	// -resume closes and drops the writers, so summarize them first.
+	if err := writeMarkerStats(filepath.Join(outDir, markerStatsName), writers, emptyDropped, done); err != nil {
+		return err
+	}
+	if cfg.SummaryPath != "" {
+		if err := writeMarkerSummary(cfg.SummaryPath, writers, cfg.GzipOut); err != nil {
+			return err
+		}
+	}
+	if cfg.Resume {
+		if err := closeMarkerWriters(writers); err != nil {
+			return err
+		}
+	}
+	if allowed != nil {
+		logf("markers: skipped %d records outside -markers %s", skipped, strings.Join(cfg.Markers, ","))
+	}
+	if cfg.DedupeGlobal {
+		logf("markers: dedupe-global unique=%d collapsed cross-marker=%d same-marker=%d", len(seenSeqs), crossDupes, sameDupes)
+	}
+	if cfg.Derep {
+		logf("markers: derep collapsed %d duplicate sequences within markers", collapsed)
+	}
 	return nil
 }
 
-func getMarkerWriter(outDir, marker string, gzipOut bool, gzipWorkers int, writers map[string]*markerWriter) (*markerWriter, error) {
+// markerShardJob is one FASTA record bound for w. The record buffer comes
+// from the shards' pool and is returned to it once written.
+type markerShardJob struct {
+	w      *markerWriter
+	record *[]byte
+}
+
+// markerShards fans records out to one goroutine per shard, keyed on the
+// marker name, so different markers write and compress concurrently while
+// each marker's records keep input order. Until wait returns, a writer's buf
+// is only touched by its shard goroutine.
+type markerShards struct {
+	chans []chan markerShardJob
+	pool  *sync.Pool
+	wg    sync.WaitGroup
+	once  sync.Once
+	// failed lets the per-record checks skip mu until a write fails.
+	failed atomic.Bool
+	mu     sync.Mutex
+	err    error
+}
+
+func newMarkerShards(n int, pool *sync.Pool) *markerShards {
+	if n < 1 {
+		n = 1
+	}
+	s := &markerShards{chans: make([]chan markerShardJob, n), pool: pool}
+	for i := range s.chans {
+		ch := make(chan markerShardJob, 256)
+		s.chans[i] = ch
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for job := range ch {
+				if s.firstErr() == nil {
+					if _, err := job.w.buf.Write(*job.record); err != nil {
+						s.fail(fmt.Errorf("write %s: %w", job.w.path, err))
+					}
+				}
+				*job.record = (*job.record)[:0]
+				s.pool.Put(job.record)
+			}
+		}()
+	}
+	return s
+}
+
+// send queues record on w's shard, reporting any write error a shard has hit
+// so far.
+func (s *markerShards) send(w *markerWriter, record *[]byte) error {
+	if err := s.firstErr(); err != nil {
+		return err
+	}
+	s.chans[w.shard] <- markerShardJob{w: w, record: record}
+	return nil
+}
+
+// markerShard picks one of n shards for marker by hashing its name, so a
+// marker always lands on the same shard.
+func markerShard(marker string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(marker))
+	return int(h.Sum32() % uint32(n))
+}
+
+// wait closes the shards and blocks until every queued record is written.
+// It is safe to call more than once.
+func (s *markerShards) wait() error {
+	s.once.Do(func() {
+		for _, ch := range s.chans {
+			close(ch)
+		}
+		s.wg.Wait()
+	})
+	return s.firstErr()
+}
+
+func (s *markerShards) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.failed.Store(true)
+}
+
+func (s *markerShards) firstErr() error {
+	if !s.failed.Load() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// markerStatsName is the per-marker statistics report written to the marker
+// directory.
+const markerStatsName = "markers_stats.json"
+
+// markerStats is one marker's entry in markers_stats.json.
+type markerStats struct {
+	Records      int     `json:"records"`
+	MinLength    int     `json:"min_length"`
+	MaxLength    int     `json:"max_length"`
+	MeanLength   float64 `json:"mean_length"`
+	EmptyDropped int     `json:"empty_dropped"`
+}
+
+// writeMarkerStats writes markers_stats.json keyed by sanitized marker name.
+// Markers skipped by -resume are left out, like in -marker-summary.
+func writeMarkerStats(path string, writers map[string]*markerWriter, emptyDropped map[string]int, done map[string]struct{}) error {
+	stats := make(map[string]markerStats, len(writers))
+	for marker, w := range writers {
+		s := markerStats{Records: w.records, MinLength: w.minLen, MaxLength: w.maxLen}
+		if w.records > 0 {
+			s.MeanLength = float64(w.bases) / float64(w.records)
+		}
+		stats[marker] = s
+	}
+	for marker, n := range emptyDropped {
+		if _, skip := done[marker]; skip {
+			continue
+		}
+		s := stats[marker]
+		s.EmptyDropped = n
+		stats[marker] = s
+	}
+	return writeJSONReport(path, stats)
+}
+
+// writeMarkerSummary writes the -marker-summary TSV. Markers skipped by
+// -resume are absent: this run did not count them.
+func writeMarkerSummary(path string, writers map[string]*markerWriter, gzipOut bool) error {
+	markers := make([]string, 0, len(writers))
+	for marker := range writers {
+		markers = append(markers, marker)
+	}
+	sort.Strings(markers)
+	gz := "off"
+	if gzipOut {
+		gz = "on"
+	}
+	return writeFileAtomic(path, func(out io.Writer) error {
+		if _, err := io.WriteString(out, "marker\trecords\tbases\tgzip\tpath\n"); err != nil {
+			return err
+		}
+		for _, marker := range markers {
+			w := writers[marker]
+			if _, err := fmt.Fprintf(out, "%s\t%d\t%d\t%s\t%s\n", marker, w.records, w.bases, gz, w.path); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func getMarkerWriter(outDir, marker string, gzipOut bool, gzipLevel, gzipWorkers, shards int, writers map[string]*markerWriter) (*markerWriter, error) {
 	if w, ok := writers[marker]; ok {
 		return w, nil
 	}
-	ext := ".fasta"
-	if gzipOut {
-		ext += ".gz"
+	path := filepath.Join(outDir, marker+markerExt(gzipOut))
+	// A stale sentinel must not vouch for the file we are about to truncate,
+	// even when this run is not -resume: a later -resume would trust it.
+	if err := os.Remove(path + markerDoneSuffix); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale sentinel: %w", err)
 	}
-	path := filepath.Join(outDir, marker+ext)
-	f, err := os.Create(path)
+	f, err := createOutput(path)
 	if err != nil {
 		return nil, fmt.Errorf("create %s: %w", path, err)
 	}
@@ -207,7 +558,10 @@ func getMarkerWriter(outDir, marker string, gzipOut bool, gzipWorkers int, write
 		if gzipWorkers <= 0 {
 			gzipWorkers = runtime.GOMAXPROCS(0)
 		}
-		pw, err := pgzip.NewWriterLevel(f, pgzip.DefaultCompression)
+		if gzipLevel == 0 {
+			gzipLevel = pgzip.DefaultCompression
+		}
+		pw, err := pgzip.NewWriterLevel(f, gzipLevel)
 		if err != nil {
 			_ = f.Close()
 			return nil, fmt.Errorf("create gzip writer: %w", err)
@@ -222,7 +576,106 @@ func getMarkerWriter(outDir, marker string, gzipOut bool, gzipWorkers int, write
 	} else {
 		buf = bufio.NewWriterSize(f, writerBufferSize)
 	}
-	w := &markerWriter{file: f, buf: buf, gz: gz}
+	w := &markerWriter{path: path, file: f, buf: buf, gz: gz, shard: markerShard(marker, shards)}
 	writers[marker] = w
 	return w, nil
 }
+
+// derepRecord records pid in the marker's derep map and reports whether seq
+// duplicates a sequence already written for this marker. The map file is
+// opened on the marker's first record.
+func (w *markerWriter) derepRecord(outDir, marker string, pid, seq []byte) (bool, error) {
+	if w.derepBuf == nil {
+		w.derepPath = filepath.Join(outDir, marker+markerDerepSuffix)
+		f, err := createOutput(w.derepPath)
+		if err != nil {
+			return false, fmt.Errorf("create %s: %w", w.derepPath, err)
+		}
+		w.derepFile = f
+		w.derepBuf = bufio.NewWriterSize(f, writerBufferSize)
+		w.derepSeen = make(map[[16]byte]string)
+		if _, err := w.derepBuf.WriteString("processid\trepresentative\n"); err != nil {
+			return false, fmt.Errorf("write derep map %s: %w", marker, err)
+		}
+	}
+	hash := md5.Sum(seq)
+	rep, dup := w.derepSeen[hash]
+	if !dup {
+		rep = string(pid)
+		w.derepSeen[hash] = rep
+	}
+	if _, err := fmt.Fprintf(w.derepBuf, "%s\t%s\n", pid, rep); err != nil {
+		return false, fmt.Errorf("write derep map %s: %w", marker, err)
+	}
+	if dup {
+		w.collapsed++
+	}
+	return dup, nil
+}
+
+// closeDerep flushes and closes the derep map, if one was opened.
+func (w *markerWriter) closeDerep() error {
+	if w.derepBuf == nil {
+		return nil
+	}
+	err := w.derepBuf.Flush()
+	if cerr := w.derepFile.Close(); err == nil {
+		err = cerr
+	}
+	w.derepBuf = nil
+	return err
+}
+
+func markerExt(gzipOut bool) string {
+	if gzipOut {
+		return ".fasta.gz"
+	}
+	return ".fasta"
+}
+
+// completedMarkers returns the sanitized names of markers in outDir whose
+// output and .done sentinel both exist.
+func completedMarkers(outDir string, gzipOut bool) (map[string]struct{}, error) {
+	ext := markerExt(gzipOut)
+	sentinels, err := filepath.Glob(filepath.Join(outDir, "*"+ext+markerDoneSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("scan sentinels: %w", err)
+	}
+	done := make(map[string]struct{}, len(sentinels))
+	for _, sentinel := range sentinels {
+		path := strings.TrimSuffix(sentinel, markerDoneSuffix)
+		if !fileExists(path) {
+			continue
+		}
+		done[strings.TrimSuffix(filepath.Base(path), ext)] = struct{}{}
+	}
+	return done, nil
+}
+
+// closeMarkerWriters flushes and closes every writer, then drops a .done
+// sentinel next to each finished marker. Writers are removed from the map so
+// the deferred cleanup in buildMarkerFastas does not close them twice.
+func closeMarkerWriters(writers map[string]*markerWriter) error {
+	for marker, w := range writers {
+		err := w.buf.Flush()
+		if w.gz != nil {
+			if cerr := w.gz.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+		if cerr := w.closeDerep(); err == nil {
+			err = cerr
+		}
+		delete(writers, marker)
+		if err != nil {
+			return fmt.Errorf("close marker %s: %w", marker, err)
+		}
+		if err := os.WriteFile(w.path+markerDoneSuffix, nil, 0o644); err != nil {
+			return fmt.Errorf("write sentinel for %s: %w", marker, err)
+		}
+	}
+	return nil
+}
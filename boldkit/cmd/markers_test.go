@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildMarkerFastasDedupeGlobal(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tCOI-5P\tACGTACGT\n" +
+		"P2\tITS\tACGTACGT\n" +
+		"P3\tCOI-5P\tTTGATTGA\n" +
+		"P4\tCOI-5P\tTTGATTGA\n" +
+		"P5\tITS\tGGGGCCCC\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1, DedupeGlobal: true}); err != nil {
+		t.Fatalf("buildMarkerFastas failed: %v", err)
+	}
+	coi, err := os.ReadFile(filepath.Join(outDir, "COI-5P.fasta"))
+	if err != nil {
+		t.Fatalf("read COI-5P: %v", err)
+	}
+	if string(coi) != ">P1\nACGTACGT\n>P3\nTTGATTGA\n" {
+		t.Fatalf("unexpected COI-5P output:\n%s", string(coi))
+	}
+	its, err := os.ReadFile(filepath.Join(outDir, "ITS.fasta"))
+	if err != nil {
+		t.Fatalf("read ITS: %v", err)
+	}
+	if string(its) != ">P5\nGGGGCCCC\n" {
+		t.Fatalf("unexpected ITS output:\n%s", string(its))
+	}
+}
+
+func TestBuildMarkerFastasResumeSkipsCompleted(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tCOI-5P\tACGTACGT\n" +
+		"P2\tITS\tGGGGCCCC\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// COI-5P completed in an earlier run; ITS was left partial without a sentinel.
+	coiPath := filepath.Join(outDir, "COI-5P.fasta")
+	if err := os.WriteFile(coiPath, []byte(">OLD\nAAAA\n"), 0o644); err != nil {
+		t.Fatalf("write COI-5P: %v", err)
+	}
+	if err := os.WriteFile(coiPath+markerDoneSuffix, nil, 0o644); err != nil {
+		t.Fatalf("write sentinel: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "ITS.fasta"), []byte(">PARTIAL\n"), 0o644); err != nil {
+		t.Fatalf("write ITS: %v", err)
+	}
+
+	if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1, Resume: true}); err != nil {
+		t.Fatalf("buildMarkerFastas failed: %v", err)
+	}
+	coi, err := os.ReadFile(coiPath)
+	if err != nil {
+		t.Fatalf("read COI-5P: %v", err)
+	}
+	if string(coi) != ">OLD\nAAAA\n" {
+		t.Fatalf("completed marker was rebuilt:\n%s", string(coi))
+	}
+	its, err := os.ReadFile(filepath.Join(outDir, "ITS.fasta"))
+	if err != nil {
+		t.Fatalf("read ITS: %v", err)
+	}
+	if string(its) != ">P2\nGGGGCCCC\n" {
+		t.Fatalf("unexpected ITS output:\n%s", string(its))
+	}
+	if !fileExists(filepath.Join(outDir, "ITS.fasta"+markerDoneSuffix)) {
+		t.Fatalf("expected ITS sentinel after resume run")
+	}
+}
+
+func TestGetMarkerWriterRemovesStaleSentinel(t *testing.T) {
+	outDir := t.TempDir()
+	path := filepath.Join(outDir, "COI-5P.fasta")
+	if err := os.WriteFile(path, []byte(">OLD\nAAAA\n"), 0o644); err != nil {
+		t.Fatalf("write COI-5P: %v", err)
+	}
+	if err := os.WriteFile(path+markerDoneSuffix, nil, 0o644); err != nil {
+		t.Fatalf("write sentinel: %v", err)
+	}
+	writers := make(map[string]*markerWriter)
+	if _, err := getMarkerWriter(outDir, "COI-5P", false, 0, 1, 1, writers); err != nil {
+		t.Fatalf("getMarkerWriter failed: %v", err)
+	}
+	// A run that dies now must leave nothing for -resume to trust.
+	if fileExists(path + markerDoneSuffix) {
+		t.Fatalf("stale sentinel survived truncating %s", path)
+	}
+	if err := closeMarkerWriters(writers); err != nil {
+		t.Fatalf("closeMarkerWriters failed: %v", err)
+	}
+	if !fileExists(path + markerDoneSuffix) {
+		t.Fatalf("expected sentinel after close")
+	}
+}
+
+func TestBuildMarkerFastasSummary(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tITS\tACGTACGT\n" +
+		"P2\tCOI-5P\tACGTAC\n" +
+		"P3\tCOI-5P\tTTGATTGA\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	want := "marker\trecords\tbases\tgzip\tpath\n" +
+		"COI-5P\t2\t14\toff\t" + filepath.Join(outDir, "COI-5P.fasta") + "\n" +
+		"ITS\t1\t8\toff\t" + filepath.Join(outDir, "ITS.fasta") + "\n"
+	// -resume closes the writers before returning; the summary must still
+	// see what they wrote.
+	for _, resume := range []bool{false, true} {
+		summary := filepath.Join(tmp, fmt.Sprintf("marker_summary_%v.tsv", resume))
+		if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1, SummaryPath: summary, Resume: resume}); err != nil {
+			t.Fatalf("buildMarkerFastas resume=%v failed: %v", resume, err)
+		}
+		data, err := os.ReadFile(summary)
+		if err != nil {
+			t.Fatalf("read summary: %v", err)
+		}
+		if string(data) != want {
+			t.Fatalf("summary mismatch resume=%v:\ngot:\n%s\nwant:\n%s", resume, string(data), want)
+		}
+	}
+}
+
+func TestBuildMarkerFastasAllowlist(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tCOI-5P\tACGTACGT\n" +
+		"P2\tITS\tGGGGCCCC\n" +
+		"P3\t16S\tTTGATTGA\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1, Markers: []string{"coi-5p", "16s"}}); err != nil {
+		t.Fatalf("buildMarkerFastas failed: %v", err)
+	}
+	for _, name := range []string{"COI-5P.fasta", "16S.fasta"} {
+		if !fileExists(filepath.Join(outDir, name)) {
+			t.Fatalf("allowlisted %s not written", name)
+		}
+	}
+	if fileExists(filepath.Join(outDir, "ITS.fasta")) {
+		t.Fatalf("ITS.fasta written despite -markers")
+	}
+}
+
+func TestBuildMarkerFastasDerep(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tCOI-5P\tACGTACGT\n" +
+		"P2\tCOI-5P\tACGTACGT\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1, Derep: true}); err != nil {
+		t.Fatalf("buildMarkerFastas failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "COI-5P.fasta"))
+	if err != nil {
+		t.Fatalf("read fasta: %v", err)
+	}
+	if string(data) != ">P1\nACGTACGT\n" {
+		t.Fatalf("derep fasta=%q want only P1", data)
+	}
+	data, err = os.ReadFile(filepath.Join(outDir, "COI-5P.derep.tsv"))
+	if err != nil {
+		t.Fatalf("read derep map: %v", err)
+	}
+	if want := "processid\trepresentative\nP1\tP1\nP2\tP1\n"; string(data) != want {
+		t.Fatalf("derep map=%q want %q", data, want)
+	}
+}
+
+func TestBuildMarkerFastasStats(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	tsv := "processid\tmarker_code\tnuc\n" +
+		"P1\tCOI-5P\tACGTACGT\n" +
+		"P2\tCOI-5P\tACGTAC\n" +
+		"P3\tCOI-5P\t\n" +
+		"P4\tITS\tGGGGCCCCGG\n"
+	if err := os.WriteFile(input, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := buildMarkerFastas(input, outDir, markerConfig{Workers: 1}); err != nil {
+		t.Fatalf("buildMarkerFastas failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, markerStatsName))
+	if err != nil {
+		t.Fatalf("read stats: %v", err)
+	}
+	var stats map[string]markerStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("parse stats: %v", err)
+	}
+	want := map[string]markerStats{
+		"COI-5P": {Records: 2, MinLength: 6, MaxLength: 8, MeanLength: 7, EmptyDropped: 1},
+		"ITS":    {Records: 1, MinLength: 10, MaxLength: 10, MeanLength: 10},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("stats=%v want %v", stats, want)
+	}
+	for marker, w := range want {
+		if stats[marker] != w {
+			t.Fatalf("stats[%s]=%+v want %+v", marker, stats[marker], w)
+		}
+	}
+}
+
+func TestBuildMarkerFastasWorkersMatch(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "bold.tsv")
+	var tsv strings.Builder
+	tsv.WriteString("processid\tmarker_code\tnuc\n")
+	markers := []string{"COI-5P", "ITS", "16S", "rbcL", "matK", "18S"}
+	bases := "ACGT"
+	for i := 0; i < 6000; i++ {
+		seq := make([]byte, 20+i%17)
+		for j := range seq {
+			seq[j] = bases[(i*7+j*3)%4]
+		}
+		fmt.Fprintf(&tsv, "P%d\t%s\t%s\n", i, markers[(i*5)%len(markers)], seq)
+	}
+	if err := os.WriteFile(input, []byte(tsv.String()), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	outputs := make(map[int]map[string]string)
+	for _, workers := range []int{1, 4} {
+		outDir := filepath.Join(tmp, fmt.Sprintf("w%d", workers))
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := buildMarkerFastas(input, outDir, markerConfig{Workers: workers}); err != nil {
+			t.Fatalf("buildMarkerFastas workers=%d failed: %v", workers, err)
+		}
+		files := make(map[string]string)
+		for _, marker := range markers {
+			data, err := os.ReadFile(filepath.Join(outDir, marker+".fasta"))
+			if err != nil {
+				t.Fatalf("read %s: %v", marker, err)
+			}
+			files[marker] = string(data)
+		}
+		outputs[workers] = files
+	}
+	for _, marker := range markers {
+		if outputs[1][marker] != outputs[4][marker] {
+			t.Fatalf("%s output differs between 1 and 4 workers", marker)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type membersConfig struct {
+	TaxdumpDir string
+	TaxidMap   string
+	TaxID      int
+	Name       string
+	Output     string
+	// Lineage switches the plain id list to a processid/taxid/lineage TSV
+	// over Ranks.
+	Lineage bool
+	Ranks   []string
+}
+
+func runMembers(args []string) {
+	fs := flag.NewFlagSet("members", flag.ExitOnError)
+	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory (nodes.dmp, names.dmp)")
+	taxidMap := fs.String("taxid-map", "", "processid -> taxid map (default: <taxdump-dir>/taxid.map)")
+	taxid := fs.Int("taxid", 0, "Taxid whose subtree to list")
+	name := fs.String("name", "", "Scientific name whose subtree to list (e.g. \"Homo sapiens\"); must match one taxid")
+	output := fs.String("output", "", "Output path (default: stdout)")
+	lineage := fs.Bool("lineage", false, "Write processid, taxid and lineage TSV instead of a plain id list")
+	ranks := fs.String("ranks", strings.Join(canonicalRanks, ","), "Comma-separated ranks in the -lineage column")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	if (*taxid > 0) == (*name != "") {
+		fatalf("exactly one of -taxid or -name is required")
+	}
+	cfg := membersConfig{
+		TaxdumpDir: *taxdumpDir,
+		TaxidMap:   *taxidMap,
+		TaxID:      *taxid,
+		Name:       *name,
+		Output:     *output,
+		Lineage:    *lineage,
+		Ranks:      splitList(*ranks),
+	}
+	if err := writeMembers(cfg); err != nil {
+		fatalf("members failed: %v", err)
+	}
+}
+
+// writeMembers lists the taxid map entries whose taxid lies in the subtree
+// under cfg.TaxID (or the taxon named cfg.Name), sorted by processid.
+// Merged taxids count under their replacement; deleted ones never match.
+func writeMembers(cfg membersConfig) error {
+	dump, err := loadTaxDump(filepath.Join(cfg.TaxdumpDir, "nodes.dmp"), filepath.Join(cfg.TaxdumpDir, "names.dmp"))
+	if err != nil {
+		return err
+	}
+	root := cfg.TaxID
+	if cfg.Name != "" {
+		ids := dump.taxidsByName(cfg.Name)
+		switch len(ids) {
+		case 0:
+			return fmt.Errorf("no taxon named %q", cfg.Name)
+		case 1:
+			root = ids[0]
+		default:
+			return fmt.Errorf("name %q matches taxids %s; use -taxid", cfg.Name, formatTaxidList(ids))
+		}
+	}
+	root = dump.resolve(root)
+	if _, ok := dump.nodes[root]; !ok {
+		return fmt.Errorf("taxid %d not in %s", root, cfg.TaxdumpDir)
+	}
+
+	mapPath := cfg.TaxidMap
+	if mapPath == "" {
+		mapPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+	}
+	pidToTaxid, err := loadTaxidMap(mapPath)
+	if err != nil {
+		return err
+	}
+	subtree := dump.subtree(root)
+	var members []string
+	for pid, taxid := range pidToTaxid {
+		resolved := dump.resolve(taxid)
+		if dump.isDeleted(resolved) {
+			continue
+		}
+		if _, ok := subtree[resolved]; ok {
+			members = append(members, pid)
+		}
+	}
+	sort.Strings(members)
+
+	var out io.Writer = os.Stdout
+	if cfg.Output != "" {
+		if dir := filepath.Dir(cfg.Output); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("create output dir: %w", err)
+			}
+		}
+		f, err := createOutput(cfg.Output)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		out = f
+	}
+	w := bufio.NewWriterSize(out, writerBufferSize)
+	if cfg.Lineage {
+		if _, err := w.WriteString("processid\ttaxid\tlineage\n"); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	for _, pid := range members {
+		line := pid
+		if cfg.Lineage {
+			taxid := pidToTaxid[pid]
+			lineage := dump.lineage(taxid)
+			names := make([]string, 0, len(cfg.Ranks))
+			for _, rank := range cfg.Ranks {
+				names = append(names, lineage[rank])
+			}
+			line += "\t" + strconv.Itoa(taxid) + "\t" + strings.Join(names, ";")
+		}
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush output: %w", err)
+	}
+	if cfg.Output != "" {
+		keepOutputs(cfg.Output)
+	}
+	logf("members: %d processids under %s (%d)", len(members), dump.nodes[root].name, root)
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMembers(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+
+	output := filepath.Join(tmp, "mammalia.txt")
+	if err := writeMembers(membersConfig{TaxdumpDir: tmp, TaxID: 4, Output: output}); err != nil {
+		t.Fatalf("writeMembers failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(data) != "P1\nP2\n" {
+		t.Fatalf("members of Mammalia=%q want P1,P2", data)
+	}
+
+	output = filepath.Join(tmp, "primates.tsv")
+	cfg := membersConfig{TaxdumpDir: tmp, Name: "Primates", Output: output, Lineage: true, Ranks: []string{"order", "genus", "species"}}
+	if err := writeMembers(cfg); err != nil {
+		t.Fatalf("writeMembers failed: %v", err)
+	}
+	data, err = os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if want := "processid\ttaxid\tlineage\nP1\t9606\tPrimates;Homo;Homo sapiens\n"; string(data) != want {
+		t.Fatalf("members lineage=%q want %q", data, want)
+	}
+
+	if err := writeMembers(membersConfig{TaxdumpDir: tmp, Name: "Felidae", Output: filepath.Join(tmp, "none.txt")}); err == nil || !strings.Contains(err.Error(), "no taxon named") {
+		t.Fatalf("expected unknown-name error, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -20,6 +21,36 @@ type packageConfig struct {
 	SkipManifest  bool
 	SkipChecksums bool
 	MoveInputs    bool
+	// ArchiveFormat selects the directory archives: tar.gz, zip, or both.
+	ArchiveFormat string
+	// ChecksumAlgo is the release digest (sha256, sha512, blake2b).
+	ChecksumAlgo string
+	// Reproducible normalizes archive metadata (timestamps, owners, modes)
+	// so identical inputs give byte-identical archives.
+	Reproducible bool
+	// SkipReleaseInfo omits RELEASE_INFO.txt from the archives and release dir.
+	SkipReleaseInfo bool
+	// GzipLevel is the gzip level of the tar.gz archives and taxonkit gzip;
+	// 0 means gzip.BestSpeed.
+	GzipLevel int
+}
+
+const releaseInfoName = "RELEASE_INFO.txt"
+
+const (
+	archiveFormatTarGz = "tar.gz"
+	archiveFormatZip   = "zip"
+	archiveFormatBoth  = "both"
+)
+
+func normalizeArchiveFormat(format string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case "", archiveFormatTarGz, "tgz":
+		return archiveFormatTarGz, nil
+	case archiveFormatZip, archiveFormatBoth:
+		return f, nil
+	}
+	return "", fmt.Errorf("archive-format must be one of tar.gz, zip, both (got %q)", format)
 }
 
 func runPackage(args []string) {
@@ -31,11 +62,27 @@ func runPackage(args []string) {
 	snapshot := fs.String("snapshot-id", "", "Snapshot ID suffix for releases")
 	force := fs.Bool("force", false, "Overwrite existing outputs")
 	skipManifest := fs.Bool("skip-manifest", false, "Skip manifest.json")
-	skipChecksums := fs.Bool("skip-checksums", false, "Skip SHA256SUMS.txt")
+	skipChecksums := fs.Bool("skip-checksums", false, "Skip the checksum file (SHA256SUMS.txt by default)")
+	checksumAlgo := fs.String("checksum-algo", checksumAlgoSHA256, "Checksum digest: sha256, sha512, or blake2b (names <ALGO>SUMS.txt)")
 	moveInputs := fs.Bool("move", true, "Move inputs into releases dir before packaging")
+	archiveFormat := fs.String("archive-format", archiveFormatTarGz, "Directory archive format: tar.gz, zip, or both")
+	reproducible := fs.Bool("reproducible", false, "Zero timestamps/owners and fix modes in archives so identical inputs are byte-identical")
+	skipReleaseInfo := fs.Bool("skip-release-info", false, "Skip RELEASE_INFO.txt (provenance text) in archives and the releases dir")
+	gzipLevel := fs.Int("gzip-level", 0, "Gzip level 1-9 for tar.gz archives and the taxonkit gzip (0 keeps the default of 1; 9 compresses best)")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
+	if err := validateGzipLevel(*gzipLevel); err != nil {
+		fatalf("%v", err)
+	}
+	format, err := normalizeArchiveFormat(*archiveFormat)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	algo, err := normalizeChecksumAlgo(*checksumAlgo)
+	if err != nil {
+		fatalf("%v", err)
+	}
 
 	snap := *snapshot
 	if snap == "" {
@@ -43,15 +90,20 @@ func runPackage(args []string) {
 	}
 
 	cfg := packageConfig{
-		TaxdumpDir:    *taxdumpDir,
-		MarkerDir:     *markerDir,
-		TaxonkitOut:   *taxonkitOut,
-		ReleaseDir:    *releaseDir,
-		Snapshot:      snap,
-		Force:         *force,
-		SkipManifest:  *skipManifest,
-		SkipChecksums: *skipChecksums,
-		MoveInputs:    *moveInputs,
+		TaxdumpDir:      *taxdumpDir,
+		MarkerDir:       *markerDir,
+		TaxonkitOut:     *taxonkitOut,
+		ReleaseDir:      *releaseDir,
+		Snapshot:        snap,
+		Force:           *force,
+		SkipManifest:    *skipManifest,
+		SkipChecksums:   *skipChecksums,
+		MoveInputs:      *moveInputs,
+		ArchiveFormat:   format,
+		Reproducible:    *reproducible,
+		ChecksumAlgo:    algo,
+		SkipReleaseInfo: *skipReleaseInfo,
+		GzipLevel:       *gzipLevel,
 	}
 
 	if err := packageRelease(cfg); err != nil {
@@ -94,19 +146,23 @@ func packageRelease(cfg packageConfig) error {
 	markerZip := packageMarkerPath(markerDir, cfg.ReleaseDir, cfg.Snapshot)
 	taxdumpArchive := packageTaxdumpArchivePath(taxdumpDir, cfg.ReleaseDir, cfg.Snapshot)
 
-	logf("Package taxdump archive -> %s", taxdumpArchive)
-	if err := packageDirGzip(taxdumpDir, taxdumpArchive, cfg.Force); err != nil {
-		return err
+	if !cfg.SkipReleaseInfo {
+		logf("Write release info -> %s", filepath.Join(cfg.ReleaseDir, releaseInfoName))
+		if err := writeReleaseInfos(cfg, taxdumpDir, markerDir); err != nil {
+			return fmt.Errorf("release info: %w", err)
+		}
 	}
 
-	logf("Package marker archive -> %s", markerZip)
-	if err := packageDirGzip(markerDir, markerZip, cfg.Force); err != nil {
+	if err := packageDirArchives("taxdump", taxdumpDir, taxdumpArchive, cfg); err != nil {
+		return err
+	}
+	if err := packageDirArchives("marker", markerDir, markerZip, cfg); err != nil {
 		return err
 	}
 
 	if !taxonkitIsGz {
 		logf("Package taxonkit input gzip -> %s", taxonkitGz)
-		if err := packageTaxonkitGzip(taxonkitSource, taxonkitGz, cfg.Force); err != nil {
+		if err := packageTaxonkitGzip(taxonkitSource, taxonkitGz, cfg.Force, cfg.GzipLevel); err != nil {
 			return err
 		}
 	} else if taxonkitSource != taxonkitGz {
@@ -125,13 +181,26 @@ func packageRelease(cfg packageConfig) error {
 	}
 
 	if !cfg.SkipChecksums {
-		sumPath := filepath.Join(cfg.ReleaseDir, "SHA256SUMS.txt")
+		algo := cfg.ChecksumAlgo
+		if algo == "" {
+			algo = checksumAlgoSHA256
+		}
+		sumPath := filepath.Join(cfg.ReleaseDir, checksumFileName(algo))
 		logf("Write checksums -> %s", sumPath)
-		if err := writeChecksums(cfg.ReleaseDir, sumPath, cfg.Force); err != nil {
+		if err := writeChecksums(cfg.ReleaseDir, sumPath, algo, cfg.Force); err != nil {
 			return fmt.Errorf("checksums: %w", err)
 		}
 	}
 
+	if !cfg.SkipReleaseInfo && !cfg.MoveInputs {
+		// Inputs stay where they were; take back the copies added for the archives.
+		for _, dir := range []string{taxdumpDir, markerDir} {
+			if err := os.Remove(filepath.Join(dir, releaseInfoName)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove release info: %w", err)
+			}
+		}
+	}
+
 	if cfg.MoveInputs {
 		if removeTaxonkitPlain && taxonkitRelease != "" {
 			if err := os.Remove(taxonkitRelease); err != nil && !os.IsNotExist(err) {
@@ -149,6 +218,64 @@ func packageRelease(cfg packageConfig) error {
 	return nil
 }
 
+// writeReleaseInfos writes RELEASE_INFO.txt into the taxdump and marker
+// directories, so each archive carries it, and into the releases dir.
+func writeReleaseInfos(cfg packageConfig, taxdumpDir, markerDir string) error {
+	counts, _, err := countRelease(taxdumpDir, markerDir)
+	if err != nil {
+		return err
+	}
+	text := releaseInfoText(cfg.Snapshot, releaseCommit(), "boldkit "+strings.Join(os.Args[1:], " "), counts)
+	for _, dir := range []string{taxdumpDir, markerDir, cfg.ReleaseDir} {
+		err := writeFileAtomic(filepath.Join(dir, releaseInfoName), func(w io.Writer) error {
+			_, err := io.WriteString(w, text)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseInfoText renders the plain-text provenance block. It has no
+// timestamp so -reproducible archives stay byte-identical.
+func releaseInfoText(snapshot, commit, command string, c releaseCounts) string {
+	var b strings.Builder
+	b.WriteString("BoldKit release\n\n")
+	fmt.Fprintf(&b, "snapshot_id: %s\n", snapshot)
+	fmt.Fprintf(&b, "boldkit_version: %s\n", appVersion)
+	fmt.Fprintf(&b, "commit_hash: %s\n", commit)
+	fmt.Fprintf(&b, "command: %s\n\n", command)
+	b.WriteString("counts:\n")
+	fmt.Fprintf(&b, "  nodes: %d\n", c.Nodes)
+	fmt.Fprintf(&b, "  names: %d\n", c.Names)
+	fmt.Fprintf(&b, "  taxid_map: %d\n", c.TaxidMap)
+	fmt.Fprintf(&b, "  marker_fasta_files: %d\n", c.MarkerFastaFiles)
+	fmt.Fprintf(&b, "  marker_fasta_sequences: %d\n", c.MarkerFastaSequences)
+	return b.String()
+}
+
+// packageDirArchives writes srcDir as tarGzPath, as the matching .zip, or
+// both, depending on cfg.ArchiveFormat.
+func packageDirArchives(label, srcDir, tarGzPath string, cfg packageConfig) error {
+	format := cfg.ArchiveFormat
+	if format != archiveFormatZip {
+		logf("Package %s archive -> %s", label, tarGzPath)
+		if err := packageDirGzip(srcDir, tarGzPath, cfg.Force, cfg.Reproducible, cfg.GzipLevel); err != nil {
+			return err
+		}
+	}
+	if format == archiveFormatZip || format == archiveFormatBoth {
+		zipPath := strings.TrimSuffix(tarGzPath, ".tar.gz") + ".zip"
+		logf("Package %s archive -> %s", label, zipPath)
+		if err := packageDirZip(srcDir, zipPath, cfg.Force, cfg.Reproducible); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func moveDirInto(srcDir, releaseDir string, force bool) (string, error) {
 	dest := filepath.Join(releaseDir, filepath.Base(srcDir))
 	if err := movePath(srcDir, dest, force); err != nil {
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackageDirArchivesZip(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "marker_fastas")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"COI-5P.fasta":                    ">P1\nACGT\n",
+		"sub/ITS.fasta":                   ">P2\nGGCC\n",
+		"COI-5P.fasta" + markerDoneSuffix: "",
+		"COI-5P" + markerDerepSuffix:      "processid\trepresentative\nP1\tP1\n",
+		markerStatsName:                   "{}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	tarGz := filepath.Join(tmp, "releases", "marker_fastas.snap.tar.gz")
+	if err := packageDirArchives("marker", src, tarGz, packageConfig{ArchiveFormat: archiveFormatZip}); err != nil {
+		t.Fatalf("packageDirArchives failed: %v", err)
+	}
+	if fileExists(tarGz) {
+		t.Fatalf("format=zip should not write %s", tarGz)
+	}
+	zr, err := zip.OpenReader(filepath.Join(tmp, "releases", "marker_fastas.snap.zip"))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+
+	var names []string
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open entry %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("read entry %s: %v", f.Name, err)
+		}
+		contents[f.Name] = string(data)
+	}
+	sort.Strings(names)
+	want := []string{"marker_fastas/COI-5P.fasta", "marker_fastas/sub/", "marker_fastas/sub/ITS.fasta"}
+	if len(names) != len(want) {
+		t.Fatalf("zip entries=%v want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("zip entries=%v want %v", names, want)
+		}
+	}
+	if contents["marker_fastas/sub/ITS.fasta"] != files["sub/ITS.fasta"] {
+		t.Fatalf("unexpected ITS content: %q", contents["marker_fastas/sub/ITS.fasta"])
+	}
+}
+
+func TestPackageDirGzipSkipsMarkerArtifacts(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "marker_fastas")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for _, name := range []string{"COI-5P.fasta", "COI-5P.fasta" + markerDoneSuffix, "COI-5P" + markerDerepSuffix, markerStatsName} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(">P1\nACGT\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	dest := filepath.Join(tmp, "marker_fastas.tar.gz")
+	if err := packageDirGzip(src, dest, false, false, 0); err != nil {
+		t.Fatalf("packageDirGzip failed: %v", err)
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 1 || names[0] != "marker_fastas/COI-5P.fasta" {
+		t.Fatalf("tar entries=%v want only marker_fastas/COI-5P.fasta", names)
+	}
+}
+
+func TestPackageDirGzipReproducible(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "bold-taxdump")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, content := range map[string]string{"nodes.dmp": "1\t|\t1\t|\tno rank\t|\n", "names.dmp": "1\t|\troot\t|\n"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	first := filepath.Join(tmp, "first.tar.gz")
+	if err := packageDirGzip(src, first, false, true, 0); err != nil {
+		t.Fatalf("packageDirGzip failed: %v", err)
+	}
+	// Change metadata that a plain archive would record.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(src, "nodes.dmp"), later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(src, "names.dmp"), 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	second := filepath.Join(tmp, "second.tar.gz")
+	if err := packageDirGzip(src, second, false, true, 0); err != nil {
+		t.Fatalf("packageDirGzip failed: %v", err)
+	}
+
+	sum1, err := sha256File(first)
+	if err != nil {
+		t.Fatalf("sha256 first: %v", err)
+	}
+	sum2, err := sha256File(second)
+	if err != nil {
+		t.Fatalf("sha256 second: %v", err)
+	}
+	if sum1 != sum2 {
+		t.Fatalf("reproducible archives differ: %s vs %s", sum1, sum2)
+	}
+}
+
+func TestPackageDirGzipLevel(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "marker_fastas")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	var b strings.Builder
+	seed := uint32(1)
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, ">P%d\n", i)
+		for j := 0; j < 120; j++ {
+			seed = seed*1664525 + 1013904223
+			b.WriteByte("ACGT"[seed>>30])
+		}
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(filepath.Join(src, "COI-5P.fasta"), []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("write fasta: %v", err)
+	}
+
+	sizes := make(map[int]int64)
+	for _, level := range []int{1, 9} {
+		dest := filepath.Join(tmp, fmt.Sprintf("level%d.tar.gz", level))
+		if err := packageDirGzip(src, dest, false, true, level); err != nil {
+			t.Fatalf("packageDirGzip level %d failed: %v", level, err)
+		}
+		info, err := os.Stat(dest)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		sizes[level] = info.Size()
+	}
+	if sizes[9] >= sizes[1] {
+		t.Fatalf("level 9 archive %d bytes not smaller than level 1 %d bytes", sizes[9], sizes[1])
+	}
+	if err := validateGzipLevel(10); err == nil {
+		t.Fatalf("expected gzip-level 10 to be rejected")
+	}
+	// 0 means "keep the default" for markers, pipeline and package alike.
+	if err := validateGzipLevel(0); err != nil {
+		t.Fatalf("gzip-level 0 rejected: %v", err)
+	}
+}
+
+func TestWriteChecksumsAlgorithms(t *testing.T) {
+	// Digests of "hello\n" as printed by sha256sum, sha512sum and b2sum.
+	want := map[string]string{
+		checksumAlgoSHA256:  "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		checksumAlgoSHA512:  "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629",
+		checksumAlgoBLAKE2b: "f60ce482e5cc1229f39d71313171a8d9f4ca3a87d066bf4b205effb528192a75f14f3271e2c1a90e1de53f275b4d4793eef2f5e31ea90d2ce29d2e481c36435f",
+	}
+	for algo, sum := range want {
+		releaseDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(releaseDir, "marker_fastas.tar.gz"), []byte("hello\n"), 0o644); err != nil {
+			t.Fatalf("write archive: %v", err)
+		}
+		sumPath := filepath.Join(releaseDir, checksumFileName(algo))
+		if err := writeChecksums(releaseDir, sumPath, algo, false); err != nil {
+			t.Fatalf("writeChecksums(%s) failed: %v", algo, err)
+		}
+		data, err := os.ReadFile(sumPath)
+		if err != nil {
+			t.Fatalf("read %s: %v", sumPath, err)
+		}
+		if string(data) != sum+"  marker_fastas.tar.gz\n" {
+			t.Fatalf("%s checksum line=%q", algo, string(data))
+		}
+	}
+	if checksumFileName(checksumAlgoSHA512) != "SHA512SUMS.txt" {
+		t.Fatalf("checksumFileName(sha512)=%q", checksumFileName(checksumAlgoSHA512))
+	}
+}
+
+func TestPackageReleaseWritesReleaseInfo(t *testing.T) {
+	tmp := t.TempDir()
+	taxdump := filepath.Join(tmp, "bold-taxdump")
+	markers := filepath.Join(tmp, "marker_fastas")
+	for _, dir := range []string{taxdump, markers} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(taxdump, "nodes.dmp"):      "1\t|\t1\t|\tno rank\t|\n",
+		filepath.Join(taxdump, "names.dmp"):      "1\t|\troot\t|\n",
+		filepath.Join(taxdump, "taxid.map"):      "P1\t1\n",
+		filepath.Join(markers, "COI-5P.fasta"):   ">P1\nACGT\n",
+		filepath.Join(tmp, "taxonkit_input.tsv"): "processid\tkingdom\nP1\tAnimalia\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	releases := filepath.Join(tmp, "releases")
+	cfg := packageConfig{
+		TaxdumpDir:    taxdump,
+		MarkerDir:     markers,
+		TaxonkitOut:   filepath.Join(tmp, "taxonkit_input.tsv"),
+		ReleaseDir:    releases,
+		Snapshot:      "20260101",
+		SkipManifest:  true,
+		SkipChecksums: true,
+	}
+	if err := packageRelease(cfg); err != nil {
+		t.Fatalf("packageRelease failed: %v", err)
+	}
+
+	f, err := os.Open(packageTaxdumpArchivePath(taxdump, releases, cfg.Snapshot))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var info string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		if hdr.Name == "bold-taxdump/"+releaseInfoName {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("read entry: %v", err)
+			}
+			info = string(data)
+		}
+	}
+	if !strings.Contains(info, "snapshot_id: 20260101\n") {
+		t.Fatalf("archive RELEASE_INFO.txt missing snapshot line: %q", info)
+	}
+	if !strings.Contains(info, "marker_fasta_sequences: 1\n") {
+		t.Fatalf("RELEASE_INFO.txt missing marker count: %q", info)
+	}
+	if !fileExists(filepath.Join(releases, releaseInfoName)) {
+		t.Fatalf("expected %s alongside the archives", releaseInfoName)
+	}
+	if fileExists(filepath.Join(taxdump, releaseInfoName)) {
+		t.Fatalf("RELEASE_INFO.txt should not be left in the input dir")
+	}
+}
+
+func TestWriteManifestPerMarkerCounts(t *testing.T) {
+	tmp := t.TempDir()
+	taxdump := filepath.Join(tmp, "bold-taxdump")
+	markers := filepath.Join(tmp, "marker_fastas")
+	for _, dir := range []string{taxdump, markers} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for path, content := range map[string]string{
+		filepath.Join(taxdump, "nodes.dmp"):      "1\t|\t1\t|\tno rank\t|\n",
+		filepath.Join(taxdump, "names.dmp"):      "1\t|\troot\t|\n",
+		filepath.Join(taxdump, "taxid.map"):      "P1\t1\nP2\t1\nP3\t1\n",
+		filepath.Join(markers, "COI-5P.fasta"):   ">P1\nACGT\n>P2\nACGT\n",
+		filepath.Join(markers, "ITS.fasta"):      ">P3\nGGCC\n",
+		filepath.Join(markers, "ITS.fasta.done"): "",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	path := filepath.Join(tmp, "manifest.json")
+	if err := writeManifest(path, taxdump, markers, "snap", false); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	var got releaseManifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal manifest: %v", err)
+	}
+	if got.SnapshotID != "snap" || got.Counts.MarkerFastaFiles != 2 || got.Counts.MarkerFastaSequences != 3 {
+		t.Fatalf("unexpected manifest totals: %+v", got)
+	}
+	if len(got.Markers) != 2 {
+		t.Fatalf("markers=%v want COI-5P and ITS", got.Markers)
+	}
+	coi := got.Markers["COI-5P"]
+	if coi.Sequences != 2 || coi.Files["COI-5P.fasta"] != 2 {
+		t.Fatalf("COI-5P=%+v want 2 sequences in COI-5P.fasta", coi)
+	}
+	its := got.Markers["ITS"]
+	if its.Sequences != 1 || its.Files["ITS.fasta"] != 1 {
+		t.Fatalf("ITS=%+v want 1 sequence in ITS.fasta", its)
+	}
+}
@@ -2,14 +2,16 @@ package cmd
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bufio"
 	"compress/gzip"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"os/exec"
@@ -17,11 +19,14 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 func runPipeline(args []string) {
 	fs := flag.NewFlagSet("pipeline", flag.ExitOnError)
-	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV or Parquet)")
+	input := fs.String("input", "BOLD_Public.*/BOLD_Public.*.tsv", "BOLD input file (TSV, CSV or Parquet)")
 	taxonkitOut := fs.String("taxonkit-output", "taxonkit_input.tsv", "Output taxonkit input TSV")
 	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Output taxdump directory")
 	markerDir := fs.String("marker-dir", "marker_fastas", "Output marker FASTA directory")
@@ -33,35 +38,96 @@ func runPipeline(args []string) {
 	force := fs.Bool("force", false, "Overwrite existing outputs")
 	packageFlag := fs.Bool("package", false, "Create release zips, manifest, and checksums")
 	skipManifest := fs.Bool("skip-manifest", false, "Skip manifest.json (only when --package)")
-	skipChecksums := fs.Bool("skip-checksums", false, "Skip SHA256SUMS.txt (only when --package)")
+	skipChecksums := fs.Bool("skip-checksums", false, "Skip the checksum file (only when --package)")
+	skipReleaseInfo := fs.Bool("skip-release-info", false, "Skip RELEASE_INFO.txt in release archives (only when --package)")
+	checksumAlgo := fs.String("checksum-algo", checksumAlgoSHA256, "Checksum digest when --package: sha256, sha512, or blake2b")
+	archiveFormat := fs.String("archive-format", archiveFormatTarGz, "Directory archive format when --package: tar.gz, zip, or both")
+	reproducible := fs.Bool("reproducible", false, "Byte-identical archives for identical inputs (only when --package)")
 	snapshot := fs.String("snapshot-id", "", "Snapshot ID suffix for releases (default: derive from input filename)")
-	extractCurateProtocol := fs.String("extract-curate-protocol", extractCurationProtocolNone, "Extraction curation profile (none,bioscan-5m)")
+	extractCurateProtocol := fs.String("extract-curate-protocol", extractCurationProtocolNone, "Extraction curation profile (none,bioscan-5m,gbif-backbone)")
 	extractCurateReport := fs.String("extract-curate-report", "", "Optional extraction curation JSON report path")
 	extractCurateAudit := fs.String("extract-curate-audit", "", "Optional extraction curation audit TSV path")
+	extractCurateMaxBins := fs.Int("extract-curate-max-bins", 0, "Cap bioscan-5m priming at this many BINs with resolved species; rare BINs are pruned mid-pass with -extract-curate-min-bin-records, otherwise priming aborts (0 disables)")
+	extractCurateMinBinRecords := fs.Int("extract-curate-min-bin-records", 0, "Forget BINs with fewer resolved records than this during bioscan-5m priming (<=1 keeps all)")
+	extractCurateProvisionalMinSupport := fs.Int("extract-curate-provisional-min-support", 0, "Only form \"Genus sp. BIN\" labels for BINs seen in at least this many rows (<=1 disables)")
+	extractBinMajority := fs.Float64("extract-bioscan-bin-majority", 0.5, "Share of a BIN's resolved records its top species must exceed to become the BIN canonical species (bioscan-5m; 0 accepts any unique top species)")
+	extractBinMinCount := fs.Int("extract-bioscan-bin-min-count", 0, "Observations of the top species required before a BIN adopts it (bioscan-5m; 0 or 1 disables)")
+	extractCurateReportOnly := fs.Bool("extract-curate-report-only", false, "Run bioscan-5m curation for its report and audit only; the TSV keeps the none-protocol labels")
+	extractCurateSubfamilyTemplate := fs.String("extract-curate-subfamily-template", defaultSubfamilyTemplate, "bioscan-5m label for a subfamily missing between family and tribe ({family} is replaced; empty disables the fill)")
+	extractCurateProvisionalTemplate := fs.String("extract-curate-provisional-template", defaultProvisionalTemplate, "bioscan-5m provisional species label; needs {genus} and {bin} or {processid}")
+	extractGBIFBackbone := fs.String("extract-gbif-backbone", "", "GBIF backbone Taxon.tsv for -extract-curate-protocol gbif-backbone")
+	nullTokens := fs.String("null-tokens", defaultNullTokens, "Comma-separated labels treated as null by extract and taxonkit create-taxdump --null (case-sensitive)")
+	extractPlaceholderTokens := fs.String("extract-placeholder-tokens", "", "Comma-separated extra labels treated as empty during extract (case-insensitive)")
+	timingReport := fs.String("timing-report", "", "Optional JSON report of per-stage and total wall time in seconds")
+	gzipLevel := fs.Int("gzip-level", 0, "Gzip level 1-9 for marker FASTAs and release archives (0 keeps the defaults: pgzip's for markers, 1 for archives)")
+	expectSHA256 := fs.String("expect-sha256", "", "Abort before any stage runs unless the input file's SHA-256 hex digest matches this value")
+	dryRun := fs.Bool("dry-run", false, "Log each planned stage with its paths and whether it would run or be skipped, then exit without writing anything")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
+	if err := validateGzipLevel(*gzipLevel); err != nil {
+		fatalf("%v", err)
+	}
 	extractCfg := extractCurationConfig{
-		Protocol:   *extractCurateProtocol,
-		ReportPath: *extractCurateReport,
-		AuditPath:  *extractCurateAudit,
+		Protocol:              *extractCurateProtocol,
+		ReportPath:            *extractCurateReport,
+		AuditPath:             *extractCurateAudit,
+		GBIFBackbonePath:      *extractGBIFBackbone,
+		MaxBins:               *extractCurateMaxBins,
+		MinBinRecords:         *extractCurateMinBinRecords,
+		ProvisionalMinSupport: *extractCurateProvisionalMinSupport,
+		BinMajority:           *extractBinMajority,
+		BinMinCount:           *extractBinMinCount,
+		ReportOnly:            *extractCurateReportOnly,
+		SubfamilyTemplate:     *extractCurateSubfamilyTemplate,
+		NoSubfamilyFill:       *extractCurateSubfamilyTemplate == "",
+		ProvisionalTemplate:   *extractCurateProvisionalTemplate,
+		Tokens:                newLabelTokens(*nullTokens, *extractPlaceholderTokens),
 	}.normalized()
 	if err := extractCfg.validate(); err != nil {
 		fatalf("invalid extraction curation config: %v", err)
 	}
+	format, err := normalizeArchiveFormat(*archiveFormat)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	algo, err := normalizeChecksumAlgo(*checksumAlgo)
+	if err != nil {
+		fatalf("%v", err)
+	}
 
 	snap := *snapshot
 	if snap == "" {
 		snap = snapshotID(*input)
 	}
 
+	if *dryRun {
+		var pkg *packageConfig
+		if *packageFlag {
+			pkg = &packageConfig{
+				ReleaseDir:      *releaseDir,
+				Snapshot:        snap,
+				SkipManifest:    *skipManifest,
+				SkipChecksums:   *skipChecksums,
+				SkipReleaseInfo: *skipReleaseInfo,
+				ArchiveFormat:   format,
+				ChecksumAlgo:    algo,
+				GzipLevel:       *gzipLevel,
+			}
+		}
+		for _, line := range pipelinePlan(*input, *taxonkitOut, *taxdumpDir, *markerDir, *taxonkitBin, *force, pkg) {
+			logf("dry-run: %s", line)
+		}
+		return
+	}
+
+	rows, err := checkInput(*input, "", *expectSHA256)
+	if err != nil {
+		fatalf("input check failed: %v", err)
+	}
 	totalRows := -1
 	if *progressOn {
-		count, err := RowCount(*input)
-		if err != nil {
-			fatalf("count rows failed: %v", err)
-		}
-		totalRows = int(count)
+		totalRows = inputProgressTotal(rows)
 	}
 
 	reportEvery := 0
@@ -69,67 +135,136 @@ func runPipeline(args []string) {
 		reportEvery = 1
 	}
 
-	if err := pipeline(*input, *taxonkitOut, *taxdumpDir, *markerDir, *releaseDir, *taxonkitBin, reportEvery, totalRows, *workers, !*noGzip, *force, *packageFlag, *skipManifest, *skipChecksums, snap, extractCfg); err != nil {
+	if err := pipeline(*input, *taxonkitOut, *taxdumpDir, *markerDir, *releaseDir, *taxonkitBin, reportEvery, totalRows, *workers, !*noGzip, *force, *packageFlag, *skipManifest, *skipChecksums, *skipReleaseInfo, *reproducible, snap, format, algo, *gzipLevel, parseNullTokens(*nullTokens), extractCfg, *timingReport); err != nil {
 		fatalf("pipeline failed: %v", err)
 	}
 }
 
-func pipeline(input, taxonkitOut, taxdumpDir, markerDir, releaseDir, taxonkitBin string, reportEvery, totalRows, workers int, gzipOut, force, doPackage, skipManifest, skipChecksums bool, snapshot string, extractCfg extractCurationConfig) error {
+func pipeline(input, taxonkitOut, taxdumpDir, markerDir, releaseDir, taxonkitBin string, reportEvery, totalRows, workers int, gzipOut, force, doPackage, skipManifest, skipChecksums, skipReleaseInfo, reproducible bool, snapshot, archiveFormat, checksumAlgo string, gzipLevel int, nullTokens []string, extractCfg extractCurationConfig, timingReport string) error {
+	timings := newStageTimings()
 	logf("Input format: %s", InputFormat(input))
 	logf("Extract taxonomy -> %s", taxonkitOut)
-	if fileExists(taxonkitOut) && !force {
-		logf("taxonkit TSV exists, skipping (use --force to overwrite): %s", taxonkitOut)
-	} else {
-		if _, err := buildTaxonkit(input, taxonkitOut, reportEvery, totalRows, extractCfg); err != nil {
+	err := timings.track("extract", func() error {
+		if fileExists(taxonkitOut) && !force {
+			logf("taxonkit TSV exists, skipping (use --force to overwrite): %s", taxonkitOut)
+			return nil
+		}
+		if _, err := buildTaxonkit(input, taxonkitOut, reportEvery, totalRows, extractCfg, extractOptions{}); err != nil {
 			return fmt.Errorf("build taxonkit TSV: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	logf("Build taxdump -> %s", taxdumpDir)
-	if err := runTaxonkitCreate(taxonkitBin, taxonkitOut, taxdumpDir, force); err != nil {
-		return fmt.Errorf("taxonkit create-taxdump: %w", err)
+	err = timings.track("taxdump", func() error {
+		if err := runTaxonkitCreate(taxonkitBin, taxonkitOut, taxdumpDir, nullTokens, force); err != nil {
+			return fmt.Errorf("taxonkit create-taxdump: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	logf("Build marker FASTAs -> %s", markerDir)
-	if outputsExist(markerDir) && !force {
-		logf("marker FASTAs exist, skipping (use --force to overwrite): %s", markerDir)
-	} else {
+	err = timings.track("markers", func() error {
+		if outputsExist(markerDir) && !force {
+			logf("marker FASTAs exist, skipping (use --force to overwrite): %s", markerDir)
+			return nil
+		}
 		if err := os.MkdirAll(markerDir, 0o755); err != nil {
 			return fmt.Errorf("create marker output dir: %w", err)
 		}
-		if err := buildMarkerFastas(input, markerDir, gzipOut, reportEvery, totalRows, workers); err != nil {
+		markerCfg := markerConfig{
+			GzipOut:     gzipOut,
+			ReportEvery: reportEvery,
+			TotalRows:   totalRows,
+			Workers:     workers,
+			GzipLevel:   gzipLevel,
+		}
+		if err := buildMarkerFastas(input, markerDir, markerCfg); err != nil {
 			return fmt.Errorf("build markers: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if !doPackage {
-		return nil
+		return timings.finish(timingReport)
 	}
 
 	cfg := packageConfig{
-		TaxdumpDir:    taxdumpDir,
-		MarkerDir:     markerDir,
-		TaxonkitOut:   taxonkitOut,
-		ReleaseDir:    releaseDir,
-		Snapshot:      snapshot,
-		Force:         force,
-		SkipManifest:  skipManifest,
-		SkipChecksums: skipChecksums,
-		MoveInputs:    true,
-	}
-	return packageRelease(cfg)
-}
-
-func runTaxonkitCreate(bin, input, outputDir string, force bool) error {
-	taxonkit := bin
-	if taxonkit == "" {
-		if p, err := exec.LookPath("taxonkit"); err == nil {
-			taxonkit = p
-		} else if p, err := exec.LookPath("taxonkit.exe"); err == nil {
-			taxonkit = p
-		} else {
-			return errors.New("taxonkit not found in PATH (set --taxonkit-bin)")
-		}
+		TaxdumpDir:      taxdumpDir,
+		MarkerDir:       markerDir,
+		TaxonkitOut:     taxonkitOut,
+		ReleaseDir:      releaseDir,
+		Snapshot:        snapshot,
+		Force:           force,
+		SkipManifest:    skipManifest,
+		SkipChecksums:   skipChecksums,
+		SkipReleaseInfo: skipReleaseInfo,
+		MoveInputs:      true,
+		ArchiveFormat:   archiveFormat,
+		Reproducible:    reproducible,
+		ChecksumAlgo:    checksumAlgo,
+		GzipLevel:       gzipLevel,
+	}
+	if err := timings.track("package", func() error { return packageRelease(cfg) }); err != nil {
+		return err
+	}
+	return timings.finish(timingReport)
+}
+
+// stageTimings records the wall time of each pipeline stage in run order.
+type stageTimings struct {
+	start  time.Time
+	order  []string
+	stages map[string]float64
+}
+
+// stageTimingReport is the -timing-report JSON.
+type stageTimingReport struct {
+	Stages       map[string]float64 `json:"stages_seconds"`
+	TotalSeconds float64            `json:"total_seconds"`
+}
+
+func newStageTimings() *stageTimings {
+	return &stageTimings{start: time.Now(), stages: make(map[string]float64)}
+}
+
+// track runs fn and records its duration under name, including when it fails.
+func (t *stageTimings) track(name string, fn func() error) error {
+	began := time.Now()
+	err := fn()
+	t.order = append(t.order, name)
+	t.stages[name] = time.Since(began).Seconds()
+	return err
+}
+
+// finish logs the one-line summary and writes the report when path is set.
+func (t *stageTimings) finish(path string) error {
+	total := time.Since(t.start).Seconds()
+	parts := make([]string, 0, len(t.order)+1)
+	for _, name := range t.order {
+		parts = append(parts, fmt.Sprintf("%s=%.1fs", name, t.stages[name]))
+	}
+	parts = append(parts, fmt.Sprintf("total=%.1fs", total))
+	logf("pipeline timing: %s", strings.Join(parts, " "))
+	if path == "" {
+		return nil
+	}
+	return writeJSONReport(path, stageTimingReport{Stages: t.stages, TotalSeconds: total})
+}
+
+func runTaxonkitCreate(bin, input, outputDir string, nullTokens []string, force bool) error {
+	taxonkit, err := lookupTool("taxonkit", bin, "-taxonkit-bin")
+	if err != nil {
+		return err
 	}
 
 	if !force && fileExists(filepath.Join(outputDir, "nodes.dmp")) && fileExists(filepath.Join(outputDir, "names.dmp")) && fileExists(filepath.Join(outputDir, "taxid.map")) {
@@ -141,12 +276,23 @@ func runTaxonkitCreate(bin, input, outputDir string, force bool) error {
 		return fmt.Errorf("create taxdump dir: %w", err)
 	}
 
-	cmd := exec.Command(taxonkit, "create-taxdump", input, "-A", "10", "--null", "None,NULL,NA", "-O", outputDir, "--force")
+	cmd := exec.Command(taxonkit, taxonkitCreateArgs(input, outputDir, nullTokens)...)
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// taxonkitCreateArgs builds the create-taxdump arguments. nullTokens is the
+// same -null-tokens list extract blanks, so no label is null in one stage and
+// a taxon in the other.
+func taxonkitCreateArgs(input, outputDir string, nullTokens []string) []string {
+	args := []string{"create-taxdump", input, "-A", "10"}
+	if len(nullTokens) > 0 {
+		args = append(args, "--null", strings.Join(nullTokens, ","))
+	}
+	return append(args, "-O", outputDir, "--force")
+}
+
 func packageMarkerPath(markerDir, releaseDir, snapshot string) string {
 	suffix := ""
 	if snapshot != "" {
@@ -188,7 +334,24 @@ func packageTaxonkitGzipPath(taxonkitOut, releaseDir, snapshot string) string {
 	return filepath.Join(releaseDir, base)
 }
 
-func packageTaxonkitGzip(src, dest string, force bool) error {
+// validateGzipLevel checks a -gzip-level value. 0 is accepted everywhere and
+// keeps each output's default level.
+func validateGzipLevel(level int) error {
+	if level != 0 && (level < gzip.BestSpeed || level > gzip.BestCompression) {
+		return fmt.Errorf("gzip-level must be 0 or %d-%d (got %d)", gzip.BestSpeed, gzip.BestCompression, level)
+	}
+	return nil
+}
+
+// packageGzipLevel maps an unset (0) archive gzip level to gzip.BestSpeed.
+func packageGzipLevel(level int) int {
+	if level == 0 {
+		return gzip.BestSpeed
+	}
+	return level
+}
+
+func packageTaxonkitGzip(src, dest string, force bool, level int) error {
 	if filepath.Clean(src) == filepath.Clean(dest) {
 		logf("taxonkit gzip already in release dir: %s", dest)
 		return nil
@@ -212,7 +375,7 @@ func packageTaxonkitGzip(src, dest string, force bool) error {
 		_ = in.Close()
 	}()
 
-	out, err := os.Create(dest)
+	out, err := createOutput(dest)
 	if err != nil {
 		return fmt.Errorf("create taxonkit gzip: %w", err)
 	}
@@ -220,17 +383,18 @@ func packageTaxonkitGzip(src, dest string, force bool) error {
 		_ = out.Close()
 	}()
 
-	gzw, err := gzip.NewWriterLevel(out, gzip.BestSpeed)
+	gzw, err := gzip.NewWriterLevel(out, packageGzipLevel(level))
 	if err != nil {
 		return fmt.Errorf("create gzip writer: %w", err)
 	}
-	if _, err := io.Copy(gzw, in); err != nil {
+	if _, err := io.Copy(gzw, contextReader(runCtx, in)); err != nil {
 		_ = gzw.Close()
 		return fmt.Errorf("gzip taxonkit input: %w", err)
 	}
 	if err := gzw.Close(); err != nil {
 		return fmt.Errorf("finalize gzip: %w", err)
 	}
+	keepOutputs(dest)
 	return nil
 }
 
@@ -243,7 +407,7 @@ func copyFile(src, dest string) error {
 		_ = in.Close()
 	}()
 
-	out, err := os.Create(dest)
+	out, err := createOutput(dest)
 	if err != nil {
 		return fmt.Errorf("create %s: %w", dest, err)
 	}
@@ -251,13 +415,30 @@ func copyFile(src, dest string) error {
 		_ = out.Close()
 	}()
 
-	if _, err := io.Copy(out, in); err != nil {
+	if _, err := io.Copy(out, contextReader(runCtx, in)); err != nil {
 		return fmt.Errorf("copy %s: %w", src, err)
 	}
+	keepOutputs(dest)
 	return nil
 }
 
-func packageDirGzip(srcDir, destTarGz string, force bool) error {
+// reproducibleModTime is the fixed entry timestamp for -reproducible
+// archives. Zip stores MS-DOS times, which start in 1980.
+var reproducibleModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reproducibleMode is the fixed permission set for -reproducible entries.
+func reproducibleMode(isDir bool) os.FileMode {
+	if isDir {
+		return 0o755
+	}
+	return 0o644
+}
+
+// packageDirGzip archives srcDir as a tar.gz, leaving out markers build
+// artifacts (see isMarkerBuildArtifact). filepath.Walk visits entries in
+// lexical order, so the entry order is already deterministic; reproducible
+// additionally drops the metadata that differs between machines.
+func packageDirGzip(srcDir, destTarGz string, force, reproducible bool, level int) error {
 	if fileExists(destTarGz) && !force {
 		logf("archive exists, skipping (use --force to overwrite): %s", destTarGz)
 		return nil
@@ -267,7 +448,7 @@ func packageDirGzip(srcDir, destTarGz string, force bool) error {
 		return fmt.Errorf("create releases dir: %w", err)
 	}
 
-	out, err := os.Create(destTarGz)
+	out, err := createOutput(destTarGz)
 	if err != nil {
 		return fmt.Errorf("create archive: %w", err)
 	}
@@ -275,7 +456,7 @@ func packageDirGzip(srcDir, destTarGz string, force bool) error {
 		_ = out.Close()
 	}()
 
-	gzw, err := gzip.NewWriterLevel(out, gzip.BestSpeed)
+	gzw, err := gzip.NewWriterLevel(out, packageGzipLevel(level))
 	if err != nil {
 		return fmt.Errorf("create gzip writer: %w", err)
 	}
@@ -293,11 +474,24 @@ func packageDirGzip(srcDir, destTarGz string, force bool) error {
 		if rel == "." {
 			return nil
 		}
+		if !info.IsDir() && isMarkerBuildArtifact(info.Name()) {
+			return nil
+		}
 		hdr, err := tar.FileInfoHeader(info, "")
 		if err != nil {
 			return err
 		}
 		hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if reproducible {
+			hdr.ModTime = reproducibleModTime
+			hdr.AccessTime = time.Time{}
+			hdr.ChangeTime = time.Time{}
+			hdr.Uid, hdr.Gid = 0, 0
+			hdr.Uname, hdr.Gname = "", ""
+			hdr.Mode = hdr.Mode&^0o7777 | int64(reproducibleMode(info.IsDir()))
+			hdr.PAXRecords = nil
+			hdr.Format = tar.FormatUSTAR
+		}
 		if err := tw.WriteHeader(hdr); err != nil {
 			return err
 		}
@@ -308,7 +502,7 @@ func packageDirGzip(srcDir, destTarGz string, force bool) error {
 		if err != nil {
 			return err
 		}
-		_, err = io.Copy(tw, in)
+		_, err = io.Copy(tw, contextReader(runCtx, in))
 		_ = in.Close()
 		return err
 	}); err != nil {
@@ -324,10 +518,123 @@ func packageDirGzip(srcDir, destTarGz string, force bool) error {
 	if err := gzw.Close(); err != nil {
 		return err
 	}
+	keepOutputs(destTarGz)
+	return nil
+}
+
+// packageDirZip is the zip counterpart of packageDirGzip: the same walk,
+// entry names prefixed with srcDir's base name, and markers build artifacts
+// skipped.
+func packageDirZip(srcDir, destZip string, force, reproducible bool) error {
+	if fileExists(destZip) && !force {
+		logf("archive exists, skipping (use --force to overwrite): %s", destZip)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destZip), 0o755); err != nil {
+		return fmt.Errorf("create releases dir: %w", err)
+	}
+
+	out, err := createOutput(destZip)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	zw := zip.NewWriter(out)
+	base := filepath.Base(srcDir)
+	if err := filepath.Walk(srcDir, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if !info.IsDir() && isMarkerBuildArtifact(info.Name()) {
+			return nil
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if reproducible {
+			hdr.Modified = reproducibleModTime
+			hdr.SetMode(info.Mode().Type() | reproducibleMode(info.IsDir()))
+		}
+		if info.IsDir() {
+			hdr.Name += "/"
+			_, err := zw.CreateHeader(hdr)
+			return err
+		}
+		hdr.Method = zip.Deflate
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, contextReader(runCtx, in))
+		_ = in.Close()
+		return err
+	}); err != nil {
+		_ = zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	keepOutputs(destZip)
 	return nil
 }
 
-func writeChecksums(releaseDir, outputFile string, force bool) error {
+const (
+	checksumAlgoSHA256  = "sha256"
+	checksumAlgoSHA512  = "sha512"
+	checksumAlgoBLAKE2b = "blake2b"
+)
+
+func normalizeChecksumAlgo(algo string) (string, error) {
+	switch a := strings.ToLower(strings.TrimSpace(algo)); a {
+	case "", checksumAlgoSHA256:
+		return checksumAlgoSHA256, nil
+	case checksumAlgoSHA512, checksumAlgoBLAKE2b:
+		return a, nil
+	case "blake2b-512", "b2":
+		return checksumAlgoBLAKE2b, nil
+	}
+	return "", fmt.Errorf("checksum-algo must be one of sha256, sha512, blake2b (got %q)", algo)
+}
+
+// checksumFileName names the release checksum file for algo, e.g.
+// SHA512SUMS.txt.
+func checksumFileName(algo string) string {
+	return strings.ToUpper(algo) + "SUMS.txt"
+}
+
+// newChecksumHash returns the digest for algo. blake2b is BLAKE2b-512, the
+// b2sum default.
+func newChecksumHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case checksumAlgoSHA256:
+		return sha256.New(), nil
+	case checksumAlgoSHA512:
+		return sha512.New(), nil
+	case checksumAlgoBLAKE2b:
+		return blake2b.New512(nil)
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+}
+
+func writeChecksums(releaseDir, outputFile, algo string, force bool) error {
 	if fileExists(outputFile) && !force {
 		logf("checksums exist, skipping (use --force to overwrite): %s", outputFile)
 		return nil
@@ -353,27 +660,30 @@ func writeChecksums(releaseDir, outputFile string, force bool) error {
 	}
 	sort.Strings(files)
 
-	out, err := os.Create(outputFile)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = out.Close()
-	}()
-
-	for _, f := range files {
-		sum, err := sha256File(f)
-		if err != nil {
-			return err
-		}
-		if _, err := fmt.Fprintf(out, "%s  %s\n", sum, filepath.Base(f)); err != nil {
-			return err
+	return writeFileAtomic(outputFile, func(out io.Writer) error {
+		for _, f := range files {
+			sum, err := checksumFile(f, algo)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(out, "%s  %s\n", sum, filepath.Base(f)); err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func sha256File(path string) (string, error) {
+	return checksumFile(path, checksumAlgoSHA256)
+}
+
+// checksumFile returns the hex digest of path under algo.
+func checksumFile(path, algo string) (string, error) {
+	h, err := newChecksumHash(algo)
+	if err != nil {
+		return "", err
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
@@ -382,8 +692,7 @@ func sha256File(path string) (string, error) {
 		_ = f.Close()
 	}()
 
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
+	if _, err := io.Copy(h, contextReader(runCtx, f)); err != nil {
 		return "", err
 	}
 	return hex.EncodeToString(h.Sum(nil)), nil
@@ -399,58 +708,105 @@ func writeManifest(path, taxdumpDir, markerDir, snapshot string, force bool) err
 		return err
 	}
 
-	commit := "unknown"
-	if c, err := gitCommitHash(); err == nil && c != "" {
-		commit = c
-	}
-
-	nodes, err := countLines(filepath.Join(taxdumpDir, "nodes.dmp"))
+	counts, markers, err := countRelease(taxdumpDir, markerDir)
 	if err != nil {
 		return err
 	}
-	names, err := countLines(filepath.Join(taxdumpDir, "names.dmp"))
-	if err != nil {
-		return err
+
+	manifest := releaseManifest{
+		SnapshotID: snapshot,
+		CommitHash: releaseCommit(),
+		Counts:     counts,
+		Markers:    markers,
 	}
-	taxid, err := countLines(filepath.Join(taxdumpDir, "taxid.map"))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return err
 	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// releaseCounts are the taxdump and marker totals recorded in manifest.json
+// and RELEASE_INFO.txt.
+type releaseCounts struct {
+	Nodes                int `json:"nodes"`
+	Names                int `json:"names"`
+	TaxidMap             int `json:"taxid_map"`
+	MarkerFastaFiles     int `json:"marker_fasta_files"`
+	MarkerFastaSequences int `json:"marker_fasta_sequences"`
+}
+
+// releaseManifest is manifest.json. Markers breaks the marker totals down
+// by marker name (the FASTA file name without .fasta/.fasta.gz).
+type releaseManifest struct {
+	SnapshotID string                    `json:"snapshot_id"`
+	CommitHash string                    `json:"commit_hash"`
+	Counts     releaseCounts             `json:"counts"`
+	Markers    map[string]manifestMarker `json:"markers"`
+}
 
+// manifestMarker counts one marker's sequences, in total and per file
+// (paths relative to the marker directory).
+type manifestMarker struct {
+	Sequences int            `json:"sequences"`
+	Files     map[string]int `json:"files"`
+}
+
+func countRelease(taxdumpDir, markerDir string) (releaseCounts, map[string]manifestMarker, error) {
+	var c releaseCounts
+	var err error
+	if c.Nodes, err = countLines(filepath.Join(taxdumpDir, "nodes.dmp")); err != nil {
+		return c, nil, err
+	}
+	if c.Names, err = countLines(filepath.Join(taxdumpDir, "names.dmp")); err != nil {
+		return c, nil, err
+	}
+	if c.TaxidMap, err = countLines(filepath.Join(taxdumpDir, "taxid.map")); err != nil {
+		return c, nil, err
+	}
 	markerFiles, err := listMarkerFiles(markerDir)
 	if err != nil {
-		return err
+		return c, nil, err
 	}
-	markerSeqs, err := countMarkerSeqs(markerFiles)
+	c.MarkerFastaFiles = len(markerFiles)
+	perFile, err := countMarkerSeqs(markerFiles)
 	if err != nil {
-		return err
+		return c, nil, err
 	}
-
-	manifest := struct {
-		SnapshotID string `json:"snapshot_id"`
-		CommitHash string `json:"commit_hash"`
-		Counts     struct {
-			Nodes                int `json:"nodes"`
-			Names                int `json:"names"`
-			TaxidMap             int `json:"taxid_map"`
-			MarkerFastaFiles     int `json:"marker_fasta_files"`
-			MarkerFastaSequences int `json:"marker_fasta_sequences"`
-		} `json:"counts"`
-	}{
-		SnapshotID: snapshot,
-		CommitHash: commit,
+	markers := make(map[string]manifestMarker)
+	for i, p := range markerFiles {
+		c.MarkerFastaSequences += perFile[i]
+		rel, err := filepath.Rel(markerDir, p)
+		if err != nil {
+			rel = filepath.Base(p)
+		}
+		name := markerNameFromFile(p)
+		m, ok := markers[name]
+		if !ok {
+			m = manifestMarker{Files: make(map[string]int)}
+		}
+		m.Sequences += perFile[i]
+		m.Files[filepath.ToSlash(rel)] = perFile[i]
+		markers[name] = m
 	}
-	manifest.Counts.Nodes = nodes
-	manifest.Counts.Names = names
-	manifest.Counts.TaxidMap = taxid
-	manifest.Counts.MarkerFastaFiles = len(markerFiles)
-	manifest.Counts.MarkerFastaSequences = markerSeqs
+	return c, markers, nil
+}
 
-	data, err := json.MarshalIndent(manifest, "", "  ")
-	if err != nil {
-		return err
+// markerNameFromFile reverses getMarkerWriter's <marker>.fasta[.gz] naming.
+func markerNameFromFile(path string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".fasta")
+}
+
+// releaseCommit is gitCommitHash, or "unknown" outside a git checkout.
+func releaseCommit() string {
+	if c, err := gitCommitHash(); err == nil && c != "" {
+		return c
 	}
-	return os.WriteFile(path, append(data, '\n'), 0o644)
+	return "unknown"
 }
 
 func gitCommitHash() (string, error) {
@@ -480,26 +836,27 @@ func listMarkerFiles(markerDir string) ([]string, error) {
 	return files, err
 }
 
-func countMarkerSeqs(paths []string) (int, error) {
-	total := 0
-	for _, p := range paths {
+// countMarkerSeqs returns the FASTA record count of each path, in order.
+func countMarkerSeqs(paths []string) ([]int, error) {
+	counts := make([]int, len(paths))
+	for i, p := range paths {
 		rc, err := openInput(p)
 		if err != nil {
-			return 0, fmt.Errorf("open %s: %w", p, err)
+			return nil, fmt.Errorf("open %s: %w", p, err)
 		}
 		scanner := bufio.NewScanner(rc)
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			if len(line) > 0 && line[0] == '>' {
-				total++
+				counts[i]++
 			}
 		}
 		_ = rc.Close()
 		if err := scanner.Err(); err != nil {
-			return 0, fmt.Errorf("scan %s: %w", p, err)
+			return nil, fmt.Errorf("scan %s: %w", p, err)
 		}
 	}
-	return total, nil
+	return counts, nil
 }
 
 func safeTag(s string) string {
@@ -515,7 +872,3 @@ func safeTag(s string) string {
 	}
 	return b.String()
 }
-
-func logf(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, "[boldkit] "+format+"\n", args...)
-}
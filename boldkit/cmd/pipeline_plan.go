@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// pipelinePlan lists what pipeline would do with the same arguments, one
+// line per stage or artifact, with the resolved paths and whether the step
+// runs or is skipped because its output exists. It only checks for existing
+// files: nothing is written and taxonkit is looked up but never invoked.
+// pkg is nil unless -package was given.
+func pipelinePlan(input, taxonkitOut, taxdumpDir, markerDir, taxonkitBin string, force bool, pkg *packageConfig) []string {
+	var lines []string
+	step := func(stage, detail string, exists bool) {
+		if exists && !force {
+			lines = append(lines, fmt.Sprintf("%s: skip, exists (use --force to overwrite): %s", stage, detail))
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: run: %s", stage, detail))
+	}
+
+	step("extract", fmt.Sprintf("%s (%s) -> %s", input, InputFormat(input), taxonkitOut), fileExists(taxonkitOut))
+
+	taxonkit := "taxonkit"
+	if path, err := lookupTool("taxonkit", taxonkitBin, "-taxonkit-bin"); err == nil {
+		taxonkit = path
+	} else {
+		taxonkit += " (not found)"
+	}
+	taxdumpDone := fileExists(filepath.Join(taxdumpDir, "nodes.dmp")) && fileExists(filepath.Join(taxdumpDir, "names.dmp")) && fileExists(filepath.Join(taxdumpDir, "taxid.map"))
+	step("taxdump", fmt.Sprintf("%s create-taxdump %s -> %s", taxonkit, taxonkitOut, taxdumpDir), taxdumpDone)
+
+	step("markers", fmt.Sprintf("%s -> %s", input, markerDir), outputsExist(markerDir))
+
+	if pkg == nil {
+		return append(lines, "package: off (use -package)")
+	}
+	releaseTaxdump := filepath.Join(pkg.ReleaseDir, filepath.Base(taxdumpDir))
+	releaseMarkers := filepath.Join(pkg.ReleaseDir, filepath.Base(markerDir))
+	lines = append(lines,
+		fmt.Sprintf("package: move %s -> %s", taxdumpDir, releaseTaxdump),
+		fmt.Sprintf("package: move %s -> %s", markerDir, releaseMarkers),
+		fmt.Sprintf("package: move %s -> %s", taxonkitOut, packageTaxonkitPath(taxonkitOut, pkg.ReleaseDir, pkg.Snapshot)),
+	)
+	if !pkg.SkipReleaseInfo {
+		lines = append(lines, "package: run: "+filepath.Join(pkg.ReleaseDir, releaseInfoName))
+	}
+	for _, archive := range []string{
+		packageTaxdumpArchivePath(releaseTaxdump, pkg.ReleaseDir, pkg.Snapshot),
+		packageMarkerPath(releaseMarkers, pkg.ReleaseDir, pkg.Snapshot),
+	} {
+		if pkg.ArchiveFormat != archiveFormatZip {
+			step("package", archive, fileExists(archive))
+		}
+		if pkg.ArchiveFormat == archiveFormatZip || pkg.ArchiveFormat == archiveFormatBoth {
+			zipPath := strings.TrimSuffix(archive, ".tar.gz") + ".zip"
+			step("package", zipPath, fileExists(zipPath))
+		}
+	}
+	if !strings.HasSuffix(taxonkitOut, ".gz") {
+		gz := packageTaxonkitGzipPath(taxonkitOut, pkg.ReleaseDir, pkg.Snapshot)
+		step("package", gz, fileExists(gz))
+	}
+	if !pkg.SkipManifest {
+		manifest := filepath.Join(pkg.ReleaseDir, "manifest.json")
+		step("package", manifest, fileExists(manifest))
+	}
+	if !pkg.SkipChecksums {
+		sums := filepath.Join(pkg.ReleaseDir, checksumFileName(pkg.ChecksumAlgo))
+		step("package", sums, fileExists(sums))
+	}
+	return lines
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipelinePlanSkipsExistingTaxonkit(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "BOLD_Public.snap.tsv")
+	taxonkitOut := filepath.Join(tmp, "taxonkit_input.tsv")
+	for _, path := range []string{input, taxonkitOut} {
+		if err := os.WriteFile(path, []byte("processid\tkingdom\nP1\tAnimalia\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	taxdumpDir := filepath.Join(tmp, "bold-taxdump")
+	markerDir := filepath.Join(tmp, "marker_fastas")
+	releases := filepath.Join(tmp, "releases")
+	missingBin := filepath.Join(tmp, "no-taxonkit")
+
+	got := pipelinePlan(input, taxonkitOut, taxdumpDir, markerDir, missingBin, false, &packageConfig{
+		ReleaseDir:      releases,
+		Snapshot:        "snap",
+		SkipReleaseInfo: true,
+		SkipChecksums:   true,
+		ArchiveFormat:   archiveFormatTarGz,
+	})
+	want := []string{
+		"extract: skip, exists (use --force to overwrite): " + input + " (tsv) -> " + taxonkitOut,
+		"taxdump: run: taxonkit (not found) create-taxdump " + taxonkitOut + " -> " + taxdumpDir,
+		"markers: run: " + input + " -> " + markerDir,
+		"package: move " + taxdumpDir + " -> " + filepath.Join(releases, "bold-taxdump"),
+		"package: move " + markerDir + " -> " + filepath.Join(releases, "marker_fastas"),
+		"package: move " + taxonkitOut + " -> " + filepath.Join(releases, "taxonkit_input.snap.tsv"),
+		"package: run: " + filepath.Join(releases, "bold-taxdump.snap.tar.gz"),
+		"package: run: " + filepath.Join(releases, "marker_fastas.snap.tar.gz"),
+		"package: run: " + filepath.Join(releases, "taxonkit_input.snap.tsv.gz"),
+		"package: run: " + filepath.Join(releases, "manifest.json"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("plan has %d lines, want %d:\n%q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("plan line %d=%q want %q", i, got[i], want[i])
+		}
+	}
+	if pathExists(taxdumpDir) || pathExists(markerDir) || pathExists(releases) {
+		t.Fatalf("dry-run plan must not create outputs")
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPipelineTimingReport(t *testing.T) {
+	tmp := t.TempDir()
+	taxdump := filepath.Join(tmp, "bold-taxdump")
+	markers := filepath.Join(tmp, "marker_fastas")
+	for _, dir := range []string{taxdump, markers} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	// Every stage's output already exists, so the run only skips and packages.
+	taxonkitBin := filepath.Join(tmp, "taxonkit")
+	files := map[string]string{
+		filepath.Join(tmp, "BOLD_Public.snap.tsv"):  "processid\tkingdom\nP1\tAnimalia\n",
+		filepath.Join(tmp, "taxonkit_input.tsv"):    "processid\tkingdom\nP1\tAnimalia\n",
+		filepath.Join(taxdump, "nodes.dmp"):         "1\t|\t1\t|\tno rank\t|\n",
+		filepath.Join(taxdump, "names.dmp"):         "1\t|\troot\t|\n",
+		filepath.Join(taxdump, "taxid.map"):         "P1\t1\n",
+		filepath.Join(markers, "COI-5P.fasta"):      ">P1\nACGT\n",
+		filepath.Join(markers, "COI-5P.fasta.done"): "",
+		taxonkitBin: "#!/bin/sh\nexit 1\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	reportPath := filepath.Join(tmp, "timing.json")
+	err := pipeline(filepath.Join(tmp, "BOLD_Public.snap.tsv"), filepath.Join(tmp, "taxonkit_input.tsv"), taxdump, markers,
+		filepath.Join(tmp, "releases"), taxonkitBin, 0, -1, 1, false, false, true, true, true, true, false,
+		"snap", archiveFormatTarGz, checksumAlgoSHA256, 0, nil, extractCurationConfig{}, reportPath)
+	if err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read timing report: %v", err)
+	}
+	var report stageTimingReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("unmarshal timing report: %v", err)
+	}
+	for _, stage := range []string{"extract", "taxdump", "markers", "package"} {
+		secs, ok := report.Stages[stage]
+		if !ok || secs < 0 {
+			t.Fatalf("stage %s=%v (present=%v) in %s", stage, secs, ok, data)
+		}
+	}
+	if report.TotalSeconds < 0 || len(report.Stages) != 4 {
+		t.Fatalf("unexpected timing report: %s", data)
+	}
+}
+
+func TestCheckInputExpectSHA256(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "BOLD_Public.test.tsv")
+	content := "processid\tnuc\nP1\tACGT\nP2\tGGCC\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	sum, err := sha256File(input)
+	if err != nil {
+		t.Fatalf("sha256File: %v", err)
+	}
+
+	wrong := strings.Repeat("0", len(sum))
+	if _, err := checkInput(input, "", wrong); err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected sha256 mismatch, got %v", err)
+	}
+
+	rows, err := checkInput(input, "", strings.ToUpper(sum))
+	if err != nil {
+		t.Fatalf("checkInput with matching digest: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("rows=%d want 2", rows)
+	}
+}
+
+// TestExpectSHA256MismatchWritesNothing runs extract and pipeline in a child
+// test process, since a digest mismatch ends the command through fatalf, and
+// checks that neither leaves an output behind.
+func TestExpectSHA256MismatchWritesNothing(t *testing.T) {
+	if args := os.Getenv("BOLDKIT_TEST_EXECUTE"); args != "" {
+		Execute(strings.Split(args, "\x1f"), "test")
+		os.Exit(0)
+	}
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "BOLD_Public.test.tsv")
+	content := "processid\tmarker_code\tnuc\tkingdom\nP1\tCOI-5P\tACGT\tAnimalia\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	wrong := strings.Repeat("0", 64)
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	taxdump := filepath.Join(tmp, "bold-taxdump")
+	markers := filepath.Join(tmp, "marker_fastas")
+
+	cases := []struct {
+		name    string
+		args    []string
+		outputs []string
+	}{
+		{"extract", []string{"extract", "-input", input, "-output", taxonkit, "-expect-sha256", wrong, "-progress=false"}, []string{taxonkit}},
+		{"pipeline", []string{"pipeline", "-input", input, "-taxonkit-output", taxonkit, "-taxdump-dir", taxdump, "-marker-dir", markers, "-expect-sha256", wrong, "-progress=false"}, []string{taxonkit, taxdump, markers}},
+	}
+	for _, tc := range cases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExpectSHA256MismatchWritesNothing$")
+		cmd.Env = append(os.Environ(), "BOLDKIT_TEST_EXECUTE="+strings.Join(tc.args, "\x1f"))
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("%s succeeded despite a digest mismatch:\n%s", tc.name, out)
+		}
+		if !strings.Contains(string(out), "sha256 mismatch") {
+			t.Fatalf("%s failed without a sha256 mismatch:\n%s", tc.name, out)
+		}
+		for _, path := range tc.outputs {
+			if pathExists(path) {
+				t.Fatalf("%s left %s after a digest mismatch", tc.name, path)
+			}
+		}
+	}
+}
@@ -1,110 +1,120 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
+)
 
-	"github.com/schollz/progressbar/v3"
+const (
+	progressRedrawEvery = 250 * time.Millisecond
+	// progressRateAlpha weights the newest sample in the moving-average rate.
+	progressRateAlpha = 0.3
 )
 
-// progress wraps schollz/progressbar with an opt-out flag (reportEvery == 0).
+// progressNow is the clock behind rates and ETAs; tests replace it.
+var progressNow = time.Now
+
+// progress reports row counts on stderr (opt-out with reportEvery == 0).
 type progress struct {
-	bar *progressbar.ProgressBar
+	line *progressLine
 }
 
 func newProgress(total, reportEvery int) *progress {
 	if reportEvery == 0 {
-		return &progress{bar: nil}
-	}
-
-	opts := []progressbar.Option{
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionThrottle(250 * time.Millisecond),
-		progressbar.OptionClearOnFinish(),
-	}
-
-	var bar *progressbar.ProgressBar
-	if total > 0 {
-		opts = append(opts,
-			progressbar.OptionSetWidth(30),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionSetPredictTime(true),
-		)
-		bar = progressbar.NewOptions(total, opts...)
-	} else {
-		opts = append(opts,
-			progressbar.OptionSpinnerType(14),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-		)
-		bar = progressbar.NewOptions(-1, opts...)
+		return &progress{line: nil}
 	}
-
-	return &progress{bar: bar}
+	return &progress{line: newProgressLine(os.Stderr, "", int64(total), false)}
 }
 
 func (p *progress) increment() {
-	if p.bar == nil {
+	if p.line == nil {
 		return
 	}
-	_ = p.bar.Add(1)
+	p.line.add(1)
 }
 
 func (p *progress) finish() {
-	if p.bar == nil {
+	if p.line == nil {
 		return
 	}
-	_ = p.bar.Finish()
+	p.line.finish()
 }
 
 type byteProgress struct {
-	bar *progressbar.ProgressBar
+	line *progressLine
+	// records counts calls to updateByteProgress instead of bytes.
+	records bool
 }
 
 func newByteProgress(total int64, label string) *byteProgress {
-	opts := []progressbar.Option{
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionThrottle(250 * time.Millisecond),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionShowBytes(true),
-	}
-	if label != "" {
-		opts = append(opts, progressbar.OptionSetDescription(label))
-	}
+	return &byteProgress{line: newProgressLine(os.Stderr, label, total, true)}
+}
 
-	if total > 0 {
-		opts = append(opts,
-			progressbar.OptionSetWidth(30),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionSetPredictTime(true),
-		)
-		return &byteProgress{bar: progressbar.NewOptions64(total, opts...)}
+func (b *byteProgress) Add(delta int64) {
+	if b == nil || b.line == nil {
+		return
 	}
-	opts = append(opts,
-		progressbar.OptionSpinnerType(14),
-		progressbar.OptionShowCount(),
-		progressbar.OptionShowIts(),
-	)
-	return &byteProgress{bar: progressbar.NewOptions(-1, opts...)}
+	b.line.add(delta)
 }
 
-func (b *byteProgress) Add(delta int64) {
-	if b == nil || b.bar == nil {
+// finishByteProgress adds any bytes read after the last record and clears
+// the bar.
+func finishByteProgress(bar *byteProgress, counter *countReader, last *int64) {
+	if bar == nil {
 		return
 	}
-	_ = b.bar.Add64(delta)
+	if !bar.records {
+		updateByteProgress(bar, counter, last)
+	}
+	bar.Finish()
 }
 
 func (b *byteProgress) Finish() {
-	if b == nil || b.bar == nil {
+	if b == nil || b.line == nil {
 		return
 	}
-	_ = b.bar.Finish()
+	b.line.finish()
 }
 
+// progressRows is the row total for a TSV progress bar: -progress-total
+// when given, otherwise a counting pass over input.
+func progressRows(input, format string) (int, error) {
+	if globalOpts.ProgressTotal > 0 {
+		return int(globalOpts.ProgressTotal), nil
+	}
+	count, err := RowCountAs(input, format)
+	return int(count), err
+}
+
+// inputProgressTotal is progressRows for a row count already taken by
+// checkInput.
+func inputProgressTotal(rows int64) int {
+	if globalOpts.ProgressTotal > 0 {
+		return int(globalOpts.ProgressTotal)
+	}
+	return int(rows)
+}
+
+// newInputProgress is the approximate byte bar over input, or a record bar
+// when -progress-total is given.
+func newInputProgress(input, label string) *byteProgress {
+	if globalOpts.ProgressTotal > 0 {
+		return &byteProgress{line: newProgressLine(os.Stderr, label, globalOpts.ProgressTotal, false), records: true}
+	}
+	return newByteProgress(fileSize(input), label)
+}
+
+// updateByteProgress is called once per record: it adds the bytes read
+// since the last call, or one record on a record bar.
 func updateByteProgress(bar *byteProgress, counter *countReader, last *int64) {
+	if bar != nil && bar.records {
+		bar.Add(1)
+		return
+	}
 	if bar == nil || counter == nil || last == nil {
 		return
 	}
@@ -116,3 +126,159 @@ func updateByteProgress(bar *byteProgress, counter *countReader, last *int64) {
 	bar.Add(delta)
 	*last = cur
 }
+
+// progressLine redraws one status line in place, at most every
+// progressRedrawEvery, and clears it on finish:
+//
+//	45% | 1.2M/2.7M | 18k rows/s | ETA 00:01:12
+//
+// Without a total it shows only the count and rate.
+type progressLine struct {
+	mu     sync.Mutex
+	w      io.Writer
+	label  string
+	total  int64
+	bytes  bool
+	count  int64
+	meter  rateMeter
+	drawn  time.Time
+	width  int
+	closed bool
+}
+
+func newProgressLine(w io.Writer, label string, total int64, bytes bool) *progressLine {
+	now := progressNow()
+	return &progressLine{
+		w:     w,
+		label: label,
+		total: total,
+		bytes: bytes,
+		meter: rateMeter{lastAt: now},
+		drawn: now,
+	}
+}
+
+func (p *progressLine) add(delta int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.count += delta
+	now := progressNow()
+	if now.Sub(p.drawn) < progressRedrawEvery {
+		return
+	}
+	p.meter.observe(p.count, now)
+	p.drawn = now
+	p.draw(p.status())
+}
+
+func (p *progressLine) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	if p.width > 0 {
+		_, _ = fmt.Fprint(p.w, "\r"+strings.Repeat(" ", p.width)+"\r")
+	}
+}
+
+func (p *progressLine) status() string {
+	s := formatProgressStatus(p.count, p.total, p.meter.rate, p.bytes)
+	if p.label != "" {
+		s = p.label + " " + s
+	}
+	return s
+}
+
+// draw overwrites the previous line, padding with spaces when it shrank.
+func (p *progressLine) draw(s string) {
+	pad := ""
+	if len(s) < p.width {
+		pad = strings.Repeat(" ", p.width-len(s))
+	}
+	p.width = len(s)
+	_, _ = fmt.Fprint(p.w, "\r"+s+pad)
+}
+
+// rateMeter keeps an exponential moving average of units per second over
+// the samples passed to observe.
+type rateMeter struct {
+	lastAt    time.Time
+	lastCount int64
+	rate      float64
+}
+
+func (m *rateMeter) observe(count int64, now time.Time) {
+	dt := now.Sub(m.lastAt).Seconds()
+	if dt <= 0 {
+		return
+	}
+	inst := float64(count-m.lastCount) / dt
+	if m.rate == 0 {
+		m.rate = inst
+	} else {
+		m.rate = progressRateAlpha*inst + (1-progressRateAlpha)*m.rate
+	}
+	m.lastAt = now
+	m.lastCount = count
+}
+
+func formatProgressStatus(count, total int64, rate float64, bytes bool) string {
+	amount := formatProgressCount
+	unit := " rows/s"
+	if bytes {
+		amount = formatProgressBytes
+		unit = "/s"
+	}
+	speed := amount(rate) + unit
+	if total <= 0 {
+		return amount(float64(count)) + " | " + speed
+	}
+	pct := count * 100 / total
+	if pct > 100 {
+		pct = 100
+	}
+	eta := "--:--:--"
+	if rate > 0 {
+		remaining := total - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		eta = formatClock(time.Duration(float64(remaining) / rate * float64(time.Second)))
+	}
+	return fmt.Sprintf("%d%% | %s/%s | %s | ETA %s", pct, amount(float64(count)), amount(float64(total)), speed, eta)
+}
+
+// formatProgressCount abbreviates with k/M/G, one decimal below 10.
+func formatProgressCount(v float64) string {
+	return scaleProgress(v, 1000, []string{"", "k", "M", "G", "T"})
+}
+
+func formatProgressBytes(v float64) string {
+	return scaleProgress(v, 1024, []string{"B", "KiB", "MiB", "GiB", "TiB"})
+}
+
+func scaleProgress(v, base float64, units []string) string {
+	i := 0
+	for v >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", int64(v), units[0])
+	}
+	if v < 10 {
+		return fmt.Sprintf("%.1f%s", v, units[i])
+	}
+	return fmt.Sprintf("%d%s", int64(v), units[i])
+}
+
+// formatClock renders d as HH:MM:SS, truncated to whole seconds.
+func formatClock(d time.Duration) string {
+	s := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s%3600/60, s%60)
+}
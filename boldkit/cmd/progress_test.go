@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLineRateAndETA(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	orig := progressNow
+	progressNow = func() time.Time { return now }
+	defer func() { progressNow = orig }()
+
+	var out bytes.Buffer
+	line := newProgressLine(&out, "", 54000, false)
+	for step := 0; step < 2; step++ {
+		now = now.Add(time.Second)
+		line.add(18000)
+	}
+	frames := strings.Split(out.String(), "\r")
+	got := frames[len(frames)-1]
+	want := "66% | 36k/54k | 18k rows/s | ETA 00:00:01"
+	if got != want {
+		t.Fatalf("status=%q want %q", got, want)
+	}
+
+	line.finish()
+	if !strings.HasSuffix(out.String(), "\r"+strings.Repeat(" ", len(want))+"\r") {
+		t.Fatalf("finish should clear the line, got %q", out.String())
+	}
+}
+
+func TestFormatProgressStatus(t *testing.T) {
+	if got := formatProgressStatus(1200000, 2700000, 18000, false); got != "44% | 1.2M/2.7M | 18k rows/s | ETA 00:01:23" {
+		t.Fatalf("rows status=%q", got)
+	}
+	if got := formatProgressStatus(512, 0, 0, true); got != "512B | 0B/s" {
+		t.Fatalf("unknown-total status=%q", got)
+	}
+	if got := formatProgressStatus(3<<20, 6<<20, 1<<20, true); got != "50% | 3.0MiB/6.0MiB | 1.0MiB/s | ETA 00:00:03" {
+		t.Fatalf("bytes status=%q", got)
+	}
+}
+
+func TestProgressTotalOverride(t *testing.T) {
+	orig := globalOpts.ProgressTotal
+	globalOpts.ProgressTotal = 2700000
+	defer func() { globalOpts.ProgressTotal = orig }()
+
+	// No counting pass: the input need not exist.
+	rows, err := progressRows("missing.tsv", "")
+	if err != nil || rows != 2700000 {
+		t.Fatalf("progressRows=%d, %v want 2700000", rows, err)
+	}
+	bar := newInputProgress("missing.fasta", "qc (approx)")
+	if !bar.records || bar.line.total != 2700000 || bar.line.bytes {
+		t.Fatalf("expected a record bar with the override total, got %+v", bar)
+	}
+	updateByteProgress(bar, nil, nil)
+	updateByteProgress(bar, nil, nil)
+	if bar.line.count != 2 {
+		t.Fatalf("record bar count=%d want 2", bar.line.count)
+	}
+	bar.Finish()
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type pruneTaxdumpConfig struct {
+	IDsPath    string
+	TaxdumpDir string
+	TaxidMap   string
+	OutDir     string
+	EmitTree   bool
+	// AllowMissing counts and skips ids absent from the taxid map instead of
+	// failing.
+	AllowMissing bool
+}
+
+func runPruneTaxdump(args []string) {
+	fs := flag.NewFlagSet("prune-taxdump", flag.ExitOnError)
+	ids := fs.String("ids", "", "FASTA/FASTQ (optionally .gz) or one-per-line list of processids to keep")
+	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory (nodes.dmp, names.dmp)")
+	taxidMap := fs.String("taxid-map", "", "processid -> taxid map (default: <taxdump-dir>/taxid.map)")
+	outDir := fs.String("outdir", "taxdump_pruned", "Output directory for the pruned nodes.dmp, names.dmp and taxid.map")
+	emitTree := fs.Bool("emit-tree-json", false, "Also write tree.json, the kept taxonomy as nested JSON")
+	allowMissing := fs.Bool("allow-missing-taxid", false, "Skip and count ids missing from the taxid map instead of failing")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	if *ids == "" {
+		fatalf("-ids is required")
+	}
+	cfg := pruneTaxdumpConfig{
+		IDsPath:      *ids,
+		TaxdumpDir:   *taxdumpDir,
+		TaxidMap:     *taxidMap,
+		OutDir:       *outDir,
+		EmitTree:     *emitTree,
+		AllowMissing: *allowMissing,
+	}
+	if _, err := pruneTaxdumpToIDs(cfg); err != nil {
+		fatalf("prune-taxdump failed: %v", err)
+	}
+}
+
+// pruneTaxdumpToIDs prunes cfg.TaxdumpDir to the lineages of the ids in
+// cfg.IDsPath and returns the kept taxid count.
+func pruneTaxdumpToIDs(cfg pruneTaxdumpConfig) (int, error) {
+	ids, err := loadIDList(cfg.IDsPath)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("no ids found in %s", cfg.IDsPath)
+	}
+	kept, missing, err := pruneTaxdump(ids, nil, cfg.TaxdumpDir, cfg.TaxidMap, cfg.OutDir, cfg.EmitTree, cfg.AllowMissing)
+	if err != nil {
+		return 0, err
+	}
+	if missing > 0 {
+		warnf("missing_taxid", missing, "prune-taxdump: %d ids have no taxid (left out of the pruned taxdump)", missing)
+	}
+	logf("prune-taxdump: ids=%d kept_taxids=%d -> %s", len(ids)-missing, kept, cfg.OutDir)
+	return kept, nil
+}
+
+// loadIDList reads record ids from a FASTA/FASTQ file, sniffed by its first
+// non-blank byte, or else from a list of one id per line (blank lines and
+// '#' comments skipped).
+func loadIDList(path string) (map[string]struct{}, error) {
+	in, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ids: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	br := bufio.NewReaderSize(in, 64*1024)
+	ids := make(map[string]struct{})
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return ids, nil
+			}
+			return nil, fmt.Errorf("read ids: %w", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+			continue
+		case '>', '@':
+			err := parseSequences(br, func(rec fastaRecord) error {
+				ids[rec.id] = struct{}{}
+				return nil
+			})
+			return ids, err
+		}
+		break
+	}
+
+	scanner := bufio.NewScanner(br)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan ids: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPruneTaxdumpToIDs(t *testing.T) {
+	tmp := t.TempDir()
+	taxdump := filepath.Join(tmp, "taxdump")
+	if err := os.MkdirAll(taxdump, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"nodes.dmp": "1\t|\t1\t|\tno rank\t|\n2\t|\t1\t|\tgenus\t|\n3\t|\t2\t|\tspecies\t|\n4\t|\t2\t|\tspecies\t|\n" +
+			"5\t|\t1\t|\tgenus\t|\n6\t|\t5\t|\tspecies\t|\n",
+		"names.dmp": "1\t|\troot\t|\t\t|\tscientific name\t|\n2\t|\tHomo\t|\t\t|\tscientific name\t|\n" +
+			"3\t|\tHomo sapiens\t|\t\t|\tscientific name\t|\n4\t|\tHomo erectus\t|\t\t|\tscientific name\t|\n" +
+			"5\t|\tCanis\t|\t\t|\tscientific name\t|\n6\t|\tCanis lupus\t|\t\t|\tscientific name\t|\n",
+		"taxid.map": "P1\t3\nP2\t4\nP3\t6\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taxdump, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	idsPath := filepath.Join(tmp, "keep.txt")
+	if err := os.WriteFile(idsPath, []byte("P1\n# comment\n\nP3\n"), 0o644); err != nil {
+		t.Fatalf("write ids: %v", err)
+	}
+
+	outDir := filepath.Join(tmp, "pruned")
+	kept, err := pruneTaxdumpToIDs(pruneTaxdumpConfig{IDsPath: idsPath, TaxdumpDir: taxdump, OutDir: outDir})
+	if err != nil {
+		t.Fatalf("pruneTaxdumpToIDs failed: %v", err)
+	}
+	if kept != 5 {
+		t.Fatalf("kept=%d want 5 (root, Homo, Homo sapiens, Canis, Canis lupus)", kept)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "nodes.dmp"))
+	if err != nil {
+		t.Fatalf("read pruned nodes.dmp: %v", err)
+	}
+	var taxids []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		taxids = append(taxids, strings.TrimSpace(strings.SplitN(line, "|", 2)[0]))
+	}
+	if got := strings.Join(taxids, ","); got != "1,2,3,5,6" {
+		t.Fatalf("pruned taxids=%s want 1,2,3,5,6", got)
+	}
+
+	fasta := filepath.Join(tmp, "keep.fasta")
+	writeTestFasta(t, fasta, ">P2\nACGT\n")
+	ids, err := loadIDList(fasta)
+	if err != nil {
+		t.Fatalf("loadIDList fasta: %v", err)
+	}
+	if _, ok := ids["P2"]; !ok || len(ids) != 1 {
+		t.Fatalf("fasta ids=%v want P2", ids)
+	}
+}
@@ -2,50 +2,183 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-type qcConfig struct {
-	MinLen       int
-	MaxLen       int
-	MaxN         int
-	MaxAmbig     int
-	MaxInvalid   int
-	DedupeSeqs   bool
-	DedupeIDs    bool
-	RequireRanks []string
-	TaxdumpDir   string
-	TaxidMapPath string
-	OutputPath   string
-	ReportPath   string
+// LineageLookup resolves a taxid to its rank -> name lineage.
+type LineageLookup func(taxid int) map[string]string
+
+// QCConfig controls QC filtering; see QCStream and runQC.
+type QCConfig struct {
+	MinLen     int
+	MaxLen     int
+	MaxN       int
+	MaxAmbig   int
+	MaxInvalid int
+	// MinGC and MaxGC bound the GC percent over unambiguous bases; MinGC 0
+	// and a nil MaxGC leave that side unbounded.
+	MinGC float64
+	MaxGC *float64
+	// MaxHomopolymer rejects runs of one base longer than this (0 disables).
+	MaxHomopolymer int
+	// NormalizeCase uppercases bases and StripGaps drops '-' and '.' before
+	// any other sequence rule, so case/gap variants dedupe together.
+	NormalizeCase bool
+	StripGaps     bool
+	// TrimTerminalN strips leading/trailing N and '-' before any sequence rule.
+	TrimTerminalN bool
+	// MinMeanQual rejects FASTQ records below this mean Phred score (0 disables).
+	MinMeanQual float64
+	// ForwardPrimer/ReversePrimer are IUPAC primers trimmed (with everything
+	// outside them) before length checks; both given 5'->3'.
+	ForwardPrimer     string
+	ReversePrimer     string
+	PrimerMismatches  int
+	DropMissingPrimer bool
+	// PrimerMaxOffset is how far from its end of the read a primer match may
+	// start; 0 anchors primers to the read ends.
+	PrimerMaxOffset int
+	// LengthStatsBucket buckets the kept-length histogram (<=1 is exact).
+	LengthStatsBucket int
+	DedupeSeqs        bool
+	// DedupeRevComp keys sequence dedupe on min(seq, revcomp(seq)).
+	DedupeRevComp bool
+	DedupeIDs     bool
+	RequireRanks  []string
+	TaxdumpDir    string
+	TaxidMapPath  string
+	OutputPath    string
+	ReportPath    string
+	RejectsPath   string
+	// TaxIDs and Lineage preload the taxid map and rank lookup. qcFasta fills
+	// them from TaxidMapPath/TaxdumpDir when nil; QCStream requires them for
+	// rank checks.
+	TaxIDs  map[string]int
+	Lineage LineageLookup
+	// Rejects receives rejected records; qcFasta opens RejectsPath into it.
+	Rejects io.Writer
+	// ScorePath receives "processid<TAB>score" for every record (see
+	// qcScore); qcFasta opens it into Scores. Zero ScoreWeights use
+	// defaultQCScoreWeights.
+	ScorePath    string
+	Scores       io.Writer
+	ScoreWeights qcScoreWeights
 	Progress     bool
+	// OnEmptySeq is the empty-sequence policy (skip, keep, error); skipped
+	// records count as empty_sequence rejects.
+	OnEmptySeq string
+	// Workers runs the stateless sequence checks concurrently (<=1 is serial).
+	Workers int
+	// FilteredTaxonkitPath, with TaxonkitInput, makes qcFasta write the
+	// taxonkit TSV rows whose processid passed QC.
+	TaxonkitInput        string
+	FilteredTaxonkitPath string
+	// Passed, when non-nil, collects the IDs of written records.
+	Passed map[string]struct{}
+}
+
+// QCStats counts QC outcomes: every record read, those written, and one
+// counter per drop reason or trim step. QCStream returns it and qcFasta
+// writes it as the -report JSON.
+type QCStats struct {
+	Total                int `json:"total"`
+	Written              int `json:"written"`
+	MissingTaxID         int `json:"missing_taxid"`
+	MissingRanks         int `json:"missing_ranks"`
+	TooShort             int `json:"too_short"`
+	TooLong              int `json:"too_long"`
+	TooManyN             int `json:"too_many_n"`
+	TooManyAmbig         int `json:"too_many_ambig"`
+	TooManyInvalid       int `json:"too_many_invalid"`
+	GCFiltered           int `json:"gc_filtered"`
+	Homopolymer          int `json:"homopolymer"`
+	TrimmedN             int `json:"trimmed_terminal_n"`
+	LowQuality           int `json:"low_quality"`
+	Normalized           int `json:"normalized"`
+	EmptySeq             int `json:"empty_sequence"`
+	ForwardPrimerTrimmed int `json:"forward_primer_trimmed"`
+	ReversePrimerTrimmed int `json:"reverse_primer_trimmed"`
+	PrimerNotFound       int `json:"primer_not_found"`
+	// Lengths summarises cleaned lengths of written records.
+	Lengths *lengthStats `json:"length_stats,omitempty"`
+	DupeSeq int          `json:"duplicate_sequence"`
+	DupeID  int          `json:"duplicate_id"`
+	// Config is the effective QCConfig of the run that wrote the report.
+	Config *qcReportConfig `json:"config,omitempty"`
+}
+
+// qcReportConfig is the part of QCConfig that decides which records QC keeps
+// and how it rewrites them. split -reuse-qc compares it against the current
+// settings before reusing an earlier QC output.
+type qcReportConfig struct {
+	MinLen            int      `json:"min_length"`
+	MaxLen            int      `json:"max_length"`
+	MaxN              int      `json:"max_n"`
+	MaxAmbig          int      `json:"max_ambig"`
+	MaxInvalid        int      `json:"max_invalid"`
+	MinGC             float64  `json:"min_gc"`
+	MaxGC             *float64 `json:"max_gc"`
+	MaxHomopolymer    int      `json:"max_homopolymer"`
+	NormalizeCase     bool     `json:"normalize_case"`
+	StripGaps         bool     `json:"strip_gaps"`
+	TrimTerminalN     bool     `json:"trim_terminal_n"`
+	MinMeanQual       float64  `json:"min_mean_qual"`
+	ForwardPrimer     string   `json:"forward_primer"`
+	ReversePrimer     string   `json:"reverse_primer"`
+	PrimerMismatches  int      `json:"primer_mismatches"`
+	PrimerMaxOffset   int      `json:"primer_max_offset"`
+	DropMissingPrimer bool     `json:"drop_missing_primer"`
+	DedupeSeqs        bool     `json:"dedupe_seqs"`
+	DedupeRevComp     bool     `json:"dedupe_revcomp"`
+	DedupeIDs         bool     `json:"dedupe_ids"`
+	RequireRanks      []string `json:"require_ranks"`
+	TaxdumpDir        string   `json:"taxdump_dir"`
+	TaxidMapPath      string   `json:"taxid_map"`
+	OnEmptySeq        string   `json:"on_empty_seq"`
 }
 
-type qcStats struct {
-	Total          int `json:"total"`
-	Written        int `json:"written"`
-	MissingTaxID   int `json:"missing_taxid"`
-	MissingRanks   int `json:"missing_ranks"`
-	TooShort       int `json:"too_short"`
-	TooLong        int `json:"too_long"`
-	TooManyN       int `json:"too_many_n"`
-	TooManyAmbig   int `json:"too_many_ambig"`
-	TooManyInvalid int `json:"too_many_invalid"`
-	DupeSeq        int `json:"duplicate_sequence"`
-	DupeID         int `json:"duplicate_id"`
+func (cfg QCConfig) reportConfig() *qcReportConfig {
+	return &qcReportConfig{
+		MinLen:            cfg.MinLen,
+		MaxLen:            cfg.MaxLen,
+		MaxN:              cfg.MaxN,
+		MaxAmbig:          cfg.MaxAmbig,
+		MaxInvalid:        cfg.MaxInvalid,
+		MinGC:             cfg.MinGC,
+		MaxGC:             cfg.MaxGC,
+		MaxHomopolymer:    cfg.MaxHomopolymer,
+		NormalizeCase:     cfg.NormalizeCase,
+		StripGaps:         cfg.StripGaps,
+		TrimTerminalN:     cfg.TrimTerminalN,
+		MinMeanQual:       cfg.MinMeanQual,
+		ForwardPrimer:     cfg.ForwardPrimer,
+		ReversePrimer:     cfg.ReversePrimer,
+		PrimerMismatches:  cfg.PrimerMismatches,
+		PrimerMaxOffset:   cfg.PrimerMaxOffset,
+		DropMissingPrimer: cfg.DropMissingPrimer,
+		DedupeSeqs:        cfg.DedupeSeqs,
+		DedupeRevComp:     cfg.DedupeRevComp,
+		DedupeIDs:         cfg.DedupeIDs,
+		RequireRanks:      cfg.RequireRanks,
+		TaxdumpDir:        cfg.TaxdumpDir,
+		TaxidMapPath:      cfg.TaxidMapPath,
+		OnEmptySeq:        cfg.OnEmptySeq,
+	}
 }
 
 func runQC(args []string) {
 	fs := flag.NewFlagSet("qc", flag.ExitOnError)
-	input := fs.String("input", "", "Input FASTA/FASTA.gz")
-	output := fs.String("output", "", "Output FASTA path")
+	input := fs.String("input", "", "Input FASTA/FASTQ (optionally .gz), or a directory or glob of them")
+	output := fs.String("output", "", "Output FASTA path (output directory for a directory/glob -input)")
 	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory with nodes.dmp/names.dmp/taxid.map")
 	taxidMap := fs.String("taxid-map", "", "Optional taxid.map override")
 	requireRanks := fs.String("require-ranks", "kingdom,phylum,class,order,family,genus,species", "Comma-separated ranks required to keep a sequence (empty disables)")
@@ -54,10 +187,32 @@ func runQC(args []string) {
 	maxN := fs.Int("max-n", -1, "Maximum N count allowed (-1 disables)")
 	maxAmbig := fs.Int("max-ambig", -1, "Maximum IUPAC ambiguous count allowed (-1 disables)")
 	maxInvalid := fs.Int("max-invalid", 0, "Maximum invalid character count allowed")
+	minGC := fs.Float64("min-gc", 0, "Minimum GC percent over unambiguous bases (0 disables)")
+	maxGC := fs.Float64("max-gc", 100, "Maximum GC percent over unambiguous bases (100 disables)")
+	maxHomopolymer := fs.Int("max-homopolymer", 0, "Maximum single-base run length; ambiguity codes break runs (0 disables)")
+	minMeanQual := fs.Float64("min-mean-qual", 0, "Minimum mean Phred quality for FASTQ input (0 disables)")
+	forwardPrimer := fs.String("forward-primer", "", "IUPAC forward primer to trim from the 5' end (with anything upstream)")
+	reversePrimer := fs.String("reverse-primer", "", "IUPAC reverse primer (5'->3') whose reverse complement is trimmed from the 3' end")
+	primerMismatches := fs.Int("primer-mismatches", 2, "Mismatches allowed when locating primers")
+	primerMaxOffset := fs.Int("primer-max-offset", defaultPrimerMaxOffset, "Bases a primer match may sit from its end of the read")
+	lengthApprox := fs.Int("length-stats-approx", 0, "Bucket width for the length_stats histogram; stats are within width/2 (0 keeps exact lengths)")
+	dropMissingPrimer := fs.Bool("drop-missing-primer", false, "Drop records where a configured primer is not found")
+	normalizeCase := fs.Bool("normalize-case", false, "Uppercase every base before checks")
+	stripGaps := fs.Bool("strip-gaps", false, "Remove '-' and '.' gap characters before checks")
+	trimTerminalN := fs.Bool("trim-terminal-n", false, "Strip leading/trailing N and '-' before length and N checks")
 	dedupeSeqs := fs.Bool("dedupe", true, "Drop duplicate sequences (cleaned)")
+	dedupeRevComp := fs.Bool("dedupe-revcomp", false, "With -dedupe, also treat reverse complements as duplicates (first orientation kept)")
 	dedupeIDs := fs.Bool("dedupe-ids", true, "Drop duplicate sequence IDs")
 	progressOn := fs.Bool("progress", true, "Show progress bar (approximate)")
 	report := fs.String("report", "", "Optional JSON report output path")
+	rejects := fs.String("qc-rejects", "", "Optional FASTA path for rejected records (header annotated with reasons; a directory for a directory/glob -input)")
+	fs.StringVar(rejects, "rejects", "", "Alias for -qc-rejects")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
+	taxonkitIn := fs.String("taxonkit-input", "", "Taxonkit TSV to filter with -emit-filtered-taxonkit")
+	filteredTaxonkit := fs.String("emit-filtered-taxonkit", "", "Write the -taxonkit-input rows whose processid passed QC to this path")
+	qcScorePath := fs.String("qc-score", "", "Optional TSV of processid and a 0-100 quality score for every record (a directory for a directory/glob -input)")
+	qcScoreWeightsPath := fs.String("qc-score-weights", "", "Optional JSON weights for -qc-score ({\"length\":40,\"n\":20,\"ambig\":20,\"homopolymer\":20})")
+	workers := fs.Int("workers", 1, "Goroutines for per-record sequence checks (output order follows global -keep-order)")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
@@ -74,157 +229,207 @@ func runQC(args []string) {
 	if *maxInvalid < 0 {
 		fatalf("max-invalid must be >= 0")
 	}
+	if err := validateGCBounds(*minGC, maxGC); err != nil {
+		fatalf("%v", err)
+	}
+	if *maxHomopolymer < 0 {
+		fatalf("max-homopolymer must be >= 0")
+	}
+	if *lengthApprox < 0 {
+		fatalf("length-stats-approx must be >= 0")
+	}
+	emptyPolicy, err := normalizeEmptySeqPolicy(*onEmptySeq)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *minMeanQual < 0 {
+		fatalf("min-mean-qual must be >= 0")
+	}
+	scoreWeights, err := loadQCScoreWeights(*qcScoreWeightsPath)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if err := validatePrimerConfig("", normalizePrimer(*forwardPrimer), normalizePrimer(*reversePrimer), *primerMismatches, *primerMaxOffset); err != nil {
+		fatalf("%v", err)
+	}
 
-	cfg := qcConfig{
-		MinLen:       *minLen,
-		MaxLen:       *maxLen,
-		MaxN:         *maxN,
-		MaxAmbig:     *maxAmbig,
-		MaxInvalid:   *maxInvalid,
-		DedupeSeqs:   *dedupeSeqs,
-		DedupeIDs:    *dedupeIDs,
-		RequireRanks: splitList(*requireRanks),
-		TaxdumpDir:   *taxdumpDir,
-		TaxidMapPath: *taxidMap,
-		OutputPath:   *output,
-		ReportPath:   *report,
-		Progress:     *progressOn,
+	cfg := QCConfig{
+		MinLen:               *minLen,
+		MaxLen:               *maxLen,
+		MaxN:                 *maxN,
+		MaxAmbig:             *maxAmbig,
+		MaxInvalid:           *maxInvalid,
+		MinGC:                *minGC,
+		MaxGC:                maxGC,
+		MaxHomopolymer:       *maxHomopolymer,
+		NormalizeCase:        *normalizeCase,
+		StripGaps:            *stripGaps,
+		TrimTerminalN:        *trimTerminalN,
+		MinMeanQual:          *minMeanQual,
+		ForwardPrimer:        normalizePrimer(*forwardPrimer),
+		ReversePrimer:        normalizePrimer(*reversePrimer),
+		PrimerMismatches:     *primerMismatches,
+		PrimerMaxOffset:      *primerMaxOffset,
+		DropMissingPrimer:    *dropMissingPrimer,
+		LengthStatsBucket:    *lengthApprox,
+		DedupeSeqs:           *dedupeSeqs,
+		DedupeRevComp:        *dedupeRevComp,
+		DedupeIDs:            *dedupeIDs,
+		RequireRanks:         splitList(*requireRanks),
+		TaxdumpDir:           *taxdumpDir,
+		TaxidMapPath:         *taxidMap,
+		OutputPath:           *output,
+		ReportPath:           *report,
+		RejectsPath:          *rejects,
+		ScorePath:            *qcScorePath,
+		ScoreWeights:         scoreWeights,
+		Progress:             *progressOn,
+		OnEmptySeq:           emptyPolicy,
+		Workers:              *workers,
+		TaxonkitInput:        *taxonkitIn,
+		FilteredTaxonkitPath: *filteredTaxonkit,
+	}
+	if cfg.FilteredTaxonkitPath != "" && cfg.TaxonkitInput == "" {
+		fatalf("emit-filtered-taxonkit requires -taxonkit-input")
 	}
 
+	if isQCBatchInput(*input) {
+		inputs, err := listQCInputs(*input)
+		if err != nil {
+			fatalf("qc failed: %v", err)
+		}
+		if err := qcBatch(inputs, cfg); err != nil {
+			fatalf("qc failed: %v", err)
+		}
+		return
+	}
 	if err := qcFasta(*input, cfg); err != nil {
 		fatalf("qc failed: %v", err)
 	}
 }
 
-func qcFasta(input string, cfg qcConfig) error {
-	in, counter, err := openInputWithCounter(input)
-	if err != nil {
-		return fmt.Errorf("open input: %w", err)
-	}
-	defer func() {
-		_ = in.Close()
-	}()
+// QCStream filters FASTA/FASTQ records from r and writes the kept records to
+// w as cleaned FASTA. It opens no files: taxid and rank checks use the
+// preloaded cfg.TaxIDs and cfg.Lineage, and rejects go to cfg.Rejects.
+func QCStream(r io.Reader, w io.Writer, cfg QCConfig) (QCStats, error) {
+	return qcStream(r, w, cfg, nil)
+}
 
-	var bar *byteProgress
-	var lastCount int64
-	if cfg.Progress {
-		total := fileSize(input)
-		bar = newByteProgress(total, "qc (approx)")
+// qcStream is QCStream with a per-record hook, used for progress reporting.
+func qcStream(r io.Reader, w io.Writer, cfg QCConfig, onRecord func()) (QCStats, error) {
+	stats := QCStats{}
+	if len(cfg.RequireRanks) > 0 && (cfg.TaxIDs == nil || cfg.Lineage == nil) {
+		return stats, fmt.Errorf("qc: require-ranks needs a taxid map and lineage lookup")
 	}
-
-	if err := os.MkdirAll(filepath.Dir(cfg.OutputPath), 0o755); err != nil {
-		return fmt.Errorf("create output dir: %w", err)
+	if err := validateGCBounds(cfg.MinGC, cfg.MaxGC); err != nil {
+		return stats, fmt.Errorf("qc: %w", err)
 	}
-	out, err := os.Create(cfg.OutputPath)
-	if err != nil {
-		return fmt.Errorf("create output: %w", err)
+
+	writer := bufio.NewWriterSize(w, writerBufferSize)
+	var rejects *bufio.Writer
+	if cfg.Rejects != nil {
+		rejects = bufio.NewWriterSize(cfg.Rejects, writerBufferSize)
 	}
-	defer func() {
-		_ = out.Close()
-	}()
-	writer := bufio.NewWriterSize(out, writerBufferSize)
-	defer func() {
-		_ = writer.Flush()
-	}()
 
-	var taxidMap map[string]int
-	var dump *taxDump
-	if len(cfg.RequireRanks) > 0 || cfg.TaxidMapPath != "" {
-		taxidPath := cfg.TaxidMapPath
-		if taxidPath == "" {
-			taxidPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+	var scores *bufio.Writer
+	weights := cfg.ScoreWeights
+	if cfg.Scores != nil {
+		scores = bufio.NewWriterSize(cfg.Scores, writerBufferSize)
+		if _, err := scores.WriteString("processid\tscore\n"); err != nil {
+			return stats, fmt.Errorf("write qc scores: %w", err)
 		}
-		taxidMap, err = loadTaxidMap(taxidPath)
-		if err != nil {
-			return err
-		}
-	}
-	if len(cfg.RequireRanks) > 0 {
-		nodesPath := filepath.Join(cfg.TaxdumpDir, "nodes.dmp")
-		namesPath := filepath.Join(cfg.TaxdumpDir, "names.dmp")
-		dump, err = loadTaxDump(nodesPath, namesPath)
-		if err != nil {
-			return err
+		if weights == (qcScoreWeights{}) {
+			weights = defaultQCScoreWeights
 		}
 	}
 
-	stats := qcStats{}
 	seenSeqs := make(map[string]struct{})
 	seenIDs := make(map[string]struct{})
+	lengths := newLengthHistogram(cfg.LengthStatsBucket)
 
-	err = parseFasta(in, func(rec fastaRecord) error {
+	reject := func(rec fastaRecord, reasons []string, length int) error {
+		stats.addReject(reasons[0])
+		if onRecord != nil {
+			onRecord()
+		}
+		if rejects == nil {
+			return nil
+		}
+		return writeQCReject(rejects, rec, reasons, length)
+	}
+
+	err := runQCChecks(r, cfg, cfg.Workers, func(chk qcCheck) error {
+		rec := chk.rec
 		stats.Total++
+		if len(rec.seq) == 0 {
+			switch cfg.OnEmptySeq {
+			case emptySeqKeep:
+			case emptySeqError:
+				return fmt.Errorf("record %q has an empty sequence", rec.id)
+			default:
+				return reject(rec, []string{qcReasonEmptySeq}, 0)
+			}
+		}
 		if rec.id == "" {
-			stats.MissingTaxID++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+			return reject(rec, []string{qcReasonMissingTaxID}, len(rec.seq))
+		}
+		if scores != nil {
+			// Scored before any rule drops the record, so the file ranks
+			// the whole input, not only what passed.
+			if _, err := scores.WriteString(rec.id + "\t" + formatQCScore(qcScore(chk, cfg, weights)) + "\n"); err != nil {
+				return fmt.Errorf("write qc scores: %w", err)
+			}
 		}
 		if cfg.DedupeIDs {
 			if _, ok := seenIDs[rec.id]; ok {
-				stats.DupeID++
-				updateByteProgress(bar, counter, &lastCount)
-				return nil
+				return reject(rec, []string{qcReasonDupeID}, len(rec.seq))
 			}
 			seenIDs[rec.id] = struct{}{}
 		}
 
 		var taxid int
-		if taxidMap != nil {
+		if cfg.TaxIDs != nil {
 			var ok bool
-			taxid, ok = taxidMap[rec.id]
+			taxid, ok = cfg.TaxIDs[rec.id]
 			if !ok {
-				stats.MissingTaxID++
-				updateByteProgress(bar, counter, &lastCount)
-				return nil
+				return reject(rec, []string{qcReasonMissingTaxID}, len(rec.seq))
 			}
 		}
 
-		if len(cfg.RequireRanks) > 0 && dump != nil {
-			lineage := dump.lineage(taxid)
+		if len(cfg.RequireRanks) > 0 {
+			lineage := cfg.Lineage(taxid)
 			if !hasAllRanks(lineage, cfg.RequireRanks) {
-				stats.MissingRanks++
-				updateByteProgress(bar, counter, &lastCount)
-				return nil
+				return reject(rec, []string{qcReasonMissingRanks}, len(rec.seq))
 			}
 		}
 
-		clean, counts := cleanSequence(rec.seq)
-		if len(clean) == 0 {
-			stats.TooShort++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		if chk.normalized {
+			stats.Normalized++
 		}
-		if cfg.MinLen > 0 && len(clean) < cfg.MinLen {
-			stats.TooShort++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		if chk.trimmed {
+			stats.TrimmedN++
 		}
-		if cfg.MaxLen > 0 && len(clean) > cfg.MaxLen {
-			stats.TooLong++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		if chk.fwdPrimer {
+			stats.ForwardPrimerTrimmed++
 		}
-		if cfg.MaxN >= 0 && counts.n > cfg.MaxN {
-			stats.TooManyN++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		if chk.revPrimer {
+			stats.ReversePrimerTrimmed++
 		}
-		if cfg.MaxAmbig >= 0 && counts.ambig > cfg.MaxAmbig {
-			stats.TooManyAmbig++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		if chk.primerMissing {
+			stats.PrimerNotFound++
 		}
-		if counts.invalid > cfg.MaxInvalid {
-			stats.TooManyInvalid++
-			updateByteProgress(bar, counter, &lastCount)
-			return nil
+		rec.seq = chk.seq
+		clean := chk.clean
+		if len(chk.reasons) > 0 {
+			return reject(rec, chk.reasons, len(clean))
 		}
 		if cfg.DedupeSeqs {
 			key := string(clean)
+			if cfg.DedupeRevComp {
+				key = canonicalSeqKey(clean)
+			}
 			if _, ok := seenSeqs[key]; ok {
-				stats.DupeSeq++
-				updateByteProgress(bar, counter, &lastCount)
-				return nil
+				return reject(rec, []string{qcReasonDupeSeq}, len(clean))
 			}
 			seenSeqs[key] = struct{}{}
 		}
@@ -239,24 +444,363 @@ func qcFasta(input string, cfg qcConfig) error {
 			return fmt.Errorf("write newline: %w", err)
 		}
 		stats.Written++
-		updateByteProgress(bar, counter, &lastCount)
+		if cfg.Passed != nil {
+			cfg.Passed[rec.id] = struct{}{}
+		}
+		lengths.add(len(clean))
+		if onRecord != nil {
+			onRecord()
+		}
 		return nil
 	})
 	if err != nil {
-		return err
+		return stats, err
 	}
-	updateByteProgress(bar, counter, &lastCount)
-	if bar != nil {
-		bar.Finish()
+	if err := writer.Flush(); err != nil {
+		return stats, fmt.Errorf("flush output: %w", err)
+	}
+	if rejects != nil {
+		if err := rejects.Flush(); err != nil {
+			return stats, fmt.Errorf("flush rejects: %w", err)
+		}
+	}
+	if scores != nil {
+		if err := scores.Flush(); err != nil {
+			return stats, fmt.Errorf("flush qc scores: %w", err)
+		}
+	}
+	lengthSummary := lengths.summary()
+	stats.Lengths = &lengthSummary
+	return stats, nil
+}
+
+func qcFasta(input string, cfg QCConfig) error {
+	_, err := qcFastaStats(input, cfg)
+	return err
+}
+
+// qcFastaStats is qcFasta returning the run's stats.
+func qcFastaStats(input string, cfg QCConfig) (QCStats, error) {
+	in, counter, err := openInputWithCounter(input)
+	if err != nil {
+		return QCStats{}, fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	var bar *byteProgress
+	var lastCount int64
+	if cfg.Progress {
+		bar = newInputProgress(input, "qc (approx)")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.OutputPath), 0o755); err != nil {
+		return QCStats{}, fmt.Errorf("create output dir: %w", err)
+	}
+	out, err := createOutput(cfg.OutputPath)
+	if err != nil {
+		return QCStats{}, fmt.Errorf("create output: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	if err := loadQCTaxonomy(&cfg); err != nil {
+		return QCStats{}, err
+	}
+	if cfg.FilteredTaxonkitPath != "" && cfg.Passed == nil {
+		cfg.Passed = make(map[string]struct{}, 1<<16)
+	}
+
+	if cfg.RejectsPath != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.RejectsPath), 0o755); err != nil {
+			return QCStats{}, fmt.Errorf("create rejects dir: %w", err)
+		}
+		rf, err := createOutput(cfg.RejectsPath)
+		if err != nil {
+			return QCStats{}, fmt.Errorf("create rejects: %w", err)
+		}
+		defer func() {
+			_ = rf.Close()
+		}()
+		cfg.Rejects = rf
+	}
+	var scores *reportWriter
+	if cfg.ScorePath != "" {
+		scores, err = createReport(cfg.ScorePath)
+		if err != nil {
+			return QCStats{}, fmt.Errorf("create qc scores: %w", err)
+		}
+		defer func() {
+			_ = scores.Close()
+		}()
+		cfg.Scores = scores
+	}
+
+	stats, err := qcStream(in, out, cfg, func() {
+		updateByteProgress(bar, counter, &lastCount)
+	})
+	if err != nil {
+		return stats, err
+	}
+	finishByteProgress(bar, counter, &lastCount)
+	if scores != nil {
+		if err := scores.Close(); err != nil {
+			return stats, err
+		}
+		keepOutputs(scores.path)
+	}
+	keepOutputs(cfg.OutputPath, cfg.RejectsPath)
+	if cfg.FilteredTaxonkitPath != "" {
+		if err := writeFilteredTaxonkit(cfg.TaxonkitInput, cfg.FilteredTaxonkitPath, cfg.Passed); err != nil {
+			return stats, err
+		}
 	}
 
 	if cfg.ReportPath != "" {
+		stats.Config = cfg.reportConfig()
 		if err := writeQCReport(cfg.ReportPath, stats); err != nil {
+			return stats, err
+		}
+	}
+	logf("qc: total=%d kept=%d drop taxid=%d ranks=%d short=%d long=%d n=%d ambig=%d invalid=%d gc=%d homopolymer=%d dup-seq=%d dup-id=%d empty=%d low-qual=%d normalized=%d trimmed-n=%d primer-fwd=%d primer-rev=%d primer-missing=%d",
+		stats.Total, stats.Written, stats.MissingTaxID, stats.MissingRanks, stats.TooShort, stats.TooLong, stats.TooManyN, stats.TooManyAmbig, stats.TooManyInvalid, stats.GCFiltered, stats.Homopolymer, stats.DupeSeq, stats.DupeID, stats.EmptySeq, stats.LowQuality, stats.Normalized, stats.TrimmedN, stats.ForwardPrimerTrimmed, stats.ReversePrimerTrimmed, stats.PrimerNotFound)
+	lengths := stats.Lengths
+	logf("qc: lengths min=%d median=%d max=%d n50=%d l50=%d", lengths.Min, lengths.Median, lengths.Max, lengths.N50, lengths.L50)
+	return stats, nil
+}
+
+// writeFilteredTaxonkit copies the header and the rows of the taxonkit TSV
+// whose processid is in keep, so create-taxdump sees only QC-passing records.
+func writeFilteredTaxonkit(input, output string, keep map[string]struct{}) error {
+	if input == "" {
+		return fmt.Errorf("emit-filtered-taxonkit requires -taxonkit-input")
+	}
+	in, err := openInput(input)
+	if err != nil {
+		return fmt.Errorf("open taxonkit input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return fmt.Errorf("create filtered taxonkit dir: %w", err)
+	}
+	f, err := createOutput(output)
+	if err != nil {
+		return fmt.Errorf("create filtered taxonkit: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	w := bufio.NewWriterSize(f, writerBufferSize)
+
+	idxProcess := -1
+	var rows, kept int
+	err = ParseTSV(in, DefaultOptions(), func(row Row) error {
+		if idxProcess < 0 {
+			idxProcess = indexOfBytes(row.Fields, "processid")
+			if idxProcess < 0 {
+				return fmt.Errorf("required header missing in taxonkit input (processid)")
+			}
+		} else {
+			rows++
+			if _, ok := keep[string(fieldBytes(row.Fields, idxProcess))]; !ok {
+				return nil
+			}
+			kept++
+		}
+		if _, err := w.Write(bytes.Join(row.Fields, []byte{'\t'})); err != nil {
+			return fmt.Errorf("write filtered taxonkit: %w", err)
+		}
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush filtered taxonkit: %w", err)
+	}
+	keepOutputs(output)
+	logf("qc: filtered taxonkit rows=%d kept=%d -> %s", rows, kept, output)
+	return nil
+}
+
+// loadQCTaxonomy fills cfg.TaxIDs and cfg.Lineage from the taxdump when the
+// configured checks need them and they are not preloaded.
+func loadQCTaxonomy(cfg *QCConfig) error {
+	if cfg.TaxIDs == nil && (len(cfg.RequireRanks) > 0 || cfg.TaxidMapPath != "") {
+		taxidPath := cfg.TaxidMapPath
+		if taxidPath == "" {
+			taxidPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+		}
+		taxIDs, err := loadTaxidMap(taxidPath)
+		if err != nil {
 			return err
 		}
+		cfg.TaxIDs = taxIDs
+	}
+	if cfg.Lineage == nil && len(cfg.RequireRanks) > 0 {
+		nodesPath := filepath.Join(cfg.TaxdumpDir, "nodes.dmp")
+		namesPath := filepath.Join(cfg.TaxdumpDir, "names.dmp")
+		dump, err := loadTaxDump(nodesPath, namesPath)
+		if err != nil {
+			return err
+		}
+		cfg.Lineage = dump.lineage
+	}
+	return nil
+}
+
+const (
+	qcReasonMissingTaxID   = "missing_taxid"
+	qcReasonMissingRanks   = "missing_ranks"
+	qcReasonTooShort       = "too_short"
+	qcReasonTooLong        = "too_long"
+	qcReasonTooManyN       = "too_many_n"
+	qcReasonTooManyAmbig   = "too_many_ambig"
+	qcReasonTooManyInvalid = "too_many_invalid"
+	qcReasonGC             = "gc_filtered"
+	qcReasonHomopolymer    = "homopolymer"
+	qcReasonLowQuality     = "low_quality"
+	qcReasonEmptySeq       = "empty_sequence"
+	qcReasonPrimerMissing  = "primer_not_found"
+	qcReasonDupeSeq        = "duplicate_sequence"
+	qcReasonDupeID         = "duplicate_id"
+)
+
+// addReject counts a dropped record under its primary (first) reason.
+func (s *QCStats) addReject(reason string) {
+	switch reason {
+	case qcReasonMissingTaxID:
+		s.MissingTaxID++
+	case qcReasonMissingRanks:
+		s.MissingRanks++
+	case qcReasonTooShort:
+		s.TooShort++
+	case qcReasonTooLong:
+		s.TooLong++
+	case qcReasonTooManyN:
+		s.TooManyN++
+	case qcReasonTooManyAmbig:
+		s.TooManyAmbig++
+	case qcReasonTooManyInvalid:
+		s.TooManyInvalid++
+	case qcReasonGC:
+		s.GCFiltered++
+	case qcReasonHomopolymer:
+		s.Homopolymer++
+	case qcReasonLowQuality:
+		s.LowQuality++
+	case qcReasonEmptySeq:
+		s.EmptySeq++
+	case qcReasonPrimerMissing:
+		// Already tallied in PrimerNotFound whether or not the record drops.
+	case qcReasonDupeSeq:
+		s.DupeSeq++
+	case qcReasonDupeID:
+		s.DupeID++
+	}
+}
+
+// qcSequenceReasons returns every sequence-level rule the record fails, in
+// evaluation order. Most rules look at the cleaned sequence; the homopolymer
+// rule scans raw so that ambiguity codes still break runs.
+func qcSequenceReasons(raw, clean []byte, counts seqCounts, cfg QCConfig) []string {
+	var reasons []string
+	if len(clean) == 0 || (cfg.MinLen > 0 && len(clean) < cfg.MinLen) {
+		reasons = append(reasons, qcReasonTooShort)
+	}
+	if cfg.MaxLen > 0 && len(clean) > cfg.MaxLen {
+		reasons = append(reasons, qcReasonTooLong)
+	}
+	if cfg.MaxN >= 0 && counts.n > cfg.MaxN {
+		reasons = append(reasons, qcReasonTooManyN)
+	}
+	if cfg.MaxAmbig >= 0 && counts.ambig > cfg.MaxAmbig {
+		reasons = append(reasons, qcReasonTooManyAmbig)
+	}
+	if counts.invalid > cfg.MaxInvalid {
+		reasons = append(reasons, qcReasonTooManyInvalid)
+	}
+	if gcFilterEnabled(cfg) && len(clean) > 0 {
+		gc := gcPercent(clean)
+		if gc < cfg.MinGC || (cfg.MaxGC != nil && gc > *cfg.MaxGC) {
+			reasons = append(reasons, qcReasonGC)
+		}
+	}
+	if cfg.MaxHomopolymer > 0 && longestHomopolymer(raw) > cfg.MaxHomopolymer {
+		reasons = append(reasons, qcReasonHomopolymer)
+	}
+	return reasons
+}
+
+// longestHomopolymer returns the longest run of one A/C/G/T base
+// (case-insensitive). Any other character ends the current run.
+func longestHomopolymer(seq []byte) int {
+	longest := 0
+	run := 0
+	var prev byte
+	for _, c := range seq {
+		if c >= 'a' && c <= 'z' {
+			c -= 32
+		}
+		switch c {
+		case 'A', 'C', 'G', 'T':
+			if c == prev {
+				run++
+			} else {
+				prev = c
+				run = 1
+			}
+		default:
+			prev = 0
+			run = 0
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// gcFilterEnabled reports whether GC bounds are narrower than 0-100.
+func gcFilterEnabled(cfg QCConfig) bool {
+	return cfg.MinGC > 0 || (cfg.MaxGC != nil && *cfg.MaxGC < 100)
+}
+
+// gcPercent returns the G+C share of a cleaned (A/C/G/T only) sequence.
+func gcPercent(clean []byte) float64 {
+	if len(clean) == 0 {
+		return 0
+	}
+	gc := 0
+	for _, c := range clean {
+		if c == 'G' || c == 'C' {
+			gc++
+		}
+	}
+	return float64(gc) * 100 / float64(len(clean))
+}
+
+// validateGCBounds checks 0 <= minGC <= maxGC <= 100; a nil maxGC is 100.
+func validateGCBounds(minGC float64, maxGC *float64) error {
+	upper := 100.0
+	if maxGC != nil {
+		upper = *maxGC
+	}
+	if minGC < 0 || upper < 0 || upper > 100 || minGC > upper {
+		return fmt.Errorf("gc bounds must satisfy 0 <= min-gc <= max-gc <= 100")
+	}
+	return nil
+}
+
+func writeQCReject(w *bufio.Writer, rec fastaRecord, reasons []string, length int) error {
+	header := rec.id + " reason=" + strings.Join(reasons, ",") + " len=" + strconv.Itoa(length)
+	if err := writeFasta(w, header, rec.seq); err != nil {
+		return fmt.Errorf("write reject: %w", err)
 	}
-	logf("qc: total=%d kept=%d drop taxid=%d ranks=%d short=%d long=%d n=%d ambig=%d invalid=%d dup-seq=%d dup-id=%d",
-		stats.Total, stats.Written, stats.MissingTaxID, stats.MissingRanks, stats.TooShort, stats.TooLong, stats.TooManyN, stats.TooManyAmbig, stats.TooManyInvalid, stats.DupeSeq, stats.DupeID)
 	return nil
 }
 
@@ -266,6 +810,86 @@ type seqCounts struct {
 	invalid int
 }
 
+// normalizeSequence uppercases and/or strips gap characters. It returns seq
+// itself when nothing changes.
+func normalizeSequence(seq []byte, upper, stripGaps bool) ([]byte, bool) {
+	changed := false
+	for _, c := range seq {
+		if (upper && c >= 'a' && c <= 'z') || (stripGaps && (c == '-' || c == '.')) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return seq, false
+	}
+	out := make([]byte, 0, len(seq))
+	for _, c := range seq {
+		if stripGaps && (c == '-' || c == '.') {
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 32
+		}
+		out = append(out, c)
+	}
+	return out, true
+}
+
+// canonicalSeqKey returns the lexicographically smaller of a cleaned ACGT
+// sequence and its reverse complement.
+func canonicalSeqKey(clean []byte) string {
+	rc := make([]byte, len(clean))
+	for i, c := range clean {
+		var comp byte
+		switch c {
+		case 'A':
+			comp = 'T'
+		case 'C':
+			comp = 'G'
+		case 'G':
+			comp = 'C'
+		case 'T':
+			comp = 'A'
+		default:
+			comp = c
+		}
+		rc[len(clean)-1-i] = comp
+	}
+	if bytes.Compare(rc, clean) < 0 {
+		return string(rc)
+	}
+	return string(clean)
+}
+
+// terminalNBounds returns the [start, end) span of seq left after stripping
+// leading and trailing N/'-' padding.
+func terminalNBounds(seq []byte) (int, int) {
+	isPad := func(c byte) bool {
+		return c == 'N' || c == 'n' || c == '-'
+	}
+	start, end := 0, len(seq)
+	for start < end && isPad(seq[start]) {
+		start++
+	}
+	for end > start && isPad(seq[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+// stripGapQual drops the qualities of the '-' and '.' gaps in seq, matching
+// normalizeSequence with stripGaps.
+func stripGapQual(seq, qual []byte) []byte {
+	out := make([]byte, 0, len(qual))
+	for i, c := range seq {
+		if c != '-' && c != '.' {
+			out = append(out, qual[i])
+		}
+	}
+	return out
+}
+
 func cleanSequence(seq []byte) ([]byte, seqCounts) {
 	clean := make([]byte, 0, len(seq))
 	counts := seqCounts{}
@@ -314,7 +938,7 @@ func loadTaxidMap(path string) (map[string]int, error) {
 		_ = f.Close()
 	}()
 	out := make(map[string]int, 1<<20)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 	for scanner.Scan() {
@@ -345,21 +969,21 @@ func loadTaxidMap(path string) (map[string]int, error) {
 	return out, nil
 }
 
-func writeQCReport(path string, stats qcStats) error {
+func writeQCReport(path string, stats QCStats) error {
+	return writeJSONReport(path, stats)
+}
+
+// writeJSONReport writes v as indented JSON, creating parent directories.
+func writeJSONReport(path string, v any) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("create report dir: %w", err)
 	}
-	f, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("create report: %w", err)
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(stats); err != nil {
-		return fmt.Errorf("write report: %w", err)
-	}
-	return nil
+	return writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		return nil
+	})
 }
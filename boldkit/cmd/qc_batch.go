@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// qcBatchReport is the combined -report for a directory or glob -input.
+// Total sums the per-file counters; it has no length_stats because the
+// per-file histograms are not merged.
+type qcBatchReport struct {
+	Files map[string]QCStats `json:"files"`
+	Total QCStats            `json:"total"`
+}
+
+// isQCBatchInput reports whether -input names several files: a directory or
+// a glob pattern.
+func isQCBatchInput(input string) bool {
+	if strings.ContainsAny(input, "*?[") {
+		return true
+	}
+	info, err := os.Stat(input)
+	return err == nil && info.IsDir()
+}
+
+// listQCInputs expands a glob, or walks a directory for FASTA/FASTQ files
+// (optionally .gz), in sorted order.
+func listQCInputs(input string) ([]string, error) {
+	var files []string
+	if strings.ContainsAny(input, "*?[") {
+		matches, err := filepath.Glob(input)
+		if err != nil {
+			return nil, fmt.Errorf("glob %s: %w", input, err)
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				files = append(files, m)
+			}
+		}
+	} else {
+		err := filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && qcInputName(path) != filepath.Base(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no FASTA/FASTQ inputs found for %s", input)
+	}
+	return files, nil
+}
+
+// qcInputName strips a FASTA/FASTQ extension (and .gz) from the file name;
+// other names come back unchanged.
+func qcInputName(path string) string {
+	base := filepath.Base(path)
+	trimmed := strings.TrimSuffix(base, ".gz")
+	for _, ext := range []string{".fasta", ".fa", ".fna", ".fas", ".fastq", ".fq"} {
+		if strings.HasSuffix(strings.ToLower(trimmed), ext) {
+			return trimmed[:len(trimmed)-len(ext)]
+		}
+	}
+	return base
+}
+
+// qcBatch runs qcFasta over each input. cfg.OutputPath (and RejectsPath and
+// ScorePath when set) are directories receiving <name>.fasta,
+// <name>.rejects.fasta and <name>.scores.tsv;
+// cfg.ReportPath receives the combined qcBatchReport. The taxonomy is loaded
+// once and shared.
+func qcBatch(inputs []string, cfg QCConfig) error {
+	names := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		name := qcInputName(input)
+		if prev, ok := names[name]; ok {
+			return fmt.Errorf("inputs %s and %s both map to output %s.fasta", prev, input, name)
+		}
+		names[name] = input
+	}
+	if err := loadQCTaxonomy(&cfg); err != nil {
+		return err
+	}
+	// One filtered taxonkit TSV covers every input.
+	filteredTaxonkit := cfg.FilteredTaxonkitPath
+	cfg.FilteredTaxonkitPath = ""
+	if filteredTaxonkit != "" && cfg.Passed == nil {
+		cfg.Passed = make(map[string]struct{}, 1<<16)
+	}
+
+	report := qcBatchReport{Files: make(map[string]QCStats, len(inputs))}
+	for _, input := range inputs {
+		name := qcInputName(input)
+		fileCfg := cfg
+		fileCfg.OutputPath = filepath.Join(cfg.OutputPath, name+".fasta")
+		fileCfg.ReportPath = ""
+		if cfg.RejectsPath != "" {
+			fileCfg.RejectsPath = filepath.Join(cfg.RejectsPath, name+".rejects.fasta")
+		}
+		if cfg.ScorePath != "" {
+			fileCfg.ScorePath = filepath.Join(cfg.ScorePath, name+".scores.tsv")
+		}
+		logf("qc: %s -> %s", input, fileCfg.OutputPath)
+		stats, err := qcFastaStats(input, fileCfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		stats.Lengths = nil
+		report.Files[name] = stats
+		report.Total.add(stats)
+	}
+
+	if filteredTaxonkit != "" {
+		if err := writeFilteredTaxonkit(cfg.TaxonkitInput, filteredTaxonkit, cfg.Passed); err != nil {
+			return err
+		}
+	}
+
+	t := report.Total
+	logf("qc: %d files total=%d kept=%d dropped=%d", len(inputs), t.Total, t.Written, t.Total-t.Written)
+	if cfg.ReportPath != "" {
+		return writeJSONReport(cfg.ReportPath, report)
+	}
+	return nil
+}
+
+// add sums o's counters into s. Lengths is left alone.
+func (s *QCStats) add(o QCStats) {
+	s.Total += o.Total
+	s.Written += o.Written
+	s.MissingTaxID += o.MissingTaxID
+	s.MissingRanks += o.MissingRanks
+	s.TooShort += o.TooShort
+	s.TooLong += o.TooLong
+	s.TooManyN += o.TooManyN
+	s.TooManyAmbig += o.TooManyAmbig
+	s.TooManyInvalid += o.TooManyInvalid
+	s.GCFiltered += o.GCFiltered
+	s.Homopolymer += o.Homopolymer
+	s.TrimmedN += o.TrimmedN
+	s.LowQuality += o.LowQuality
+	s.Normalized += o.Normalized
+	s.EmptySeq += o.EmptySeq
+	s.ForwardPrimerTrimmed += o.ForwardPrimerTrimmed
+	s.ReversePrimerTrimmed += o.ReversePrimerTrimmed
+	s.PrimerNotFound += o.PrimerNotFound
+	s.DupeSeq += o.DupeSeq
+	s.DupeID += o.DupeID
+}
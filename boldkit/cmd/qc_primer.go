@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// iupacMask maps a nucleotide code to a bitmask of the bases it allows
+// (A=1, C=2, G=4, T=8). Unknown characters map to 0.
+func iupacMask(c byte) byte {
+	switch c {
+	case 'A', 'a':
+		return 1
+	case 'C', 'c':
+		return 2
+	case 'G', 'g':
+		return 4
+	case 'T', 't', 'U', 'u':
+		return 8
+	case 'R', 'r':
+		return 1 | 4
+	case 'Y', 'y':
+		return 2 | 8
+	case 'S', 's':
+		return 2 | 4
+	case 'W', 'w':
+		return 1 | 8
+	case 'K', 'k':
+		return 4 | 8
+	case 'M', 'm':
+		return 1 | 2
+	case 'B', 'b':
+		return 2 | 4 | 8
+	case 'D', 'd':
+		return 1 | 4 | 8
+	case 'H', 'h':
+		return 1 | 2 | 8
+	case 'V', 'v':
+		return 1 | 2 | 4
+	case 'N', 'n':
+		return 1 | 2 | 4 | 8
+	}
+	return 0
+}
+
+// complementMask swaps A<->T and C<->G within a mask.
+func complementMask(m byte) byte {
+	return (m&1)<<3 | (m&8)>>3 | (m&2)<<1 | (m&4)>>1
+}
+
+// primerMasks converts an IUPAC primer to masks, reverse-complemented when rc
+// is set so a reverse primer can be matched on the forward strand.
+func primerMasks(primer string, rc bool) []byte {
+	masks := make([]byte, len(primer))
+	for i := 0; i < len(primer); i++ {
+		m := iupacMask(primer[i])
+		if rc {
+			masks[len(primer)-1-i] = complementMask(m)
+		} else {
+			masks[i] = m
+		}
+	}
+	return masks
+}
+
+func validatePrimer(flagName, primer string) error {
+	for i := 0; i < len(primer); i++ {
+		if iupacMask(primer[i]) == 0 {
+			return fmt.Errorf("%s: invalid IUPAC character %q", flagName, primer[i])
+		}
+	}
+	return nil
+}
+
+// primerMatchAt reports whether masks match seq at offset with at most
+// maxMismatch mismatches. A sequence base matches when every base it allows is
+// allowed by the primer, so an N in the read never matches a specific base.
+func primerMatchAt(seq, masks []byte, offset, maxMismatch int) bool {
+	mismatches := 0
+	for i, pm := range masks {
+		sm := iupacMask(seq[offset+i])
+		if sm == 0 || sm&pm != sm {
+			mismatches++
+			if mismatches > maxMismatch {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// defaultPrimerMaxOffset is the -primer-max-offset default.
+const defaultPrimerMaxOffset = 10
+
+// trimPrimers locates the forward primer and everything upstream of its
+// leftmost match, then the reverse primer (matched as its reverse complement)
+// and everything downstream of its rightmost match, and returns the [start,
+// end) span of seq left between them. Matches must start within
+// cfg.PrimerMaxOffset bases of their end of the read, so a primer-like stretch
+// inside the amplicon is never taken for the primer. missing is set when a
+// configured primer was not found.
+func trimPrimers(seq []byte, cfg QCConfig) (start, end int, fwd, rev, missing bool) {
+	start, end = 0, len(seq)
+	if cfg.ForwardPrimer != "" {
+		masks := primerMasks(cfg.ForwardPrimer, false)
+		found := false
+		for i := 0; i <= cfg.PrimerMaxOffset && i+len(masks) <= end; i++ {
+			if primerMatchAt(seq, masks, i, cfg.PrimerMismatches) {
+				start = i + len(masks)
+				found = true
+				break
+			}
+		}
+		fwd = found
+		missing = !found
+	}
+	if cfg.ReversePrimer != "" {
+		masks := primerMasks(cfg.ReversePrimer, true)
+		found := false
+		for i := end - len(masks); i >= start && i >= len(seq)-len(masks)-cfg.PrimerMaxOffset; i-- {
+			if primerMatchAt(seq, masks, i, cfg.PrimerMismatches) {
+				end = i
+				found = true
+				break
+			}
+		}
+		rev = found
+		missing = missing || !found
+	}
+	return start, end, fwd, rev, missing
+}
+
+func normalizePrimer(primer string) string {
+	return strings.ToUpper(strings.TrimSpace(primer))
+}
+
+// validatePrimerConfig checks primer strings, the mismatch budget and the
+// match offset. prefix is the flag prefix used in error messages ("" or
+// "qc-").
+func validatePrimerConfig(prefix, forward, reverse string, mismatches, maxOffset int) error {
+	if err := validatePrimer(prefix+"forward-primer", forward); err != nil {
+		return err
+	}
+	if err := validatePrimer(prefix+"reverse-primer", reverse); err != nil {
+		return err
+	}
+	if mismatches < 0 {
+		return fmt.Errorf("%sprimer-mismatches must be >= 0", prefix)
+	}
+	if maxOffset < 0 {
+		return fmt.Errorf("%sprimer-max-offset must be >= 0", prefix)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// qcScoreWeights weight the components of the -qc-score composite. Each
+// component is in [0,1]:
+//
+//   - length: 1 inside [-min-length, -max-length], else the ratio of the
+//     nearest bound to the cleaned length (or the reverse when too short)
+//   - n and ambig: 1 minus the N or IUPAC-ambiguous fraction of all bases
+//   - homopolymer: 1 while the longest run is within -max-homopolymer (or
+//     qcScoreHomopolymerRef when that is disabled), else limit/run
+//
+// The score is 100 * sum(weight*component) / sum(weight).
+type qcScoreWeights struct {
+	Length      float64 `json:"length"`
+	N           float64 `json:"n"`
+	Ambig       float64 `json:"ambig"`
+	Homopolymer float64 `json:"homopolymer"`
+}
+
+// defaultQCScoreWeights favour length, the metric most thresholds target.
+var defaultQCScoreWeights = qcScoreWeights{Length: 40, N: 20, Ambig: 20, Homopolymer: 20}
+
+// qcScoreHomopolymerRef is the run length tolerated by the homopolymer
+// component when -max-homopolymer is 0.
+const qcScoreHomopolymerRef = 8
+
+// loadQCScoreWeights reads a JSON object of weights; keys it omits keep
+// their defaults. An empty path returns the defaults.
+func loadQCScoreWeights(path string) (qcScoreWeights, error) {
+	weights := defaultQCScoreWeights
+	if path == "" {
+		return weights, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return weights, fmt.Errorf("read qc-score weights: %w", err)
+	}
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return weights, fmt.Errorf("parse qc-score weights: %w", err)
+	}
+	if weights.Length < 0 || weights.N < 0 || weights.Ambig < 0 || weights.Homopolymer < 0 {
+		return weights, fmt.Errorf("qc-score weights must be >= 0")
+	}
+	if weights.Length+weights.N+weights.Ambig+weights.Homopolymer == 0 {
+		return weights, fmt.Errorf("qc-score weights sum to 0")
+	}
+	return weights, nil
+}
+
+// qcScore is the 0-100 composite quality of a checked record.
+func qcScore(chk qcCheck, cfg QCConfig, weights qcScoreWeights) float64 {
+	length := len(chk.clean)
+	lengthPart := 1.0
+	switch {
+	case length == 0:
+		lengthPart = 0
+	case cfg.MinLen > 0 && length < cfg.MinLen:
+		lengthPart = float64(length) / float64(cfg.MinLen)
+	case cfg.MaxLen > 0 && length > cfg.MaxLen:
+		lengthPart = float64(cfg.MaxLen) / float64(length)
+	}
+
+	bases := length + chk.counts.n + chk.counts.ambig + chk.counts.invalid
+	nPart, ambigPart := 1.0, 1.0
+	if bases > 0 {
+		nPart = 1 - float64(chk.counts.n)/float64(bases)
+		ambigPart = 1 - float64(chk.counts.ambig)/float64(bases)
+	}
+
+	limit := cfg.MaxHomopolymer
+	if limit <= 0 {
+		limit = qcScoreHomopolymerRef
+	}
+	homopolymerPart := 1.0
+	if run := longestHomopolymer(chk.seq); run > limit {
+		homopolymerPart = float64(limit) / float64(run)
+	}
+
+	total := weights.Length + weights.N + weights.Ambig + weights.Homopolymer
+	sum := weights.Length*lengthPart + weights.N*nPart + weights.Ambig*ambigPart + weights.Homopolymer*homopolymerPart
+	return 100 * sum / total
+}
+
+// formatQCScore renders a score for the -qc-score TSV.
+func formatQCScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 1, 64)
+}
@@ -0,0 +1,654 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFasta(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write fasta: %v", err)
+	}
+}
+
+func TestQCRejectsFileReasons(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	rejects := filepath.Join(tmp, "rejects.fasta")
+	writeTestFasta(t, input, ">SHORT\nACGT\n>MANYN\nACGTACGTNNACGT\n>PASS\nACGTACGTACGT\n")
+
+	err := qcFasta(input, QCConfig{
+		MinLen:      10,
+		MaxN:        0,
+		MaxAmbig:    -1,
+		OutputPath:  output,
+		RejectsPath: rejects,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+
+	data, err := os.ReadFile(rejects)
+	if err != nil {
+		t.Fatalf("read rejects: %v", err)
+	}
+	got := string(data)
+	want := ">SHORT reason=too_short len=4\nACGT\n>MANYN reason=too_many_n len=12\nACGTACGTNNACGT\n"
+	if got != want {
+		t.Fatalf("rejects mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(kept) != ">PASS\nACGTACGTACGT\n" {
+		t.Fatalf("unexpected QC output:\n%s", string(kept))
+	}
+}
+
+func TestQCScore(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	scores := filepath.Join(tmp, "scores.tsv")
+	writeTestFasta(t, input, ">PASS\nACGTACGTACGT\n>HALFN\nACGTACGTNN\n")
+
+	cfg := QCConfig{
+		MinLen:     10,
+		MaxN:       -1,
+		MaxAmbig:   -1,
+		OutputPath: filepath.Join(tmp, "qc.fasta"),
+		ScorePath:  scores,
+	}
+	if err := qcFasta(input, cfg); err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(scores)
+	if err != nil {
+		t.Fatalf("read scores: %v", err)
+	}
+	// HALFN: 8 clean bases of 10 give length 0.8 and n 0.8;
+	// 40*0.8 + 20*0.8 + 20 + 20 = 88.
+	if want := "processid\tscore\nPASS\t100.0\nHALFN\t88.0\n"; string(data) != want {
+		t.Fatalf("scores mismatch:\ngot:\n%s\nwant:\n%s", string(data), want)
+	}
+
+	weightsPath := filepath.Join(tmp, "weights.json")
+	writeTestFasta(t, weightsPath, `{"length":1,"n":1,"ambig":0,"homopolymer":0}`)
+	weights, err := loadQCScoreWeights(weightsPath)
+	if err != nil {
+		t.Fatalf("loadQCScoreWeights: %v", err)
+	}
+	cfg.ScoreWeights = weights
+	if err := qcFasta(input, cfg); err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	data, err = os.ReadFile(scores)
+	if err != nil {
+		t.Fatalf("read scores: %v", err)
+	}
+	if !strings.Contains(string(data), "HALFN\t80.0\n") {
+		t.Fatalf("custom weights not applied:\n%s", string(data))
+	}
+}
+
+func TestQCRejectsConcatenatesReasons(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	rejects := filepath.Join(tmp, "rejects.fasta")
+	writeTestFasta(t, input, ">BOTH\nACNGT\n")
+
+	err := qcFasta(input, QCConfig{
+		MinLen:      10,
+		MaxN:        0,
+		MaxAmbig:    -1,
+		OutputPath:  filepath.Join(tmp, "qc.fasta"),
+		RejectsPath: rejects,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(rejects)
+	if err != nil {
+		t.Fatalf("read rejects: %v", err)
+	}
+	if !strings.HasPrefix(string(data), ">BOTH reason=too_short,too_many_n len=4\n") {
+		t.Fatalf("expected concatenated reasons, got:\n%s", string(data))
+	}
+}
+
+func TestQCGCContentFilter(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	writeTestFasta(t, input, ">LOWGC\nACAAAAAAAA\n>MIDGC\nGCGCGCAAAA\n")
+
+	maxGC := 70.0
+	err := qcFasta(input, QCConfig{
+		MaxN:       -1,
+		MaxAmbig:   -1,
+		MinGC:      40,
+		MaxGC:      &maxGC,
+		OutputPath: output,
+		ReportPath: report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(kept) != ">MIDGC\nGCGCGCAAAA\n" {
+		t.Fatalf("expected only the 60%% GC record, got:\n%s", string(kept))
+	}
+	stats := readQCReport(t, report)
+	if stats.GCFiltered != 1 {
+		t.Fatalf("gc_filtered=%d want 1", stats.GCFiltered)
+	}
+}
+
+func TestQCMaxGCZero(t *testing.T) {
+	in := strings.NewReader(">NOGC\nAAAATTTT\n>SOMEGC\nGCAATTTT\n")
+	var out bytes.Buffer
+	maxGC := 0.0
+	stats, err := QCStream(in, &out, QCConfig{MaxN: -1, MaxAmbig: -1, MaxGC: &maxGC})
+	if err != nil {
+		t.Fatalf("QCStream failed: %v", err)
+	}
+	if out.String() != ">NOGC\nAAAATTTT\n" || stats.GCFiltered != 1 {
+		t.Fatalf("max-gc 0 kept %q (gc_filtered=%d), want only the 0%% GC record", out.String(), stats.GCFiltered)
+	}
+
+	maxGC = 150
+	if _, err := QCStream(strings.NewReader(">A\nACGT\n"), &out, QCConfig{MaxGC: &maxGC}); err == nil {
+		t.Fatalf("expected max-gc 150 to be rejected")
+	}
+}
+
+func TestQCPrimerInteriorMatchNotTrimmed(t *testing.T) {
+	fwd := "GGTCAACAAATCATAAAGATATTGG"
+	rev := "TAAACTTCAGGGTGACCAAAAAATCA"
+	revRC := "TGATTTTTTGGTCACCCTGAAGTTTA"
+	// The read lacks both primers at its ends but carries them well inside
+	// the amplicon, beyond -primer-max-offset.
+	seq := strings.Repeat("ACGT", 10) + fwd + strings.Repeat("TGCA", 5) + revRC + strings.Repeat("ACGT", 10)
+	var out bytes.Buffer
+	stats, err := QCStream(strings.NewReader(">INTERIOR\n"+seq+"\n"), &out, QCConfig{
+		MaxN:            -1,
+		MaxAmbig:        -1,
+		ForwardPrimer:   fwd,
+		ReversePrimer:   rev,
+		PrimerMaxOffset: defaultPrimerMaxOffset,
+	})
+	if err != nil {
+		t.Fatalf("QCStream failed: %v", err)
+	}
+	if out.String() != ">INTERIOR\n"+seq+"\n" {
+		t.Fatalf("interior primer-like match trimmed the read:\n%s", out.String())
+	}
+	if stats.ForwardPrimerTrimmed != 0 || stats.ReversePrimerTrimmed != 0 || stats.PrimerNotFound != 1 {
+		t.Fatalf("fwd=%d rev=%d missing=%d want 0/0/1", stats.ForwardPrimerTrimmed, stats.ReversePrimerTrimmed, stats.PrimerNotFound)
+	}
+}
+
+func readQCReport(t *testing.T, path string) QCStats {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var stats QCStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	return stats
+}
+
+func TestQCHomopolymerFilter(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	writeTestFasta(t, input, ">RUN\nACGTAAAAAAAAAAAAAAAAAAAACGT\n>BROKEN\nACGTAAAAAARAAAAAACGT\n>CLEAN\nACGTACGTACGT\n")
+
+	err := qcFasta(input, QCConfig{
+		MaxN:           -1,
+		MaxAmbig:       -1,
+		MaxHomopolymer: 12,
+		OutputPath:     output,
+		ReportPath:     report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := ">BROKEN\nACGTAAAAAAAAAAAACGT\n>CLEAN\nACGTACGTACGT\n"
+	if string(kept) != want {
+		t.Fatalf("unexpected QC output:\n%s\nwant:\n%s", string(kept), want)
+	}
+	if stats := readQCReport(t, report); stats.Homopolymer != 1 {
+		t.Fatalf("homopolymer=%d want 1", stats.Homopolymer)
+	}
+}
+
+func TestQCTrimTerminalN(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	writeTestFasta(t, input, ">PADDED\nNNNACGTACGT--NNN\n>SHORTCORE\n-NNACGTNN\n>INNER\nNNACGTNACGTNN\n")
+
+	err := qcFasta(input, QCConfig{
+		MinLen:        6,
+		MaxN:          0,
+		MaxAmbig:      -1,
+		TrimTerminalN: true,
+		OutputPath:    output,
+		ReportPath:    report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(kept) != ">PADDED\nACGTACGT\n" {
+		t.Fatalf("expected only the trimmed core, got:\n%s", string(kept))
+	}
+	stats := readQCReport(t, report)
+	if stats.TrimmedN != 3 || stats.TooShort != 1 || stats.TooManyN != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestQCReportPerReasonCounts(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	taxidMap := filepath.Join(tmp, "qc_taxid.map")
+	ids := []string{"PASS", "SHORT", "LONG", "MANYN", "AMBIG", "INVALID", "DUPSEQ", "DUPID"}
+	var mapLines strings.Builder
+	for _, id := range ids {
+		mapLines.WriteString(id + "\t9606\n")
+	}
+	mapLines.WriteString("RANKS\t7\n")
+	if err := os.WriteFile(taxidMap, []byte(mapLines.String()), 0o644); err != nil {
+		t.Fatalf("write taxid map: %v", err)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	report := filepath.Join(tmp, "report.json")
+	writeTestFasta(t, input, strings.Join([]string{
+		">PASS", "ACGTACGTAC",
+		">SHORT", "ACGT",
+		">LONG", "ACGTACGTACGTACGTACGT",
+		">MANYN", "ACGTNACGTAC",
+		">AMBIG", "ACGTRACGTAC",
+		">INVALID", "ACGTXACGTAC",
+		">DUPSEQ", "ACGTACGTAC",
+		">PASS", "TTTTACGTAC",
+		">NOTAXID", "ACGTACGTAA",
+		">RANKS", "ACGTACGTCC",
+	}, "\n")+"\n")
+
+	err := qcFasta(input, QCConfig{
+		MinLen:       8,
+		MaxLen:       15,
+		MaxN:         0,
+		MaxAmbig:     0,
+		MaxInvalid:   0,
+		DedupeSeqs:   true,
+		DedupeIDs:    true,
+		RequireRanks: []string{"genus", "species"},
+		TaxdumpDir:   tmp,
+		TaxidMapPath: taxidMap,
+		OutputPath:   filepath.Join(tmp, "qc.fasta"),
+		ReportPath:   report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	got := readQCReport(t, report)
+	got.Lengths = nil
+	got.Config = nil
+	want := QCStats{
+		Total:          10,
+		Written:        1,
+		MissingTaxID:   1,
+		MissingRanks:   1,
+		TooShort:       1,
+		TooLong:        1,
+		TooManyN:       1,
+		TooManyAmbig:   1,
+		TooManyInvalid: 1,
+		DupeSeq:        1,
+		DupeID:         1,
+	}
+	if got != want {
+		t.Fatalf("report mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestQCWorkersMatchSerialOutput(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	var b strings.Builder
+	bases := "ACGT"
+	for i := 0; i < 500; i++ {
+		b.WriteString(">R" + strings.Repeat("x", i%3) + string(rune('a'+i%26)) + "\n")
+		seq := make([]byte, 0, 40)
+		for j := 0; j < 20+i%30; j++ {
+			seq = append(seq, bases[(i*7+j*j)%4])
+		}
+		if i%11 == 0 {
+			seq = append(seq, 'N')
+		}
+		if i%13 == 0 {
+			seq = append([]byte("NNN"), seq...)
+		}
+		b.Write(seq)
+		b.WriteString("\n")
+	}
+	writeTestFasta(t, input, b.String())
+
+	run := func(workers int) (string, string) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "qc.fasta")
+		rejects := filepath.Join(dir, "rejects.fasta")
+		err := qcFasta(input, QCConfig{
+			MinLen:        25,
+			MaxLen:        45,
+			MaxN:          0,
+			MaxAmbig:      -1,
+			TrimTerminalN: true,
+			DedupeSeqs:    true,
+			DedupeIDs:     true,
+			OutputPath:    out,
+			RejectsPath:   rejects,
+			Workers:       workers,
+		})
+		if err != nil {
+			t.Fatalf("qcFasta workers=%d failed: %v", workers, err)
+		}
+		kept, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		dropped, err := os.ReadFile(rejects)
+		if err != nil {
+			t.Fatalf("read rejects: %v", err)
+		}
+		return string(kept), string(dropped)
+	}
+
+	serialKept, serialDropped := run(1)
+	parallelKept, parallelDropped := run(4)
+	if serialKept == "" || serialDropped == "" {
+		t.Fatalf("expected both kept and rejected records in fixture")
+	}
+	if serialKept != parallelKept {
+		t.Fatalf("output differs between workers=1 and workers=4")
+	}
+	if serialDropped != parallelDropped {
+		t.Fatalf("rejects differ between workers=1 and workers=4")
+	}
+}
+
+func TestQCPrimerTrimming(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	// Forward primer GGTCAACAAATCATAAAGATATTGG (LCO1490); reverse primer
+	// TAAACTTCAGGGTGACCAAAAAATCA (HCO2198), so the read ends in its reverse
+	// complement TGATTTTTTGGTCACCCTGAAGTTTA.
+	fwd := "GGTCAACAAATCATAAAGATATTGG"
+	revRC := "TGATTTTTTGGTCACCCTGAAGTTTA"
+	core := "ACGTACGTTTGACCAGT"
+	oneMismatch := "GGTCAACAAATCATAAAGATATTGC"
+	writeTestFasta(t, input, ">BOTH\nAA"+fwd+core+revRC+"CC\n>FWDMM\n"+oneMismatch+core+revRC+"\n>NOPRIMER\n"+core+core+"\n")
+
+	err := qcFasta(input, QCConfig{
+		MinLen:            10,
+		MaxN:              -1,
+		MaxAmbig:          -1,
+		ForwardPrimer:     "GGTCAACAAATCATAAAGATATTGG",
+		ReversePrimer:     "TAAACTTCAGGGTGACCAAAAAATCA",
+		PrimerMismatches:  1,
+		PrimerMaxOffset:   defaultPrimerMaxOffset,
+		DropMissingPrimer: true,
+		OutputPath:        output,
+		ReportPath:        report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := ">BOTH\n" + core + "\n>FWDMM\n" + core + "\n"
+	if string(kept) != want {
+		t.Fatalf("unexpected QC output:\n%s\nwant:\n%s", string(kept), want)
+	}
+	stats := readQCReport(t, report)
+	if stats.ForwardPrimerTrimmed != 2 || stats.ReversePrimerTrimmed != 2 || stats.PrimerNotFound != 1 {
+		t.Fatalf("unexpected primer stats: %+v", stats)
+	}
+	if stats.Written != 2 {
+		t.Fatalf("written=%d want 2", stats.Written)
+	}
+}
+
+func TestQCStreamInMemory(t *testing.T) {
+	in := bytes.NewBufferString(">KEEP\nacgtacgtac\n>DROP\nACGT\n>NORANK\nACGTACGTAA\n")
+	var out, rejects bytes.Buffer
+	lineages := map[int]map[string]string{
+		1: {"genus": "Homo", "species": "Homo sapiens"},
+		2: {"genus": "Canis"},
+	}
+
+	stats, err := QCStream(in, &out, QCConfig{
+		MinLen:       8,
+		MaxN:         -1,
+		MaxAmbig:     -1,
+		RequireRanks: []string{"genus", "species"},
+		TaxIDs:       map[string]int{"KEEP": 1, "DROP": 1, "NORANK": 2},
+		Lineage: func(taxid int) map[string]string {
+			return lineages[taxid]
+		},
+		Rejects: &rejects,
+	})
+	if err != nil {
+		t.Fatalf("QCStream failed: %v", err)
+	}
+	if out.String() != ">KEEP\nACGTACGTAC\n" {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if stats.Total != 3 || stats.Written != 1 || stats.TooShort != 1 || stats.MissingRanks != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if !strings.Contains(rejects.String(), ">DROP reason=too_short") {
+		t.Fatalf("expected DROP in rejects, got:\n%s", rejects.String())
+	}
+}
+
+func TestQCNormalizeCaseAndStripGaps(t *testing.T) {
+	got, changed := normalizeSequence([]byte("acgt-acgt"), true, true)
+	if !changed || string(got) != "ACGTACGT" {
+		t.Fatalf("normalizeSequence=%q changed=%v", got, changed)
+	}
+
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	output := filepath.Join(tmp, "qc.fasta")
+	report := filepath.Join(tmp, "report.json")
+	writeTestFasta(t, input, ">UPPER\nACGTACGT\n>GAPPED\nacgt-acgt\n>DOTTED\nTT.GA\n")
+
+	err := qcFasta(input, QCConfig{
+		MaxN:          -1,
+		MaxAmbig:      -1,
+		NormalizeCase: true,
+		StripGaps:     true,
+		DedupeSeqs:    true,
+		OutputPath:    output,
+		ReportPath:    report,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	kept, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(kept) != ">UPPER\nACGTACGT\n>DOTTED\nTTGA\n" {
+		t.Fatalf("unexpected QC output:\n%s", string(kept))
+	}
+	stats := readQCReport(t, report)
+	if stats.Normalized != 2 || stats.DupeSeq != 1 {
+		t.Fatalf("normalized=%d dup-seq=%d want 2 and 1", stats.Normalized, stats.DupeSeq)
+	}
+}
+
+func TestQCOnEmptySeqPolicy(t *testing.T) {
+	input := ">EMPTY\n>FULL\nACGTACGT\n"
+	var out bytes.Buffer
+	stats, err := QCStream(strings.NewReader(input), &out, QCConfig{MaxN: -1, MaxAmbig: -1, OnEmptySeq: emptySeqSkip})
+	if err != nil {
+		t.Fatalf("QCStream skip failed: %v", err)
+	}
+	if out.String() != ">FULL\nACGTACGT\n" || stats.EmptySeq != 1 {
+		t.Fatalf("skip: output=%q empty=%d", out.String(), stats.EmptySeq)
+	}
+
+	if _, err := QCStream(strings.NewReader(input), &bytes.Buffer{}, QCConfig{MaxN: -1, MaxAmbig: -1, OnEmptySeq: emptySeqError}); err == nil {
+		t.Fatalf("expected error policy to fail on EMPTY")
+	}
+
+	out.Reset()
+	stats, err = QCStream(strings.NewReader(input), &out, QCConfig{MinLen: 4, MaxN: -1, MaxAmbig: -1, OnEmptySeq: emptySeqKeep})
+	if err != nil {
+		t.Fatalf("QCStream keep failed: %v", err)
+	}
+	if out.String() != ">EMPTY\n\n>FULL\nACGTACGT\n" || stats.Written != 2 || stats.TooShort != 0 {
+		t.Fatalf("keep: output=%q written=%d too-short=%d", out.String(), stats.Written, stats.TooShort)
+	}
+}
+
+func TestQCDedupeRevComp(t *testing.T) {
+	input := ">FWD\nAACCGGTTAG\n>REV\nCTAACCGGTT\n"
+	for _, tc := range []struct {
+		revcomp bool
+		want    string
+	}{
+		{revcomp: true, want: ">FWD\nAACCGGTTAG\n"},
+		{revcomp: false, want: ">FWD\nAACCGGTTAG\n>REV\nCTAACCGGTT\n"},
+	} {
+		var out bytes.Buffer
+		_, err := QCStream(strings.NewReader(input), &out, QCConfig{
+			MaxN:          -1,
+			MaxAmbig:      -1,
+			DedupeSeqs:    true,
+			DedupeRevComp: tc.revcomp,
+		})
+		if err != nil {
+			t.Fatalf("QCStream failed: %v", err)
+		}
+		if out.String() != tc.want {
+			t.Fatalf("revcomp=%v output:\n%s\nwant:\n%s", tc.revcomp, out.String(), tc.want)
+		}
+	}
+}
+
+func TestQCBatchDirectory(t *testing.T) {
+	tmp := t.TempDir()
+	inDir := filepath.Join(tmp, "markers")
+	if err := os.MkdirAll(inDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeTestFasta(t, filepath.Join(inDir, "COI-5P.fasta"), ">P1\nACGTACGTACGT\n>P2\nACGT\n")
+	writeTestFasta(t, filepath.Join(inDir, "ITS.fasta"), ">P3\nGGCCGGCCGGCC\n")
+	writeTestFasta(t, filepath.Join(inDir, "notes.txt"), "not a fasta\n")
+
+	if !isQCBatchInput(inDir) || isQCBatchInput(filepath.Join(inDir, "ITS.fasta")) {
+		t.Fatalf("isQCBatchInput misclassified inputs")
+	}
+	inputs, err := listQCInputs(inDir)
+	if err != nil {
+		t.Fatalf("listQCInputs: %v", err)
+	}
+	if len(inputs) != 2 {
+		t.Fatalf("inputs=%v want the two FASTAs", inputs)
+	}
+	outDir := filepath.Join(tmp, "qc")
+	reportPath := filepath.Join(tmp, "qc.json")
+	err = qcBatch(inputs, QCConfig{MinLen: 10, MaxN: -1, MaxAmbig: -1, OutputPath: outDir, ReportPath: reportPath})
+	if err != nil {
+		t.Fatalf("qcBatch failed: %v", err)
+	}
+
+	kept, err := os.ReadFile(filepath.Join(outDir, "COI-5P.fasta"))
+	if err != nil {
+		t.Fatalf("read COI-5P output: %v", err)
+	}
+	if string(kept) != ">P1\nACGTACGTACGT\n" {
+		t.Fatalf("unexpected COI-5P output: %q", kept)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report qcBatchReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	coi, its := report.Files["COI-5P"], report.Files["ITS"]
+	if coi.Total != 2 || coi.Written != 1 || coi.TooShort != 1 || its.Total != 1 || its.Written != 1 {
+		t.Fatalf("per-file stats wrong: COI-5P=%+v ITS=%+v", coi, its)
+	}
+	if report.Total.Total != 3 || report.Total.Written != 2 || report.Total.TooShort != 1 {
+		t.Fatalf("aggregate stats wrong: %+v", report.Total)
+	}
+}
+
+func TestQCEmitFilteredTaxonkit(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">P1\nACGTACGTACGT\n>P2\nACGT\n>P3\nGGCCGGCCGGCC\n")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	content := "processid\tkingdom\tspecies\nP1\tAnimalia\tHomo sapiens\nP2\tAnimalia\tCanis lupus\nP4\tAnimalia\tFelis catus\nP3\tAnimalia\tMus musculus\n"
+	if err := os.WriteFile(taxonkit, []byte(content), 0o644); err != nil {
+		t.Fatalf("write taxonkit: %v", err)
+	}
+
+	filtered := filepath.Join(tmp, "out", "taxonkit_filtered.tsv")
+	err := qcFasta(input, QCConfig{
+		MinLen:               10,
+		MaxN:                 -1,
+		MaxAmbig:             -1,
+		OutputPath:           filepath.Join(tmp, "qc.fasta"),
+		TaxonkitInput:        taxonkit,
+		FilteredTaxonkitPath: filtered,
+	})
+	if err != nil {
+		t.Fatalf("qcFasta failed: %v", err)
+	}
+	data, err := os.ReadFile(filtered)
+	if err != nil {
+		t.Fatalf("read filtered taxonkit: %v", err)
+	}
+	want := "processid\tkingdom\tspecies\nP1\tAnimalia\tHomo sapiens\nP3\tAnimalia\tMus musculus\n"
+	if string(data) != want {
+		t.Fatalf("filtered taxonkit=%q want %q", data, want)
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// qcCheck holds the stateless per-record QC results. Everything here depends
+// only on the record and the config, so it can be computed in parallel.
+type qcCheck struct {
+	rec fastaRecord
+	seq []byte
+	// qual follows seq through gap stripping and trimming; nil for FASTA.
+	qual       []byte
+	normalized bool
+	trimmed    bool
+	// Primer trimming outcome; see trimPrimers.
+	fwdPrimer     bool
+	revPrimer     bool
+	primerMissing bool
+	clean         []byte
+	counts        seqCounts
+	reasons       []string
+}
+
+type qcJob struct {
+	idx int
+	rec fastaRecord
+}
+
+type qcResult struct {
+	idx int
+	chk qcCheck
+}
+
+func evaluateQCRecord(rec fastaRecord, cfg QCConfig) qcCheck {
+	chk := qcCheck{rec: rec, seq: rec.seq, qual: rec.qual}
+	if len(rec.seq) == 0 && cfg.OnEmptySeq == emptySeqKeep {
+		// Kept empty records bypass the sequence rules; too_short would
+		// otherwise drop every one of them.
+		return chk
+	}
+	if cfg.NormalizeCase || cfg.StripGaps {
+		if cfg.StripGaps && chk.qual != nil {
+			chk.qual = stripGapQual(chk.seq, chk.qual)
+		}
+		chk.seq, chk.normalized = normalizeSequence(chk.seq, cfg.NormalizeCase, cfg.StripGaps)
+	}
+	if cfg.TrimTerminalN {
+		if start, end := terminalNBounds(chk.seq); end-start != len(chk.seq) {
+			chk.seq, chk.qual = trimSeqQual(chk.seq, chk.qual, start, end)
+			chk.trimmed = true
+		}
+	}
+	if cfg.ForwardPrimer != "" || cfg.ReversePrimer != "" {
+		var start, end int
+		start, end, chk.fwdPrimer, chk.revPrimer, chk.primerMissing = trimPrimers(chk.seq, cfg)
+		chk.seq, chk.qual = trimSeqQual(chk.seq, chk.qual, start, end)
+		if chk.primerMissing && cfg.DropMissingPrimer {
+			chk.reasons = append(chk.reasons, qcReasonPrimerMissing)
+		}
+	}
+	chk.clean, chk.counts = cleanSequence(chk.seq)
+	chk.reasons = append(chk.reasons, qcSequenceReasons(chk.seq, chk.clean, chk.counts, cfg)...)
+	if cfg.MinMeanQual > 0 && chk.qual != nil && meanPhred(chk.qual) < cfg.MinMeanQual {
+		chk.reasons = append(chk.reasons, qcReasonLowQuality)
+	}
+	return chk
+}
+
+// trimSeqQual cuts seq, and qual when present, to [start, end).
+func trimSeqQual(seq, qual []byte, start, end int) ([]byte, []byte) {
+	if qual != nil {
+		qual = qual[start:end]
+	}
+	return seq[start:end], qual
+}
+
+// runQCChecks parses FASTA or FASTQ from r, evaluates the stateless checks on
+// up to workers goroutines, and calls finish serially in input order. Rules
+// that need shared state (dedupe, taxid and rank lookups) belong in finish.
+func runQCChecks(r io.Reader, cfg QCConfig, workers int, finish func(qcCheck) error) error {
+	if workers <= 1 {
+		return parseSequences(r, func(rec fastaRecord) error {
+			return finish(evaluateQCRecord(rec, cfg))
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan qcJob, workers*64)
+	results := make(chan qcResult, workers*64)
+	parseErrCh := make(chan error, 1)
+
+	go func() {
+		defer close(jobs)
+		idx := 0
+		parseErrCh <- parseSequences(r, func(rec fastaRecord) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case jobs <- qcJob{idx: idx, rec: rec}:
+				idx++
+				return nil
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				res := qcResult{idx: job.idx, chk: evaluateQCRecord(job.rec, cfg)}
+				select {
+				case <-ctx.Done():
+					return
+				case results <- res:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var finishErr error
+	pending := make(map[int]qcCheck)
+	next := 0
+	for res := range results {
+		if finishErr != nil {
+			continue
+		}
+		if !globalOpts.KeepOrder {
+			if err := finish(res.chk); err != nil {
+				finishErr = err
+				cancel()
+			}
+			continue
+		}
+		pending[res.idx] = res.chk
+		for {
+			chk, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := finish(chk); err != nil {
+				finishErr = err
+				cancel()
+				break
+			}
+		}
+	}
+
+	parseErr := <-parseErrCh
+	if finishErr != nil {
+		return finishErr
+	}
+	return parseErr
+}
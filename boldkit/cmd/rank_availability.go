@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var canonicalRanks = []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"}
+
+// standardRankOrder is the lineage order positional outputs (RDP levels,
+// IDTAXA and protax lineages) assume: the canonical ranks plus subspecies.
+var standardRankOrder = []string{"kingdom", "phylum", "class", "order", "family", "genus", "species", "subspecies"}
+
+// rankPrefixSuggestion returns the contiguous prefix of standardRankOrder
+// through the deepest rank in ranks, and whether the standard ranks in ranks
+// already are one. Other ranks (superfamily, tribe, ...) may sit anywhere
+// between them. Empty ranks (require-ranks disabled) always pass.
+func rankPrefixSuggestion(ranks []string) ([]string, bool) {
+	var std []string
+	for _, rank := range ranks {
+		if isStandardRank(rank) {
+			std = append(std, rank)
+		}
+	}
+	ok := len(std) <= len(standardRankOrder)
+	deepest := -1
+	for i, rank := range std {
+		if ok && rank != standardRankOrder[i] {
+			ok = false
+		}
+		for j, std := range standardRankOrder {
+			if std == rank && j > deepest {
+				deepest = j
+			}
+		}
+	}
+	return standardRankOrder[:deepest+1], ok
+}
+
+func isStandardRank(rank string) bool {
+	for _, std := range standardRankOrder {
+		if std == rank {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRankPrefix warns when -require-ranks skips or reorders standard
+// ranks, which leaves gaps in positional lineages; with strict
+// the mismatch is an error instead.
+func checkRankPrefix(command string, ranks []string, strict bool) error {
+	suggest, ok := rankPrefixSuggestion(ranks)
+	if ok {
+		return nil
+	}
+	msg := fmt.Sprintf("%s: -require-ranks %s is not a contiguous prefix of %s", command, strings.Join(ranks, ","), strings.Join(standardRankOrder, ","))
+	if len(suggest) > 0 {
+		msg += fmt.Sprintf("; did you mean -require-ranks %s?", strings.Join(suggest, ","))
+	}
+	if strict {
+		return fmt.Errorf("%s", msg)
+	}
+	warnf("require_ranks_gap", 1, "%s", msg)
+	return nil
+}
+
+type rankCoverage struct {
+	Rank     string  `json:"rank"`
+	Records  int     `json:"records"`
+	Fraction float64 `json:"fraction"`
+}
+
+type rankSetCoverage struct {
+	Ranks    []string `json:"ranks"`
+	Records  int      `json:"records"`
+	Fraction float64  `json:"fraction"`
+}
+
+// rankAvailabilityReport summarises how many input records carry each rank
+// and how many would survive each candidate -require-ranks set. Fractions are
+// relative to all input records, so taxid misses count against every rank.
+type rankAvailabilityReport struct {
+	Records      int               `json:"records"`
+	MissingTaxID int               `json:"missing_taxid"`
+	Ranks        []rankCoverage    `json:"ranks"`
+	RankSets     []rankSetCoverage `json:"rank_sets"`
+}
+
+// rankAvailability scans cfg.Input against the taxid map and taxdump without
+// writing classifier outputs. Candidate sets are the cumulative prefixes of
+// cfg.RequireRanks (or the canonical seven ranks when empty).
+func rankAvailability(cfg formatConfig) (rankAvailabilityReport, error) {
+	report := rankAvailabilityReport{}
+	ranks := cfg.RequireRanks
+	if len(ranks) == 0 {
+		ranks = canonicalRanks
+	}
+
+	taxidPath := cfg.TaxidMapPath
+	if taxidPath == "" {
+		taxidPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+	}
+	taxidMap, err := loadTaxidMap(taxidPath)
+	if err != nil {
+		return report, err
+	}
+	dump, err := loadTaxDump(filepath.Join(cfg.TaxdumpDir, "nodes.dmp"), filepath.Join(cfg.TaxdumpDir, "names.dmp"))
+	if err != nil {
+		return report, err
+	}
+
+	in, err := openInput(cfg.Input)
+	if err != nil {
+		return report, fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	rankCounts := make([]int, len(ranks))
+	prefixCounts := make([]int, len(ranks))
+	err = parseFasta(in, func(rec fastaRecord) error {
+		report.Records++
+		taxid, ok := taxidMap[rec.id]
+		if rec.id == "" || !ok {
+			report.MissingTaxID++
+			return nil
+		}
+		lineage := dump.lineage(taxid)
+		prefixOK := true
+		for i, rank := range ranks {
+			present := lineage[rank] != ""
+			if present {
+				rankCounts[i]++
+			}
+			prefixOK = prefixOK && present
+			if prefixOK {
+				prefixCounts[i]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	fraction := func(n int) float64 {
+		if report.Records == 0 {
+			return 0
+		}
+		return float64(n) / float64(report.Records)
+	}
+	for i, rank := range ranks {
+		report.Ranks = append(report.Ranks, rankCoverage{Rank: rank, Records: rankCounts[i], Fraction: fraction(rankCounts[i])})
+		set := append([]string(nil), ranks[:i+1]...)
+		report.RankSets = append(report.RankSets, rankSetCoverage{Ranks: set, Records: prefixCounts[i], Fraction: fraction(prefixCounts[i])})
+	}
+	return report, nil
+}
+
+func logRankAvailability(report rankAvailabilityReport) {
+	logf("rank-availability: records=%d missing-taxid=%d", report.Records, report.MissingTaxID)
+	for _, r := range report.Ranks {
+		logf("rank-availability: %-8s %6.2f%% (%d)", r.Rank, 100*r.Fraction, r.Records)
+	}
+	for _, s := range report.RankSets {
+		logf("rank-availability: require-ranks=%s keeps %6.2f%% (%d)", strings.Join(s.Ranks, ","), 100*s.Fraction, s.Records)
+	}
+}
@@ -32,12 +32,16 @@ var rdpRankAliases = map[string]string{
 	"kingdom":      "domain",
 }
 
-// newRdpTaxonomyBuilder creates a new taxonomy builder with the given rank order
-func newRdpTaxonomyBuilder(ranks []string) *rdpTaxonomyBuilder {
+// newRdpTaxonomyBuilder creates a new taxonomy builder with the given rank
+// order. rankNames (from -output-rank-names) takes precedence over
+// rdpRankAliases.
+func newRdpTaxonomyBuilder(ranks []string, rankNames map[string]string) *rdpTaxonomyBuilder {
 	// Map ranks to RDP labels
 	rdpRanks := make([]string, len(ranks))
 	for i, r := range ranks {
-		if alias, ok := rdpRankAliases[r]; ok {
+		if name, ok := rankNames[r]; ok {
+			rdpRanks[i] = name
+		} else if alias, ok := rdpRankAliases[r]; ok {
 			rdpRanks[i] = alias
 		} else {
 			rdpRanks[i] = r
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -11,15 +13,40 @@ func isParquetPath(path string) bool {
 }
 
 func ParseRows(path string, opts Options, onRow func(Row) error) error {
-	if isParquetPath(path) {
+	return ParseRowsAs(path, "", opts, onRow)
+}
+
+// ParseRowsAs is ParseRows with an explicit input format (see
+// normalizeInputFormat); an empty format detects it from the extension.
+func ParseRowsAs(path, format string, opts Options, onRow func(Row) error) error {
+	if format == "" {
+		format = InputFormat(path)
+	}
+	switch format {
+	case "parquet":
 		return parseParquet(path, opts, onRow)
+	case "csv":
+		return parseCSVRows(path, opts, onRow)
+	case "json":
+		return parseJSONRows(path, opts, onRow)
 	}
 	return parseTSVRows(path, opts, onRow)
 }
 
 func RowCount(path string) (int64, error) {
-	if isParquetPath(path) {
+	return RowCountAs(path, "")
+}
+
+// RowCountAs is RowCount with an explicit input format.
+func RowCountAs(path, format string) (int64, error) {
+	if format == "" {
+		format = InputFormat(path)
+	}
+	switch format {
+	case "parquet":
 		return parquetRowCount(path)
+	case "json":
+		return jsonRowCount(path)
 	}
 	n, err := countLines(path)
 	if err != nil {
@@ -31,9 +58,79 @@ func RowCount(path string) (int64, error) {
 	return 0, nil
 }
 
+// expandInputs resolves an -input glob pattern to its matching files in
+// sorted order; a path without glob metacharacters is returned as is.
+func expandInputs(pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("glob %s: %w", pattern, err)
+	}
+	var files []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && !info.IsDir() {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no inputs match %s", pattern)
+	}
+	return files, nil
+}
+
+// checkInput logs the input's size and row count before a long run and, when
+// expectSHA256 is set, fails on a digest mismatch so a truncated download is
+// caught before anything is written. The row count is returned for reuse as
+// the progress total.
+func checkInput(path, format, expectSHA256 string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("stat input: %w", err)
+	}
+	if expectSHA256 != "" {
+		sum, err := sha256File(path)
+		if err != nil {
+			return 0, fmt.Errorf("hash input: %w", err)
+		}
+		if !strings.EqualFold(sum, strings.TrimSpace(expectSHA256)) {
+			return 0, fmt.Errorf("input %s sha256 mismatch: got %s, expected %s", path, sum, expectSHA256)
+		}
+		logf("input: sha256 verified for %s", path)
+	}
+	rows, err := RowCountAs(path, format)
+	if err != nil {
+		return 0, fmt.Errorf("count rows: %w", err)
+	}
+	logf("input: %s size=%d bytes rows=%d", path, info.Size(), rows)
+	if rows == 0 {
+		warnf("empty_input", 1, "input: %s has no data rows", path)
+	}
+	return rows, nil
+}
+
 func InputFormat(path string) string {
 	if isParquetPath(path) {
 		return "parquet"
 	}
+	if isCSVPath(path) {
+		return "csv"
+	}
+	if isJSONPath(path) {
+		return "json"
+	}
 	return "tsv"
 }
+
+// normalizeInputFormat validates an -input-format value. "auto" and "" map
+// to "" so the format is detected from the file extension.
+func normalizeInputFormat(format string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case "", "auto":
+		return "", nil
+	case "tsv", "csv", "parquet", "json":
+		return f, nil
+	}
+	return "", fmt.Errorf("input-format must be one of auto, tsv, csv, parquet, json (got %q)", format)
+}
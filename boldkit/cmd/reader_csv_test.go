@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildTaxonkitCSVQuotedComma(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.csv")
+	output := filepath.Join(tmp, "output.tsv")
+	content := strings.Join([]string{
+		"processid,bin_uri,kingdom,phylum,class,order,family,subfamily,tribe,genus,species",
+		`P1,BOLD:BIN1,Animalia,Chordata,Mammalia,Primates,Hominidae,,,Homo,"Homo sapiens, Linnaeus"`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	if InputFormat(input) != "csv" {
+		t.Fatalf("InputFormat=%q want csv", InputFormat(input))
+	}
+	rows, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{}.normalized(), extractOptions{})
+	if err != nil {
+		t.Fatalf("buildTaxonkit failed: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("rows=%d want 1", rows)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.Contains(string(data), "Homo\tHomo sapiens, Linnaeus\tP1\n") {
+		t.Fatalf("expected quoted species kept in one field, got:\n%s", data)
+	}
+}
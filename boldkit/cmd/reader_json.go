@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// jsonColumns is the header parseJSONRows emits, named like the BOLD TSV so
+// header-driven consumers (extract) need no JSON-specific code.
+var jsonColumns = []string{
+	"processid", "bin_uri", "kingdom", "phylum", "class", "order", "family",
+	"subfamily", "tribe", "genus", "species", "subspecies", "marker_code",
+	"nuc", "country/ocean", "inst",
+}
+
+// jsonFieldAliases maps BOLD API JSON keys onto jsonColumns entries. Keys
+// that already match a column name need no entry.
+var jsonFieldAliases = map[string]string{
+	"bin":           "bin_uri",
+	"markercode":    "marker_code",
+	"marker":        "marker_code",
+	"nucleotides":   "nuc",
+	"country":       "country/ocean",
+	"country_ocean": "country/ocean",
+	"institution":   "inst",
+}
+
+// isJSONPath reports whether path names a JSON array or NDJSON file,
+// optionally gzipped (.json, .ndjson, .jsonl).
+func isJSONPath(path string) bool {
+	switch filepath.Ext(strings.TrimSuffix(strings.ToLower(path), ".gz")) {
+	case ".json", ".ndjson", ".jsonl":
+		return true
+	}
+	return false
+}
+
+// parseJSONRows streams BOLD API records from a top-level JSON array or from
+// NDJSON (one object per line). The first Row is the fixed jsonColumns header;
+// every record follows as one Row in file order. Records are decoded one at a
+// time so the file is never held in memory. Strings and numbers are taken
+// verbatim, null and unknown keys are dropped, and nested values are an error.
+func parseJSONRows(path string, opts Options, onRow func(Row) error) error {
+	in, err := openInput(path)
+	if err != nil {
+		return fmt.Errorf("open input %s: %w", path, err)
+	}
+	defer func() { _ = in.Close() }()
+
+	br := bufio.NewReaderSize(in, 1<<20)
+	first, err := peekNonSpace(br)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read json %s: %w", path, err)
+	}
+
+	index := make(map[string]int, len(jsonColumns))
+	header := make([][]byte, len(jsonColumns))
+	for i, name := range jsonColumns {
+		index[name] = i
+		header[i] = []byte(name)
+	}
+	if err := onRow(Row{Line: 1, Fields: header}); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	array := first == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("parse json %s: %w", path, err)
+		}
+	}
+
+	var lineNum int64 = 1
+	fields := make([][]byte, len(jsonColumns))
+	for {
+		if array && !dec.More() {
+			break
+		}
+		if err := canceled(opts.ctx()); err != nil {
+			return err
+		}
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			if !array && errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("parse json %s record %d: %w", path, lineNum, err)
+		}
+		lineNum++
+		for i := range fields {
+			fields[i] = nil
+		}
+		for key, value := range obj {
+			name := strings.ToLower(key)
+			if alias, ok := jsonFieldAliases[name]; ok {
+				name = alias
+			}
+			i, ok := index[name]
+			if !ok {
+				continue
+			}
+			switch v := value.(type) {
+			case nil:
+			case string:
+				fields[i] = []byte(v)
+			case json.Number:
+				fields[i] = []byte(v.String())
+			default:
+				return fmt.Errorf("parse json %s record %d: field %q is not a string or number", path, lineNum-1, key)
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress.increment()
+		}
+		if err := onRow(Row{Line: lineNum, Fields: fields}); err != nil {
+			return err
+		}
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("parse json %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// jsonRowCount counts records with a decode-only pass, since a JSON array
+// need not have one record per line.
+func jsonRowCount(path string) (int64, error) {
+	var n int64
+	err := parseJSONRows(path, Options{}, func(Row) error {
+		n++
+		return nil
+	})
+	if n > 0 {
+		n-- // header
+	}
+	return n, err
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildTaxonkitJSONArrayAndNDJSON(t *testing.T) {
+	tmp := t.TempDir()
+	array := `[
+  {"processid": "P1", "bin_uri": "BOLD:BIN1", "kingdom": "Animalia", "phylum": "Chordata", "class": "Mammalia",
+   "order": "Primates", "family": "Hominidae", "subfamily": null, "genus": "Homo", "species": "Homo sapiens", "elev": 12},
+  {"processid": "P2", "bin": "BOLD:BIN2", "kingdom": "Animalia", "phylum": "Chordata", "class": "Mammalia",
+   "order": "Carnivora", "family": "Canidae", "genus": "Canis", "species": "Canis lupus"}
+]`
+	ndjson := `{"processid": "P1", "bin_uri": "BOLD:BIN1", "kingdom": "Animalia", "phylum": "Chordata", "class": "Mammalia", "order": "Primates", "family": "Hominidae", "subfamily": null, "genus": "Homo", "species": "Homo sapiens", "elev": 12}
+{"processid": "P2", "bin": "BOLD:BIN2", "kingdom": "Animalia", "phylum": "Chordata", "class": "Mammalia", "order": "Carnivora", "family": "Canidae", "genus": "Canis", "species": "Canis lupus"}
+`
+	for name, content := range map[string]string{"records.json": array, "records.ndjson": ndjson} {
+		input := filepath.Join(tmp, name)
+		output := filepath.Join(tmp, name+".tsv")
+		if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+			t.Fatalf("write input: %v", err)
+		}
+		if InputFormat(input) != "json" {
+			t.Fatalf("InputFormat(%s)=%q want json", name, InputFormat(input))
+		}
+		count, err := RowCount(input)
+		if err != nil || count != 2 {
+			t.Fatalf("RowCount(%s)=%d, %v want 2", name, count, err)
+		}
+		rows, err := buildTaxonkit(input, output, 0, -1, extractCurationConfig{}.normalized(), extractOptions{})
+		if err != nil {
+			t.Fatalf("buildTaxonkit(%s) failed: %v", name, err)
+		}
+		if rows != 2 {
+			t.Fatalf("%s rows=%d want 2", name, rows)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		got := string(data)
+		if !strings.Contains(got, "Primates\tHominidae\t\t\tHomo\tHomo sapiens\tP1\n") {
+			t.Fatalf("%s: unexpected P1 row:\n%s", name, got)
+		}
+		if !strings.Contains(got, "Canis\tCanis lupus\tP2\n") {
+			t.Fatalf("%s: unexpected P2 row:\n%s", name, got)
+		}
+	}
+}
+
+func TestParseJSONRowsRejectsNestedValue(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "records.json")
+	if err := os.WriteFile(input, []byte(`[{"processid": "P1", "species": {"name": "Homo sapiens"}}]`), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	err := ParseRows(input, DefaultOptions(), func(Row) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), `field "species"`) {
+		t.Fatalf("expected nested-value error, got %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -43,7 +42,7 @@ func parseParquet(path string, opts Options, onRow func(Row) error) error {
 		return fmt.Errorf("create arrow file reader: %w", err)
 	}
 
-	ctx := context.Background()
+	ctx := opts.ctx()
 	colIndices := make([]int, numCols)
 	for i := range colIndices {
 		colIndices[i] = i
@@ -67,6 +66,10 @@ func parseParquet(path string, opts Options, onRow func(Row) error) error {
 		}
 
 		for r := 0; r < nRows; r++ {
+			if err := canceled(ctx); err != nil {
+				tbl.Release()
+				return err
+			}
 			lineNum++
 			fields := make([][]byte, numCols)
 			for c := 0; c < nCols; c++ {
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportFilePath is the on-disk name of a streaming TSV report: path with
+// ".gz" appended under -report-gzip.
+func reportFilePath(path string) string {
+	if path != "" && globalOpts.ReportGzip && !strings.HasSuffix(path, ".gz") {
+		return path + ".gz"
+	}
+	return path
+}
+
+// reportWriter is a streaming TSV report (rejected ids, curation audit),
+// gzip-compressed under -report-gzip. JSON reports go through
+// writeJSONReport and are never compressed.
+type reportWriter struct {
+	*bufio.Writer
+	path string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// createReport opens reportFilePath(path), creating parent directories. The
+// file is tracked as a partial output until keepOutputs(r.path).
+func createReport(path string) (*reportWriter, error) {
+	path = reportFilePath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create report dir: %w", err)
+	}
+	f, err := createOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &reportWriter{path: path, file: f}
+	if globalOpts.ReportGzip {
+		r.gz = gzip.NewWriter(f)
+		r.Writer = bufio.NewWriterSize(r.gz, writerBufferSize)
+	} else {
+		r.Writer = bufio.NewWriterSize(f, writerBufferSize)
+	}
+	return r, nil
+}
+
+// Close flushes the buffer and gzip stream and closes the file. It is safe
+// to call again from a deferred cleanup after an explicit Close.
+func (r *reportWriter) Close() error {
+	if r == nil || r.file == nil {
+		return nil
+	}
+	f := r.file
+	r.file = nil
+	err := r.Flush()
+	if r.gz != nil {
+		if cerr := r.gz.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("close %s: %w", r.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type representativesConfig struct {
+	Input         string
+	TaxonkitInput string
+	Output        string
+	ReportPath    string
+	MinLen        int
+	MaxN          int
+	OnEmptySeq    string
+}
+
+type representativesStats struct {
+	Records          int      `json:"records"`
+	Unlabeled        int      `json:"unlabeled"`
+	Unqualified      int      `json:"unqualified"`
+	Species          int      `json:"species"`
+	Written          int      `json:"written"`
+	NoRepresentative []string `json:"no_representative,omitempty"`
+}
+
+// representativeCandidate is the current best record for a species. Only the
+// ID and its score are kept; sequences are re-read in the write pass.
+type representativeCandidate struct {
+	id     string
+	n      int
+	length int
+}
+
+// better ranks candidates by fewest Ns, then longest cleaned length. Ties keep
+// the earlier record so the choice is stable for a given input order.
+func (c representativeCandidate) better(other representativeCandidate) bool {
+	if c.n != other.n {
+		return c.n < other.n
+	}
+	return c.length > other.length
+}
+
+func runRepresentatives(args []string) {
+	fs := flag.NewFlagSet("representatives", flag.ExitOnError)
+	input := fs.String("input", "", "Input FASTA (optionally .gz)")
+	taxonkitIn := fs.String("taxonkit-input", "taxonkit_input.tsv", "Taxonkit TSV with processid/species labels")
+	output := fs.String("output", "representatives.fasta", "Output FASTA with one sequence per species")
+	report := fs.String("report", "", "Optional JSON report output path")
+	minLen := fs.Int("min-length", 0, "Minimum cleaned sequence length for a representative (0 disables)")
+	maxN := fs.Int("max-n", -1, "Maximum N count for a representative (-1 disables)")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
+	force := fs.Bool("force", false, "Overwrite existing outputs")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+
+	if *input == "" {
+		fatalf("input is required")
+	}
+	if *minLen < 0 {
+		fatalf("min-length must be >= 0")
+	}
+	if *maxN < -1 {
+		fatalf("max-n must be >= -1")
+	}
+	emptyPolicy, err := normalizeEmptySeqPolicy(*onEmptySeq)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if !*force && fileExists(*output) {
+		fmt.Fprintf(os.Stderr, "Output exists, skipping: %s\n", *output)
+		return
+	}
+
+	cfg := representativesConfig{
+		Input:         *input,
+		TaxonkitInput: *taxonkitIn,
+		Output:        *output,
+		ReportPath:    *report,
+		MinLen:        *minLen,
+		MaxN:          *maxN,
+		OnEmptySeq:    emptyPolicy,
+	}
+	if _, err := buildRepresentatives(cfg); err != nil {
+		fatalf("representatives failed: %v", err)
+	}
+}
+
+// buildRepresentatives picks one representative record per species label.
+// A record qualifies when it passes MinLen and MaxN; among qualifying records
+// the one with the fewest Ns, then the longest cleaned length, wins. Species
+// whose records all fail the thresholds are listed in NoRepresentative.
+func buildRepresentatives(cfg representativesConfig) (representativesStats, error) {
+	stats := representativesStats{}
+
+	ids, _, err := collectFastaIDs(cfg.Input, cfg.OnEmptySeq, duplicateIDError)
+	if err != nil {
+		return stats, err
+	}
+	labels, _, err := loadProcessLabelMap(cfg.TaxonkitInput, ids)
+	if err != nil {
+		return stats, err
+	}
+
+	in, err := openInput(cfg.Input)
+	if err != nil {
+		return stats, fmt.Errorf("open input: %w", err)
+	}
+	best := make(map[string]representativeCandidate)
+	seenSpecies := make(map[string]struct{})
+	err = parseFasta(in, withEmptySeqPolicy(cfg.OnEmptySeq, nil, func(rec fastaRecord) error {
+		stats.Records++
+		label, ok := labels[rec.id]
+		if !ok {
+			stats.Unlabeled++
+			return nil
+		}
+		seenSpecies[label] = struct{}{}
+		clean, counts := cleanSequence(rec.seq)
+		if (cfg.MinLen > 0 && len(clean) < cfg.MinLen) || (cfg.MaxN >= 0 && counts.n > cfg.MaxN) {
+			stats.Unqualified++
+			return nil
+		}
+		cand := representativeCandidate{id: rec.id, n: counts.n, length: len(clean)}
+		if cur, ok := best[label]; !ok || cand.better(cur) {
+			best[label] = cand
+		}
+		return nil
+	}))
+	_ = in.Close()
+	if err != nil {
+		return stats, err
+	}
+
+	selected := make(map[string]struct{}, len(best))
+	for _, cand := range best {
+		selected[cand.id] = struct{}{}
+	}
+	stats.Species = len(seenSpecies)
+	for label := range seenSpecies {
+		if _, ok := best[label]; !ok {
+			stats.NoRepresentative = append(stats.NoRepresentative, label)
+		}
+	}
+	sort.Strings(stats.NoRepresentative)
+
+	written, err := writeSelectedFasta(cfg.Input, cfg.Output, selected, cfg.OnEmptySeq)
+	if err != nil {
+		return stats, err
+	}
+	stats.Written = written
+
+	logf("representatives: records=%d species=%d written=%d unqualified=%d unlabeled=%d", stats.Records, stats.Species, stats.Written, stats.Unqualified, stats.Unlabeled)
+	if len(stats.NoRepresentative) > 0 {
+		warnf("no_representative", len(stats.NoRepresentative), "representatives: %d species have no qualifying representative: %s", len(stats.NoRepresentative), strings.Join(stats.NoRepresentative, ", "))
+	}
+	if cfg.ReportPath != "" {
+		if err := writeJSONReport(cfg.ReportPath, stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// writeSelectedFasta copies the records whose IDs are in selected from input
+// to output, preserving input order.
+func writeSelectedFasta(input, output string, selected map[string]struct{}, emptySeq string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return 0, fmt.Errorf("create output dir: %w", err)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return 0, fmt.Errorf("create output: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	w := bufio.NewWriterSize(out, writerBufferSize)
+
+	in, err := openInput(input)
+	if err != nil {
+		return 0, fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	written := 0
+	err = parseFasta(in, withEmptySeqPolicy(emptySeq, nil, func(rec fastaRecord) error {
+		if _, ok := selected[rec.id]; !ok {
+			return nil
+		}
+		written++
+		return writeFasta(w, rec.id, rec.seq)
+	}))
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("flush output: %w", err)
+	}
+	return written, nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildRepresentativesPicksFewestNThenLongest(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "in.fasta")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	output := filepath.Join(tmp, "reps.fasta")
+
+	writeTestFasta(t, input, strings.Join([]string{
+		">P1", "ACGTACGTNN",
+		">P2", "ACGTACGT",
+		">P3", "ACGTACGTACGT",
+		">P4", "ACGTNNNNNN",
+		">P5", "ACGTAC",
+	}, "\n")+"\n")
+	labels := strings.Join([]string{
+		"processid\tspecies",
+		"P1\tHomo sapiens",
+		"P2\tHomo sapiens",
+		"P3\tHomo sapiens",
+		"P4\tCanis lupus",
+		"P5\tPan troglodytes",
+	}, "\n") + "\n"
+	if err := os.WriteFile(taxonkit, []byte(labels), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+
+	stats, err := buildRepresentatives(representativesConfig{
+		Input:         input,
+		TaxonkitInput: taxonkit,
+		Output:        output,
+		MaxN:          2,
+		OnEmptySeq:    emptySeqSkip,
+	})
+	if err != nil {
+		t.Fatalf("buildRepresentatives failed: %v", err)
+	}
+	if stats.Species != 3 || stats.Written != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(stats.NoRepresentative) != 1 || stats.NoRepresentative[0] != "Canis lupus" {
+		t.Fatalf("no_representative=%v want [Canis lupus]", stats.NoRepresentative)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, ">P3\n") || !strings.Contains(got, ">P5\n") {
+		t.Fatalf("expected P3 and P5 as representatives, got:\n%s", got)
+	}
+	if strings.Contains(got, ">P1\n") || strings.Contains(got, ">P2\n") || strings.Contains(got, ">P4\n") {
+		t.Fatalf("unexpected extra representatives, got:\n%s", got)
+	}
+}
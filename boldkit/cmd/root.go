@@ -1,19 +1,47 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 var appVersion string
 
+// globalOptions holds flags accepted before the subcommand name.
+type globalOptions struct {
+	Verbose        bool
+	LogFormat      string
+	FailOnWarnings bool
+	// KeepOrder makes parallel paths emit records in input order. Without it
+	// rows and QC results are written as workers finish them.
+	KeepOrder bool
+	// ProgressTotal, when > 0, replaces the computed progress total with a
+	// record count and skips the counting pass.
+	ProgressTotal int64
+	// ReportGzip gzip-compresses streaming TSV reports and appends ".gz".
+	ReportGzip bool
+}
+
+var globalOpts = globalOptions{KeepOrder: true}
+
 func Execute(args []string, version string) {
 	appVersion = version
+	defer cleanupTempFiles()
 
+	args = parseGlobalFlags(args)
 	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
+	format, err := normalizeLogFormat(globalOpts.LogFormat)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	appLogger.format = format
+	appLogger.command = args[0]
+	installSignalCancel()
 
 	switch args[0] {
 	case "extract":
@@ -32,6 +60,18 @@ func Execute(args []string, version string) {
 		runQC(args[1:])
 	case "format":
 		runFormat(args[1:])
+	case "representatives":
+		runRepresentatives(args[1:])
+	case "convert":
+		runConvert(args[1:])
+	case "tree":
+		runTree(args[1:])
+	case "verify":
+		runVerify(args[1:])
+	case "members":
+		runMembers(args[1:])
+	case "prune-taxdump":
+		runPruneTaxdump(args[1:])
 	case "version", "-v", "--version":
 		fmt.Println("boldkit", appVersion)
 	case "-h", "--help", "help":
@@ -41,23 +81,77 @@ func Execute(args []string, version string) {
 		printUsage()
 		os.Exit(1)
 	}
+
+	if globalOpts.FailOnWarnings {
+		if summary := appWarnings.summary(); summary != "" {
+			fatalf("%s finished with warnings (-fail-on-warnings): %s", args[0], summary)
+		}
+	}
+}
+
+// parseGlobalFlags consumes leading global flags and returns the remaining
+// subcommand arguments. Help/version spellings are left for the dispatcher.
+func parseGlobalFlags(args []string) []string {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	switch args[0] {
+	case "-h", "--help", "-v", "--version":
+		return args
+	}
+	fs := flag.NewFlagSet("boldkit", flag.ExitOnError)
+	fs.Usage = printUsage
+	fs.BoolVar(&globalOpts.Verbose, "verbose", false, "Log debug detail (temp files, internal steps)")
+	fs.StringVar(&globalOpts.LogFormat, "log-format", logFormatText, "Log line format: text or json")
+	fs.BoolVar(&globalOpts.FailOnWarnings, "fail-on-warnings", false, "Exit nonzero at the end of a command that logged warnings")
+	fs.BoolVar(&globalOpts.KeepOrder, "keep-order", true, "Write parallel output in input order (-keep-order=false writes as workers finish)")
+	fs.Int64Var(&globalOpts.ProgressTotal, "progress-total", 0, "Known record count to use as the progress total")
+	fs.BoolVar(&globalOpts.ReportGzip, "report-gzip", false, "Gzip streaming TSV reports (format -rejected-ids, extract curation audit) and add .gz")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "progress-total" && globalOpts.ProgressTotal <= 0 {
+			fatalf("progress-total must be > 0")
+		}
+	})
+	return fs.Args()
 }
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "BoldKit %s - BOLD TSV processing tools\n", appVersion)
 	fmt.Fprintln(os.Stderr)
 	fmt.Fprintln(os.Stderr, "Usage:")
-	fmt.Fprintln(os.Stderr, "  boldkit <command> [options]")
+	fmt.Fprintln(os.Stderr, "  boldkit [global options] <command> [options]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Global options:")
+	fmt.Fprintln(os.Stderr, "  -verbose               Log debug detail (temp files, internal steps)")
+	fmt.Fprintln(os.Stderr, "  -log-format text|json  Log line format; json emits timestamp/level/command/message objects")
+	fmt.Fprintln(os.Stderr, "  -fail-on-warnings      Exit nonzero when the command logged warnings (with a per-category summary)")
+	fmt.Fprintln(os.Stderr, "  -keep-order            Keep input order in parallel output (default true); -keep-order=false trades")
+	fmt.Fprintln(os.Stderr, "                         determinism for speed in extract/markers row parsing and qc/split/classify")
+	fmt.Fprintln(os.Stderr, "                         QC workers. format is serial and always ordered; markers dedupe and qc")
+	fmt.Fprintln(os.Stderr, "                         dedupe then keep whichever duplicate a worker finishes first")
+	fmt.Fprintln(os.Stderr, "  -progress-total N      Known record count for progress percentage/ETA (skips the row-counting pass;")
+	fmt.Fprintln(os.Stderr, "                         qc and format then count records instead of bytes)")
+	fmt.Fprintln(os.Stderr, "  -report-gzip           Gzip streaming TSV reports (format -rejected-ids, extract/pipeline curation")
+	fmt.Fprintln(os.Stderr, "                         audit) and append .gz; JSON reports stay plain")
 	fmt.Fprintln(os.Stderr)
 	fmt.Fprintln(os.Stderr, "Commands:")
-	fmt.Fprintln(os.Stderr, "  extract    Build taxonkit_input.tsv")
-	fmt.Fprintln(os.Stderr, "  markers    Build per-marker FASTA files")
-	fmt.Fprintln(os.Stderr, "  package    Package release artifacts")
-	fmt.Fprintln(os.Stderr, "  pipeline   Full pipeline: extract -> taxdump -> markers -> package (optional)")
-	fmt.Fprintln(os.Stderr, "  classify   QC + classifier formatting pipeline")
-	fmt.Fprintln(os.Stderr, "  split      QC + open/closed-world split + taxdump prune")
-	fmt.Fprintln(os.Stderr, "  qc         QC filter a FASTA against length/ambiguity/taxonomy rules")
-	fmt.Fprintln(os.Stderr, "  format     Generate classifier-specific FASTA/map outputs")
+	fmt.Fprintln(os.Stderr, "  extract          Build taxonkit_input.tsv")
+	fmt.Fprintln(os.Stderr, "  markers          Build per-marker FASTA files")
+	fmt.Fprintln(os.Stderr, "  package          Package release artifacts")
+	fmt.Fprintln(os.Stderr, "  pipeline         Full pipeline: extract -> taxdump -> markers -> package (optional)")
+	fmt.Fprintln(os.Stderr, "  classify         QC + classifier formatting pipeline")
+	fmt.Fprintln(os.Stderr, "  split            QC + open/closed-world split + taxdump prune")
+	fmt.Fprintln(os.Stderr, "  qc               QC filter a FASTA against length/ambiguity/taxonomy rules")
+	fmt.Fprintln(os.Stderr, "  format           Generate classifier-specific FASTA/map outputs")
+	fmt.Fprintln(os.Stderr, "  representatives  Pick one best-quality sequence per species")
+	fmt.Fprintln(os.Stderr, "  convert          Re-emit a SINTAX/RDP/IDTAXA reference in another classifier format")
+	fmt.Fprintln(os.Stderr, "  tree             Write the taxonomy induced by a reference FASTA as Newick")
+	fmt.Fprintln(os.Stderr, "  verify           Check release files against their <ALGO>SUMS.txt, or a taxdump's lineages (-taxdump-dir)")
+	fmt.Fprintln(os.Stderr, "  members          List the processids under a taxid or taxon name")
+	fmt.Fprintln(os.Stderr, "  prune-taxdump    Restrict a taxdump to the lineages of a FASTA or list of processids")
 	fmt.Fprintln(os.Stderr)
 	fmt.Fprintln(os.Stderr, "Run 'boldkit <command> -h' for command-specific options.")
 }
@@ -2,14 +2,19 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/md5"
+	"encoding/binary"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -21,6 +26,8 @@ const (
 	bucketUnseenKeys = "keys_unseen"
 	bucketHeldout    = "other_heldout"
 	bucketPretrain   = "pretrain"
+	// bucketGenusHeldout holds every record of a -genus-holdout-fraction genus.
+	bucketGenusHeldout = "genus_heldout"
 )
 
 type splitStats struct {
@@ -37,6 +44,19 @@ type splitStats struct {
 	UnseenKey        int `json:"keys_unseen_records"`
 	HeldoutRecords   int `json:"other_heldout_records"`
 	PretrainRecords  int `json:"pretrain_records"`
+	// OutsideReference counts records whose species is absent from
+	// -reference-fasta; they are routed to pretrain.
+	OutsideReference int `json:"outside_reference_records"`
+	// GenusHeldoutGenera and GenusHeldoutRecords count the genera (and their
+	// records) held out whole by -genus-holdout-fraction.
+	GenusHeldoutGenera  int `json:"genus_heldout_genera,omitempty"`
+	GenusHeldoutRecords int `json:"genus_heldout_records,omitempty"`
+	// MissingTaxid counts seen_train records left out of the pruned taxdump
+	// for lacking a taxid (-allow-missing-taxid).
+	MissingTaxid int `json:"missing_taxid_records,omitempty"`
+	// LeakedRecords counts evaluation-bucket records within -leak-max-dist
+	// IUPAC-aware mismatches of a seen_train sequence (-leak-check).
+	LeakedRecords int `json:"leaked_records,omitempty"`
 }
 
 type splitReport struct {
@@ -45,35 +65,200 @@ type splitReport struct {
 	Classifiers []string   `json:"classifiers"`
 	PrunedTaxa  int        `json:"pruned_taxids"`
 	Stats       splitStats `json:"stats"`
+	// FormatSkipped records a -no-format run.
+	FormatSkipped bool `json:"format_skipped,omitempty"`
 }
 
 type splitQCConfig struct {
-	Enabled    bool
-	MinLen     int
-	MaxLen     int
-	MaxN       int
-	MaxAmbig   int
-	MaxInvalid int
-	DedupeSeqs bool
-	DedupeIDs  bool
-	Progress   bool
+	Enabled           bool     `json:"enabled"`
+	MinLen            int      `json:"min_length"`
+	MaxLen            int      `json:"max_length"`
+	MaxN              int      `json:"max_n"`
+	MaxAmbig          int      `json:"max_ambig"`
+	MaxInvalid        int      `json:"max_invalid"`
+	MinGC             float64  `json:"min_gc"`
+	MaxGC             *float64 `json:"max_gc,omitempty"`
+	MaxHomopolymer    int      `json:"max_homopolymer"`
+	NormalizeCase     bool     `json:"normalize_case"`
+	StripGaps         bool     `json:"strip_gaps"`
+	TrimTerminalN     bool     `json:"trim_terminal_n"`
+	ForwardPrimer     string   `json:"forward_primer"`
+	ReversePrimer     string   `json:"reverse_primer"`
+	PrimerMismatches  int      `json:"primer_mismatches"`
+	PrimerMaxOffset   int      `json:"primer_max_offset"`
+	DropMissingPrimer bool     `json:"drop_missing_primer"`
+	DedupeSeqs        bool     `json:"dedupe"`
+	DedupeRevComp     bool     `json:"dedupe_revcomp"`
+	DedupeIDs         bool     `json:"dedupe_ids"`
+	Progress          bool     `json:"progress"`
+	Workers           int      `json:"workers"`
+	Rejects           bool     `json:"rejects"`
 }
 
+// splitJob describes one split run. It doubles as the schema for -config
+// batch files, where each job overrides the command-line defaults.
+type splitJob struct {
+	Name           string        `json:"name"`
+	Input          string        `json:"input"`
+	OutDir         string        `json:"outdir"`
+	MarkerDir      string        `json:"marker_dir"`
+	Markers        []string      `json:"markers"`
+	TaxonkitInput  string        `json:"taxonkit_input"`
+	RequireRanks   []string      `json:"require_ranks"`
+	StrictRanks    bool          `json:"strict_ranks"`
+	Classifiers    []string      `json:"classifiers"`
+	TaxdumpDir     string        `json:"taxdump_dir"`
+	TaxidMap       string        `json:"taxid_map"`
+	QC             splitQCConfig `json:"qc"`
+	FormatProgress bool          `json:"format_progress"`
+	FormatSubdirs  bool          `json:"format_subdirs"`
+	ReferenceFasta string        `json:"reference_fasta"`
+	ReferenceMap   string        `json:"reference_map"`
+	OnEmptySeq     string        `json:"on_empty_seq"`
+	OnDuplicateID  string        `json:"on_duplicate_id"`
+	EmitTreeJSON   bool          `json:"emit_tree_json"`
+	// OutputFormat and FastqQual select the bucket file format (see
+	// parseFastqQual); formatted references stay FASTA.
+	OutputFormat string `json:"output_format"`
+	FastqQual    string `json:"fastq_qual"`
+	// GroupBy is the unit kept within one bucket: "seq" (sequence md5) or
+	// "bin" (bin_uri from TaxonkitInput, joined with every record sharing a
+	// sequence with the BIN).
+	GroupBy string `json:"group_by"`
+	// GenusHoldoutFraction, when > 0, moves this fraction of genera (chosen
+	// by a hash of the genus name) with all their species to genus_heldout.
+	GenusHoldoutFraction float64 `json:"genus_holdout_fraction"`
+	// NoFormat skips formatting seen_train, and with it the taxdump prune
+	// unless PruneTaxdump asks for the prune on its own.
+	NoFormat     bool `json:"no_format"`
+	PruneTaxdump bool `json:"prune_taxdump"`
+	// ReuseQC keeps an existing qc/<input>.fasta that is newer than the input
+	// and whose record count matches its QC report, instead of re-running QC.
+	ReuseQC bool `json:"reuse_qc"`
+	// ReportFormat selects split_report.json, split_report.csv, or both.
+	ReportFormat string `json:"report_format"`
+	// AllowMissingTaxid counts and skips seen_train records without a taxid
+	// during the prune instead of failing.
+	AllowMissingTaxid bool `json:"allow_missing_taxid"`
+	// LeakCheck compares every evaluation-bucket record against seen_train
+	// and counts those within LeakMaxDist mismatches, where overlapping IUPAC
+	// codes match (see iupacMatch).
+	LeakCheck   bool `json:"leak_check"`
+	LeakMaxDist int  `json:"leak_max_dist"`
+}
+
+// Split report formats (-report-format).
+const (
+	reportFormatJSON = "json"
+	reportFormatCSV  = "csv"
+	reportFormatBoth = "both"
+)
+
+// Split grouping units (-group-by).
+const (
+	splitGroupSeq = "seq"
+	splitGroupBin = "bin"
+)
+
 type barcodeUnit struct {
 	hash  [16]byte
 	count int
 }
 
+// barcodeGroup is kept per unique barcode for the whole planning pass, so
+// the species label is an index into a labelTable rather than a string:
+// 16 bytes per group instead of 32 on 64-bit builds, which adds up to tens
+// of MB on multi-million-barcode inputs. count leads so label and conflict
+// share its trailing word; see TestBarcodeGroupSize.
 type barcodeGroup struct {
-	label    string
 	count    int
+	label    uint32
 	conflict bool
 }
 
+// labelTable interns species labels into dense integer ids.
+type labelTable struct {
+	ids   map[string]uint32
+	names []string
+}
+
+func newLabelTable() *labelTable {
+	return &labelTable{ids: make(map[string]uint32)}
+}
+
+func (t *labelTable) id(label string) uint32 {
+	if id, ok := t.ids[label]; ok {
+		return id
+	}
+	id := uint32(len(t.names))
+	t.ids[label] = id
+	t.names = append(t.names, label)
+	return id
+}
+
 type splitPlan struct {
 	seqBucket  map[[16]byte]string
 	conflicted map[[16]byte]struct{}
 	invalidIDs map[string]struct{}
+	// emptySeq and duplicateID are the -on-empty-seq and -on-duplicate-id
+	// policies the plan was built with.
+	emptySeq    string
+	duplicateID string
+	// groups joins sequences and BINs under -group-by bin; nil groups by
+	// sequence only.
+	groups groupUnion
+	// genusHeldout lists the genera routed whole to bucketGenusHeldout.
+	genusHeldout map[string]struct{}
+}
+
+// groupKey is the grouping unit of rec: the root of its sequence md5 under
+// -group-by bin, otherwise the sequence md5 itself.
+func (p splitPlan) groupKey(rec fastaRecord) [16]byte {
+	hash := md5.Sum(rec.seq)
+	if p.groups != nil {
+		return p.groups.find(hash)
+	}
+	return hash
+}
+
+// groupUnion is a union-find over sequence md5s and BIN keys. Joining each
+// record's sequence with its BIN puts a BIN, every sequence its records
+// carry, and any BIN-less record sharing one of those sequences in a single
+// group. The root is the smallest key in the set, so it does not depend on
+// input order.
+type groupUnion map[[16]byte][16]byte
+
+func (u groupUnion) find(key [16]byte) [16]byte {
+	root := key
+	for {
+		parent, ok := u[root]
+		if !ok || parent == root {
+			break
+		}
+		root = parent
+	}
+	for key != root {
+		next := u[key]
+		u[key] = root
+		key = next
+	}
+	return root
+}
+
+func (u groupUnion) union(a, b [16]byte) {
+	ra, rb := u.find(a), u.find(b)
+	if ra == rb {
+		return
+	}
+	if lessHash(rb, ra) {
+		ra, rb = rb, ra
+	}
+	u[rb] = ra
+}
+
+// binGroupKey is the union-find key of a BIN, kept apart from sequence md5s.
+func binGroupKey(bin string) [16]byte {
+	return md5.Sum([]byte("bin:" + bin))
 }
 
 type splitTarget struct {
@@ -91,100 +276,319 @@ func runSplit(args []string) {
 	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory with nodes.dmp/names.dmp/taxid.map")
 	taxidMap := fs.String("taxid-map", "", "Optional taxid.map override")
 	taxonkitIn := fs.String("taxonkit-input", "taxonkit_input.tsv", "Taxonkit TSV with processid/species labels")
+	referenceFasta := fs.String("reference-fasta", "", "Optional reference FASTA; only species present in it are split (others go to pretrain)")
+	onEmptySeq := fs.String("on-empty-seq", emptySeqSkip, "Records with an empty sequence: skip, keep, or error")
+	onDuplicateID := fs.String("on-duplicate-id", duplicateIDError, "Repeated processids: error, skip (keep the first), or rename (append _2, _3, ...)")
+	referenceMap := fs.String("reference-map", "", "Taxonkit TSV with processid/species labels for -reference-fasta (defaults to -taxonkit-input)")
 	requireRanks := fs.String("require-ranks", "kingdom,phylum,class,order,family,genus,species", "Comma-separated ranks required to keep a sequence (empty disables)")
+	strictRanks := fs.Bool("strict-ranks", false, "Fail instead of warning when -require-ranks is not a contiguous prefix of kingdom..subspecies")
 	runQC := fs.Bool("run-qc", true, "Run QC before splitting")
 	qcMin := fs.Int("qc-min-length", 200, "QC minimum cleaned length")
 	qcMax := fs.Int("qc-max-length", 700, "QC maximum cleaned length")
 	qcMaxN := fs.Int("qc-max-n", 0, "QC maximum N count")
 	qcMaxAmbig := fs.Int("qc-max-ambig", 0, "QC maximum IUPAC ambiguous count")
 	qcMaxInvalid := fs.Int("qc-max-invalid", 0, "QC maximum invalid character count")
+	qcMinGC := fs.Float64("qc-min-gc", 0, "QC minimum GC percent (0 disables)")
+	qcMaxGC := fs.Float64("qc-max-gc", 100, "QC maximum GC percent (100 disables)")
+	qcMaxHomopolymer := fs.Int("qc-max-homopolymer", 0, "QC maximum single-base run length (0 disables)")
+	qcNormalizeCase := fs.Bool("qc-normalize-case", false, "QC uppercase every base before checks")
+	qcStripGaps := fs.Bool("qc-strip-gaps", false, "QC remove '-' and '.' gap characters before checks")
+	qcTrimTerminalN := fs.Bool("qc-trim-terminal-n", false, "QC strip leading/trailing N and '-' before length and N checks")
+	qcForwardPrimer := fs.String("qc-forward-primer", "", "QC IUPAC forward primer to trim from the 5' end")
+	qcReversePrimer := fs.String("qc-reverse-primer", "", "QC IUPAC reverse primer (5'->3') to trim from the 3' end")
+	qcPrimerMismatches := fs.Int("qc-primer-mismatches", 2, "QC mismatches allowed when locating primers")
+	qcPrimerMaxOffset := fs.Int("qc-primer-max-offset", defaultPrimerMaxOffset, "QC bases a primer match may sit from its end of the read")
+	qcDropMissingPrimer := fs.Bool("qc-drop-missing-primer", false, "QC drop records where a configured primer is not found")
 	qcDedupe := fs.Bool("qc-dedupe", true, "QC drop duplicate sequences")
+	qcDedupeRevComp := fs.Bool("qc-dedupe-revcomp", false, "QC also treat reverse complements as duplicates")
 	qcDedupeIDs := fs.Bool("qc-dedupe-ids", true, "QC drop duplicate IDs")
 	qcProgress := fs.Bool("qc-progress", true, "Show QC progress bar (approximate)")
+	qcRejects := fs.Bool("qc-rejects", false, "QC write rejected records (header annotated with reasons) to <outdir>/qc/<input>.rejects.fasta")
+	qcWorkers := fs.Int("qc-workers", 1, "QC goroutines for per-record sequence checks (output order follows global -keep-order)")
 	formatProgress := fs.Bool("format-progress", true, "Show format progress bar (approximate)")
+	formatSubdirs := fs.Bool("format-subdirs", false, "Write each classifier's reference outputs to its own subdirectory")
+	emitTreeJSON := fs.Bool("emit-tree-json", false, "Also write taxdump_pruned/tree.json, the kept taxonomy as nested JSON")
+	outputFormat := fs.String("output-format", outputFormatFasta, "Split bucket format: fasta, or fastq with synthetic constant qualities (formatted references stay FASTA)")
+	fastqQual := fs.String("fastq-qual", defaultFastqQual, "Phred+33 character used for every base with -output-format fastq")
+	genusHoldout := fs.Float64("genus-holdout-fraction", 0, "Fraction of genera (hash-selected) whose species all go to genus_heldout.fasta (0 disables)")
+	noFormat := fs.Bool("no-format", false, "Skip formatting seen_train (and the taxdump prune that feeds it)")
+	pruneTaxdump := fs.Bool("prune-taxdump", false, "Prune the taxdump to seen_train even with -no-format")
+	reportFormat := fs.String("report-format", reportFormatJSON, "Split report format: json, csv (key,value rows), or both")
+	allowMissingTaxid := fs.Bool("allow-missing-taxid", false, "Skip and count seen_train processids missing from the taxid map instead of failing the prune")
+	leakCheck := fs.Bool("leak-check", false, "Count evaluation-bucket records within -leak-max-dist IUPAC-aware mismatches of a seen_train sequence (holds seen_train in memory; sequences with ambiguity codes are compared against every train sequence of the same length)")
+	leakMaxDist := fs.Int("leak-max-dist", 0, "Mismatches allowed by -leak-check (equal-length sequences only)")
+	reuseQC := fs.Bool("reuse-qc", false, "Reuse an existing qc output newer than the input (record count checked against its QC report)")
+	groupBy := fs.String("group-by", splitGroupSeq, "Unit kept within one split bucket: seq (sequence md5) or bin (taxonkit bin_uri, joined through shared sequences)")
+	configPath := fs.String("config", "", "Optional JSON batch file of split jobs (flags act as per-job defaults; jobs sharing a -taxdump-dir load it once)")
+	jobWorkers := fs.Int("job-workers", 1, "Batch jobs to run concurrently (with -config)")
 	if err := fs.Parse(args); err != nil {
 		fatalf("parse args failed: %v", err)
 	}
 
-	ranks := splitList(*requireRanks)
-	classifierList := splitList(*classifiers)
-	if len(classifierList) == 0 {
-		fatalf("classifier must not be empty")
-	}
 	qcCfg := splitQCConfig{
-		Enabled:    *runQC,
-		MinLen:     *qcMin,
-		MaxLen:     *qcMax,
-		MaxN:       *qcMaxN,
-		MaxAmbig:   *qcMaxAmbig,
-		MaxInvalid: *qcMaxInvalid,
-		DedupeSeqs: *qcDedupe,
-		DedupeIDs:  *qcDedupeIDs,
-		Progress:   *qcProgress,
-	}
-
-	if *input == "" {
-		markerList := splitList(*markers)
-		if len(markerList) == 0 {
-			fatalf("input is empty and markers list is empty")
-		}
-		for _, marker := range markerList {
-			markerInput, err := resolveMarkerInput(*markerDir, marker)
-			if err != nil {
-				fatalf("marker %s: %v", marker, err)
-			}
-			baseOut := filepath.Join(*outDir, safeTag(marker))
-			if err := splitOne(markerInput, baseOut, *taxonkitIn, ranks, classifierList, *taxdumpDir, *taxidMap, qcCfg, *formatProgress); err != nil {
-				fatalf("split %s failed: %v", marker, err)
-			}
+		Enabled:           *runQC,
+		MinLen:            *qcMin,
+		MaxLen:            *qcMax,
+		MaxN:              *qcMaxN,
+		MaxAmbig:          *qcMaxAmbig,
+		MaxInvalid:        *qcMaxInvalid,
+		MinGC:             *qcMinGC,
+		MaxGC:             qcMaxGC,
+		MaxHomopolymer:    *qcMaxHomopolymer,
+		NormalizeCase:     *qcNormalizeCase,
+		StripGaps:         *qcStripGaps,
+		TrimTerminalN:     *qcTrimTerminalN,
+		ForwardPrimer:     *qcForwardPrimer,
+		ReversePrimer:     *qcReversePrimer,
+		PrimerMismatches:  *qcPrimerMismatches,
+		PrimerMaxOffset:   *qcPrimerMaxOffset,
+		DropMissingPrimer: *qcDropMissingPrimer,
+		DedupeSeqs:        *qcDedupe,
+		DedupeRevComp:     *qcDedupeRevComp,
+		DedupeIDs:         *qcDedupeIDs,
+		Progress:          *qcProgress,
+		Workers:           *qcWorkers,
+		Rejects:           *qcRejects,
+	}
+	base := splitJob{
+		Input:                *input,
+		OutDir:               *outDir,
+		MarkerDir:            *markerDir,
+		Markers:              splitList(*markers),
+		TaxonkitInput:        *taxonkitIn,
+		ReferenceFasta:       *referenceFasta,
+		ReferenceMap:         *referenceMap,
+		OnEmptySeq:           *onEmptySeq,
+		OnDuplicateID:        *onDuplicateID,
+		RequireRanks:         splitList(*requireRanks),
+		StrictRanks:          *strictRanks,
+		Classifiers:          splitList(*classifiers),
+		TaxdumpDir:           *taxdumpDir,
+		TaxidMap:             *taxidMap,
+		QC:                   qcCfg,
+		FormatProgress:       *formatProgress,
+		FormatSubdirs:        *formatSubdirs,
+		EmitTreeJSON:         *emitTreeJSON,
+		OutputFormat:         *outputFormat,
+		FastqQual:            *fastqQual,
+		GroupBy:              *groupBy,
+		GenusHoldoutFraction: *genusHoldout,
+		NoFormat:             *noFormat,
+		PruneTaxdump:         *pruneTaxdump,
+		ReuseQC:              *reuseQC,
+		ReportFormat:         *reportFormat,
+		AllowMissingTaxid:    *allowMissingTaxid,
+		LeakCheck:            *leakCheck,
+		LeakMaxDist:          *leakMaxDist,
+	}
+
+	if *configPath != "" {
+		jobs, err := loadSplitJobs(*configPath, base)
+		if err != nil {
+			fatalf("load split config: %v", err)
+		}
+		if err := runSplitJobs(jobs, *jobWorkers); err != nil {
+			fatalf("split batch failed: %v", err)
 		}
 		return
 	}
 
-	if err := splitOne(*input, *outDir, *taxonkitIn, ranks, classifierList, *taxdumpDir, *taxidMap, qcCfg, *formatProgress); err != nil {
+	if err := validateSplitJob(base); err != nil {
+		fatalf("%v", err)
+	}
+	if err := runSplitJob(base); err != nil {
 		fatalf("split failed: %v", err)
 	}
 }
 
-func splitOne(input, outDir, taxonkitIn string, ranks, classifiers []string, taxdumpDir, taxidMap string, qcCfg splitQCConfig, formatProgress bool) error {
+// qcConfig maps the job's QC thresholds onto a qcFasta config writing to out.
+func (job splitJob) qcConfig(out string) QCConfig {
+	return QCConfig{
+		MinLen:            job.QC.MinLen,
+		MaxLen:            job.QC.MaxLen,
+		MaxN:              job.QC.MaxN,
+		MaxAmbig:          job.QC.MaxAmbig,
+		MaxInvalid:        job.QC.MaxInvalid,
+		MinGC:             job.QC.MinGC,
+		MaxGC:             job.QC.MaxGC,
+		MaxHomopolymer:    job.QC.MaxHomopolymer,
+		NormalizeCase:     job.QC.NormalizeCase,
+		StripGaps:         job.QC.StripGaps,
+		TrimTerminalN:     job.QC.TrimTerminalN,
+		ForwardPrimer:     normalizePrimer(job.QC.ForwardPrimer),
+		ReversePrimer:     normalizePrimer(job.QC.ReversePrimer),
+		PrimerMismatches:  job.QC.PrimerMismatches,
+		PrimerMaxOffset:   job.QC.PrimerMaxOffset,
+		DropMissingPrimer: job.QC.DropMissingPrimer,
+		DedupeSeqs:        job.QC.DedupeSeqs,
+		DedupeRevComp:     job.QC.DedupeRevComp,
+		DedupeIDs:         job.QC.DedupeIDs,
+		RequireRanks:      job.RequireRanks,
+		TaxdumpDir:        job.TaxdumpDir,
+		TaxidMapPath:      job.TaxidMap,
+		OutputPath:        out,
+		OnEmptySeq:        job.OnEmptySeq,
+		Progress:          job.QC.Progress,
+		Workers:           job.QC.Workers,
+	}
+}
+
+func validateSplitJob(job splitJob) error {
+	if _, err := normalizeEmptySeqPolicy(job.OnEmptySeq); err != nil {
+		return err
+	}
+	if _, err := normalizeDuplicateIDPolicy(job.OnDuplicateID); err != nil {
+		return err
+	}
+	if len(job.Classifiers) == 0 {
+		return fmt.Errorf("classifier must not be empty")
+	}
+	if job.Input == "" && len(job.Markers) == 0 {
+		return fmt.Errorf("input is empty and markers list is empty")
+	}
+	if job.QC.MaxHomopolymer < 0 {
+		return fmt.Errorf("qc max-homopolymer must be >= 0")
+	}
+	if _, err := parseFastqQual(job.OutputFormat, job.FastqQual); err != nil {
+		return err
+	}
+	if job.GenusHoldoutFraction < 0 || job.GenusHoldoutFraction >= 1 {
+		return fmt.Errorf("genus-holdout-fraction must be in [0, 1)")
+	}
+	if job.LeakMaxDist < 0 {
+		return fmt.Errorf("leak-max-dist must be >= 0")
+	}
+	switch job.ReportFormat {
+	case "", reportFormatJSON, reportFormatCSV, reportFormatBoth:
+	default:
+		return fmt.Errorf("report-format must be json, csv or both (got %q)", job.ReportFormat)
+	}
+	switch job.GroupBy {
+	case "", splitGroupSeq, splitGroupBin:
+	default:
+		return fmt.Errorf("group-by must be seq or bin (got %q)", job.GroupBy)
+	}
+	if err := checkRankPrefix("split", job.RequireRanks, job.StrictRanks); err != nil {
+		return err
+	}
+	if err := validatePrimerConfig("qc-", normalizePrimer(job.QC.ForwardPrimer), normalizePrimer(job.QC.ReversePrimer), job.QC.PrimerMismatches, job.QC.PrimerMaxOffset); err != nil {
+		return err
+	}
+	return validateGCBounds(job.QC.MinGC, job.QC.MaxGC)
+}
+
+// runSplitJob splits a single input, or each marker FASTA under MarkerDir
+// into <outdir>/<marker> when Input is empty.
+func runSplitJob(job splitJob) error {
+	policy, err := normalizeEmptySeqPolicy(job.OnEmptySeq)
+	if err != nil {
+		return err
+	}
+	job.OnEmptySeq = policy
+	if job.OnDuplicateID, err = normalizeDuplicateIDPolicy(job.OnDuplicateID); err != nil {
+		return err
+	}
+	if job.Input != "" {
+		return splitOne(job.Input, job.OutDir, job)
+	}
+	for _, marker := range job.Markers {
+		markerInput, err := resolveMarkerInput(job.MarkerDir, marker)
+		if err != nil {
+			return fmt.Errorf("marker %s: %w", marker, err)
+		}
+		baseOut := filepath.Join(job.OutDir, safeTag(marker))
+		if err := splitOne(markerInput, baseOut, job); err != nil {
+			return fmt.Errorf("split %s: %w", marker, err)
+		}
+	}
+	return nil
+}
+
+func splitOne(input, outDir string, job splitJob) error {
 	splitInput := input
-	if qcCfg.Enabled {
+	if job.QC.Enabled {
 		qcOut := filepath.Join(outDir, "qc", qcBaseName(input)+".fasta")
-		logf("split: QC -> %s", qcOut)
-		if err := qcFasta(input, qcConfig{
-			MinLen:       qcCfg.MinLen,
-			MaxLen:       qcCfg.MaxLen,
-			MaxN:         qcCfg.MaxN,
-			MaxAmbig:     qcCfg.MaxAmbig,
-			MaxInvalid:   qcCfg.MaxInvalid,
-			DedupeSeqs:   qcCfg.DedupeSeqs,
-			DedupeIDs:    qcCfg.DedupeIDs,
-			RequireRanks: ranks,
-			TaxdumpDir:   taxdumpDir,
-			TaxidMapPath: taxidMap,
-			OutputPath:   qcOut,
-			Progress:     qcCfg.Progress,
-		}); err != nil {
-			return fmt.Errorf("qc failed: %w", err)
+		qcReport := filepath.Join(outDir, "qc", qcBaseName(input)+".qc_report.json")
+		reuse := false
+		if job.ReuseQC {
+			reason, err := reusableQC(input, qcOut, qcReport, job.qcConfig(qcOut))
+			if err != nil {
+				return err
+			}
+			if reuse = reason == ""; !reuse {
+				logf("split: -reuse-qc cannot reuse %s (%s), re-running QC", qcOut, reason)
+			}
+		}
+		if reuse {
+			logf("split: reusing QC output %s", qcOut)
+		} else {
+			logf("split: QC -> %s", qcOut)
+			cfg := job.qcConfig(qcOut)
+			cfg.ReportPath = qcReport
+			if job.QC.Rejects {
+				cfg.RejectsPath = filepath.Join(outDir, "qc", qcBaseName(input)+".rejects.fasta")
+			}
+			if err := qcFasta(input, cfg); err != nil {
+				return fmt.Errorf("qc failed: %w", err)
+			}
 		}
 		splitInput = qcOut
 	}
 
-	fastaIDs, err := collectFastaIDs(splitInput)
+	fastaIDs, renamed, err := collectFastaIDs(splitInput, job.OnEmptySeq, job.OnDuplicateID)
 	if err != nil {
 		return err
 	}
-	labels, invalidIDs, err := loadProcessLabelMap(taxonkitIn, fastaIDs)
+	labels, invalidIDs, err := loadProcessLabelMap(job.TaxonkitInput, fastaIDs)
 	if err != nil {
 		return err
 	}
+	// Renamed duplicates carry their original processid's label.
+	withRenamedIDs(labels, renamed)
+	for id := range renamed {
+		if _, ok := labels[id]; ok {
+			delete(invalidIDs, id)
+		}
+	}
+	if len(invalidIDs) > 0 {
+		warnf("missing_species_label", len(invalidIDs), "split: %d records missing species label (moved to %s)", len(invalidIDs), bucketPretrain)
+	}
+
+	var allowed map[string]struct{}
+	if job.ReferenceFasta != "" {
+		allowed, err = loadReferenceSpecies(job.ReferenceFasta, job.referenceMap())
+		if err != nil {
+			return err
+		}
+		logf("split: reference %s allows %d species", job.ReferenceFasta, len(allowed))
+	}
+
+	var bins map[string]string
+	if job.GroupBy == splitGroupBin {
+		bins, err = loadProcessBins(job.TaxonkitInput, fastaIDs)
+		if err != nil {
+			return err
+		}
+		if bins != nil {
+			withRenamedIDs(bins, renamed)
+		}
+		if bins == nil {
+			warnf("missing_bin_column", 1, "split: %s has no bin_uri column; -group-by bin falls back to sequence hash", job.TaxonkitInput)
+		} else {
+			logf("split: grouping %d records by BIN", len(bins))
+		}
+	}
 
-	plan, stats, err := buildSplitPlan(splitInput, labels, invalidIDs)
+	plan, stats, err := buildSplitPlan(splitInput, labels, invalidIDs, allowed, bins, job.GenusHoldoutFraction, job.OnEmptySeq, job.OnDuplicateID)
 	if err != nil {
 		return err
 	}
 
-	writeStats, seenTrainIDs, err := writeSplitFastas(splitInput, outDir, plan, labels)
+	fastqQual, err := parseFastqQual(job.OutputFormat, job.FastqQual)
+	if err != nil {
+		return err
+	}
+	writeStats, seenTrainIDs, err := writeSplitFastas(splitInput, outDir, plan, labels, fastqQual)
 	if err != nil {
 		return err
 	}
@@ -196,70 +600,179 @@ func splitOne(input, outDir, taxonkitIn string, ranks, classifiers []string, tax
 	stats.UnseenKey = writeStats[bucketUnseenKeys]
 	stats.HeldoutRecords = writeStats[bucketHeldout]
 	stats.PretrainRecords = writeStats[bucketPretrain]
+	stats.GenusHeldoutRecords = writeStats[bucketGenusHeldout]
+	if job.LeakCheck {
+		stats.LeakedRecords, err = countSplitLeaks(outDir, seqFileExt(fastqQual), job.LeakMaxDist)
+		if err != nil {
+			return err
+		}
+		if stats.LeakedRecords > 0 {
+			warnf("split_leak", stats.LeakedRecords, "split: %d evaluation records within %d mismatches of seen_train", stats.LeakedRecords, job.LeakMaxDist)
+		}
+	}
 
-	prunedDir, keptTaxids, err := pruneTaxdumpForSeenTrain(seenTrainIDs, taxdumpDir, taxidMap, outDir)
-	if err != nil {
-		return err
+	var prunedDir string
+	var keptTaxids int
+	if !job.NoFormat || job.PruneTaxdump {
+		prunedDir, keptTaxids, stats.MissingTaxid, err = pruneTaxdumpForSeenTrain(seenTrainIDs, renamed, job.TaxdumpDir, job.TaxidMap, outDir, job.EmitTreeJSON, job.AllowMissingTaxid)
+		if err != nil {
+			return err
+		}
 	}
 
-	seenTrain := filepath.Join(outDir, "seen_train.fasta")
-	formatOut := filepath.Join(outDir, "formatted")
-	logf("split: format references from %s -> %s", seenTrain, formatOut)
-	if err := formatFasta(formatConfig{
-		Classifiers:  classifiers,
-		RequireRanks: ranks,
-		Input:        seenTrain,
-		OutDir:       formatOut,
-		TaxdumpDir:   prunedDir,
-		TaxidMapPath: filepath.Join(prunedDir, "taxid.map"),
-		Progress:     formatProgress,
-	}); err != nil {
-		return fmt.Errorf("format references: %w", err)
+	if job.NoFormat {
+		logf("split: -no-format set, skipping reference formatting")
+	} else {
+		seenTrain := filepath.Join(outDir, "seen_train"+seqFileExt(fastqQual))
+		formatOut := filepath.Join(outDir, "formatted")
+		logf("split: format references from %s -> %s", seenTrain, formatOut)
+		if err := formatFasta(formatConfig{
+			Classifiers:  job.Classifiers,
+			RequireRanks: job.RequireRanks,
+			Input:        seenTrain,
+			OutDir:       formatOut,
+			TaxdumpDir:   prunedDir,
+			TaxidMapPath: filepath.Join(prunedDir, "taxid.map"),
+			Progress:     job.FormatProgress,
+			Subdirs:      job.FormatSubdirs,
+			OnEmptySeq:   job.OnEmptySeq,
+		}); err != nil {
+			return fmt.Errorf("format references: %w", err)
+		}
 	}
 
 	logf("split: records=%d classes=%d seen-classes=%d unseen-classes=%d heldout-classes=%d", stats.TotalRecords, stats.TotalClasses, stats.SeenClasses, stats.UnseenClasses, stats.HeldoutClasses)
-	logf("split: pruned taxdump -> %s (kept_taxids=%d)", prunedDir, keptTaxids)
-	reportPath := filepath.Join(outDir, "split_report.json")
-	if err := writeSplitReport(reportPath, splitReport{
-		Input:       splitInput,
-		OutDir:      outDir,
-		Classifiers: classifiers,
-		PrunedTaxa:  keptTaxids,
-		Stats:       stats,
-	}); err != nil {
-		return err
+	if stats.GenusHeldoutGenera > 0 {
+		logf("split: genus holdout genera=%d records=%d", stats.GenusHeldoutGenera, stats.GenusHeldoutRecords)
+	}
+	if prunedDir != "" {
+		logf("split: pruned taxdump -> %s (kept_taxids=%d)", prunedDir, keptTaxids)
+	}
+	report := splitReport{
+		Input:         splitInput,
+		OutDir:        outDir,
+		Classifiers:   job.Classifiers,
+		PrunedTaxa:    keptTaxids,
+		Stats:         stats,
+		FormatSkipped: job.NoFormat,
+	}
+	if job.ReportFormat != reportFormatCSV {
+		reportPath := filepath.Join(outDir, "split_report.json")
+		if err := writeSplitReport(reportPath, report); err != nil {
+			return err
+		}
+		logf("split: report -> %s", reportPath)
+	}
+	if job.ReportFormat == reportFormatCSV || job.ReportFormat == reportFormatBoth {
+		reportPath := filepath.Join(outDir, "split_report.csv")
+		if err := writeSplitReportCSV(reportPath, report); err != nil {
+			return err
+		}
+		logf("split: report -> %s", reportPath)
 	}
-	logf("split: report -> %s", reportPath)
 	return nil
 }
 
-func collectFastaIDs(input string) (map[string]struct{}, error) {
+// reusableQC returns "" when qcOut can stand in for a fresh QC run of
+// input with cfg: it and its QC report exist, are newer than input, the
+// report's config matches cfg and its written count matches qcOut.
+// Otherwise it returns why not.
+func reusableQC(input, qcOut, reportPath string, cfg QCConfig) (string, error) {
+	inInfo, err := os.Stat(input)
+	if err != nil {
+		return "", fmt.Errorf("stat input: %w", err)
+	}
+	for _, path := range []string{qcOut, reportPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "missing " + filepath.Base(path), nil
+		}
+		if !info.ModTime().After(inInfo.ModTime()) {
+			return filepath.Base(path) + " is older than the input", nil
+		}
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return "", fmt.Errorf("read qc report: %w", err)
+	}
+	var stats QCStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return "unreadable qc report", nil
+	}
+	if stats.Config == nil {
+		return "qc report has no config", nil
+	}
+	// Compare encodings: that is the form the report stored.
+	had, err := json.Marshal(stats.Config)
+	if err != nil {
+		return "", err
+	}
+	want, err := json.Marshal(cfg.reportConfig())
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(had, want) {
+		return "qc settings changed", nil
+	}
+	counts, err := countMarkerSeqs([]string{qcOut})
+	if err != nil {
+		return "", err
+	}
+	if counts[0] != stats.Written {
+		return fmt.Sprintf("%d records, qc report says %d", counts[0], stats.Written), nil
+	}
+	return "", nil
+}
+
+// collectFastaIDs returns the record IDs of input, applying the
+// -on-empty-seq and -on-duplicate-id policies. renamed maps each ID made by
+// the rename policy to the original ID.
+func collectFastaIDs(input, emptySeq, duplicateID string) (map[string]struct{}, map[string]string, error) {
 	in, err := openInput(input)
 	if err != nil {
-		return nil, fmt.Errorf("open input: %w", err)
+		return nil, nil, fmt.Errorf("open input: %w", err)
 	}
 	defer func() {
 		_ = in.Close()
 	}()
 
 	ids := make(map[string]struct{}, 1<<20)
-	err = parseFasta(in, func(rec fastaRecord) error {
+	renamed := make(map[string]string)
+	skipped, duplicates := 0, 0
+	err = parseFasta(in, withEmptySeqPolicy(emptySeq, &skipped, withDuplicateIDPolicy(duplicateID, &duplicates, renamed, func(rec fastaRecord) error {
 		if rec.id == "" {
 			return fmt.Errorf("found FASTA record with empty ID")
 		}
-		if _, dup := ids[rec.id]; dup {
-			return fmt.Errorf("duplicate processid in input FASTA: %s", rec.id)
-		}
 		ids[rec.id] = struct{}{}
 		return nil
-	})
+	})))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if skipped > 0 {
+		logf("split: skipped %d empty-sequence records in %s", skipped, input)
+	}
+	if duplicates > 0 {
+		verb := "skipped"
+		if duplicateID == duplicateIDRename {
+			verb = "renamed"
+		}
+		logf("split: %s %d duplicate-id records in %s", verb, duplicates, input)
 	}
 	if len(ids) == 0 {
-		return nil, fmt.Errorf("input FASTA appears empty: %s", input)
+		return nil, nil, fmt.Errorf("input FASTA appears empty: %s", input)
+	}
+	return ids, renamed, nil
+}
+
+// withRenamedIDs copies the entry of each renamed ID's original ID in m to
+// the new ID.
+func withRenamedIDs(m map[string]string, renamed map[string]string) {
+	for id, orig := range renamed {
+		if v, ok := m[orig]; ok {
+			m[id] = v
+		}
 	}
-	return ids, nil
 }
 
 func loadProcessLabelMap(path string, wantedIDs map[string]struct{}) (map[string]string, map[string]struct{}, error) {
@@ -330,13 +843,90 @@ func loadProcessLabelMap(path string, wantedIDs map[string]struct{}) (map[string
 	if found == 0 {
 		return nil, nil, fmt.Errorf("taxonkit input has no matching process IDs for input FASTA: %s", path)
 	}
-	if len(invalid) > 0 {
-		logf("split: %d records missing species label (moved to %s)", len(invalid), bucketPretrain)
-	}
 	return labels, invalid, nil
 }
 
-func buildSplitPlan(input string, labels map[string]string, invalidIDs map[string]struct{}) (splitPlan, splitStats, error) {
+// loadProcessBins reads bin_uri for wantedIDs from a taxonkit TSV. It
+// returns nil when the TSV has no bin_uri column; ids with an empty BIN are
+// left out so they group by sequence.
+func loadProcessBins(path string, wantedIDs map[string]struct{}) (map[string]string, error) {
+	in, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("open taxonkit input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	headerSeen := false
+	idxProcess := -1
+	idxBin := -1
+	bins := make(map[string]string)
+	err = ParseTSV(in, DefaultOptions(), func(row Row) error {
+		if !headerSeen {
+			headerSeen = true
+			idxProcess = indexOfBytes(row.Fields, "processid")
+			idxBin = indexOfBytes(row.Fields, "bin_uri")
+			if idxProcess < 0 {
+				return fmt.Errorf("required headers missing in taxonkit input (need processid)")
+			}
+			return nil
+		}
+		if idxBin < 0 {
+			return nil
+		}
+		if idxProcess >= len(row.Fields) || idxBin >= len(row.Fields) {
+			return fmt.Errorf("line %d: expected at least %d fields", row.Line, maxIndex(idxProcess, idxBin)+1)
+		}
+		pid := string(row.Fields[idxProcess])
+		if _, need := wantedIDs[pid]; !need {
+			return nil
+		}
+		if bin := normalizeBytes(row.Fields[idxBin]); len(bin) > 0 {
+			bins[pid] = string(bin)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if idxBin < 0 {
+		return nil, nil
+	}
+	return bins, nil
+}
+
+func (job splitJob) referenceMap() string {
+	if job.ReferenceMap != "" {
+		return job.ReferenceMap
+	}
+	return job.TaxonkitInput
+}
+
+// loadReferenceSpecies returns the species labels of the records in a
+// reference FASTA, resolved through a processid/species TSV.
+func loadReferenceSpecies(fastaPath, mapPath string) (map[string]struct{}, error) {
+	ids, _, err := collectFastaIDs(fastaPath, emptySeqSkip, duplicateIDError)
+	if err != nil {
+		return nil, fmt.Errorf("reference fasta: %w", err)
+	}
+	labels, _, err := loadProcessLabelMap(mapPath, ids)
+	if err != nil {
+		return nil, fmt.Errorf("reference map: %w", err)
+	}
+	species := make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		species[label] = struct{}{}
+	}
+	return species, nil
+}
+
+// buildSplitPlan groups records by barcode, joined through shared BINs for
+// the ids in bins, and assigns species to buckets. A genusFraction > 0 first holds out whole
+// genera, so their species never reach the species-level buckets.
+// A non-nil allowed set restricts the plan to those species; records of any
+// other species are marked invalid so they land in pretrain.
+func buildSplitPlan(input string, labels map[string]string, invalidIDs map[string]struct{}, allowed map[string]struct{}, bins map[string]string, genusFraction float64, emptySeq, duplicateID string) (splitPlan, splitStats, error) {
 	in, err := openInput(input)
 	if err != nil {
 		return splitPlan{}, splitStats{}, fmt.Errorf("open input: %w", err)
@@ -346,9 +936,14 @@ func buildSplitPlan(input string, labels map[string]string, invalidIDs map[strin
 	}()
 
 	barcodeGroups := make(map[[16]byte]barcodeGroup, 1<<20)
+	var groups groupUnion
+	if bins != nil {
+		groups = make(groupUnion)
+	}
+	table := newLabelTable()
 	stats := splitStats{}
 
-	err = parseFasta(in, func(rec fastaRecord) error {
+	err = parseFasta(in, withEmptySeqPolicy(emptySeq, nil, withDuplicateIDPolicy(duplicateID, nil, nil, func(rec fastaRecord) error {
 		stats.TotalRecords++
 		if _, bad := invalidIDs[rec.id]; bad {
 			return nil
@@ -358,26 +953,40 @@ func buildSplitPlan(input string, labels map[string]string, invalidIDs map[strin
 			invalidIDs[rec.id] = struct{}{}
 			return nil
 		}
+		if allowed != nil {
+			if _, keep := allowed[label]; !keep {
+				invalidIDs[rec.id] = struct{}{}
+				stats.OutsideReference++
+				return nil
+			}
+		}
 
 		hash := md5.Sum(rec.seq)
+		if bin, ok := bins[rec.id]; ok {
+			groups.union(hash, binGroupKey(bin))
+		}
+		id := table.id(label)
 		group := barcodeGroups[hash]
 		if group.count == 0 {
-			group.label = label
-		} else if group.label != label {
+			group.label = id
+		} else if group.label != id {
 			group.conflict = true
 		}
 		group.count++
 		barcodeGroups[hash] = group
 		return nil
-	})
+	})))
 	if err != nil {
 		return splitPlan{}, splitStats{}, err
 	}
+	if groups != nil {
+		barcodeGroups = mergeBarcodeGroups(barcodeGroups, groups)
+	}
 
 	seqBucket := make(map[[16]byte]string, len(barcodeGroups))
 	conflicted := make(map[[16]byte]struct{})
-	speciesUnits := make(map[string][]barcodeUnit)
-	speciesCounts := make(map[string]int)
+	speciesUnits := make(map[uint32][]barcodeUnit)
+	speciesCounts := make(map[uint32]int)
 
 	for hash, group := range barcodeGroups {
 		if group.conflict {
@@ -388,9 +997,27 @@ func buildSplitPlan(input string, labels map[string]string, invalidIDs map[strin
 		speciesCounts[group.label] += group.count
 	}
 
+	var genusHeldout map[string]struct{}
+	if genusFraction > 0 {
+		genusHeldout = make(map[string]struct{})
+		for id := range speciesUnits {
+			if genus := labelGenus(table.names[id]); genusHeldOut(genus, genusFraction) {
+				genusHeldout[genus] = struct{}{}
+			}
+		}
+		stats.GenusHeldoutGenera = len(genusHeldout)
+	}
+
 	stats.TotalClasses = len(speciesUnits)
-	for label, units := range speciesUnits {
-		total := speciesCounts[label]
+	for id, units := range speciesUnits {
+		label := table.names[id]
+		if _, held := genusHeldout[labelGenus(label)]; held {
+			for _, unit := range units {
+				seqBucket[unit.hash] = bucketGenusHeldout
+			}
+			continue
+		}
+		total := speciesCounts[id]
 		uniqueBarcodes := len(units)
 		sort.Slice(units, func(i, j int) bool {
 			return lessHash(units[i].hash, units[j].hash)
@@ -427,16 +1054,40 @@ func buildSplitPlan(input string, labels map[string]string, invalidIDs map[strin
 	}
 
 	if len(conflicted) > 0 {
-		logf("split: %d barcode groups span multiple species labels (moved to %s)", len(conflicted), bucketPretrain)
+		warnf("conflicted_barcodes", len(conflicted), "split: %d barcode groups span multiple species labels (moved to %s)", len(conflicted), bucketPretrain)
 	}
 
 	return splitPlan{
-		seqBucket:  seqBucket,
-		conflicted: conflicted,
-		invalidIDs: invalidIDs,
+		seqBucket:    seqBucket,
+		conflicted:   conflicted,
+		invalidIDs:   invalidIDs,
+		emptySeq:     emptySeq,
+		duplicateID:  duplicateID,
+		groups:       groups,
+		genusHeldout: genusHeldout,
 	}, stats, nil
 }
 
+// mergeBarcodeGroups folds per-sequence groups into their union-find root; a
+// merged group is conflicted when its members disagree on the species.
+func mergeBarcodeGroups(barcodeGroups map[[16]byte]barcodeGroup, groups groupUnion) map[[16]byte]barcodeGroup {
+	merged := make(map[[16]byte]barcodeGroup, len(barcodeGroups))
+	for hash, group := range barcodeGroups {
+		root := groups.find(hash)
+		m, ok := merged[root]
+		if !ok {
+			merged[root] = group
+			continue
+		}
+		if group.conflict || group.label != m.label {
+			m.conflict = true
+		}
+		m.count += group.count
+		merged[root] = m
+	}
+	return merged
+}
+
 func assignUnits(seqBucket map[[16]byte]string, units []barcodeUnit, targets []splitTarget) {
 	idx := 0
 	for _, t := range targets {
@@ -459,20 +1110,26 @@ func assignUnits(seqBucket map[[16]byte]string, units []barcodeUnit, targets []s
 	}
 }
 
-func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]string) (map[string]int, map[string]struct{}, error) {
+// writeSplitFastas writes each record to its bucket file, as FASTQ with a
+// constant quality when fastqQual is non-zero.
+func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]string, fastqQual byte) (map[string]int, map[string]struct{}, error) {
 	if err := os.MkdirAll(outDir, 0o755); err != nil {
 		return nil, nil, fmt.Errorf("create output dir: %w", err)
 	}
 
+	ext := seqFileExt(fastqQual)
 	paths := map[string]string{
-		bucketSeenTrain:  filepath.Join(outDir, "seen_train.fasta"),
-		bucketSeenVal:    filepath.Join(outDir, "seen_val.fasta"),
-		bucketSeenTest:   filepath.Join(outDir, "seen_test.fasta"),
-		bucketUnseenTest: filepath.Join(outDir, "test_unseen.fasta"),
-		bucketUnseenVal:  filepath.Join(outDir, "val_unseen.fasta"),
-		bucketUnseenKeys: filepath.Join(outDir, "keys_unseen.fasta"),
-		bucketHeldout:    filepath.Join(outDir, "other_heldout.fasta"),
-		bucketPretrain:   filepath.Join(outDir, "pretrain.fasta"),
+		bucketSeenTrain:  filepath.Join(outDir, "seen_train"+ext),
+		bucketSeenVal:    filepath.Join(outDir, "seen_val"+ext),
+		bucketSeenTest:   filepath.Join(outDir, "seen_test"+ext),
+		bucketUnseenTest: filepath.Join(outDir, "test_unseen"+ext),
+		bucketUnseenVal:  filepath.Join(outDir, "val_unseen"+ext),
+		bucketUnseenKeys: filepath.Join(outDir, "keys_unseen"+ext),
+		bucketHeldout:    filepath.Join(outDir, "other_heldout"+ext),
+		bucketPretrain:   filepath.Join(outDir, "pretrain"+ext),
+	}
+	if plan.genusHeldout != nil {
+		paths[bucketGenusHeldout] = filepath.Join(outDir, "genus_heldout"+ext)
 	}
 
 	type splitWriter struct {
@@ -481,7 +1138,7 @@ func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]st
 	}
 	writers := make(map[string]splitWriter, len(paths))
 	for key, path := range paths {
-		f, err := os.Create(path)
+		f, err := createOutput(path)
 		if err != nil {
 			return nil, nil, fmt.Errorf("create %s: %w", path, err)
 		}
@@ -507,16 +1164,20 @@ func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]st
 
 	counts := make(map[string]int)
 	seenTrainIDs := make(map[string]struct{})
-	err = parseFasta(in, func(rec fastaRecord) error {
+	err = parseFasta(in, withEmptySeqPolicy(plan.emptySeq, nil, withDuplicateIDPolicy(plan.duplicateID, nil, nil, func(rec fastaRecord) error {
 		bucket := bucketPretrain
 		if _, bad := plan.invalidIDs[rec.id]; !bad {
-			if _, ok := labels[rec.id]; ok {
-				hash := md5.Sum(rec.seq)
+			if label, ok := labels[rec.id]; ok {
+				hash := plan.groupKey(rec)
 				if _, conflict := plan.conflicted[hash]; !conflict {
 					if mapped, ok := plan.seqBucket[hash]; ok {
 						bucket = mapped
 					}
 				}
+				// A held-out genus stays whole even through barcode conflicts.
+				if _, held := plan.genusHeldout[labelGenus(label)]; held {
+					bucket = bucketGenusHeldout
+				}
 			}
 		}
 
@@ -524,7 +1185,7 @@ func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]st
 		if !ok {
 			return fmt.Errorf("unknown split bucket %s", bucket)
 		}
-		if err := writeFasta(w.buf, rec.id, rec.seq); err != nil {
+		if err := writeSeqRecord(w.buf, rec.id, rec.seq, fastqQual); err != nil {
 			return err
 		}
 		counts[bucket]++
@@ -532,11 +1193,14 @@ func writeSplitFastas(input, outDir string, plan splitPlan, labels map[string]st
 			seenTrainIDs[rec.id] = struct{}{}
 		}
 		return nil
-	})
+	})))
 	if err != nil {
 		return nil, nil, err
 	}
 
+	for _, path := range paths {
+		keepOutputs(path)
+	}
 	return counts, seenTrainIDs, nil
 }
 
@@ -552,6 +1216,21 @@ func lessHash(a, b [16]byte) bool {
 	return false
 }
 
+// labelGenus is the genus of a species label: its first word.
+func labelGenus(label string) string {
+	if i := strings.IndexByte(label, ' '); i >= 0 {
+		return label[:i]
+	}
+	return label
+}
+
+// genusHeldOut deterministically selects about fraction of all genera by
+// the md5 of the genus name.
+func genusHeldOut(genus string, fraction float64) bool {
+	sum := md5.Sum([]byte(genus))
+	return float64(binary.BigEndian.Uint32(sum[:4])) < fraction*(1<<32)
+}
+
 func classHashByte(label string) byte {
 	sum := md5.Sum([]byte(label))
 	return sum[0]
@@ -572,83 +1251,234 @@ func minInt(a, b int) int {
 }
 
 func writeSplitReport(path string, report splitReport) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("create split report: %w", err)
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(report); err != nil {
-		return fmt.Errorf("write split report: %w", err)
+	return writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("write split report: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeSplitReportCSV writes report as key,value rows, flattening the stats
+// under their JSON names.
+func writeSplitReportCSV(path string, report splitReport) error {
+	s := report.Stats
+	rows := [][2]string{
+		{"input", report.Input},
+		{"out_dir", report.OutDir},
+		{"classifiers", strings.Join(report.Classifiers, ";")},
+		{"pruned_taxids", strconv.Itoa(report.PrunedTaxa)},
+		{"format_skipped", strconv.FormatBool(report.FormatSkipped)},
+		{"total_records", strconv.Itoa(s.TotalRecords)},
+		{"total_classes", strconv.Itoa(s.TotalClasses)},
+		{"seen_classes", strconv.Itoa(s.SeenClasses)},
+		{"unseen_classes", strconv.Itoa(s.UnseenClasses)},
+		{"heldout_classes", strconv.Itoa(s.HeldoutClasses)},
+		{"seen_train_records", strconv.Itoa(s.SeenTrainRecords)},
+		{"seen_val_records", strconv.Itoa(s.SeenValRecords)},
+		{"seen_test_records", strconv.Itoa(s.SeenTestRecords)},
+		{"test_unseen_records", strconv.Itoa(s.UnseenTest)},
+		{"val_unseen_records", strconv.Itoa(s.UnseenVal)},
+		{"keys_unseen_records", strconv.Itoa(s.UnseenKey)},
+		{"other_heldout_records", strconv.Itoa(s.HeldoutRecords)},
+		{"pretrain_records", strconv.Itoa(s.PretrainRecords)},
+		{"outside_reference_records", strconv.Itoa(s.OutsideReference)},
+		{"genus_heldout_genera", strconv.Itoa(s.GenusHeldoutGenera)},
+		{"genus_heldout_records", strconv.Itoa(s.GenusHeldoutRecords)},
+		{"missing_taxid_records", strconv.Itoa(s.MissingTaxid)},
+		{"leaked_records", strconv.Itoa(s.LeakedRecords)},
 	}
-	return nil
+	return writeFileAtomic(path, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"key", "value"}); err != nil {
+			return fmt.Errorf("write split report: %w", err)
+		}
+		for _, row := range rows {
+			if err := cw.Write(row[:]); err != nil {
+				return fmt.Errorf("write split report: %w", err)
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("write split report: %w", err)
+		}
+		return nil
+	})
 }
 
-func pruneTaxdumpForSeenTrain(seenTrainIDs map[string]struct{}, taxdumpDir, taxidMapPath, outDir string) (string, int, error) {
+// pruneTaxdumpForSeenTrain writes taxdump_pruned with the lineages of the
+// seen_train records and returns its path and kept taxid count. A seen_train
+// processid without a taxid is an error unless allowMissing, which leaves it
+// out of the pruned map and returns how many were missing. renamed maps
+// -on-duplicate-id rename IDs to the original processid whose taxid they take.
+func pruneTaxdumpForSeenTrain(seenTrainIDs map[string]struct{}, renamed map[string]string, taxdumpDir, taxidMapPath, outDir string, emitTree, allowMissing bool) (string, int, int, error) {
 	if len(seenTrainIDs) == 0 {
-		return "", 0, fmt.Errorf("no seen_train sequences found; cannot prune taxdump")
+		return "", 0, 0, fmt.Errorf("no seen_train sequences found; cannot prune taxdump")
+	}
+	prunedDir := filepath.Join(outDir, "taxdump_pruned")
+	kept, missing, err := pruneTaxdump(seenTrainIDs, renamed, taxdumpDir, taxidMapPath, prunedDir, emitTree, allowMissing)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("prune seen_train taxdump: %w", err)
+	}
+	if missing > 0 {
+		warnf("missing_taxid", missing, "split: %d seen_train processids have no taxid (left out of the pruned taxdump)", missing)
 	}
+	return prunedDir, kept, missing, nil
+}
 
+// pruneTaxdump writes to prunedDir the nodes, names and taxid.map of the
+// taxdump restricted to the lineages of ids, returning the kept taxid count
+// and, under allowMissing, how many ids had no taxid. Merged taxids resolve
+// to their replacement and deleted ones count as missing. An id in renamed
+// takes its original processid's taxid and is written to the pruned taxid.map
+// under its own name.
+func pruneTaxdump(ids map[string]struct{}, renamed map[string]string, taxdumpDir, taxidMapPath, prunedDir string, emitTree, allowMissing bool) (int, int, error) {
 	if taxidMapPath == "" {
 		taxidMapPath = filepath.Join(taxdumpDir, "taxid.map")
 	}
 	pidToTaxid, err := loadTaxidMap(taxidMapPath)
 	if err != nil {
-		return "", 0, err
+		return 0, 0, err
 	}
 
-	nodesPath := filepath.Join(taxdumpDir, "nodes.dmp")
-	namesPath := filepath.Join(taxdumpDir, "names.dmp")
-	dump, err := loadTaxDump(nodesPath, namesPath)
+	dump, err := loadPruneSourceDump(taxdumpDir)
 	if err != nil {
-		return "", 0, err
+		return 0, 0, err
 	}
 
-	keep := make(map[int]struct{}, len(seenTrainIDs)*2)
-	seenTrainTaxids := make(map[string]int, len(seenTrainIDs))
-	for pid := range seenTrainIDs {
+	keep := make(map[int]struct{}, len(ids)*2)
+	pidTaxids := make(map[string]int, len(ids))
+	missing := 0
+	for pid := range ids {
 		taxid, ok := pidToTaxid[pid]
-		if !ok {
-			return "", 0, fmt.Errorf("taxid not found for seen_train processid %s", pid)
+		if orig, isRenamed := renamed[pid]; !ok && isRenamed {
+			taxid, ok = pidToTaxid[orig]
 		}
-		seenTrainTaxids[pid] = taxid
-
-		cur := taxid
-		for depth := 0; depth < 128 && cur > 0; depth++ {
-			if _, done := keep[cur]; done {
-				break
-			}
-			keep[cur] = struct{}{}
-			node, ok := dump.nodes[cur]
-			if !ok {
-				break
+		if !ok {
+			if !allowMissing {
+				return 0, 0, fmt.Errorf("taxid not found for processid %s", pid)
 			}
-			if node.parent == cur || node.parent <= 0 {
-				break
+			debugf("no taxid for processid %s", pid)
+			missing++
+			continue
+		}
+		taxid = dump.resolve(taxid)
+		if dump.isDeleted(taxid) {
+			if !allowMissing {
+				return 0, 0, fmt.Errorf("taxid %d for processid %s is deleted", taxid, pid)
 			}
-			cur = node.parent
+			debugf("deleted taxid %d for processid %s", taxid, pid)
+			missing++
+			continue
 		}
+		pidTaxids[pid] = taxid
+		addAncestors(dump.nodes, taxid, keep)
 	}
 
-	prunedDir := filepath.Join(outDir, "taxdump_pruned")
 	if err := os.MkdirAll(prunedDir, 0o755); err != nil {
-		return "", 0, fmt.Errorf("create pruned taxdump dir: %w", err)
+		return 0, 0, fmt.Errorf("create pruned taxdump dir: %w", err)
 	}
 
 	if err := writePrunedNodes(filepath.Join(prunedDir, "nodes.dmp"), dump.nodes, keep); err != nil {
-		return "", 0, err
+		return 0, 0, err
 	}
 	if err := writePrunedNames(filepath.Join(prunedDir, "names.dmp"), dump.nodes, keep); err != nil {
-		return "", 0, err
+		return 0, 0, err
 	}
-	if err := writePrunedTaxidMap(filepath.Join(prunedDir, "taxid.map"), seenTrainTaxids); err != nil {
-		return "", 0, err
+	if err := writePrunedTaxidMap(filepath.Join(prunedDir, "taxid.map"), pidTaxids); err != nil {
+		return 0, 0, err
+	}
+	if emitTree {
+		if err := writePrunedTreeJSON(filepath.Join(prunedDir, "tree.json"), dump.nodes, keep); err != nil {
+			return 0, 0, err
+		}
 	}
 
-	return prunedDir, len(keep), nil
+	return len(keep), missing, nil
+}
+
+// addAncestors adds taxid and every ancestor up to the root to keep,
+// stopping early at a node already kept.
+func addAncestors(nodes map[int]taxNode, taxid int, keep map[int]struct{}) {
+	cur := taxid
+	for depth := 0; depth < 128 && cur > 0; depth++ {
+		if _, done := keep[cur]; done {
+			return
+		}
+		keep[cur] = struct{}{}
+		node, ok := nodes[cur]
+		if !ok {
+			return
+		}
+		if node.parent == cur || node.parent <= 0 {
+			return
+		}
+		cur = node.parent
+	}
+}
+
+// taxTreeNode is one node of tree.json. Children is always an array, empty
+// for leaves.
+type taxTreeNode struct {
+	TaxID    int            `json:"taxid"`
+	Name     string         `json:"name"`
+	Rank     string         `json:"rank"`
+	Children []*taxTreeNode `json:"children"`
+}
+
+// buildTaxTree nests the kept nodes under their parents with children in
+// taxid order. Kept nodes whose parent is not kept (or is themselves) are
+// roots; more than one root is wrapped in a synthetic taxid 0 root.
+func buildTaxTree(nodes map[int]taxNode, keep map[int]struct{}) *taxTreeNode {
+	ids := sortedIntSet(keep)
+	tree := make(map[int]*taxTreeNode, len(ids))
+	for _, id := range ids {
+		node, ok := nodes[id]
+		if !ok {
+			continue
+		}
+		tree[id] = &taxTreeNode{TaxID: id, Name: node.name, Rank: node.rank, Children: []*taxTreeNode{}}
+	}
+	var roots []*taxTreeNode
+	for _, id := range ids {
+		t, ok := tree[id]
+		if !ok {
+			continue
+		}
+		parent, ok := tree[nodes[id].parent]
+		if !ok || parent == t {
+			roots = append(roots, t)
+			continue
+		}
+		parent.Children = append(parent.Children, t)
+	}
+	if len(roots) == 1 {
+		return roots[0]
+	}
+	if roots == nil {
+		roots = []*taxTreeNode{}
+	}
+	return &taxTreeNode{TaxID: 0, Name: "root", Rank: "no rank", Children: roots}
+}
+
+func writePrunedTreeJSON(path string, nodes map[int]taxNode, keep map[int]struct{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create tree.json: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	w := bufio.NewWriterSize(f, writerBufferSize)
+	if err := json.NewEncoder(w).Encode(buildTaxTree(nodes, keep)); err != nil {
+		return fmt.Errorf("write tree.json: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush tree.json: %w", err)
+	}
+	return nil
 }
 
 func writePrunedNodes(path string, nodes map[int]taxNode, keep map[int]struct{}) error {
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+type splitBatchConfig struct {
+	Jobs []json.RawMessage `json:"jobs"`
+}
+
+// loadSplitJobs reads a {"jobs": [...]} file. Each job starts from base (the
+// command-line flags) and overrides only the keys it sets.
+func loadSplitJobs(path string, base splitJob) ([]splitJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var cfg splitBatchConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if len(cfg.Jobs) == 0 {
+		return nil, fmt.Errorf("config has no jobs: %s", path)
+	}
+
+	jobs := make([]splitJob, 0, len(cfg.Jobs))
+	outDirs := make(map[string]string, len(cfg.Jobs))
+	for i, raw := range cfg.Jobs {
+		job := base.clone()
+		job.Name = ""
+		if err := json.Unmarshal(raw, &job); err != nil {
+			return nil, fmt.Errorf("job %d: %w", i+1, err)
+		}
+		if job.Name == "" {
+			job.Name = "job" + strconv.Itoa(i+1)
+		}
+		if err := validateSplitJob(job); err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		clean := filepath.Clean(job.OutDir)
+		if prev, dup := outDirs[clean]; dup {
+			return nil, fmt.Errorf("jobs %s and %s share outdir %s", prev, job.Name, job.OutDir)
+		}
+		outDirs[clean] = job.Name
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// clone copies job with its own slices and pointers, so decoding a batch job
+// over it cannot write through to the defaults the other jobs start from.
+func (job splitJob) clone() splitJob {
+	job.Markers = append([]string(nil), job.Markers...)
+	job.RequireRanks = append([]string(nil), job.RequireRanks...)
+	job.Classifiers = append([]string(nil), job.Classifiers...)
+	if job.QC.MaxGC != nil {
+		maxGC := *job.QC.MaxGC
+		job.QC.MaxGC = &maxGC
+	}
+	return job
+}
+
+// runSplitJobs executes jobs with up to workers running at once and returns
+// the first failure after all started jobs finish.
+func runSplitJobs(jobs []splitJob, workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, job := range jobs {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job splitJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			logf("split: job %s -> %s", job.Name, job.OutDir)
+			if err := runSplitJob(job); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("job %s: %w", job.Name, err)
+				}
+				mu.Unlock()
+			}
+		}(job)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// pruneSourceDumps holds the source taxdump of each -taxdump-dir that
+// pruneTaxdump has read, so batch jobs sharing a directory parse it once.
+// pruneTaxdump only reads nodes, resolve and isDeleted, none of which write
+// to the dump, so concurrent jobs can share one *taxDump.
+var pruneSourceDumps = struct {
+	mu   sync.Mutex
+	dirs map[string]*sharedTaxDump
+}{dirs: make(map[string]*sharedTaxDump)}
+
+// sharedTaxDump serializes the first load of one directory, so parallel jobs
+// wait for it instead of each parsing the same files.
+type sharedTaxDump struct {
+	mu   sync.Mutex
+	dump *taxDump
+}
+
+// loadPruneSourceDump returns the cached taxdump of dir, loading it on first
+// use. A failed load is not cached.
+func loadPruneSourceDump(dir string) (*taxDump, error) {
+	key := filepath.Clean(dir)
+	pruneSourceDumps.mu.Lock()
+	shared, ok := pruneSourceDumps.dirs[key]
+	if !ok {
+		shared = &sharedTaxDump{}
+		pruneSourceDumps.dirs[key] = shared
+	}
+	pruneSourceDumps.mu.Unlock()
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.dump == nil {
+		dump, err := loadTaxDump(filepath.Join(dir, "nodes.dmp"), filepath.Join(dir, "names.dmp"))
+		if err != nil {
+			return nil, err
+		}
+		shared.dump = dump
+	}
+	return shared.dump, nil
+}
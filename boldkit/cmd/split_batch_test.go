@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoadSplitJobsOverridesDefaults(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "jobs.json")
+	content := `{"jobs": [
+  {"name": "coi", "input": "coi.fasta", "outdir": "out/coi", "qc": {"min_length": 500}},
+  {"input": "its.fasta", "outdir": "out/its", "require_ranks": ["genus", "species"]}
+]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	base := splitJob{
+		Classifiers:  []string{"blast"},
+		RequireRanks: []string{"kingdom", "species"},
+		TaxdumpDir:   "bold-taxdump",
+		QC:           splitQCConfig{Enabled: true, MinLen: 200, MaxLen: 700},
+	}
+	jobs, err := loadSplitJobs(path, base)
+	if err != nil {
+		t.Fatalf("loadSplitJobs failed: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("jobs=%d want 2", len(jobs))
+	}
+	if jobs[0].Name != "coi" || jobs[0].QC.MinLen != 500 || jobs[0].QC.MaxLen != 700 || !jobs[0].QC.Enabled {
+		t.Fatalf("unexpected first job: %+v", jobs[0])
+	}
+	if jobs[1].Name != "job2" || len(jobs[1].RequireRanks) != 2 || jobs[1].RequireRanks[0] != "genus" {
+		t.Fatalf("unexpected second job: %+v", jobs[1])
+	}
+	if jobs[1].TaxdumpDir != "bold-taxdump" || jobs[1].QC.MinLen != 200 {
+		t.Fatalf("second job lost defaults: %+v", jobs[1])
+	}
+}
+
+func TestLoadSplitJobsDoesNotShareDefaults(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "jobs.json")
+	content := `{"jobs": [
+  {"input": "a.fasta", "outdir": "out/a", "require_ranks": ["genus"], "classifiers": ["rdp"], "qc": {"max_gc": 60}},
+  {"input": "b.fasta", "outdir": "out/b"}
+]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	maxGC := 100.0
+	base := splitJob{
+		Classifiers:  []string{"blast", "sintax"},
+		RequireRanks: []string{"kingdom", "species"},
+		QC:           splitQCConfig{MaxGC: &maxGC},
+	}
+	jobs, err := loadSplitJobs(path, base)
+	if err != nil {
+		t.Fatalf("loadSplitJobs failed: %v", err)
+	}
+	if got := strings.Join(jobs[0].RequireRanks, ","); got != "genus" {
+		t.Fatalf("first job require_ranks=%s want genus", got)
+	}
+	if got := strings.Join(jobs[1].RequireRanks, ","); got != "kingdom,species" {
+		t.Fatalf("second job require_ranks=%s want the flag default kingdom,species", got)
+	}
+	if got := strings.Join(jobs[1].Classifiers, ","); got != "blast,sintax" {
+		t.Fatalf("second job classifiers=%s want the flag default blast,sintax", got)
+	}
+	if *jobs[1].QC.MaxGC != 100 || maxGC != 100 {
+		t.Fatalf("second job max_gc=%v (flag value %v) want 100", *jobs[1].QC.MaxGC, maxGC)
+	}
+}
+
+func TestLoadSplitJobsRejectsSharedOutdir(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "jobs.json")
+	content := `{"jobs": [{"input": "a.fasta", "outdir": "out"}, {"input": "b.fasta", "outdir": "out/"}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := loadSplitJobs(path, splitJob{Classifiers: []string{"blast"}}); err == nil {
+		t.Fatalf("expected shared outdir error")
+	}
+}
+
+func TestLoadPruneSourceDumpShared(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	dumps := make([]*taxDump, 4)
+	var wg sync.WaitGroup
+	for i := range dumps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dump, err := loadPruneSourceDump(tmp + "/")
+			if err != nil {
+				t.Errorf("loadPruneSourceDump failed: %v", err)
+				return
+			}
+			dumps[i] = dump
+		}(i)
+	}
+	wg.Wait()
+	for i, dump := range dumps {
+		if dump == nil || dump != dumps[0] {
+			t.Fatalf("job %d got its own taxdump, want the shared one", i)
+		}
+	}
+}
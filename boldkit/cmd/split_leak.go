@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"crypto/md5"
+	"fmt"
+	"path/filepath"
+)
+
+// splitLeakBuckets are the evaluation buckets checked against seen_train.
+var splitLeakBuckets = []string{bucketSeenVal, bucketSeenTest, bucketUnseenTest, bucketUnseenVal, bucketUnseenKeys}
+
+// iupacMatch reports whether two nucleotide codes can stand for the same
+// base, i.e. their IUPAC base sets overlap, so N matches any base and R
+// matches A or G. Characters outside IUPAC (gaps) match only themselves.
+func iupacMatch(a, b byte) bool {
+	ma, mb := iupacMask(a), iupacMask(b)
+	if ma == 0 || mb == 0 {
+		return a == b
+	}
+	return ma&mb != 0
+}
+
+// iupacDistance counts the positions of equal-length a and b that fail
+// iupacMatch, stopping early once the count exceeds limit.
+func iupacDistance(a, b []byte, limit int) int {
+	d := 0
+	for i := range a {
+		if !iupacMatch(a[i], b[i]) {
+			d++
+			if d > limit {
+				return d
+			}
+		}
+	}
+	return d
+}
+
+// countSplitLeaks counts the evaluation-bucket records under outDir within
+// maxDist IUPAC-aware mismatches of a seen_train sequence of the same length.
+// Every distinct seen_train sequence is held in memory; see splitLeakIndex
+// for the lookup cost.
+func countSplitLeaks(outDir, ext string, maxDist int) (int, error) {
+	idx := newSplitLeakIndex(maxDist)
+	seen := make(map[[md5.Size]byte]struct{})
+	err := parseSequenceFile(filepath.Join(outDir, bucketSeenTrain+ext), func(rec fastaRecord) error {
+		hash := md5.Sum(rec.seq)
+		if _, dup := seen[hash]; dup {
+			return nil
+		}
+		seen[hash] = struct{}{}
+		idx.add(rec.seq)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("leak check: %w", err)
+	}
+
+	leaks := 0
+	for _, bucket := range splitLeakBuckets {
+		path := filepath.Join(outDir, bucket+ext)
+		if !fileExists(path) {
+			continue
+		}
+		err := parseSequenceFile(path, func(rec fastaRecord) error {
+			if idx.match(rec.seq) {
+				leaks++
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("leak check: %w", err)
+		}
+	}
+	return leaks, nil
+}
+
+// splitLeakIndex finds seen_train sequences within maxDist mismatches of a
+// query. Sequences made only of A/C/G/T(U) are cut into maxDist+1 segments
+// and each segment hashed: by pigeonhole, a match has at least one segment
+// identical, so a plain query only verifies the candidates sharing a segment
+// (at maxDist 0 that is one exact-hash lookup). Train sequences with other
+// IUPAC codes or gaps, and every ambiguous query, fall back to comparing all
+// sequences of the same length.
+type splitLeakIndex struct {
+	maxDist int
+	plain   map[int][][]byte
+	ambig   map[int][][]byte
+	parts   map[int][]map[[md5.Size]byte][]int
+}
+
+func newSplitLeakIndex(maxDist int) *splitLeakIndex {
+	return &splitLeakIndex{
+		maxDist: maxDist,
+		plain:   make(map[int][][]byte),
+		ambig:   make(map[int][][]byte),
+		parts:   make(map[int][]map[[md5.Size]byte][]int),
+	}
+}
+
+func (x *splitLeakIndex) add(seq []byte) {
+	n := len(seq)
+	canon, ok := canonicalBases(seq)
+	if !ok {
+		x.ambig[n] = append(x.ambig[n], append([]byte(nil), seq...))
+		return
+	}
+	pos := len(x.plain[n])
+	x.plain[n] = append(x.plain[n], canon)
+	if n <= x.maxDist {
+		return
+	}
+	parts := x.parts[n]
+	if parts == nil {
+		parts = make([]map[[md5.Size]byte][]int, x.maxDist+1)
+		for i := range parts {
+			parts[i] = make(map[[md5.Size]byte][]int)
+		}
+		x.parts[n] = parts
+	}
+	for i := range parts {
+		start, end := leakSegment(n, x.maxDist+1, i)
+		hash := md5.Sum(canon[start:end])
+		parts[i][hash] = append(parts[i][hash], pos)
+	}
+}
+
+func (x *splitLeakIndex) match(seq []byte) bool {
+	n := len(seq)
+	for _, cand := range x.ambig[n] {
+		if iupacDistance(seq, cand, x.maxDist) <= x.maxDist {
+			return true
+		}
+	}
+	canon, ok := canonicalBases(seq)
+	if !ok || n <= x.maxDist {
+		for _, cand := range x.plain[n] {
+			if iupacDistance(seq, cand, x.maxDist) <= x.maxDist {
+				return true
+			}
+		}
+		return false
+	}
+	for i, part := range x.parts[n] {
+		start, end := leakSegment(n, x.maxDist+1, i)
+		for _, pos := range part[md5.Sum(canon[start:end])] {
+			if iupacDistance(canon, x.plain[n][pos], x.maxDist) <= x.maxDist {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leakSegment returns the bounds of segment i when n positions are cut into k
+// near-equal segments.
+func leakSegment(n, k, i int) (int, int) {
+	return i * n / k, (i + 1) * n / k
+}
+
+// canonicalBases returns seq upper-cased with U read as T, and false when seq
+// holds anything other than A/C/G/T/U.
+func canonicalBases(seq []byte) ([]byte, bool) {
+	out := make([]byte, len(seq))
+	for i, c := range seq {
+		switch iupacMask(c) {
+		case 1:
+			out[i] = 'A'
+		case 2:
+			out[i] = 'C'
+		case 4:
+			out[i] = 'G'
+		case 8:
+			out[i] = 'T'
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+func parseSequenceFile(path string, onRecord func(fastaRecord) error) error {
+	r, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	return parseSequences(r, onRecord)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIUPACMatch(t *testing.T) {
+	cases := []struct {
+		a, b byte
+		want bool
+	}{
+		{'A', 'A', true},
+		{'A', 'C', false},
+		{'N', 'A', true},
+		{'R', 'G', true},
+		{'R', 'C', false},
+		{'R', 'K', true},
+		{'a', 'A', true},
+		{'-', '-', true},
+		{'-', 'A', false},
+	}
+	for _, tc := range cases {
+		if got := iupacMatch(tc.a, tc.b); got != tc.want {
+			t.Fatalf("iupacMatch(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestCountSplitLeaks(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFasta(t, filepath.Join(dir, "seen_train.fasta"), ">T1\nACGTACGT\n>T2\nGGGGCCCC\n")
+	// V1 differs from T1 only at an N, V2 by one real mismatch, V3 has a
+	// different length.
+	writeTestFasta(t, filepath.Join(dir, "seen_val.fasta"), ">V1\nACGNACGT\n>V2\nACGTACGA\n>V3\nACGTACG\n")
+	writeTestFasta(t, filepath.Join(dir, "test_unseen.fasta"), ">U1\nGGSGCCCC\n")
+
+	got, err := countSplitLeaks(dir, ".fasta", 0)
+	if err != nil {
+		t.Fatalf("countSplitLeaks failed: %v", err)
+	}
+	if got != 2 {
+		t.Fatalf("expected 2 leaks at distance 0, got %d", got)
+	}
+	got, err = countSplitLeaks(dir, ".fasta", 1)
+	if err != nil {
+		t.Fatalf("countSplitLeaks failed: %v", err)
+	}
+	if got != 3 {
+		t.Fatalf("expected 3 leaks at distance 1, got %d", got)
+	}
+}
+
+func TestCountSplitLeaksIndexed(t *testing.T) {
+	dir := t.TempDir()
+	// T2 carries an N, so it is only reachable through the pairwise scan;
+	// T3 is shorter than three segments at distance 2.
+	writeTestFasta(t, filepath.Join(dir, "seen_train.fasta"), ">T1\nACGTACGTACGT\n>T2\nTTTTNTTTTTTT\n>T3\nAC\n")
+	// V1 is T1 in lower case with U for T, V2 is T1 with mismatches in two
+	// segments, V3 matches T2 through its N, V4 sits three mismatches from T1.
+	writeTestFasta(t, filepath.Join(dir, "seen_val.fasta"),
+		">V1\nacguacguacgu\n>V2\nTCGTACGTACGA\n>V3\nTTTTCTTTTTTT\n>V4\nTTTTACGTACGT\n")
+	writeTestFasta(t, filepath.Join(dir, "test_unseen.fasta"), ">U1\nGG\n")
+
+	for _, tc := range []struct {
+		maxDist int
+		want    int
+	}{
+		{maxDist: 0, want: 2},
+		{maxDist: 2, want: 4},
+	} {
+		got, err := countSplitLeaks(dir, ".fasta", tc.maxDist)
+		if err != nil {
+			t.Fatalf("countSplitLeaks failed: %v", err)
+		}
+		if got != tc.want {
+			t.Fatalf("expected %d leaks at distance %d, got %d", tc.want, tc.maxDist, got)
+		}
+	}
+}
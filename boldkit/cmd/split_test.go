@@ -0,0 +1,607 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestBuildSplitPlanReferenceRestriction(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGT\n>A2\nACGTACGA\n>B1\nTTTTACGT\n>B2\nTTTTACGA\n")
+	labels := map[string]string{
+		"A1": "Homo sapiens",
+		"A2": "Homo sapiens",
+		"B1": "Canis lupus",
+		"B2": "Canis lupus",
+	}
+	allowed := map[string]struct{}{"Homo sapiens": {}}
+
+	plan, stats, err := buildSplitPlan(input, labels, map[string]struct{}{}, allowed, nil, 0, emptySeqSkip, duplicateIDError)
+	if err != nil {
+		t.Fatalf("buildSplitPlan failed: %v", err)
+	}
+	if stats.OutsideReference != 2 {
+		t.Fatalf("outside_reference=%d want 2", stats.OutsideReference)
+	}
+	if stats.TotalClasses != 1 {
+		t.Fatalf("total_classes=%d want 1", stats.TotalClasses)
+	}
+	for _, id := range []string{"B1", "B2"} {
+		if _, ok := plan.invalidIDs[id]; !ok {
+			t.Fatalf("expected %s routed to pretrain", id)
+		}
+	}
+	for _, id := range []string{"A1", "A2"} {
+		if _, ok := plan.invalidIDs[id]; ok {
+			t.Fatalf("expected %s kept in the split plan", id)
+		}
+	}
+}
+
+func TestPruneTaxdumpEmitsTreeJSON(t *testing.T) {
+	tmp := t.TempDir()
+	taxdump := filepath.Join(tmp, "taxdump")
+	if err := os.MkdirAll(taxdump, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"nodes.dmp": "1\t|\t1\t|\tno rank\t|\n2\t|\t1\t|\tgenus\t|\n3\t|\t2\t|\tspecies\t|\n4\t|\t2\t|\tspecies\t|\n5\t|\t1\t|\tgenus\t|\n",
+		"names.dmp": "1\t|\troot\t|\t\t|\tscientific name\t|\n2\t|\tHomo\t|\t\t|\tscientific name\t|\n" +
+			"3\t|\tHomo sapiens\t|\t\t|\tscientific name\t|\n4\t|\tHomo erectus\t|\t\t|\tscientific name\t|\n" +
+			"5\t|\tCanis\t|\t\t|\tscientific name\t|\n",
+		"taxid.map": "P1\t3\nP2\t4\nP3\t5\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taxdump, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	seen := map[string]struct{}{"P1": {}, "P2": {}}
+	prunedDir, kept, _, err := pruneTaxdumpForSeenTrain(seen, nil, taxdump, "", tmp, true, false)
+	if err != nil {
+		t.Fatalf("pruneTaxdumpForSeenTrain failed: %v", err)
+	}
+	if kept != 4 {
+		t.Fatalf("kept=%d want 4", kept)
+	}
+	data, err := os.ReadFile(filepath.Join(prunedDir, "tree.json"))
+	if err != nil {
+		t.Fatalf("read tree.json: %v", err)
+	}
+	var root taxTreeNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("unmarshal tree.json: %v", err)
+	}
+	if root.TaxID != 1 || root.Name != "root" || len(root.Children) != 1 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+	genus := root.Children[0]
+	if genus.TaxID != 2 || genus.Name != "Homo" || genus.Rank != "genus" || len(genus.Children) != 2 {
+		t.Fatalf("unexpected genus node: %+v", genus)
+	}
+	if genus.Children[0].Name != "Homo sapiens" || genus.Children[1].Name != "Homo erectus" {
+		t.Fatalf("unexpected species children: %+v, %+v", genus.Children[0], genus.Children[1])
+	}
+	if genus.Children[0].Children == nil || len(genus.Children[0].Children) != 0 {
+		t.Fatalf("expected empty children array on leaf")
+	}
+
+	// Two kept nodes without a common kept parent get a synthetic root.
+	multi := buildTaxTree(map[int]taxNode{7: {parent: 99, rank: "genus", name: "A"}, 8: {parent: 98, rank: "genus", name: "B"}},
+		map[int]struct{}{7: {}, 8: {}})
+	if multi.TaxID != 0 || len(multi.Children) != 2 {
+		t.Fatalf("expected synthetic root over two roots, got %+v", multi)
+	}
+}
+
+func TestPruneTaxdumpAllowMissingTaxid(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	seen := map[string]struct{}{"P1": {}, "PX": {}}
+	if _, _, _, err := pruneTaxdumpForSeenTrain(seen, nil, tmp, "", tmp, false, false); err == nil || !strings.Contains(err.Error(), "taxid not found for processid PX") {
+		t.Fatalf("expected strict prune to fail on PX, got %v", err)
+	}
+	prunedDir, _, missing, err := pruneTaxdumpForSeenTrain(seen, nil, tmp, "", tmp, false, true)
+	if err != nil {
+		t.Fatalf("pruneTaxdumpForSeenTrain with allowMissing failed: %v", err)
+	}
+	if missing != 1 {
+		t.Fatalf("missing=%d want 1", missing)
+	}
+	data, err := os.ReadFile(filepath.Join(prunedDir, "taxid.map"))
+	if err != nil {
+		t.Fatalf("read pruned taxid.map: %v", err)
+	}
+	if string(data) != "P1\t9606\n" {
+		t.Fatalf("pruned taxid.map=%q want only P1", data)
+	}
+}
+
+func TestPruneTaxdumpMergedAndDeletedTaxids(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	files := map[string]string{
+		"merged.dmp":   "999\t|\t9606\t|\n",
+		"delnodes.dmp": "888\t|\n",
+		"taxid.map":    "P1\t999\nP2\t888\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	seen := map[string]struct{}{"P1": {}, "P2": {}}
+	if _, _, _, err := pruneTaxdumpForSeenTrain(seen, nil, tmp, "", tmp, false, false); err == nil || !strings.Contains(err.Error(), "taxid 888 for processid P2 is deleted") {
+		t.Fatalf("expected strict prune to fail on deleted P2, got %v", err)
+	}
+	prunedDir, kept, missing, err := pruneTaxdumpForSeenTrain(seen, nil, tmp, "", tmp, false, true)
+	if err != nil {
+		t.Fatalf("pruneTaxdumpForSeenTrain with allowMissing failed: %v", err)
+	}
+	if kept != 8 || missing != 1 {
+		t.Fatalf("kept=%d missing=%d want 8 and 1", kept, missing)
+	}
+	data, err := os.ReadFile(filepath.Join(prunedDir, "taxid.map"))
+	if err != nil {
+		t.Fatalf("read pruned taxid.map: %v", err)
+	}
+	if string(data) != "P1\t9606\n" {
+		t.Fatalf("pruned taxid.map=%q want P1 remapped to 9606", data)
+	}
+}
+
+func TestBuildSplitPlanGroupByBin(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGTAA\n>A2\nACGTACGTAC\n>A3\nGGGGACGT\n>A4\nGGGTACGT\n"+
+		">A5\nGGATACGT\n>A6\nGAATACGT\n>A7\nAAATACGT\n>A8\nCAATACGT\n")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	tsv := "processid\tspecies\tbin_uri\n" +
+		"A1\tHomo sapiens\tBOLD:AAA0001\n" +
+		"A2\tHomo sapiens\tBOLD:AAA0001\n" +
+		"A3\tHomo sapiens\t\n"
+	if err := os.WriteFile(taxonkit, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	ids := map[string]struct{}{}
+	labels := map[string]string{}
+	for _, id := range []string{"A1", "A2", "A3", "A4", "A5", "A6", "A7", "A8"} {
+		ids[id] = struct{}{}
+		labels[id] = "Homo sapiens"
+	}
+	bins, err := loadProcessBins(taxonkit, ids)
+	if err != nil {
+		t.Fatalf("loadProcessBins failed: %v", err)
+	}
+	if len(bins) != 2 || bins["A1"] != "BOLD:AAA0001" {
+		t.Fatalf("bins=%v want A1/A2 in BOLD:AAA0001", bins)
+	}
+
+	plan, _, err := buildSplitPlan(input, labels, map[string]struct{}{}, nil, bins, 0, emptySeqSkip, duplicateIDError)
+	if err != nil {
+		t.Fatalf("buildSplitPlan failed: %v", err)
+	}
+	if len(plan.seqBucket) != 7 {
+		t.Fatalf("groups=%d want 7 (A1 and A2 share a BIN)", len(plan.seqBucket))
+	}
+	a1 := plan.groupKey(fastaRecord{id: "A1", seq: []byte("ACGTACGTAA")})
+	a2 := plan.groupKey(fastaRecord{id: "A2", seq: []byte("ACGTACGTAC")})
+	if a1 != a2 {
+		t.Fatalf("A1 and A2 in one BIN got different group keys")
+	}
+	if _, ok := plan.seqBucket[a1]; !ok {
+		t.Fatalf("BIN group not assigned a bucket")
+	}
+}
+
+func TestBuildSplitPlanGroupByBinJoinsSharedSequence(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	// B1/B2 share a BIN; B3 has no BIN but carries B2's sequence, and B4 sits
+	// in another BIN with B3's sequence, so all four form one group.
+	writeTestFasta(t, input, ">B1\nACGTACGTAA\n>B2\nACGTACGTCC\n>B3\nACGTACGTCC\n>B4\nACGTACGTCC\n>B5\nTTTTACGTAA\n")
+	labels := map[string]string{}
+	for _, id := range []string{"B1", "B2", "B3", "B4", "B5"} {
+		labels[id] = "Homo sapiens"
+	}
+	bins := map[string]string{"B1": "BOLD:AAA0001", "B2": "BOLD:AAA0001", "B4": "BOLD:AAA0002"}
+
+	plan, _, err := buildSplitPlan(input, labels, map[string]struct{}{}, nil, bins, 0, emptySeqSkip, duplicateIDError)
+	if err != nil {
+		t.Fatalf("buildSplitPlan failed: %v", err)
+	}
+	if len(plan.seqBucket) != 2 {
+		t.Fatalf("groups=%d want 2 (B1-B4 joined, B5 alone)", len(plan.seqBucket))
+	}
+	b1 := plan.groupKey(fastaRecord{id: "B1", seq: []byte("ACGTACGTAA")})
+	b3 := plan.groupKey(fastaRecord{id: "B3", seq: []byte("ACGTACGTCC")})
+	if b1 != b3 {
+		t.Fatalf("BIN-less B3 not grouped with the BIN sharing its sequence")
+	}
+	if b5 := plan.groupKey(fastaRecord{id: "B5", seq: []byte("TTTTACGTAA")}); b5 == b1 {
+		t.Fatalf("B5 joined an unrelated group")
+	}
+}
+
+func TestSplitGenusHoldout(t *testing.T) {
+	tmp := t.TempDir()
+	genera := []string{"Homo", "Canis", "Felis", "Ursus", "Panthera", "Mus"}
+	var held, kept []string
+	for _, genus := range genera {
+		if genusHeldOut(genus, 0.5) {
+			held = append(held, genus)
+		} else {
+			kept = append(kept, genus)
+		}
+	}
+	if len(held) == 0 || len(kept) == 0 {
+		t.Fatalf("fixture needs held and kept genera, got held=%v kept=%v", held, kept)
+	}
+
+	var fasta strings.Builder
+	labels := map[string]string{}
+	bases := "ACGT"
+	n := 0
+	for _, genus := range genera {
+		for _, epithet := range []string{"alpha", "beta"} {
+			for i := 0; i < 3; i++ {
+				id := fmt.Sprintf("%s_%s_%d", genus, epithet, i)
+				labels[id] = genus + " " + epithet
+				seq := fmt.Sprintf("ACGTACGT%c%c%c", bases[n%4], bases[(n/4)%4], bases[(n/16)%4])
+				fmt.Fprintf(&fasta, ">%s\n%s\n", id, seq)
+				n++
+			}
+		}
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, fasta.String())
+
+	plan, stats, err := buildSplitPlan(input, labels, map[string]struct{}{}, nil, nil, 0.5, emptySeqSkip, duplicateIDError)
+	if err != nil {
+		t.Fatalf("buildSplitPlan failed: %v", err)
+	}
+	if stats.GenusHeldoutGenera != len(held) {
+		t.Fatalf("genus_heldout_genera=%d want %d", stats.GenusHeldoutGenera, len(held))
+	}
+	outDir := filepath.Join(tmp, "out")
+	counts, _, err := writeSplitFastas(input, outDir, plan, labels, 0)
+	if err != nil {
+		t.Fatalf("writeSplitFastas failed: %v", err)
+	}
+	if want := len(held) * 6; counts[bucketGenusHeldout] != want {
+		t.Fatalf("genus_heldout records=%d want %d", counts[bucketGenusHeldout], want)
+	}
+
+	files, err := filepath.Glob(filepath.Join(outDir, "*.fasta"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		inHeldout := filepath.Base(path) == "genus_heldout.fasta"
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(line, ">") {
+				continue
+			}
+			genus := labelGenus(labels[line[1:]])
+			if genusHeldOut(genus, 0.5) != inHeldout {
+				t.Fatalf("record %s of genus %s in %s", line[1:], genus, filepath.Base(path))
+			}
+		}
+	}
+}
+
+func TestSplitNoFormat(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGT\n>A2\nACGTACGA\n>B1\nTTTTACGT\n")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	tsv := "processid\tspecies\nA1\tHomo sapiens\nA2\tHomo sapiens\nB1\tCanis lupus\n"
+	if err := os.WriteFile(taxonkit, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	job := splitJob{
+		Classifiers:   []string{"sintax"},
+		TaxonkitInput: taxonkit,
+		TaxdumpDir:    filepath.Join(tmp, "missing-taxdump"),
+		OnEmptySeq:    emptySeqSkip,
+		NoFormat:      true,
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("splitOne failed: %v", err)
+	}
+	if fileExists(filepath.Join(outDir, "formatted")) {
+		t.Fatalf("formatted/ created with -no-format")
+	}
+	if fileExists(filepath.Join(outDir, "taxdump_pruned")) {
+		t.Fatalf("taxdump pruned with -no-format and no -prune-taxdump")
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "split_report.json"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report splitReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if !report.FormatSkipped {
+		t.Fatalf("split report does not note the skipped format step")
+	}
+}
+
+func TestSplitQCRejects(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGT\n>A2\nACGTACGA\n>B1\nTTTT\n")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	tsv := "processid\tspecies\nA1\tHomo sapiens\nA2\tHomo sapiens\nB1\tCanis lupus\n"
+	if err := os.WriteFile(taxonkit, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	job := splitJob{
+		Classifiers:   []string{"sintax"},
+		TaxonkitInput: taxonkit,
+		OnEmptySeq:    emptySeqSkip,
+		NoFormat:      true,
+		QC:            splitQCConfig{Enabled: true, MinLen: 8, MaxLen: 100, Rejects: true},
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("splitOne failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "qc", "input.rejects.fasta"))
+	if err != nil {
+		t.Fatalf("read rejects: %v", err)
+	}
+	if !strings.HasPrefix(string(data), ">B1 reason=too_short") || strings.Count(string(data), ">") != 1 {
+		t.Fatalf("unexpected rejects:\n%s", data)
+	}
+}
+
+func TestSplitReuseQC(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGT\n>A2\nACGTACGA\n>B1\nTTTTACGT\n")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(input, past, past); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	tsv := "processid\tspecies\nA1\tHomo sapiens\nA2\tHomo sapiens\nB1\tCanis lupus\n"
+	if err := os.WriteFile(taxonkit, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	job := splitJob{
+		Classifiers:   []string{"sintax"},
+		TaxonkitInput: taxonkit,
+		OnEmptySeq:    emptySeqSkip,
+		NoFormat:      true,
+		ReuseQC:       true,
+		QC:            splitQCConfig{Enabled: true, MinLen: 1, MaxLen: 100},
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("first splitOne failed: %v", err)
+	}
+	qcOut := filepath.Join(outDir, "qc", qcBaseName(input)+".fasta")
+	first, err := os.Stat(qcOut)
+	if err != nil {
+		t.Fatalf("stat qc output: %v", err)
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("second splitOne failed: %v", err)
+	}
+	second, err := os.Stat(qcOut)
+	if err != nil {
+		t.Fatalf("stat qc output: %v", err)
+	}
+	if !second.ModTime().Equal(first.ModTime()) {
+		t.Fatalf("qc output rewritten with -reuse-qc: %v -> %v", first.ModTime(), second.ModTime())
+	}
+
+	// Different QC settings must not reuse the earlier output.
+	changed := job
+	changed.QC.MinLen = 5
+	report := filepath.Join(outDir, "qc", qcBaseName(input)+".qc_report.json")
+	reason, err := reusableQC(input, qcOut, report, changed.qcConfig(qcOut))
+	if err != nil {
+		t.Fatalf("reusableQC failed: %v", err)
+	}
+	if reason != "qc settings changed" {
+		t.Fatalf("reusableQC reason=%q want qc settings changed", reason)
+	}
+}
+
+func TestSplitReportCSV(t *testing.T) {
+	tmp := t.TempDir()
+	var fasta, tsv strings.Builder
+	tsv.WriteString("processid\tspecies\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&fasta, ">H%d\nACGTACGT%s\n", i, strings.Repeat("A", i+1))
+		fmt.Fprintf(&tsv, "H%d\tHomo sapiens\n", i)
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, fasta.String())
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	if err := os.WriteFile(taxonkit, []byte(tsv.String()), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	job := splitJob{
+		Classifiers:   []string{"sintax"},
+		TaxonkitInput: taxonkit,
+		OnEmptySeq:    emptySeqSkip,
+		NoFormat:      true,
+		ReportFormat:  reportFormatCSV,
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("splitOne failed: %v", err)
+	}
+	if fileExists(filepath.Join(outDir, "split_report.json")) {
+		t.Fatalf("split_report.json written with -report-format csv")
+	}
+	counts, err := countMarkerSeqs([]string{filepath.Join(outDir, "seen_train.fasta")})
+	if err != nil {
+		t.Fatalf("count seen_train: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "split_report.csv"))
+	if err != nil {
+		t.Fatalf("read csv report: %v", err)
+	}
+	want := fmt.Sprintf("seen_train_records,%d\n", counts[0])
+	if counts[0] == 0 || !strings.Contains(string(data), want) {
+		t.Fatalf("csv report=%q want row %q", data, want)
+	}
+}
+
+func TestSplitOnDuplicateID(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, ">A1\nACGTACGT\n>A1\nACGTACGA\n>B1\nTTTTACGT\n")
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	tsv := "processid\tspecies\nA1\tHomo sapiens\nB1\tCanis lupus\n"
+	if err := os.WriteFile(taxonkit, []byte(tsv), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	// splitIDs returns every record id written to the split buckets.
+	splitIDs := func(outDir string) []string {
+		files, err := filepath.Glob(filepath.Join(outDir, "*.fasta"))
+		if err != nil {
+			t.Fatalf("glob: %v", err)
+		}
+		var ids []string
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read %s: %v", path, err)
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.HasPrefix(line, ">") {
+					ids = append(ids, line[1:])
+				}
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	for _, tc := range []struct {
+		policy  string
+		want    []string
+		wantErr string
+	}{
+		{policy: duplicateIDError, wantErr: "duplicate processid in input FASTA: A1"},
+		{policy: duplicateIDSkip, want: []string{"A1", "B1"}},
+		{policy: duplicateIDRename, want: []string{"A1", "A1_2", "B1"}},
+	} {
+		outDir := filepath.Join(tmp, tc.policy)
+		job := splitJob{
+			Classifiers:   []string{"sintax"},
+			TaxonkitInput: taxonkit,
+			OnEmptySeq:    emptySeqSkip,
+			OnDuplicateID: tc.policy,
+			NoFormat:      true,
+		}
+		err := splitOne(input, outDir, job)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("%s: err=%v want %q", tc.policy, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: splitOne failed: %v", tc.policy, err)
+		}
+		if got := splitIDs(outDir); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%s: ids=%v want %v", tc.policy, got, tc.want)
+		}
+		if tc.policy == duplicateIDRename && fileExists(filepath.Join(outDir, "pretrain.fasta")) {
+			data, _ := os.ReadFile(filepath.Join(outDir, "pretrain.fasta"))
+			if strings.Contains(string(data), ">A1_2\n") {
+				t.Fatalf("renamed A1_2 lost its label and went to pretrain")
+			}
+		}
+	}
+}
+
+func TestSplitRenamedIDsReachFormat(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	// Ten P1 records with distinct barcodes make Homo sapiens a seen class,
+	// so renamed P1_2.. land in seen_train and must survive prune and format.
+	var fasta strings.Builder
+	bases := "ACGT"
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&fasta, ">P1\nACGTACGT%c%c\n", bases[i%4], bases[i/4])
+	}
+	input := filepath.Join(tmp, "input.fasta")
+	writeTestFasta(t, input, fasta.String())
+	taxonkit := filepath.Join(tmp, "taxonkit_input.tsv")
+	if err := os.WriteFile(taxonkit, []byte("processid\tspecies\nP1\tHomo sapiens\n"), 0o644); err != nil {
+		t.Fatalf("write taxonkit input: %v", err)
+	}
+	outDir := filepath.Join(tmp, "out")
+	job := splitJob{
+		Classifiers:   []string{"sintax"},
+		TaxonkitInput: taxonkit,
+		TaxdumpDir:    tmp,
+		RequireRanks:  []string{"genus", "species"},
+		OnEmptySeq:    emptySeqSkip,
+		OnDuplicateID: duplicateIDRename,
+	}
+	if err := splitOne(input, outDir, job); err != nil {
+		t.Fatalf("splitOne failed: %v", err)
+	}
+	seen, err := os.ReadFile(filepath.Join(outDir, "seen_train.fasta"))
+	if err != nil {
+		t.Fatalf("read seen_train: %v", err)
+	}
+	taxidMap, err := os.ReadFile(filepath.Join(outDir, "taxdump_pruned", "taxid.map"))
+	if err != nil {
+		t.Fatalf("read pruned taxid.map: %v", err)
+	}
+	sintax, err := os.ReadFile(filepath.Join(outDir, "formatted", "sintax.fasta"))
+	if err != nil {
+		t.Fatalf("read sintax: %v", err)
+	}
+	renamedSeen := 0
+	for _, line := range strings.Split(string(seen), "\n") {
+		id, ok := strings.CutPrefix(line, ">")
+		if !ok || id == "P1" {
+			continue
+		}
+		renamedSeen++
+		if !strings.Contains(string(taxidMap), id+"\t9606\n") {
+			t.Fatalf("pruned taxid.map missing renamed %s:\n%s", id, taxidMap)
+		}
+		if !strings.Contains(string(sintax), ">"+id+";tax=") {
+			t.Fatalf("sintax missing renamed %s:\n%s", id, sintax)
+		}
+	}
+	if renamedSeen == 0 {
+		t.Fatalf("fixture put no renamed IDs in seen_train:\n%s", seen)
+	}
+}
+
+func TestBarcodeGroupSize(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("size target is for 64-bit builds")
+	}
+	if got := unsafe.Sizeof(barcodeGroup{}); got != 16 {
+		t.Fatalf("barcodeGroup is %d bytes, want 16", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -16,8 +18,16 @@ type taxNode struct {
 
 type taxDump struct {
 	nodes map[int]taxNode
-	cache map[int]map[string]string
-	alias map[string]string
+	// children indexes nodes by parent, each list in taxid order. The root's
+	// self-parent link is not a child edge.
+	children map[int][]int
+	cache    map[int]map[string]string
+	idCache  map[int]map[string]int
+	alias    map[string]string
+	// merged maps old taxids to their replacement (merged.dmp); deleted holds
+	// taxids removed by delnodes.dmp. Both are empty when the files are absent.
+	merged  map[int]int
+	deleted map[int]struct{}
 }
 
 func loadTaxDump(nodesPath, namesPath string) (*taxDump, error) {
@@ -29,15 +39,74 @@ func loadTaxDump(nodesPath, namesPath string) (*taxDump, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := validateTaxNodes(nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", nodesPath, err)
+	}
+	dir := filepath.Dir(nodesPath)
+	merged, err := loadMerged(filepath.Join(dir, "merged.dmp"))
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := loadDelnodes(filepath.Join(dir, "delnodes.dmp"))
+	if err != nil {
+		return nil, err
+	}
 	return &taxDump{
-		nodes: nodes,
-		cache: make(map[int]map[string]string),
+		nodes:    nodes,
+		children: buildChildren(nodes),
+		merged:   merged,
+		deleted:  deleted,
+		cache:    make(map[int]map[string]string),
+		idCache:  make(map[int]map[string]int),
 		alias: map[string]string{
 			"superkingdom": "kingdom",
 		},
 	}, nil
 }
 
+// buildChildren inverts the parent links of nodes.
+func buildChildren(nodes map[int]taxNode) map[int][]int {
+	children := make(map[int][]int, len(nodes)/2)
+	for id, node := range nodes {
+		if node.parent == id {
+			continue
+		}
+		children[node.parent] = append(children[node.parent], id)
+	}
+	for _, ids := range children {
+		sort.Ints(ids)
+	}
+	return children
+}
+
+// subtree returns taxid and every node below it.
+func (t *taxDump) subtree(taxid int) map[int]struct{} {
+	out := make(map[int]struct{})
+	stack := []int{taxid}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, seen := out[cur]; seen {
+			continue
+		}
+		out[cur] = struct{}{}
+		stack = append(stack, t.children[cur]...)
+	}
+	return out
+}
+
+// taxidsByName returns the taxids whose scientific name is name, in order.
+func (t *taxDump) taxidsByName(name string) []int {
+	var ids []int
+	for id, node := range t.nodes {
+		if node.name == name {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
 func loadNames(path string) (map[int]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -48,7 +117,7 @@ func loadNames(path string) (map[int]string, error) {
 	}()
 
 	names := make(map[int]string, 1<<20)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 	for scanner.Scan() {
@@ -84,7 +153,7 @@ func loadNodes(path string, names map[int]string) (map[int]taxNode, error) {
 	}()
 
 	nodes := make(map[int]taxNode, 1<<20)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 	for scanner.Scan() {
@@ -113,6 +182,241 @@ func loadNodes(path string, names map[int]string) (map[int]taxNode, error) {
 	return nodes, nil
 }
 
+// taxRootID is the only node allowed to be its own parent.
+const taxRootID = 1
+
+// maxReportedTaxids caps how many offending taxids a validation error lists.
+const maxReportedTaxids = 10
+
+// validateTaxNodes checks that every parent chain ends at the root: no node
+// other than taxRootID is its own parent, every parent exists, and no chain
+// loops back on itself.
+func validateTaxNodes(nodes map[int]taxNode) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]uint8, len(nodes))
+	var orphans, selfLoops, cycles []int
+	path := make([]int, 0, 64)
+	for id := range nodes {
+		if state[id] != unvisited {
+			continue
+		}
+		path = path[:0]
+		cur := id
+		for {
+			s := state[cur]
+			if s == done {
+				break
+			}
+			if s == visiting {
+				// Report the cycle by its smallest member so it is listed once.
+				minID := cur
+				for i := len(path) - 1; i >= 0 && path[i] != cur; i-- {
+					minID = min(minID, path[i])
+				}
+				cycles = append(cycles, minID)
+				break
+			}
+			node, ok := nodes[cur]
+			if !ok {
+				orphans = append(orphans, path[len(path)-1])
+				break
+			}
+			state[cur] = visiting
+			path = append(path, cur)
+			if node.parent == cur {
+				if cur != taxRootID {
+					selfLoops = append(selfLoops, cur)
+				}
+				break
+			}
+			cur = node.parent
+		}
+		for _, p := range path {
+			state[p] = done
+		}
+	}
+
+	var problems []string
+	if len(cycles) > 0 {
+		problems = append(problems, "parent cycles through "+formatTaxidList(cycles))
+	}
+	if len(selfLoops) > 0 {
+		problems = append(problems, "non-root self-parents "+formatTaxidList(selfLoops))
+	}
+	if len(orphans) > 0 {
+		problems = append(problems, "parents missing from nodes.dmp for "+formatTaxidList(orphans))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid taxonomy: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// lineageRankOrder ranks taxdump ranks from most general; ranks missing
+// from it ("no rank", "clade", ...) are skipped by the monotonic check.
+var lineageRankOrder = map[string]int{
+	"superkingdom": 0,
+	"kingdom":      1,
+	"phylum":       2,
+	"class":        3,
+	"order":        4,
+	"family":       5,
+	"subfamily":    6,
+	"tribe":        7,
+	"genus":        8,
+	"species":      9,
+	"subspecies":   10,
+}
+
+// validateLineageMonotonic checks that every ranked node's nearest ranked
+// ancestor has a strictly more general rank, so each lineage to the root
+// lists ranks in order with none repeated. It reports the taxids whose
+// parent chain breaks the order (a family under a genus, a genus under a
+// genus). nodes must already pass validateTaxNodes.
+func validateLineageMonotonic(nodes map[int]taxNode) error {
+	type violation struct {
+		id, ancestor int
+	}
+	var repeated, inverted []int
+	var found []violation
+	for id, node := range nodes {
+		level, ok := lineageRankOrder[node.rank]
+		if !ok || id == taxRootID {
+			continue
+		}
+		cur := node.parent
+		for steps := 0; steps <= len(nodes) && cur != taxRootID; steps++ {
+			anc, ok := nodes[cur]
+			if !ok {
+				break
+			}
+			ancLevel, ranked := lineageRankOrder[anc.rank]
+			if !ranked {
+				cur = anc.parent
+				continue
+			}
+			if ancLevel >= level {
+				if ancLevel == level {
+					repeated = append(repeated, id)
+				} else {
+					inverted = append(inverted, id)
+				}
+				found = append(found, violation{id: id, ancestor: cur})
+			}
+			break
+		}
+	}
+	if len(repeated) == 0 && len(inverted) == 0 {
+		return nil
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].id < found[j].id })
+	examples := make([]string, 0, 3)
+	for _, v := range found[:min(len(found), 3)] {
+		examples = append(examples, fmt.Sprintf("%d (%s) under %d (%s)", v.id, nodes[v.id].rank, v.ancestor, nodes[v.ancestor].rank))
+	}
+	var problems []string
+	if len(inverted) > 0 {
+		problems = append(problems, "ranks out of order at "+formatTaxidList(inverted))
+	}
+	if len(repeated) > 0 {
+		problems = append(problems, "ranks repeated at "+formatTaxidList(repeated))
+	}
+	return fmt.Errorf("lineage not monotonic: %s (e.g. %s)", strings.Join(problems, "; "), strings.Join(examples, ", "))
+}
+
+// formatTaxidList renders sorted taxids, truncated to maxReportedTaxids.
+func formatTaxidList(ids []int) string {
+	sort.Ints(ids)
+	parts := make([]string, 0, min(len(ids), maxReportedTaxids))
+	for i, id := range ids {
+		if i == maxReportedTaxids {
+			break
+		}
+		parts = append(parts, strconv.Itoa(id))
+	}
+	out := strings.Join(parts, ",")
+	if len(ids) > maxReportedTaxids {
+		out += fmt.Sprintf(" (and %d more)", len(ids)-maxReportedTaxids)
+	}
+	return out
+}
+
+// loadMerged reads an optional merged.dmp (old_taxid | new_taxid). A missing
+// file yields an empty map.
+func loadMerged(path string) (map[int]int, error) {
+	merged := make(map[int]int)
+	err := scanOptionalDmp(path, func(fields []string) {
+		if len(fields) < 2 {
+			return
+		}
+		oldID, err1 := strconv.Atoi(fields[0])
+		newID, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			return
+		}
+		merged[oldID] = newID
+	})
+	return merged, err
+}
+
+// loadDelnodes reads an optional delnodes.dmp (taxid). A missing file yields
+// an empty set.
+func loadDelnodes(path string) (map[int]struct{}, error) {
+	deleted := make(map[int]struct{})
+	err := scanOptionalDmp(path, func(fields []string) {
+		if len(fields) < 1 {
+			return
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil {
+			deleted[id] = struct{}{}
+		}
+	})
+	return deleted, err
+}
+
+func scanOptionalDmp(path string, onFields func([]string)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", filepath.Base(path), err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
+	for scanner.Scan() {
+		onFields(parseDmpLine(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// resolve follows merged.dmp remaps until it reaches a current taxid.
+func (t *taxDump) resolve(taxid int) int {
+	for i := 0; i < 64; i++ {
+		next, ok := t.merged[taxid]
+		if !ok || next == taxid {
+			break
+		}
+		taxid = next
+	}
+	return taxid
+}
+
+// isDeleted reports whether taxid is listed in delnodes.dmp.
+func (t *taxDump) isDeleted(taxid int) bool {
+	_, ok := t.deleted[taxid]
+	return ok
+}
+
 func parseDmpLine(line string) []string {
 	raw := strings.Split(line, "|")
 	out := make([]string, 0, len(raw))
@@ -126,14 +430,29 @@ func parseDmpLine(line string) []string {
 }
 
 func (t *taxDump) lineage(taxid int) map[string]string {
+	names, _ := t.lineageWithIDs(taxid)
+	return names
+}
+
+// lineageWithIDs returns the rank->name lineage along with the node taxid
+// that supplied each rank. Every named rank on the path is included, so
+// non-standard ranks (superfamily, infraorder, ...) can be required like the
+// canonical ones; "no rank" nodes are skipped since a path holds many.
+// Merged taxids resolve to their replacement's lineage; deleted taxids yield
+// an empty lineage (see isDeleted).
+func (t *taxDump) lineageWithIDs(taxid int) (map[string]string, map[string]int) {
 	if taxid <= 0 {
-		return nil
+		return nil, nil
 	}
 	if cached, ok := t.cache[taxid]; ok {
-		return cached
+		return cached, t.idCache[taxid]
 	}
 	lineage := make(map[string]string, 8)
-	cur := taxid
+	ids := make(map[string]int, 8)
+	cur := t.resolve(taxid)
+	if t.isDeleted(cur) {
+		cur = 0
+	}
 	seen := 0
 	for cur > 0 && seen < 64 {
 		seen++
@@ -148,6 +467,14 @@ func (t *taxDump) lineage(taxid int) map[string]string {
 		if rank != "" && rank != "no rank" && node.name != "" {
 			if _, exists := lineage[rank]; !exists {
 				lineage[rank] = node.name
+				ids[rank] = cur
+			}
+			// Aliased ranks stay requirable under their own name too.
+			if rank != node.rank {
+				if _, exists := lineage[node.rank]; !exists {
+					lineage[node.rank] = node.name
+					ids[node.rank] = cur
+				}
 			}
 		}
 		if node.parent == cur {
@@ -156,5 +483,6 @@ func (t *taxDump) lineage(taxid int) map[string]string {
 		cur = node.parent
 	}
 	t.cache[taxid] = lineage
-	return lineage
+	t.idCache[taxid] = ids
+	return lineage, ids
 }
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTaxDumpMergedAndDeleted(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "merged.dmp"), []byte("999\t|\t9606\t|\n"), 0o644); err != nil {
+		t.Fatalf("write merged.dmp: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "delnodes.dmp"), []byte("888\t|\n"), 0o644); err != nil {
+		t.Fatalf("write delnodes.dmp: %v", err)
+	}
+
+	dump, err := loadTaxDump(filepath.Join(tmp, "nodes.dmp"), filepath.Join(tmp, "names.dmp"))
+	if err != nil {
+		t.Fatalf("loadTaxDump failed: %v", err)
+	}
+	lineage := dump.lineage(999)
+	if lineage["species"] != "Homo sapiens" || lineage["genus"] != "Homo" {
+		t.Fatalf("merged taxid lineage=%v want Homo sapiens lineage", lineage)
+	}
+	if dump.isDeleted(999) {
+		t.Fatalf("merged taxid reported as deleted")
+	}
+	if !dump.isDeleted(888) {
+		t.Fatalf("expected 888 to be reported as deleted")
+	}
+	if got := dump.lineage(888); len(got) != 0 {
+		t.Fatalf("deleted taxid lineage=%v want empty", got)
+	}
+}
+
+func writeTestNodes(t *testing.T, dir, nodes string) (string, string) {
+	t.Helper()
+	nodesPath := filepath.Join(dir, "nodes.dmp")
+	namesPath := filepath.Join(dir, "names.dmp")
+	if err := os.WriteFile(nodesPath, []byte(nodes), 0o644); err != nil {
+		t.Fatalf("write nodes.dmp: %v", err)
+	}
+	if err := os.WriteFile(namesPath, []byte("1\t|\troot\t|\t\t|\tscientific name\t|\n"), 0o644); err != nil {
+		t.Fatalf("write names.dmp: %v", err)
+	}
+	return nodesPath, namesPath
+}
+
+func TestLoadTaxDumpRejectsCycle(t *testing.T) {
+	nodesPath, namesPath := writeTestNodes(t, t.TempDir(), strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tkingdom\t|",
+		"3\t|\t5\t|\tphylum\t|",
+		"4\t|\t3\t|\tclass\t|",
+		"5\t|\t4\t|\torder\t|",
+		"6\t|\t5\t|\tfamily\t|",
+		"7\t|\t7\t|\tgenus\t|",
+	}, "\n")+"\n")
+	_, err := loadTaxDump(nodesPath, namesPath)
+	if err == nil {
+		t.Fatalf("expected cyclic nodes.dmp to fail")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "parent cycles through 3") {
+		t.Fatalf("expected cycle through 3 in error, got: %v", err)
+	}
+	if !strings.Contains(msg, "non-root self-parents 7") {
+		t.Fatalf("expected self-parent 7 in error, got: %v", err)
+	}
+}
+
+func TestLoadTaxDumpRejectsOrphanParent(t *testing.T) {
+	nodesPath, namesPath := writeTestNodes(t, t.TempDir(), strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tkingdom\t|",
+		"3\t|\t42\t|\tphylum\t|",
+		"4\t|\t3\t|\tclass\t|",
+	}, "\n")+"\n")
+	_, err := loadTaxDump(nodesPath, namesPath)
+	if err == nil {
+		t.Fatalf("expected orphan-parent nodes.dmp to fail")
+	}
+	if !strings.Contains(err.Error(), "parents missing from nodes.dmp for 3") {
+		t.Fatalf("expected orphan 3 in error, got: %v", err)
+	}
+}
+
+func TestValidateLineageMonotonic(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTaxdump(t, dir)
+	if err := verifyTaxdump(dir, true); err != nil {
+		t.Fatalf("well-formed taxdump failed: %v", err)
+	}
+
+	nodesPath, namesPath := writeTestNodes(t, t.TempDir(), strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tkingdom\t|",
+		"3\t|\t2\t|\tphylum\t|",
+		"7\t|\t3\t|\tgenus\t|",
+		"6\t|\t7\t|\tfamily\t|",
+		"8\t|\t6\t|\tno rank\t|",
+		"9\t|\t8\t|\tfamily\t|",
+		"10\t|\t7\t|\tspecies\t|",
+	}, "\n")+"\n")
+	dump, err := loadTaxDump(nodesPath, namesPath)
+	if err != nil {
+		t.Fatalf("loadTaxDump: %v", err)
+	}
+	err = validateLineageMonotonic(dump.nodes)
+	if err == nil {
+		t.Fatalf("expected a family under a genus to fail")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "ranks out of order at 6") {
+		t.Fatalf("expected taxid 6 out of order, got: %v", err)
+	}
+	if !strings.Contains(msg, "ranks repeated at 9") || !strings.Contains(msg, "9 (family) under 6 (family)") {
+		t.Fatalf("expected family 9 repeating through the unranked node, got: %v", err)
+	}
+	if strings.Contains(msg, "10") {
+		t.Fatalf("species 10 under genus 7 is fine, got: %v", err)
+	}
+}
+
+func TestLineageKeepsAliasedRank(t *testing.T) {
+	nodesPath, namesPath := writeTestNodes(t, t.TempDir(), strings.Join([]string{
+		"1\t|\t1\t|\tno rank\t|",
+		"2\t|\t1\t|\tsuperkingdom\t|",
+		"3\t|\t2\t|\tsuperfamily\t|",
+	}, "\n")+"\n")
+	names := "2\t|\tEukaryota\t|\t\t|\tscientific name\t|\n3\t|\tHominoidea\t|\t\t|\tscientific name\t|\n"
+	if err := os.WriteFile(namesPath, []byte(names), 0o644); err != nil {
+		t.Fatalf("write names.dmp: %v", err)
+	}
+	dump, err := loadTaxDump(nodesPath, namesPath)
+	if err != nil {
+		t.Fatalf("loadTaxDump: %v", err)
+	}
+	_, ids := dump.lineageWithIDs(3)
+	if ids["kingdom"] != 2 || ids["superkingdom"] != 2 || ids["superfamily"] != 3 {
+		t.Fatalf("lineage ids=%v want kingdom/superkingdom=2 superfamily=3", ids)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// tempRegistry hands out predictable scratch names
+// (boldkit-<tag>-<pid>-<seq>.tmp) and remembers them so they can be removed
+// when the process exits, including via fatalf or a panic in Execute.
+type tempRegistry struct {
+	mu    sync.Mutex
+	seq   int
+	paths map[string]struct{}
+}
+
+var tempFiles = &tempRegistry{paths: make(map[string]struct{})}
+
+// createTempFile opens a new scratch file in dir (os.TempDir() when empty).
+func createTempFile(dir, tag string) (*os.File, error) {
+	return tempFiles.create(dir, tag)
+}
+
+// removeTempFile deletes a scratch file and drops it from the registry.
+func removeTempFile(path string) {
+	tempFiles.remove(path)
+}
+
+// cleanupTempFiles removes every scratch file still registered.
+func cleanupTempFiles() {
+	tempFiles.cleanup()
+}
+
+func (r *tempRegistry) create(dir, tag string) (*os.File, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if tag == "" {
+		tag = "scratch"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for attempt := 0; attempt < 1000; attempt++ {
+		r.seq++
+		name := fmt.Sprintf("boldkit-%s-%d-%d.tmp", safeTag(tag), os.Getpid(), r.seq)
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("create temp file: %w", err)
+		}
+		r.paths[path] = struct{}{}
+		debugf("temp file created: %s", path)
+		return f, nil
+	}
+	return nil, fmt.Errorf("create temp file: no free name for tag %q in %s", tag, dir)
+}
+
+// track registers an existing path for cleanup.
+func (r *tempRegistry) track(path string) {
+	r.mu.Lock()
+	r.paths[path] = struct{}{}
+	r.mu.Unlock()
+}
+
+// untrack forgets path without removing it.
+func (r *tempRegistry) untrack(path string) {
+	r.mu.Lock()
+	delete(r.paths, path)
+	r.mu.Unlock()
+}
+
+func (r *tempRegistry) remove(path string) {
+	r.mu.Lock()
+	delete(r.paths, path)
+	r.mu.Unlock()
+	if err := os.Remove(path); err == nil {
+		debugf("temp file removed: %s", path)
+	}
+}
+
+func (r *tempRegistry) cleanup() {
+	r.mu.Lock()
+	paths := make([]string, 0, len(r.paths))
+	for path := range r.paths {
+		paths = append(paths, path)
+	}
+	r.paths = make(map[string]struct{})
+	r.mu.Unlock()
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := os.Remove(path); err == nil {
+			debugf("temp file cleaned up: %s", path)
+		}
+	}
+}
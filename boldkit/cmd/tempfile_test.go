@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestTempRegistryNamingAndCleanup(t *testing.T) {
+	dir := t.TempDir()
+	reg := &tempRegistry{paths: make(map[string]struct{})}
+
+	f1, err := reg.create(dir, "split")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_ = f1.Close()
+	f2, err := reg.create(dir, "split")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_ = f2.Close()
+
+	pattern := regexp.MustCompile(`^boldkit-split-\d+-\d+\.tmp$`)
+	for _, path := range []string{f1.Name(), f2.Name()} {
+		if !pattern.MatchString(filepath.Base(path)) {
+			t.Fatalf("unexpected temp name %s", filepath.Base(path))
+		}
+	}
+	if f1.Name() == f2.Name() {
+		t.Fatalf("expected distinct temp names, got %s twice", f1.Name())
+	}
+
+	reg.cleanup()
+	for _, path := range []string{f1.Name(), f2.Name()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed after cleanup", path)
+		}
+	}
+}
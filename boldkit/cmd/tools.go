@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// exitMissingTool is the exit status when a required external tool cannot be
+// found, matching the shell's "command not found".
+const exitMissingTool = 127
+
+// MissingToolError reports an external tool that is not installed, or an
+// explicit -*-bin override that does not point at an executable.
+type MissingToolError struct {
+	Tool string
+	// Path is the explicit binary override, or "" when PATH was searched.
+	Path string
+	// Searched is the PATH value at lookup time.
+	Searched string
+	// Flag is the command-line flag that overrides the lookup.
+	Flag string
+}
+
+func (e *MissingToolError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s not found at %s (check %s)", e.Tool, e.Path, e.Flag)
+	}
+	return fmt.Sprintf("%s not found in PATH (install it or set %s)", e.Tool, e.Flag)
+}
+
+// hint is the multi-line install advice fatalf prints after the error.
+func (e *MissingToolError) hint() string {
+	msg := fmt.Sprintf("hint: %s is required for this step.", e.Tool)
+	if install, ok := toolInstallHints[e.Tool]; ok {
+		msg += " Install it with: " + install
+	}
+	msg += fmt.Sprintf("\nhint: or pass %s /path/to/%s", e.Flag, e.Tool)
+	if e.Path == "" {
+		msg += fmt.Sprintf("\nhint: PATH searched: %s", e.Searched)
+	}
+	return msg
+}
+
+var toolInstallHints = map[string]string{
+	"taxonkit": "conda install -c bioconda taxonkit (or https://bioinf.shenwei.me/taxonkit/download/)",
+}
+
+// lookupTool resolves an external tool: override when set, otherwise name on
+// PATH, then name.exe. Failures are *MissingToolError.
+func lookupTool(name, override, flagName string) (string, error) {
+	if override != "" {
+		p, err := exec.LookPath(override)
+		if err != nil {
+			return "", &MissingToolError{Tool: name, Path: override, Searched: os.Getenv("PATH"), Flag: flagName}
+		}
+		return p, nil
+	}
+	for _, c := range []string{name, name + ".exe"} {
+		if p, err := exec.LookPath(c); err == nil {
+			return p, nil
+		}
+	}
+	return "", &MissingToolError{Tool: name, Searched: os.Getenv("PATH"), Flag: flagName}
+}
+
+// missingToolIn returns the first *MissingToolError wrapped by an error in
+// args, so fatalf can add the hint and exit status.
+func missingToolIn(args []any) *MissingToolError {
+	for _, a := range args {
+		err, ok := a.(error)
+		if !ok {
+			continue
+		}
+		var missing *MissingToolError
+		if errors.As(err, &missing) {
+			return missing
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupToolMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := lookupTool("taxonkit", "", "-taxonkit-bin")
+	wrapped := fmt.Errorf("taxonkit create-taxdump: %w", err)
+	missing := missingToolIn([]any{wrapped})
+	if missing == nil {
+		t.Fatalf("expected MissingToolError, got %v", err)
+	}
+	if missing.Tool != "taxonkit" || missing.Path != "" {
+		t.Fatalf("unexpected error fields: %+v", missing)
+	}
+	if !strings.Contains(missing.hint(), "bioconda") {
+		t.Fatalf("hint missing install advice: %q", missing.hint())
+	}
+
+	override := filepath.Join(t.TempDir(), "taxonkit")
+	_, err = lookupTool("taxonkit", override, "-taxonkit-bin")
+	var target *MissingToolError
+	if !errors.As(err, &target) || target.Path != override {
+		t.Fatalf("expected MissingToolError for override, got %v", err)
+	}
+	if missingToolIn([]any{"taxonkit", errors.New("other")}) != nil {
+		t.Fatalf("unrelated errors should not match")
+	}
+}
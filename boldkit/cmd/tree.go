@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type treeConfig struct {
+	Input      string
+	TaxdumpDir string
+	TaxidMap   string
+	Output     string
+}
+
+func runTree(args []string) {
+	fs := flag.NewFlagSet("tree", flag.ExitOnError)
+	input := fs.String("input", "", "Reference FASTA whose record taxids induce the tree (optionally .gz)")
+	taxdumpDir := fs.String("taxdump-dir", "bold-taxdump", "Taxdump directory (nodes.dmp, names.dmp)")
+	taxidMap := fs.String("taxid-map", "", "processid -> taxid map (default: <taxdump-dir>/taxid.map)")
+	output := fs.String("output", "taxonomy.nwk", "Output Newick tree")
+	force := fs.Bool("force", false, "Overwrite existing outputs")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	if *input == "" {
+		fatalf("input is required")
+	}
+	if !*force && fileExists(*output) {
+		fmt.Fprintf(os.Stderr, "Output exists, skipping: %s\n", *output)
+		return
+	}
+	cfg := treeConfig{
+		Input:      *input,
+		TaxdumpDir: *taxdumpDir,
+		TaxidMap:   *taxidMap,
+		Output:     *output,
+	}
+	if err := writeTaxonomyTree(cfg); err != nil {
+		fatalf("tree failed: %v", err)
+	}
+}
+
+// writeTaxonomyTree writes the taxonomy induced by the input records' taxids
+// as Newick. Every node is labelled with its taxon name, so internal nodes
+// carry labels and multifurcations are written as-is.
+func writeTaxonomyTree(cfg treeConfig) error {
+	mapPath := cfg.TaxidMap
+	if mapPath == "" {
+		mapPath = filepath.Join(cfg.TaxdumpDir, "taxid.map")
+	}
+	pidToTaxid, err := loadTaxidMap(mapPath)
+	if err != nil {
+		return err
+	}
+	dump, err := loadTaxDump(filepath.Join(cfg.TaxdumpDir, "nodes.dmp"), filepath.Join(cfg.TaxdumpDir, "names.dmp"))
+	if err != nil {
+		return err
+	}
+
+	in, err := openInput(cfg.Input)
+	if err != nil {
+		return fmt.Errorf("open input: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	keep := make(map[int]struct{}, 1<<12)
+	var records, missing int
+	err = parseSequences(in, func(rec fastaRecord) error {
+		records++
+		taxid, ok := pidToTaxid[rec.id]
+		if !ok || dump.isDeleted(taxid) {
+			missing++
+			return nil
+		}
+		addAncestors(dump.nodes, dump.resolve(taxid), keep)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		warnf("missing_taxid", missing, "tree: %d records without a usable taxid were left out", missing)
+	}
+	if len(keep) == 0 {
+		return fmt.Errorf("no input records map to taxonomy nodes")
+	}
+
+	if dir := filepath.Dir(cfg.Output); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create output dir: %w", err)
+		}
+	}
+	f, err := os.Create(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	w := bufio.NewWriterSize(f, writerBufferSize)
+	writeNewick(w, buildTaxTree(dump.nodes, keep))
+	if _, err := w.WriteString(";\n"); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush output: %w", err)
+	}
+	logf("tree: records=%d nodes=%d -> %s", records, len(keep), cfg.Output)
+	return nil
+}
+
+// writeNewick renders node without the closing ";". Write errors surface at
+// the caller's Flush.
+func writeNewick(w *bufio.Writer, node *taxTreeNode) {
+	if len(node.Children) > 0 {
+		_ = w.WriteByte('(')
+		for i, child := range node.Children {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			writeNewick(w, child)
+		}
+		_ = w.WriteByte(')')
+	}
+	_, _ = w.WriteString(newickLabel(node.Name))
+}
+
+// newickLabel single-quotes names containing Newick punctuation, whitespace
+// or underscores (which unquoted labels read as spaces), doubling embedded
+// quotes.
+func newickLabel(name string) string {
+	if name != "" && !strings.ContainsAny(name, " \t\r\n()[]':;,_") {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTaxonomyTreeNewick(t *testing.T) {
+	tmp := t.TempDir()
+	writeTestTaxdump(t, tmp)
+	input := filepath.Join(tmp, "ref.fasta")
+	writeTestFasta(t, input, ">P1\nACGT\n>P2\nACGT\n>P1b\nACGT\n>P3\nACGT\n")
+	if err := os.WriteFile(filepath.Join(tmp, "taxid.map"), []byte("P1\t9606\nP1b\t9606\nP2\t9615\n"), 0o644); err != nil {
+		t.Fatalf("write taxid.map: %v", err)
+	}
+
+	output := filepath.Join(tmp, "out", "taxonomy.nwk")
+	if err := writeTaxonomyTree(treeConfig{Input: input, TaxdumpDir: tmp, Output: output}); err != nil {
+		t.Fatalf("writeTaxonomyTree failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := "((((((('Homo sapiens')Homo)Hominidae)Primates,((('Canis lupus')Canis)Canidae)Carnivora)Mammalia)Chordata)Animalia)root;\n"
+	if string(data) != want {
+		t.Fatalf("newick=%q want %q", data, want)
+	}
+}
+
+func TestNewickLabelEscaping(t *testing.T) {
+	cases := map[string]string{
+		"Homo":          "Homo",
+		"Homo sapiens":  "'Homo sapiens'",
+		"O'Brien":       "'O''Brien'",
+		"sp._BOLD:AAA1": "'sp._BOLD:AAA1'",
+		"Genus (Sub)":   "'Genus (Sub)'",
+		"":              "''",
+	}
+	for in, want := range cases {
+		if got := newickLabel(in); got != want {
+			t.Fatalf("newickLabel(%q)=%q want %q", in, got, want)
+		}
+	}
+}
@@ -30,6 +30,8 @@ type Options struct {
 	Progress             *progress
 	SkipProgressFirstRow bool
 	Timeout              time.Duration
+	// Context stops the parse once done; nil means runCtx.
+	Context context.Context
 }
 
 // Row is a view over a TSV line. Fields point into an internal buffer and are
@@ -76,14 +78,15 @@ type parseResult struct {
 	buf  *bufferRef
 }
 
-// DefaultOptions returns a tuned baseline for large TSVs.
+// DefaultOptions returns a tuned baseline for large TSVs. Row order follows
+// the global -keep-order flag.
 func DefaultOptions() Options {
 	return Options{
 		BufferSize:    defaultBufferSize,
 		ChunkSize:     defaultChunkSize,
 		BatchLines:    defaultBatchLines,
 		Workers:       runtime.GOMAXPROCS(0),
-		PreserveOrder: true,
+		PreserveOrder: globalOpts.KeepOrder,
 		AllowCRLF:     true,
 	}
 }
@@ -116,6 +119,14 @@ func (o Options) withDefaults() Options {
 	return o
 }
 
+// ctx returns Context, or runCtx when it is unset.
+func (o Options) ctx() context.Context {
+	if o.Context == nil {
+		return runCtx
+	}
+	return o.Context
+}
+
 // ParseTSV streams a TSV from r, invoking onRow for each line. It keeps memory
 // bounded by reusing chunk buffers; row data is only valid inside onRow.
 func ParseTSV(r io.Reader, opts Options, onRow func(Row) error) error {
@@ -126,9 +137,9 @@ func ParseTSV(r io.Reader, opts Options, onRow func(Row) error) error {
 		cancel context.CancelFunc
 	)
 	if opts.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+		ctx, cancel = context.WithTimeout(opts.ctx(), opts.Timeout)
 	} else {
-		ctx, cancel = context.WithCancel(context.Background())
+		ctx, cancel = context.WithCancel(opts.ctx())
 	}
 	defer cancel()
 
@@ -448,7 +459,28 @@ func consumeResults(ctx context.Context, opts Options, results <-chan parseResul
 			}
 		}
 	} else {
+		// The first batch still goes first so callers can treat the first
+		// row as the header; everything after it is delivered as parsed.
+		headerDone := false
 		for res := range results {
+			if err != nil {
+				res.buf.release()
+				continue
+			}
+			if !headerDone && res.seq != 0 {
+				pending[res.seq] = res
+				continue
+			}
+			processResult(res)
+			if !headerDone {
+				headerDone = true
+				for seq, held := range pending {
+					delete(pending, seq)
+					processResult(held)
+				}
+			}
+		}
+		for _, res := range pending {
 			if err != nil {
 				res.buf.release()
 				continue
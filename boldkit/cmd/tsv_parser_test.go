@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseTSVUnorderedKeepsHeaderFirst(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("processid\tmarker_code\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&b, "P%d\tCOI-5P\n", i)
+	}
+	opts := Options{ChunkSize: 64, BatchLines: 2, Workers: 4}.WithPreserveOrder(false)
+
+	seen := make(map[string]bool)
+	first := ""
+	err := ParseTSV(strings.NewReader(b.String()), opts, func(row Row) error {
+		id := string(row.Fields[0])
+		if first == "" {
+			first = id
+		}
+		if seen[id] {
+			t.Fatalf("row %s delivered twice", id)
+		}
+		seen[id] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ParseTSV: %v", err)
+	}
+	if first != "processid" {
+		t.Fatalf("first row=%q want header", first)
+	}
+	if len(seen) != 501 {
+		t.Fatalf("rows=%d want 501", len(seen))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 )
 
 func fileExists(path string) bool {
@@ -42,6 +43,9 @@ func snapshotID(inputPath string) string {
 	if strings.HasSuffix(base, ".tsv") {
 		return strings.TrimSuffix(base, ".tsv")
 	}
+	if strings.HasSuffix(base, ".csv.gz") {
+		return strings.TrimSuffix(base, ".csv.gz")
+	}
 	return strings.TrimSuffix(base, filepath.Ext(base))
 }
 
@@ -148,21 +152,25 @@ func (r readCloser) Close() error {
 	return r.close()
 }
 
+// countReader counts bytes read. The count is atomic so progress can be
+// sampled from a goroutine other than the reader's.
 type countReader struct {
 	reader io.Reader
-	count  int64
+	count  atomic.Int64
 }
 
 func (r *countReader) Read(p []byte) (int, error) {
 	n, err := r.reader.Read(p)
-	r.count += int64(n)
+	r.count.Add(int64(n))
 	return n, err
 }
 
 func (r *countReader) Count() int64 {
-	return r.count
+	return r.count.Load()
 }
 
+// openInput opens path, gunzipping a .gz, for a read that stops once runCtx
+// is done.
 func openInput(path string) (io.ReadCloser, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -175,14 +183,14 @@ func openInput(path string) (io.ReadCloser, error) {
 			return nil, err
 		}
 		return readCloser{
-			reader: gz,
+			reader: contextReader(runCtx, gz),
 			close: func() error {
 				_ = gz.Close()
 				return f.Close()
 			},
 		}, nil
 	}
-	return f, nil
+	return readCloser{reader: contextReader(runCtx, f), close: f.Close}, nil
 }
 
 func openInputWithCounter(path string) (io.ReadCloser, *countReader, error) {
@@ -198,7 +206,7 @@ func openInputWithCounter(path string) (io.ReadCloser, *countReader, error) {
 			return nil, nil, err
 		}
 		return readCloser{
-			reader: gz,
+			reader: contextReader(runCtx, gz),
 			close: func() error {
 				_ = gz.Close()
 				return f.Close()
@@ -206,7 +214,7 @@ func openInputWithCounter(path string) (io.ReadCloser, *countReader, error) {
 		}, counter, nil
 	}
 	return readCloser{
-		reader: counter,
+		reader: contextReader(runCtx, counter),
 		close:  f.Close,
 	}, counter, nil
 }
@@ -220,10 +228,142 @@ func fileSize(path string) int64 {
 }
 
 func fatalf(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	appLogger.log("error", fmt.Sprintf(format, args...))
+	cleanupTempFiles()
+	if interruptedIn(args) {
+		removePartialOutputs()
+		os.Exit(exitInterrupted)
+	}
+	if missing := missingToolIn(args); missing != nil {
+		appLogger.log("error", missing.hint())
+		os.Exit(exitMissingTool)
+	}
 	os.Exit(1)
 }
 
 func isNone(b []byte) bool {
 	return len(b) == 4 && b[0] == 'N' && b[1] == 'o' && b[2] == 'n' && b[3] == 'e'
 }
+
+// placeholderSet holds labels treated as empty, keyed by foldPlaceholder so
+// matching is case-insensitive and exact after trimming and collapsing
+// whitespace.
+type placeholderSet map[string]struct{}
+
+func foldPlaceholder(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// newPlaceholderSet parses comma-separated token lists into a set; it is nil
+// when every list is empty.
+func newPlaceholderSet(lists ...string) placeholderSet {
+	var s placeholderSet
+	for _, list := range lists {
+		for _, token := range strings.Split(list, ",") {
+			token = foldPlaceholder(token)
+			if token == "" {
+				continue
+			}
+			if s == nil {
+				s = make(placeholderSet)
+			}
+			s[token] = struct{}{}
+		}
+	}
+	return s
+}
+
+func (s placeholderSet) has(value string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	_, ok := s[foldPlaceholder(value)]
+	return ok
+}
+
+// union returns a new set holding the labels of s and other.
+func (s placeholderSet) union(other placeholderSet) placeholderSet {
+	out := make(placeholderSet, len(s)+len(other))
+	for token := range s {
+		out[token] = struct{}{}
+	}
+	for token := range other {
+		out[token] = struct{}{}
+	}
+	return out
+}
+
+// labelTokens is the one set of labels extraction blanks. null holds the
+// -null-tokens list and matches exactly, the way taxonkit create-taxdump
+// --null does, so extract and the taxdump null the same names; placeholders
+// holds -placeholder-tokens and protocol built-ins and matches through
+// placeholderSet.
+type labelTokens struct {
+	null         map[string]struct{}
+	placeholders placeholderSet
+}
+
+// newLabelTokens builds the token set from a -null-tokens list (split like
+// parseNullTokens) and a -placeholder-tokens list.
+func newLabelTokens(nullList, placeholderList string) labelTokens {
+	t := labelTokens{placeholders: newPlaceholderSet(placeholderList)}
+	for _, token := range parseNullTokens(nullList) {
+		if t.null == nil {
+			t.null = make(map[string]struct{})
+		}
+		t.null[token] = struct{}{}
+	}
+	return t
+}
+
+// withPlaceholders returns t with extra added to its placeholders.
+func (t labelTokens) withPlaceholders(extra placeholderSet) labelTokens {
+	t.placeholders = t.placeholders.union(extra)
+	return t
+}
+
+func (t labelTokens) has(value string) bool {
+	if _, ok := t.null[value]; ok {
+		return true
+	}
+	return t.placeholders.has(value)
+}
+
+// normalizeBytes is the none-protocol field normalization: "None" and the
+// labels in t become empty.
+func (t labelTokens) normalizeBytes(value []byte) []byte {
+	if isNone(value) || t.has(string(value)) {
+		return nil
+	}
+	return value
+}
+
+// isNulled reports whether a non-empty raw value is blanked by normalizeBytes.
+func (t labelTokens) isNulled(value []byte) bool {
+	return len(value) > 0 && t.normalizeBytes(value) == nil
+}
+
+// normalizeLabel trims and collapses whitespace in value and returns "" when
+// the result is one of the labels in t.
+func (t labelTokens) normalizeLabel(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" || t.has(value) {
+		return ""
+	}
+	return value
+}
+
+// defaultNullTokens is the -null-tokens default: the labels passed to
+// taxonkit create-taxdump --null, and blanked by extract so both stages agree.
+const defaultNullTokens = "None,NULL,NA"
+
+// parseNullTokens splits a -null-tokens list, trimming and dropping blanks.
+func parseNullTokens(list string) []string {
+	var tokens []string
+	for _, token := range strings.Split(list, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
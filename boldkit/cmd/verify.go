@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumReport is the outcome of verifyChecksums, one file name per entry.
+type checksumReport struct {
+	OK       []string
+	Mismatch []string
+	Missing  []string
+}
+
+func (r checksumReport) failed() bool {
+	return len(r.Mismatch) > 0 || len(r.Missing) > 0
+}
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	sums := fs.String("checksums", filepath.Join("releases", checksumFileName(checksumAlgoSHA256)), "Checksum file written by package (<ALGO>SUMS.txt)")
+	algoFlag := fs.String("checksum-algo", "", "Digest: sha256, sha512, or blake2b (default: from the checksum file name)")
+	taxdumpDir := fs.String("taxdump-dir", "", "Also check this taxdump's parent chains (checksums are then skipped unless -checksums is given)")
+	monotonic := fs.Bool("validate-lineage-monotonic", false, "With -taxdump-dir, fail when a lineage repeats a rank or lists ranks out of order")
+	if err := fs.Parse(args); err != nil {
+		fatalf("parse args failed: %v", err)
+	}
+	if *monotonic && *taxdumpDir == "" {
+		fatalf("validate-lineage-monotonic requires -taxdump-dir")
+	}
+	checkSums := *taxdumpDir == ""
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "checksums" {
+			checkSums = true
+		}
+	})
+	if *taxdumpDir != "" {
+		if err := verifyTaxdump(*taxdumpDir, *monotonic); err != nil {
+			fatalf("verify failed: %v", err)
+		}
+		logf("verify: taxdump %s ok", *taxdumpDir)
+	}
+	if !checkSums {
+		return
+	}
+
+	algo := *algoFlag
+	if algo == "" {
+		algo = checksumAlgoFromFileName(*sums)
+	}
+	algo, err := normalizeChecksumAlgo(algo)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	report, err := verifyChecksums(*sums, algo)
+	if err != nil {
+		fatalf("verify failed: %v", err)
+	}
+	for _, name := range report.Mismatch {
+		logf("verify: MISMATCH %s", name)
+	}
+	for _, name := range report.Missing {
+		logf("verify: MISSING %s", name)
+	}
+	logf("verify: %s ok=%d mismatch=%d missing=%d", algo, len(report.OK), len(report.Mismatch), len(report.Missing))
+	if report.failed() {
+		fatalf("verify failed: %d of %d files did not match %s", len(report.Mismatch)+len(report.Missing), len(report.OK)+len(report.Mismatch)+len(report.Missing), filepath.Base(*sums))
+	}
+}
+
+// verifyTaxdump loads dir's nodes.dmp, which rejects cycles and orphaned
+// parents, and with monotonic also checks rank order along each lineage.
+func verifyTaxdump(dir string, monotonic bool) error {
+	dump, err := loadTaxDump(filepath.Join(dir, "nodes.dmp"), filepath.Join(dir, "names.dmp"))
+	if err != nil {
+		return err
+	}
+	if !monotonic {
+		return nil
+	}
+	if err := validateLineageMonotonic(dump.nodes); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Join(dir, "nodes.dmp"), err)
+	}
+	return nil
+}
+
+// checksumAlgoFromFileName maps SHA512SUMS.txt and friends back to the
+// algorithm named by checksumFileName, defaulting to sha256.
+func checksumAlgoFromFileName(path string) string {
+	for _, algo := range []string{checksumAlgoSHA256, checksumAlgoSHA512, checksumAlgoBLAKE2b} {
+		if strings.EqualFold(filepath.Base(path), checksumFileName(algo)) {
+			return algo
+		}
+	}
+	return checksumAlgoSHA256
+}
+
+// verifyChecksums recomputes every "<digest>  <name>" line of sumsPath
+// against the file of that name in the same directory. A "*" binary-mode
+// marker before the name is accepted.
+func verifyChecksums(sumsPath, algo string) (checksumReport, error) {
+	var report checksumReport
+	f, err := os.Open(sumsPath)
+	if err != nil {
+		return report, fmt.Errorf("open checksums: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	dir := filepath.Dir(sumsPath)
+	scanner := bufio.NewScanner(contextReader(runCtx, f))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		want, name, ok := strings.Cut(text, " ")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if !ok || want == "" || name == "" {
+			return report, fmt.Errorf("%s line %d: expected \"<digest>  <file>\"", filepath.Base(sumsPath), line)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !fileExists(path) {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		got, err := checksumFile(path, algo)
+		if err != nil {
+			return report, err
+		}
+		if !strings.EqualFold(got, want) {
+			report.Mismatch = append(report.Mismatch, name)
+			continue
+		}
+		report.OK = append(report.OK, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("read checksums: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksumsFlagsTamperedFile(t *testing.T) {
+	releaseDir := t.TempDir()
+	for name, content := range map[string]string{
+		"marker_fastas.tar.gz":  ">P1\nACGT\n",
+		"bold-taxdump.tar.gz":   "1\t|\t1\t|\tno rank\t|\n",
+		"taxonkit_input.tsv.gz": "processid\n",
+	} {
+		if err := os.WriteFile(filepath.Join(releaseDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	sumPath := filepath.Join(releaseDir, checksumFileName(checksumAlgoSHA256))
+	if err := writeChecksums(releaseDir, sumPath, checksumAlgoSHA256, false); err != nil {
+		t.Fatalf("writeChecksums failed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(releaseDir, "marker_fastas.tar.gz"), []byte(">P1\nACGA\n"), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := os.Remove(filepath.Join(releaseDir, "taxonkit_input.tsv.gz")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	report, err := verifyChecksums(sumPath, checksumAlgoFromFileName(sumPath))
+	if err != nil {
+		t.Fatalf("verifyChecksums failed: %v", err)
+	}
+	if !report.failed() {
+		t.Fatalf("expected failure, got %+v", report)
+	}
+	if len(report.OK) != 1 || report.OK[0] != "bold-taxdump.tar.gz" {
+		t.Fatalf("ok=%v want [bold-taxdump.tar.gz]", report.OK)
+	}
+	if len(report.Mismatch) != 1 || report.Mismatch[0] != "marker_fastas.tar.gz" {
+		t.Fatalf("mismatch=%v want [marker_fastas.tar.gz]", report.Mismatch)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "taxonkit_input.tsv.gz" {
+		t.Fatalf("missing=%v want [taxonkit_input.tsv.gz]", report.Missing)
+	}
+	if algo := checksumAlgoFromFileName("SHA512SUMS.txt"); algo != checksumAlgoSHA512 {
+		t.Fatalf("algo from SHA512SUMS.txt=%q", algo)
+	}
+}